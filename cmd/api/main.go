@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"time"
@@ -9,7 +10,12 @@ import (
 	"github.com/groovypotato/PotaFlow/internal/auth"
 	"github.com/groovypotato/PotaFlow/internal/config"
 	"github.com/groovypotato/PotaFlow/internal/database"
+	"github.com/groovypotato/PotaFlow/internal/email"
+	"github.com/groovypotato/PotaFlow/internal/filewatch"
 	apphttp "github.com/groovypotato/PotaFlow/internal/http"
+	"github.com/groovypotato/PotaFlow/internal/orgs"
+	"github.com/groovypotato/PotaFlow/internal/scheduler"
+	"github.com/groovypotato/PotaFlow/internal/webhooks"
 	"github.com/groovypotato/PotaFlow/internal/workflows"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -49,15 +55,66 @@ func main() {
 		log.Fatal().Err(err).Msg("failed to load auth params")
 	}
 	authStore := auth.NewStore(db)
-	authSvc := auth.NewService(authStore, authParams, []byte(cfg.JWTSecret), cfg.JWTExpiry)
+	authSvc := auth.NewService(authStore, authParams, []byte(cfg.JWTSecret), cfg.JWTExpiry, cfg.RefreshTokenTTL)
+	lockout := auth.DefaultLockoutPolicy()
+	lockout.AccountThreshold = cfg.LoginLockoutThreshold
+	lockout.IPThreshold = cfg.LoginIPLockoutThreshold
+	lockout.LockoutDuration = cfg.LoginLockoutDuration
+	authSvc.SetLockoutPolicy(lockout)
+
+	var mailer email.Sender = email.LogSender{}
+	switch cfg.MailSender {
+	case "file":
+		mailer = email.FileSender{Dir: cfg.MailFileDir, From: cfg.MailFrom}
+	case "smtp":
+		mailer = email.SMTPSender{
+			Addr:     cfg.MailSMTPAddr,
+			From:     cfg.MailFrom,
+			Username: cfg.MailSMTPUsername,
+			Password: cfg.MailSMTPPassword,
+		}
+	}
+	authSvc.SetEmailSender(mailer, cfg.AppURL)
 
 	wfSvc := workflows.NewService(db)
+	// The schemas can't express every rule of the code that runs a trigger; reject what it would.
+	for typ, check := range map[string]func([]byte) error{
+		"webhook": func(raw []byte) error { _, err := webhooks.ParseConfig(raw); return err },
+		"email":   func(raw []byte) error { _, err := email.ParseConfig(raw); return err },
+		"file":    func(raw []byte) error { _, err := filewatch.ParseConfig(raw); return err },
+	} {
+		wfSvc.SetTriggerConfigCheck(typ, check)
+	}
+	for _, typ := range []string{scheduler.TriggerTypeCron, scheduler.TriggerTypeInterval, scheduler.TriggerTypeAt, scheduler.TriggerTypePoll} {
+		wfSvc.SetTriggerConfigCheck(typ, func(raw []byte) error {
+			_, err := scheduler.ParseConfig(typ, raw, time.Now())
+			return err
+		})
+	}
+	orgSvc := orgs.NewService(db)
+
+	router := apphttp.NewRouter(db, authSvc, wfSvc, orgSvc)
 
-	router := apphttp.NewRouter(db, authSvc, wfSvc)
+	if cfg.SMTPAddr != "" {
+		smtpSrv := &email.Server{
+			Addr:            cfg.SMTPAddr,
+			Hostname:        cfg.SMTPDomain,
+			MaxMessageBytes: cfg.SMTPMaxMessageBytes,
+			MaxConnections:  cfg.SMTPMaxConnections,
+			SessionTimeout:  cfg.SMTPSessionTimeout,
+			Backend:         email.NewReceiver(wfSvc, cfg.SMTPDomain),
+		}
+		go func() {
+			log.Info().Str("addr", cfg.SMTPAddr).Str("domain", cfg.SMTPDomain).Msg("starting SMTP listener")
+			if err := smtpSrv.ListenAndServe(context.Background()); err != nil {
+				log.Fatal().Err(err).Msg("smtp listener shut down unexpectedly")
+			}
+		}()
+	}
 
 	addr := ":8080"
 	log.Info().Str("addr", addr).Msg("starting API server")
-	if err := http.ListenAndServe(addr, router); err != nil {
+	if err := http.ListenAndServe(addr, apphttp.TrustProxies(cfg.TrustedProxies)(router)); err != nil {
 		log.Fatal().Err(err).Msg("server shut down unexpectedly")
 	}
 }
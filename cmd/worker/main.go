@@ -7,8 +7,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/groovypotato/PotaFlow/internal/actions"
 	"github.com/groovypotato/PotaFlow/internal/config"
 	"github.com/groovypotato/PotaFlow/internal/database"
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/internal/filewatch"
+	"github.com/groovypotato/PotaFlow/internal/plugins"
+	"github.com/groovypotato/PotaFlow/internal/scheduler"
 	"github.com/groovypotato/PotaFlow/internal/worker"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -28,11 +33,73 @@ func main() {
 	}
 	defer db.Close()
 
-	processor := worker.NewProcessor(db, 2*time.Second)
-
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	sqlExec := actions.NewSQLExecutor(actions.NewEnvCredentials(db))
+	defer sqlExec.Close()
+
+	registry := actions.NewRegistry()
+	processor := worker.NewProcessor(db, registry, 2*time.Second, cfg.StepTimeout)
+	builtins := map[string]actions.Executor{
+		"transform":             actions.TransformExecutor{},
+		"sql":                   sqlExec,
+		"http":                  actions.NewHTTPExecutor(nil, actions.NewWorkflowSecrets(db)),
+		worker.CallWorkflowType: processor.CallWorkflow(),
+	}
+	for actionType, exec := range builtins {
+		if err := registry.Register(actionType, exec); err != nil {
+			log.Fatal().Err(err).Msg("failed to register built-in actions")
+		}
+	}
+
+	var listeners []chan<- struct{}
+	if cfg.PluginDir != "" {
+		pluginMgr, err := plugins.Discover(ctx, cfg.PluginDir, registry)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to load plugins")
+		}
+		defer pluginMgr.Close()
+		if err := pluginMgr.Publish(ctx, sqlc.New(db)); err != nil {
+			log.Error().Err(err).Msg("failed to publish plugin types")
+		}
+		go func() {
+			_ = pluginMgr.Run(ctx, cfg.PluginHealthInterval)
+		}()
+
+		changes := make(chan struct{}, 1)
+		listeners = append(listeners, changes)
+		triggers := pluginMgr.Triggers(db, scheduler.NewAdvisoryLock(db, plugins.TriggerLockKey), cfg.SchedulerReloadInterval)
+		go func() {
+			_ = triggers.Run(ctx, changes)
+		}()
+	}
+
+	if cfg.SchedulerEnabled {
+		changes := make(chan struct{}, 1)
+		listeners = append(listeners, changes)
+		sched := scheduler.New(db, scheduler.NewAdvisoryLock(db, scheduler.LockKey), cfg.SchedulerReloadInterval)
+		go func() {
+			_ = sched.Run(ctx, changes)
+		}()
+	}
+	if cfg.FileTriggerRoot != "" {
+		watcher, err := filewatch.New(db, scheduler.NewAdvisoryLock(db, filewatch.LockKey), cfg.FileTriggerRoot, cfg.SchedulerReloadInterval)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to set up file triggers")
+		}
+		changes := make(chan struct{}, 1)
+		listeners = append(listeners, changes)
+		go func() {
+			if err := watcher.Run(ctx, changes); err != nil && err != context.Canceled {
+				log.Error().Err(err).Msg("file watcher exited with error")
+			}
+		}()
+	}
+	if len(listeners) > 0 {
+		go scheduler.Listen(ctx, db, listeners...)
+	}
+
 	log.Info().Msg("worker started")
 	if err := processor.Run(ctx); err != nil && err != context.Canceled {
 		log.Error().Err(err).Msg("worker exited with error")
@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Step is a single action invocation handed to an Executor.
+type Step struct {
+	RunID      string
+	WorkflowID string
+	ActionID   string
+	Type       string
+	Position   int32
+	Config     json.RawMessage
+	Input      json.RawMessage
+}
+
+// Executor runs one kind of action and returns its output, which becomes the next step's input.
+type Executor interface {
+	Execute(ctx context.Context, step Step) (json.RawMessage, error)
+}
+
+// ExecutorFunc adapts a plain function to the Executor interface.
+type ExecutorFunc func(ctx context.Context, step Step) (json.RawMessage, error)
+
+// Execute calls f(ctx, step).
+func (f ExecutorFunc) Execute(ctx context.Context, step Step) (json.RawMessage, error) {
+	return f(ctx, step)
+}
+
+// ErrDuplicateType indicates an executor is already registered for the action type.
+var ErrDuplicateType = errors.New("action type already registered")
+
+// Registry maps actions.type values to the executors that handle them.
+type Registry struct {
+	mu        sync.RWMutex
+	executors map[string]Executor
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{executors: make(map[string]Executor)}
+}
+
+// Register adds an executor for actionType; registering the same type twice is an error.
+func (r *Registry) Register(actionType string, exec Executor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.executors[actionType]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateType, actionType)
+	}
+	r.executors[actionType] = exec
+	return nil
+}
+
+// Lookup returns the executor registered for actionType.
+func (r *Registry) Lookup(actionType string) (Executor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	exec, ok := r.executors[actionType]
+	return exec, ok
+}
+
+// Types lists the registered action types in sorted order.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.executors))
+	for t := range r.executors {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,41 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRegistryRegisterAndLookup(t *testing.T) {
+	r := NewRegistry()
+	echo := ExecutorFunc(func(ctx context.Context, step Step) (json.RawMessage, error) {
+		return step.Input, nil
+	})
+
+	if err := r.Register("echo", echo); err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+	if err := r.Register("echo", echo); !errors.Is(err, ErrDuplicateType) {
+		t.Fatalf("expected ErrDuplicateType, got %v", err)
+	}
+
+	exec, ok := r.Lookup("echo")
+	if !ok {
+		t.Fatalf("expected echo executor")
+	}
+	out, err := exec.Execute(context.Background(), Step{Input: json.RawMessage(`{"a":1}`)})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if string(out) != `{"a":1}` {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	if _, ok := r.Lookup("missing"); ok {
+		t.Fatalf("expected missing type to be absent")
+	}
+	if types := r.Types(); len(types) != 1 || types[0] != "echo" {
+		t.Fatalf("unexpected types: %v", types)
+	}
+}
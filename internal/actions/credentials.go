@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+)
+
+// ErrCredentialNotFound indicates no secret is stored under the requested name, or none that the
+// requesting workflow may use; the two aren't told apart so names can't be probed across tenants.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore resolves named secrets (DSNs, API tokens, ...) so action configs only carry
+// a credential name and never the secret itself. Secrets are scoped: workflowID is the workflow
+// whose step asks for the credential.
+type CredentialStore interface {
+	Credential(ctx context.Context, workflowID, name string) (string, error)
+}
+
+// credentialScopeSuffix marks the environment variable listing who may use a credential.
+const credentialScopeSuffix = "_ORGS"
+
+// EnvCredentials resolves credentials from worker environment variables: the credential
+// "analytics-db" is read from CREDENTIAL_ANALYTICS_DB and may only be used by workflows of the
+// organizations listed (comma-separated IDs) in CREDENTIAL_ANALYTICS_DB_ORGS; "*" shares it with
+// every organization.
+type EnvCredentials struct {
+	workflows workflowLookup
+}
+
+type workflowLookup interface {
+	GetWorkflowByID(ctx context.Context, id string) (sqlc.GetWorkflowByIDRow, error)
+}
+
+// NewEnvCredentials builds an EnvCredentials that looks up workflow organizations through a sqlc
+// DBTX (e.g., *pgxpool.Pool).
+func NewEnvCredentials(db sqlc.DBTX) *EnvCredentials {
+	return &EnvCredentials{workflows: sqlc.New(db)}
+}
+
+// Credential implements CredentialStore.
+func (c *EnvCredentials) Credential(ctx context.Context, workflowID, name string) (string, error) {
+	key := CredentialEnvKey(name)
+	if strings.HasSuffix(key, credentialScopeSuffix) {
+		// The variable would be another credential's scope list.
+		return "", fmt.Errorf("%w: %s", ErrCredentialNotFound, name)
+	}
+	wf, err := c.workflows.GetWorkflowByID(ctx, workflowID)
+	if err != nil {
+		return "", fmt.Errorf("look up workflow organization: %w", err)
+	}
+	val := os.Getenv(key)
+	if val == "" || !credentialAllowed(os.Getenv(key+credentialScopeSuffix), wf.OrgID) {
+		return "", fmt.Errorf("%w: %s (set %s and add organization %s to %s%s)",
+			ErrCredentialNotFound, name, key, wf.OrgID, key, credentialScopeSuffix)
+	}
+	return val, nil
+}
+
+func credentialAllowed(scope, orgID string) bool {
+	for _, entry := range strings.Split(scope, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "*" || (entry != "" && entry == orgID) {
+			return true
+		}
+	}
+	return false
+}
+
+// CredentialEnvKey maps a credential name to its environment variable.
+func CredentialEnvKey(name string) string {
+	var b strings.Builder
+	b.WriteString("CREDENTIAL_")
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/pkg/signature"
+)
+
+const (
+	defaultHTTPTimeout  = 30 * time.Second
+	maxHTTPTimeout      = 5 * time.Minute
+	maxHTTPResponseBody = 1 << 20
+)
+
+// HTTPConfig is the config of an "http" action. Body defaults to the step input. When Sign is
+// set, the request carries a signature.Header computed with the workflow's signing secret.
+type HTTPConfig struct {
+	Method    string            `json:"method,omitempty"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	TimeoutMS int               `json:"timeout_ms,omitempty"`
+	Sign      bool              `json:"sign,omitempty"`
+}
+
+// HTTPResult is the step output of an "http" action. Body holds the decoded JSON response, or
+// the raw text when the response isn't JSON.
+type HTTPResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    any               `json:"body"`
+}
+
+// SigningSecretSource looks up the secret a workflow's outgoing requests are signed with.
+type SigningSecretSource interface {
+	WorkflowSigningSecret(ctx context.Context, workflowID string) (string, error)
+}
+
+// WorkflowSecrets reads signing secrets from the workflows table.
+type WorkflowSecrets struct {
+	queries *sqlc.Queries
+}
+
+// NewWorkflowSecrets builds a WorkflowSecrets from a sqlc DBTX (e.g., *pgxpool.Pool).
+func NewWorkflowSecrets(db sqlc.DBTX) *WorkflowSecrets {
+	return &WorkflowSecrets{queries: sqlc.New(db)}
+}
+
+// WorkflowSigningSecret implements SigningSecretSource.
+func (s *WorkflowSecrets) WorkflowSigningSecret(ctx context.Context, workflowID string) (string, error) {
+	return s.queries.GetWorkflowSigningSecret(ctx, workflowID)
+}
+
+// HTTPExecutor implements the "http" action type.
+type HTTPExecutor struct {
+	client  *http.Client
+	secrets SigningSecretSource
+	now     func() time.Time
+}
+
+// NewHTTPExecutor builds an HTTPExecutor. secrets may be nil if no action signs its requests.
+func NewHTTPExecutor(client *http.Client, secrets SigningSecretSource) *HTTPExecutor {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &HTTPExecutor{client: client, secrets: secrets, now: time.Now}
+}
+
+// Execute sends the configured request. Responses with a status of 400 or above fail the step.
+func (e *HTTPExecutor) Execute(ctx context.Context, step Step) (json.RawMessage, error) {
+	cfg, err := parseHTTPConfig(step.Config)
+	if err != nil {
+		return nil, err
+	}
+	body := []byte(cfg.Body)
+	if len(body) == 0 {
+		body = step.Input
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.TimeoutMS)*time.Millisecond)
+	defer cancel()
+
+	var reader io.Reader
+	if len(body) > 0 && cfg.Method != http.MethodGet && cfg.Method != http.MethodHead {
+		reader = bytes.NewReader(body)
+	} else {
+		body = nil
+	}
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http config: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, val := range cfg.Headers {
+		req.Header.Set(name, val)
+	}
+	if cfg.Sign {
+		if e.secrets == nil {
+			return nil, errors.New("http action: signing is not configured on this worker")
+		}
+		secret, err := e.secrets.WorkflowSigningSecret(ctx, step.WorkflowID)
+		if err != nil {
+			return nil, fmt.Errorf("load signing secret: %w", err)
+		}
+		req.Header.Set(signature.Header, signature.Sign([]byte(secret), e.now(), body))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBody))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	result := HTTPResult{
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string, len(resp.Header)),
+		Body:    string(raw),
+	}
+	for name := range resp.Header {
+		result.Headers[name] = resp.Header.Get(name)
+	}
+	var decoded any
+	if len(raw) > 0 && json.Unmarshal(raw, &decoded) == nil {
+		result.Body = decoded
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("http action: %s %s returned %d", cfg.Method, cfg.URL, resp.StatusCode)
+	}
+	return json.Marshal(result)
+}
+
+func parseHTTPConfig(raw json.RawMessage) (HTTPConfig, error) {
+	var cfg HTTPConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return HTTPConfig{}, fmt.Errorf("invalid http config: %w", err)
+	}
+	if cfg.URL == "" {
+		return HTTPConfig{}, errors.New("invalid http config: url is required")
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.TimeoutMS <= 0 {
+		cfg.TimeoutMS = int(defaultHTTPTimeout / time.Millisecond)
+	}
+	if cfg.TimeoutMS > int(maxHTTPTimeout/time.Millisecond) {
+		cfg.TimeoutMS = int(maxHTTPTimeout / time.Millisecond)
+	}
+	return cfg, nil
+}
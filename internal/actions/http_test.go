@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/pkg/signature"
+)
+
+type staticSecrets map[string]string
+
+func (s staticSecrets) WorkflowSigningSecret(_ context.Context, workflowID string) (string, error) {
+	return s[workflowID], nil
+}
+
+func TestHTTPExecutorSignsRequest(t *testing.T) {
+	var verifyErr error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, verifyErr = signature.VerifyRequest(r, []byte("wf-secret"), 0)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	exec := NewHTTPExecutor(srv.Client(), staticSecrets{"wf-1": "wf-secret"})
+	out, err := exec.Execute(context.Background(), Step{
+		WorkflowID: "wf-1",
+		Config:     json.RawMessage(`{"url":"` + srv.URL + `","sign":true}`),
+		Input:      json.RawMessage(`{"event":"created"}`),
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if verifyErr != nil {
+		t.Fatalf("receiver rejected signature: %v", verifyErr)
+	}
+	var result HTTPResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if result.Status != http.StatusOK || result.Body.(map[string]any)["ok"] != true {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestHTTPExecutorUnsignedAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(signature.Header) != "" {
+			t.Errorf("expected no signature header")
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"custom":1}` {
+			t.Errorf("unexpected body: %s", body)
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	exec := NewHTTPExecutor(srv.Client(), nil)
+	_, err := exec.Execute(context.Background(), Step{
+		Config: json.RawMessage(`{"method":"put","url":"` + srv.URL + `","body":{"custom":1}}`),
+		Input:  json.RawMessage(`{"ignored":true}`),
+	})
+	if err == nil {
+		t.Fatalf("expected error for 502 response")
+	}
+
+	_, err = exec.Execute(context.Background(), Step{
+		Config: json.RawMessage(`{"url":"` + srv.URL + `","sign":true}`),
+	})
+	if err == nil {
+		t.Fatalf("expected error when signing without a secret source")
+	}
+}
+
+func TestParseHTTPConfig(t *testing.T) {
+	cfg, err := parseHTTPConfig(json.RawMessage(`{"url":"http://example.com","timeout_ms":99999999}`))
+	if err != nil {
+		t.Fatalf("parseHTTPConfig error: %v", err)
+	}
+	if cfg.Method != http.MethodPost || cfg.TimeoutMS != int(maxHTTPTimeout/time.Millisecond) {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	if _, err := parseHTTPConfig(json.RawMessage(`{}`)); err == nil {
+		t.Fatalf("expected error for missing url")
+	}
+}
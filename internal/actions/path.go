@@ -0,0 +1,302 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Paths address values inside decoded JSON documents. The syntax is a small JSONPath subset:
+//
+//	$                 the whole document (an empty path means the same)
+//	user.name         object keys separated by dots; a leading "$." is optional
+//	items[0]          array index
+//	items[*].id       every element of an array
+//	['key.with.dots'] quoted key
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+type path []segment
+
+func parsePath(raw string) (path, error) {
+	s := strings.TrimSpace(raw)
+	s = strings.TrimPrefix(s, "$")
+	var out path
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed '['", raw)
+			}
+			inner := s[i+1 : i+end]
+			i += end + 1
+			switch {
+			case inner == "*":
+				out = append(out, segment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				out = append(out, segment{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("path %q: invalid index %q", raw, inner)
+				}
+				out = append(out, segment{index: n, isIndex: true})
+			}
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			key := s[i : i+end]
+			i += end
+			if key == "*" {
+				out = append(out, segment{wildcard: true})
+			} else {
+				out = append(out, segment{key: key})
+			}
+		}
+	}
+	return out, nil
+}
+
+// Extract resolves rawPath against doc, a document decoded into generic values. Paths with a
+// wildcard return an array of every match; ok is false when a non-wildcard path is missing.
+func Extract(doc any, rawPath string) (value any, ok bool, err error) {
+	p, err := parsePath(rawPath)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok = p.get(doc)
+	return value, ok, nil
+}
+
+func (p path) hasWildcard() bool {
+	for _, seg := range p {
+		if seg.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// get resolves p against doc. Paths with a wildcard return an array of every match;
+// otherwise the single value is returned. ok is false when a non-wildcard path is missing.
+func (p path) get(doc any) (any, bool) {
+	if !p.hasWildcard() {
+		return lookup(doc, p)
+	}
+	var matches []any
+	collect(doc, p, &matches)
+	if matches == nil {
+		matches = []any{}
+	}
+	return matches, true
+}
+
+func lookup(doc any, p path) (any, bool) {
+	cur := doc
+	for _, seg := range p {
+		switch {
+		case seg.isIndex:
+			arr, ok := cur.([]any)
+			if !ok || seg.index >= len(arr) {
+				return nil, false
+			}
+			cur = arr[seg.index]
+		default:
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			cur, ok = obj[seg.key]
+			if !ok {
+				return nil, false
+			}
+		}
+	}
+	return cur, true
+}
+
+func collect(doc any, p path, out *[]any) {
+	if len(p) == 0 {
+		*out = append(*out, doc)
+		return
+	}
+	seg, rest := p[0], p[1:]
+	switch {
+	case seg.wildcard:
+		switch v := doc.(type) {
+		case []any:
+			for _, el := range v {
+				collect(el, rest, out)
+			}
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				collect(v[k], rest, out)
+			}
+		}
+	case seg.isIndex:
+		if arr, ok := doc.([]any); ok && seg.index < len(arr) {
+			collect(arr[seg.index], rest, out)
+		}
+	default:
+		if obj, ok := doc.(map[string]any); ok {
+			if v, ok := obj[seg.key]; ok {
+				collect(v, rest, out)
+			}
+		}
+	}
+}
+
+// update replaces every value addressed by p with fn(old) and returns the new document.
+// Missing object keys along a non-wildcard path are created; fn receives ok=false for them.
+func (p path) update(doc any, fn func(old any, ok bool) (any, error)) (any, error) {
+	return p.apply(doc, true, fn)
+}
+
+// updateExisting is update for values that exist: where p addresses a missing key or index,
+// including in some elements of a wildcard, the document is left untouched.
+func (p path) updateExisting(doc any, fn func(old any) (any, error)) (any, error) {
+	return p.apply(doc, false, func(old any, _ bool) (any, error) { return fn(old) })
+}
+
+func (p path) apply(doc any, create bool, fn func(old any, ok bool) (any, error)) (any, error) {
+	if len(p) == 0 {
+		return fn(doc, true)
+	}
+	seg, rest := p[0], p[1:]
+	switch {
+	case seg.wildcard:
+		switch v := doc.(type) {
+		case []any:
+			for i, el := range v {
+				nv, err := rest.apply(el, create, fn)
+				if err != nil {
+					return nil, err
+				}
+				v[i] = nv
+			}
+			return v, nil
+		case map[string]any:
+			for _, k := range sortedKeys(v) {
+				nv, err := rest.apply(v[k], create, fn)
+				if err != nil {
+					return nil, err
+				}
+				v[k] = nv
+			}
+			return v, nil
+		default:
+			return doc, nil
+		}
+	case seg.isIndex:
+		arr, ok := doc.([]any)
+		if !ok || seg.index >= len(arr) {
+			if !create {
+				return doc, nil
+			}
+			return nil, fmt.Errorf("index %d out of range", seg.index)
+		}
+		nv, err := rest.apply(arr[seg.index], create, fn)
+		if err != nil {
+			return nil, err
+		}
+		arr[seg.index] = nv
+		return arr, nil
+	default:
+		obj, ok := doc.(map[string]any)
+		if !create {
+			if _, exists := obj[seg.key]; !ok || !exists {
+				return doc, nil
+			}
+		}
+		if !ok {
+			if doc != nil {
+				return nil, fmt.Errorf("cannot set key %q on %s", seg.key, kindOf(doc))
+			}
+			obj = make(map[string]any)
+		}
+		child, exists := obj[seg.key]
+		var (
+			nv  any
+			err error
+		)
+		if len(rest) == 0 {
+			nv, err = fn(child, exists)
+		} else {
+			nv, err = rest.apply(child, create, fn)
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj[seg.key] = nv
+		return obj, nil
+	}
+}
+
+// remove deletes the value addressed by a non-wildcard path whose last segment is a key.
+func (p path) remove(doc any) {
+	if len(p) == 0 || p.hasWildcard() {
+		return
+	}
+	parent, ok := lookup(doc, p[:len(p)-1])
+	if !ok {
+		return
+	}
+	if obj, ok := parent.(map[string]any); ok && !p[len(p)-1].isIndex {
+		delete(obj, p[len(p)-1].key)
+	}
+}
+
+// decodeJSON decodes raw into generic values, keeping numbers as json.Number so they survive
+// a round trip untouched. Empty input decodes to an empty object.
+func decodeJSON(raw json.RawMessage) (any, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return map[string]any{}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func kindOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
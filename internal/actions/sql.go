@@ -0,0 +1,263 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultSQLMaxRows = 1000
+	maxSQLMaxRows     = 10000
+	defaultSQLTimeout = 30 * time.Second
+	maxSQLTimeout     = 5 * time.Minute
+)
+
+// SQLConfig is the config of a "sql" action. Params fill $1, $2, ... in Query; each one is either
+// a literal JSON value or {"path": "..."} to read the value from the step input.
+type SQLConfig struct {
+	Credential string            `json:"credential"`
+	Query      string            `json:"query"`
+	Params     []json.RawMessage `json:"params,omitempty"`
+	ReadOnly   *bool             `json:"read_only,omitempty"`
+	MaxRows    int               `json:"max_rows,omitempty"`
+	TimeoutMS  int               `json:"timeout_ms,omitempty"`
+}
+
+// SQLResult is the step output of a "sql" action.
+type SQLResult struct {
+	Rows         []map[string]any `json:"rows"`
+	RowCount     int              `json:"row_count"`
+	Truncated    bool             `json:"truncated"`
+	RowsAffected int64            `json:"rows_affected"`
+}
+
+// SQLExecutor implements the "sql" action type against external Postgres databases. Pools are
+// opened lazily per DSN and reused across runs.
+type SQLExecutor struct {
+	creds CredentialStore
+
+	mu    sync.Mutex
+	pools map[string]*pgxpool.Pool
+}
+
+// NewSQLExecutor builds a SQLExecutor that resolves DSNs through creds.
+func NewSQLExecutor(creds CredentialStore) *SQLExecutor {
+	return &SQLExecutor{
+		creds: creds,
+		pools: make(map[string]*pgxpool.Pool),
+	}
+}
+
+// Execute runs the configured query inside a transaction that is read-only unless read_only is
+// explicitly false, with statement_timeout set and at most max_rows rows returned.
+func (e *SQLExecutor) Execute(ctx context.Context, step Step) (json.RawMessage, error) {
+	cfg, err := parseSQLConfig(step.Config)
+	if err != nil {
+		return nil, err
+	}
+	input, err := decodeJSON(step.Input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sql input: %w", err)
+	}
+	params, err := resolveParams(cfg.Params, input)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn, err := e.creds.Credential(ctx, step.WorkflowID, cfg.Credential)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := e.pool(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(cfg.TimeoutMS) * time.Millisecond
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	accessMode := pgx.ReadWrite
+	if *cfg.ReadOnly {
+		accessMode = pgx.ReadOnly
+	}
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: accessMode})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	if _, err := tx.Exec(ctx, "SET LOCAL statement_timeout = "+strconv.Itoa(cfg.TimeoutMS)); err != nil {
+		return nil, fmt.Errorf("set statement timeout: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, cfg.Query, params...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := collectRows(rows, cfg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return json.Marshal(result)
+}
+
+// Close releases every pool the executor opened.
+func (e *SQLExecutor) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for dsn, pool := range e.pools {
+		pool.Close()
+		delete(e.pools, dsn)
+	}
+}
+
+func (e *SQLExecutor) pool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if pool, ok := e.pools[dsn]; ok {
+		return pool, nil
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		// The DSN is a secret, so it is deliberately left out of the error.
+		return nil, errors.New("invalid sql credential: could not parse DSN")
+	}
+	e.pools[dsn] = pool
+	return pool, nil
+}
+
+func parseSQLConfig(raw json.RawMessage) (SQLConfig, error) {
+	var cfg SQLConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return SQLConfig{}, fmt.Errorf("invalid sql config: %w", err)
+	}
+	if cfg.Credential == "" {
+		return SQLConfig{}, errors.New("invalid sql config: credential is required")
+	}
+	if strings.TrimSpace(cfg.Query) == "" {
+		return SQLConfig{}, errors.New("invalid sql config: query is required")
+	}
+	if cfg.ReadOnly == nil {
+		readOnly := true
+		cfg.ReadOnly = &readOnly
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultSQLMaxRows
+	}
+	if cfg.MaxRows > maxSQLMaxRows {
+		cfg.MaxRows = maxSQLMaxRows
+	}
+	if cfg.TimeoutMS <= 0 {
+		cfg.TimeoutMS = int(defaultSQLTimeout / time.Millisecond)
+	}
+	if cfg.TimeoutMS > int(maxSQLTimeout/time.Millisecond) {
+		cfg.TimeoutMS = int(maxSQLTimeout / time.Millisecond)
+	}
+	return cfg, nil
+}
+
+// resolveParams turns config params into query arguments, reading {"path": ...} references
+// from the step input.
+func resolveParams(raw []json.RawMessage, input any) ([]any, error) {
+	params := make([]any, 0, len(raw))
+	for i, r := range raw {
+		v, err := decodeJSON(r)
+		if err != nil {
+			return nil, fmt.Errorf("param %d: %w", i+1, err)
+		}
+		if ref, ok := v.(map[string]any); ok && len(ref) == 1 {
+			if rawPath, ok := ref["path"].(string); ok {
+				p, err := parsePath(rawPath)
+				if err != nil {
+					return nil, fmt.Errorf("param %d: %w", i+1, err)
+				}
+				v, _ = p.get(input)
+			}
+		}
+		arg, err := queryArg(v)
+		if err != nil {
+			return nil, fmt.Errorf("param %d: %w", i+1, err)
+		}
+		params = append(params, arg)
+	}
+	return params, nil
+}
+
+func queryArg(v any) (any, error) {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case map[string]any, []any:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		return t, nil
+	}
+}
+
+func collectRows(rows pgx.Rows, maxRows int) (SQLResult, error) {
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	result := SQLResult{Rows: []map[string]any{}}
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+		values, err := rows.Values()
+		if err != nil {
+			return SQLResult{}, err
+		}
+		row := make(map[string]any, len(values))
+		for i, v := range values {
+			row[fields[i].Name] = jsonValue(v)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return SQLResult{}, err
+	}
+	result.RowCount = len(result.Rows)
+	// Closing early at max_rows leaves the command tag incomplete, so a truncated result reports
+	// only the rows it collected.
+	if result.Truncated {
+		result.RowsAffected = int64(result.RowCount)
+	} else {
+		result.RowsAffected = rows.CommandTag().RowsAffected()
+	}
+	return result, nil
+}
+
+// jsonValue converts driver values that don't marshal usefully on their own.
+func jsonValue(v any) any {
+	switch t := v.(type) {
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", t[0:4], t[4:6], t[6:8], t[8:10], t[10:16])
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestParseSQLConfig(t *testing.T) {
+	cfg, err := parseSQLConfig(json.RawMessage(`{"credential":"db","query":"SELECT 1"}`))
+	if err != nil {
+		t.Fatalf("parseSQLConfig error: %v", err)
+	}
+	if !*cfg.ReadOnly || cfg.MaxRows != defaultSQLMaxRows || cfg.TimeoutMS != 30000 {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+
+	cfg, err = parseSQLConfig(json.RawMessage(`{"credential":"db","query":"DELETE FROM t","read_only":false,"max_rows":1000000,"timeout_ms":999999999}`))
+	if err != nil {
+		t.Fatalf("parseSQLConfig error: %v", err)
+	}
+	if *cfg.ReadOnly || cfg.MaxRows != maxSQLMaxRows || cfg.TimeoutMS != 300000 {
+		t.Fatalf("expected caps to apply: %+v", cfg)
+	}
+
+	for _, raw := range []string{`{"query":"SELECT 1"}`, `{"credential":"db","query":"  "}`, `nope`} {
+		if _, err := parseSQLConfig(json.RawMessage(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	input, _ := decodeJSON(json.RawMessage(`{"body":{"id":42,"tags":["a"],"ratio":0.5}}`))
+	raw := []json.RawMessage{
+		json.RawMessage(`{"path":"body.id"}`),
+		json.RawMessage(`"literal"`),
+		json.RawMessage(`{"path":"body.tags"}`),
+		json.RawMessage(`{"path":"body.ratio"}`),
+		json.RawMessage(`{"path":"body.missing"}`),
+		json.RawMessage(`true`),
+	}
+	params, err := resolveParams(raw, input)
+	if err != nil {
+		t.Fatalf("resolveParams error: %v", err)
+	}
+	want := []any{int64(42), "literal", `["a"]`, 0.5, nil, true}
+	if len(params) != len(want) {
+		t.Fatalf("expected %d params, got %d", len(want), len(params))
+	}
+	for i := range want {
+		if params[i] != want[i] {
+			t.Fatalf("param %d: expected %#v, got %#v", i+1, want[i], params[i])
+		}
+	}
+}
+
+func TestJSONValueUUID(t *testing.T) {
+	id := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	if got := jsonValue(id); got != "12345678-9abc-def0-0123-456789abcdef" {
+		t.Fatalf("unexpected uuid: %v", got)
+	}
+}
+
+type fakeWorkflowLookup map[string]string
+
+func (f fakeWorkflowLookup) GetWorkflowByID(ctx context.Context, id string) (sqlc.GetWorkflowByIDRow, error) {
+	orgID, ok := f[id]
+	if !ok {
+		return sqlc.GetWorkflowByIDRow{}, pgx.ErrNoRows
+	}
+	return sqlc.GetWorkflowByIDRow{ID: id, OrgID: orgID}, nil
+}
+
+func TestSQLExecutorMissingCredential(t *testing.T) {
+	exec := NewSQLExecutor(&EnvCredentials{workflows: fakeWorkflowLookup{"wf-1": "org-1"}})
+	defer exec.Close()
+
+	_, err := exec.Execute(context.Background(), Step{
+		WorkflowID: "wf-1",
+		Config:     json.RawMessage(`{"credential":"nope-not-set","query":"SELECT 1"}`),
+	})
+	if !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("expected ErrCredentialNotFound, got %v", err)
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("CREDENTIAL_ANALYTICS_DB", "postgres://example")
+	t.Setenv("CREDENTIAL_ANALYTICS_DB_ORGS", "org-1, org-3")
+	t.Setenv("CREDENTIAL_SHARED", "token")
+	t.Setenv("CREDENTIAL_SHARED_ORGS", "*")
+	t.Setenv("CREDENTIAL_UNSCOPED", "postgres://unscoped")
+	if key := CredentialEnvKey("analytics-db"); key != "CREDENTIAL_ANALYTICS_DB" {
+		t.Fatalf("unexpected env key: %s", key)
+	}
+	creds := &EnvCredentials{workflows: fakeWorkflowLookup{"wf-1": "org-1", "wf-2": "org-2"}}
+	ctx := context.Background()
+
+	val, err := creds.Credential(ctx, "wf-1", "analytics-db")
+	if err != nil || val != "postgres://example" {
+		t.Fatalf("unexpected credential %q, err %v", val, err)
+	}
+	if val, err := creds.Credential(ctx, "wf-2", "shared"); err != nil || val != "token" {
+		t.Fatalf("expected a credential shared with every organization, got %q, err %v", val, err)
+	}
+
+	// Another tenant's workflow, credentials without a scope, and scope lists themselves are refused.
+	for _, tc := range []struct{ workflowID, name string }{
+		{"wf-2", "analytics-db"},
+		{"wf-1", "unscoped"},
+		{"wf-1", "analytics-db-orgs"},
+	} {
+		if _, err := creds.Credential(ctx, tc.workflowID, tc.name); !errors.Is(err, ErrCredentialNotFound) {
+			t.Fatalf("%s/%s: expected ErrCredentialNotFound, got %v", tc.workflowID, tc.name, err)
+		}
+	}
+	if _, err := creds.Credential(ctx, "wf-missing", "shared"); err == nil {
+		t.Fatalf("expected an error for an unknown workflow")
+	}
+}
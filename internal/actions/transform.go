@@ -0,0 +1,555 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TransformConfig is the config of a "transform" action: a list of operations applied in order
+// to the step input. The final document becomes the step output.
+type TransformConfig struct {
+	Ops []TransformOp `json:"ops"`
+}
+
+// TransformOp is a single reshaping operation. Which fields apply depends on Op:
+//
+//	select   replace the document with the value at Path
+//	pick     build a new object; Fields maps output paths to input paths
+//	rename   move the value at From to To
+//	filter   keep the elements of the array at Path that match every Where condition
+//	flatten  arrays at Path are flattened Depth levels (default 1); objects become
+//	         Separator-joined keys (default "."), failing when two keys flatten to the same name
+//	merge    shallow-merge the objects at Paths (later wins) and store the result at Into,
+//	         or replace the document when Into is empty
+//	cast     convert the value(s) at Path to Type: string, number, integer or boolean;
+//	         integer rejects fractions and values outside int64
+type TransformOp struct {
+	Op        string            `json:"op"`
+	Path      string            `json:"path,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	From      string            `json:"from,omitempty"`
+	To        string            `json:"to,omitempty"`
+	Where     []Condition       `json:"where,omitempty"`
+	Depth     int               `json:"depth,omitempty"`
+	Separator string            `json:"separator,omitempty"`
+	Paths     []string          `json:"paths,omitempty"`
+	Into      string            `json:"into,omitempty"`
+	Type      string            `json:"type,omitempty"`
+}
+
+// Condition is a filter predicate evaluated against a field of each array element.
+// Op is one of eq (default), ne, gt, gte, lt, lte, contains, in, exists, not_exists.
+type Condition struct {
+	Field string          `json:"field"`
+	Op    string          `json:"op,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// TransformExecutor implements the "transform" action type.
+type TransformExecutor struct{}
+
+// Execute applies the step's TransformConfig to its input.
+func (TransformExecutor) Execute(_ context.Context, step Step) (json.RawMessage, error) {
+	var cfg TransformConfig
+	if err := json.Unmarshal(step.Config, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid transform config: %w", err)
+	}
+	doc, err := decodeJSON(step.Input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform input: %w", err)
+	}
+	out, err := ApplyTransform(cfg, doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// ApplyTransform runs cfg against a decoded JSON document. It is pure and deterministic:
+// the same config and input always produce the same output.
+func ApplyTransform(cfg TransformConfig, doc any) (any, error) {
+	for i, op := range cfg.Ops {
+		var err error
+		doc, err = applyOp(op, doc)
+		if err != nil {
+			return nil, fmt.Errorf("transform op %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOp(op TransformOp, doc any) (any, error) {
+	switch op.Op {
+	case "select":
+		p, err := parsePath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		v, _ := p.get(doc)
+		return v, nil
+
+	case "pick":
+		if len(op.Fields) == 0 {
+			return nil, errors.New("fields is required")
+		}
+		var out any = map[string]any{}
+		for _, dst := range sortedFieldKeys(op.Fields) {
+			src, err := parsePath(op.Fields[dst])
+			if err != nil {
+				return nil, err
+			}
+			dstPath, err := parsePath(dst)
+			if err != nil {
+				return nil, err
+			}
+			if dstPath.hasWildcard() {
+				return nil, fmt.Errorf("output path %q may not contain a wildcard", dst)
+			}
+			v, _ := src.get(doc)
+			out, err = dstPath.update(out, func(any, bool) (any, error) { return v, nil })
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+
+	case "rename":
+		from, err := parsePath(op.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePath(op.To)
+		if err != nil {
+			return nil, err
+		}
+		if len(from) == 0 || len(to) == 0 || from.hasWildcard() || to.hasWildcard() {
+			return nil, errors.New("from and to must be non-empty paths without wildcards")
+		}
+		v, ok := from.get(doc)
+		if !ok {
+			return doc, nil
+		}
+		from.remove(doc)
+		return to.update(doc, func(any, bool) (any, error) { return v, nil })
+
+	case "filter":
+		p, err := parsePath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		conds, err := compileConditions(op.Where)
+		if err != nil {
+			return nil, err
+		}
+		return p.update(doc, func(old any, _ bool) (any, error) {
+			arr, ok := old.([]any)
+			if !ok {
+				return nil, fmt.Errorf("filter target is %s, not an array", kindOf(old))
+			}
+			kept := make([]any, 0, len(arr))
+			for _, el := range arr {
+				if matchAll(conds, el) {
+					kept = append(kept, el)
+				}
+			}
+			return kept, nil
+		})
+
+	case "flatten":
+		p, err := parsePath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		depth := op.Depth
+		if depth <= 0 {
+			depth = 1
+		}
+		sep := op.Separator
+		if sep == "" {
+			sep = "."
+		}
+		return p.update(doc, func(old any, _ bool) (any, error) {
+			switch v := old.(type) {
+			case []any:
+				return flattenArray(v, depth), nil
+			case map[string]any:
+				out := make(map[string]any)
+				if err := flattenObject("", v, sep, out); err != nil {
+					return nil, err
+				}
+				return out, nil
+			default:
+				return nil, fmt.Errorf("cannot flatten %s", kindOf(old))
+			}
+		})
+
+	case "merge":
+		if len(op.Paths) == 0 {
+			return nil, errors.New("paths is required")
+		}
+		merged := make(map[string]any)
+		for _, raw := range op.Paths {
+			p, err := parsePath(raw)
+			if err != nil {
+				return nil, err
+			}
+			v, ok := p.get(doc)
+			if !ok || v == nil {
+				continue
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot merge %s at %q", kindOf(v), raw)
+			}
+			for k, val := range obj {
+				merged[k] = val
+			}
+		}
+		into, err := parsePath(op.Into)
+		if err != nil {
+			return nil, err
+		}
+		if len(into) == 0 {
+			return merged, nil
+		}
+		return into.update(doc, func(any, bool) (any, error) { return merged, nil })
+
+	case "cast":
+		p, err := parsePath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Type {
+		case "string", "number", "integer", "boolean":
+		default:
+			return nil, fmt.Errorf("unsupported cast type %q", op.Type)
+		}
+		// Only values that exist are cast, so elements of a wildcard without the field keep lacking it.
+		return p.updateExisting(doc, func(old any) (any, error) {
+			return castValue(old, op.Type)
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// castInteger parses text exactly, so integers beyond 2^53 keep every digit, and rejects
+// fractions and values that don't fit an int64. Whole numbers in decimal or exponent notation
+// ("7.0", "1e3") are accepted.
+func castInteger(text string) (any, error) {
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(n, 10)), nil
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok || strings.Contains(text, "/") {
+		return nil, fmt.Errorf("cannot cast %q to integer", text)
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("cannot cast %q to integer: not a whole number", text)
+	}
+	if !r.Num().IsInt64() {
+		return nil, fmt.Errorf("cannot cast %q to integer: out of range", text)
+	}
+	return json.Number(r.Num().String()), nil
+}
+
+// isJSONNumber reports whether text is already a valid JSON number literal.
+func isJSONNumber(text string) bool {
+	if text == "" || (text[0] != '-' && (text[0] < '0' || text[0] > '9')) {
+		return false
+	}
+	var n json.Number
+	return json.Unmarshal([]byte(text), &n) == nil
+}
+
+func sortedFieldKeys(m map[string]string) []string {
+	generic := make(map[string]any, len(m))
+	for k := range m {
+		generic[k] = nil
+	}
+	return sortedKeys(generic)
+}
+
+func flattenArray(arr []any, depth int) []any {
+	out := make([]any, 0, len(arr))
+	for _, el := range arr {
+		if nested, ok := el.([]any); ok && depth > 0 {
+			out = append(out, flattenArray(nested, depth-1)...)
+			continue
+		}
+		out = append(out, el)
+	}
+	return out
+}
+
+// flattenObject walks obj in key order and fails when two paths flatten to the same key, e.g.
+// "a.b" next to {"a":{"b":...}}, so the result never depends on map iteration order.
+func flattenObject(prefix string, obj map[string]any, sep string, out map[string]any) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + sep + k
+		}
+		if nested, ok := obj[k].(map[string]any); ok && len(nested) > 0 {
+			if err := flattenObject(key, nested, sep, out); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, dup := out[key]; dup {
+			return fmt.Errorf("cannot flatten: key %q occurs more than once", key)
+		}
+		out[key] = obj[k]
+	}
+	return nil
+}
+
+func castValue(v any, typ string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case "string":
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case json.Number:
+			return t.String(), nil
+		case bool:
+			return strconv.FormatBool(t), nil
+		default:
+			b, err := json.Marshal(t)
+			return string(b), err
+		}
+	case "number", "integer":
+		var text string
+		switch t := v.(type) {
+		case json.Number:
+			text = t.String()
+		case string:
+			text = strings.TrimSpace(t)
+		case bool:
+			text = "0"
+			if t {
+				text = "1"
+			}
+		default:
+			return nil, fmt.Errorf("cannot cast %s to %s", kindOf(v), typ)
+		}
+		if typ == "integer" {
+			return castInteger(text)
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("cannot cast %q to number", text)
+		}
+		if isJSONNumber(text) {
+			// Keep the original digits rather than round-tripping through float64.
+			return json.Number(text), nil
+		}
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	case "boolean":
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(t))
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to boolean", t)
+			}
+			return b, nil
+		case json.Number:
+			f, err := t.Float64()
+			if err != nil {
+				return nil, err
+			}
+			return f != 0, nil
+		default:
+			return nil, fmt.Errorf("cannot cast %s to boolean", kindOf(v))
+		}
+	}
+	return nil, fmt.Errorf("unsupported cast type %q", typ)
+}
+
+type compiledCondition struct {
+	field path
+	op    string
+	value any
+}
+
+func compileConditions(conds []Condition) ([]compiledCondition, error) {
+	out := make([]compiledCondition, 0, len(conds))
+	for _, c := range conds {
+		p, err := parsePath(c.Field)
+		if err != nil {
+			return nil, err
+		}
+		op := c.Op
+		if op == "" {
+			op = "eq"
+		}
+		switch op {
+		case "eq", "ne", "gt", "gte", "lt", "lte", "contains", "in", "exists", "not_exists":
+		default:
+			return nil, fmt.Errorf("unknown condition op %q", op)
+		}
+		var value any
+		if len(c.Value) > 0 {
+			value, err = decodeJSON(c.Value)
+			if err != nil {
+				return nil, fmt.Errorf("condition value: %w", err)
+			}
+		}
+		if op == "in" {
+			if _, ok := value.([]any); !ok {
+				return nil, errors.New("condition op in requires an array value")
+			}
+		}
+		out = append(out, compiledCondition{field: p, op: op, value: value})
+	}
+	return out, nil
+}
+
+func matchAll(conds []compiledCondition, el any) bool {
+	for _, c := range conds {
+		if !c.match(el) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c compiledCondition) match(el any) bool {
+	v, ok := c.field.get(el)
+	switch c.op {
+	case "exists":
+		return ok
+	case "not_exists":
+		return !ok
+	}
+	if !ok {
+		return c.op == "ne"
+	}
+	switch c.op {
+	case "eq":
+		return equalValues(v, c.value)
+	case "ne":
+		return !equalValues(v, c.value)
+	case "gt", "gte", "lt", "lte":
+		cmp, ok := compareValues(v, c.value)
+		if !ok {
+			return false
+		}
+		switch c.op {
+		case "gt":
+			return cmp > 0
+		case "gte":
+			return cmp >= 0
+		case "lt":
+			return cmp < 0
+		default:
+			return cmp <= 0
+		}
+	case "contains":
+		switch t := v.(type) {
+		case string:
+			s, ok := c.value.(string)
+			return ok && strings.Contains(t, s)
+		case []any:
+			for _, item := range t {
+				if equalValues(item, c.value) {
+					return true
+				}
+			}
+		}
+		return false
+	case "in":
+		for _, item := range c.value.([]any) {
+			if equalValues(v, item) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// equalValues compares decoded JSON values, treating numbers by value rather than spelling.
+func equalValues(a, b any) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if !equalValues(v, bv[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalValues(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func compareValues(a, b any) (int, bool) {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		if aerr != nil || berr != nil {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	}
+	return 0, false
+}
@@ -0,0 +1,226 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyTransform(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "select nested value",
+			ops:   `[{"op":"select","path":"$.body.user"}]`,
+			input: `{"body":{"user":{"name":"Ada"}}}`,
+			want:  `{"name":"Ada"}`,
+		},
+		{
+			name:  "select missing path yields null",
+			ops:   `[{"op":"select","path":"body.nope"}]`,
+			input: `{"body":{}}`,
+			want:  `null`,
+		},
+		{
+			name:  "select wildcard",
+			ops:   `[{"op":"select","path":"items[*].id"}]`,
+			input: `{"items":[{"id":1},{"id":2},{"name":"x"}]}`,
+			want:  `[1,2]`,
+		},
+		{
+			name:  "pick renames and nests",
+			ops:   `[{"op":"pick","fields":{"name":"user.first","contact.email":"user.emails[0]","missing":"user.age"}}]`,
+			input: `{"user":{"first":"Ada","emails":["ada@example.com","a@example.com"]}}`,
+			want:  `{"contact":{"email":"ada@example.com"},"missing":null,"name":"Ada"}`,
+		},
+		{
+			name:  "rename moves a field",
+			ops:   `[{"op":"rename","from":"user.first","to":"first_name"}]`,
+			input: `{"user":{"first":"Ada","last":"Lovelace"}}`,
+			want:  `{"first_name":"Ada","user":{"last":"Lovelace"}}`,
+		},
+		{
+			name:  "rename missing field is a no-op",
+			ops:   `[{"op":"rename","from":"a","to":"b"}]`,
+			input: `{"c":1}`,
+			want:  `{"c":1}`,
+		},
+		{
+			name:  "filter with several conditions",
+			ops:   `[{"op":"filter","path":"items","where":[{"field":"status","value":"open"},{"field":"priority","op":"gte","value":2}]}]`,
+			input: `{"items":[{"status":"open","priority":1},{"status":"open","priority":3},{"status":"closed","priority":5}]}`,
+			want:  `{"items":[{"priority":3,"status":"open"}]}`,
+		},
+		{
+			name:  "filter root array with in and exists",
+			ops:   `[{"op":"filter","where":[{"field":"tag","op":"in","value":["a","b"]},{"field":"id","op":"exists"}]}]`,
+			input: `[{"tag":"a","id":1},{"tag":"c","id":2},{"tag":"b"}]`,
+			want:  `[{"id":1,"tag":"a"}]`,
+		},
+		{
+			name:  "filter contains on strings and arrays",
+			ops:   `[{"op":"filter","where":[{"field":"title","op":"contains","value":"go"}]},{"op":"filter","where":[{"field":"labels","op":"contains","value":"bug"}]}]`,
+			input: `[{"title":"golang","labels":["bug"]},{"title":"golang","labels":[]},{"title":"rust","labels":["bug"]}]`,
+			want:  `[{"labels":["bug"],"title":"golang"}]`,
+		},
+		{
+			name:    "filter non-array fails",
+			ops:     `[{"op":"filter","path":"items","where":[]}]`,
+			input:   `{"items":{"a":1}}`,
+			wantErr: "not an array",
+		},
+		{
+			name:  "flatten nested arrays one level",
+			ops:   `[{"op":"flatten","path":"pages[*].rows"},{"op":"select","path":"pages[*].rows"},{"op":"flatten"}]`,
+			input: `{"pages":[{"rows":[[1,2],[3]]},{"rows":[[4,[5]]]}]}`,
+			want:  `[1,2,3,4,[5]]`,
+		},
+		{
+			name:  "flatten arrays with depth",
+			ops:   `[{"op":"flatten","depth":2}]`,
+			input: `[[1,[2,[3]]]]`,
+			want:  `[1,2,[3]]`,
+		},
+		{
+			name:  "flatten object with separator",
+			ops:   `[{"op":"flatten","path":"user","separator":"_"}]`,
+			input: `{"user":{"name":{"first":"Ada"},"tags":["x"],"empty":{}}}`,
+			want:  `{"user":{"empty":{},"name_first":"Ada","tags":["x"]}}`,
+		},
+		{
+			name:  "merge objects into path",
+			ops:   `[{"op":"merge","paths":["defaults","overrides","absent"],"into":"settings"}]`,
+			input: `{"defaults":{"a":1,"b":2},"overrides":{"b":3}}`,
+			want:  `{"defaults":{"a":1,"b":2},"overrides":{"b":3},"settings":{"a":1,"b":3}}`,
+		},
+		{
+			name:    "flatten object key collision fails",
+			ops:     `[{"op":"flatten"}]`,
+			input:   `{"a.b":1,"a":{"b":2}}`,
+			wantErr: `key "a.b" occurs more than once`,
+		},
+		{
+			name:  "merge replaces root",
+			ops:   `[{"op":"merge","paths":["x","y"]}]`,
+			input: `{"x":{"a":1},"y":{"c":2}}`,
+			want:  `{"a":1,"c":2}`,
+		},
+		{
+			name:    "merge non-object fails",
+			ops:     `[{"op":"merge","paths":["x"]}]`,
+			input:   `{"x":[1]}`,
+			wantErr: "cannot merge array",
+		},
+		{
+			name:  "cast types",
+			ops:   `[{"op":"cast","path":"n","type":"number"},{"op":"cast","path":"i","type":"integer"},{"op":"cast","path":"s","type":"string"},{"op":"cast","path":"b","type":"boolean"},{"op":"cast","path":"nothing","type":"string"}]`,
+			input: `{"n":"12.50","i":"7.0","s":42,"b":"true"}`,
+			want:  `{"b":true,"i":7,"n":12.50,"s":"42"}`,
+		},
+		{
+			name:  "cast keeps large integers exact",
+			ops:   `[{"op":"cast","path":"a","type":"integer"},{"op":"cast","path":"b","type":"integer"},{"op":"cast","path":"c","type":"number"}]`,
+			input: `{"a":"9007199254740993","b":9007199254740993,"c":"9007199254740993"}`,
+			want:  `{"a":9007199254740993,"b":9007199254740993,"c":9007199254740993}`,
+		},
+		{
+			name:    "cast fraction to integer fails",
+			ops:     `[{"op":"cast","path":"i","type":"integer"}]`,
+			input:   `{"i":"7.9"}`,
+			wantErr: "not a whole number",
+		},
+		{
+			name:    "cast integer out of range fails",
+			ops:     `[{"op":"cast","path":"i","type":"integer"}]`,
+			input:   `{"i":12345678901234567890}`,
+			wantErr: "out of range",
+		},
+		{
+			name:  "cast with wildcard",
+			ops:   `[{"op":"cast","path":"items[*].qty","type":"integer"}]`,
+			input: `{"items":[{"qty":"1"},{"qty":"2"}]}`,
+			want:  `{"items":[{"qty":1},{"qty":2}]}`,
+		},
+		{
+			name:  "cast with wildcard skips elements without the field",
+			ops:   `[{"op":"cast","path":"items[*].p","type":"integer"},{"op":"cast","path":"rows[*].a.b","type":"string"}]`,
+			input: `{"items":[{"p":"1"},{"q":2}],"rows":[{"a":{"b":1}},{"c":3},5]}`,
+			want:  `{"items":[{"p":1},{"q":2}],"rows":[{"a":{"b":"1"}},{"c":3},5]}`,
+		},
+		{
+			name:    "cast invalid value",
+			ops:     `[{"op":"cast","path":"n","type":"number"}]`,
+			input:   `{"n":"abc"}`,
+			wantErr: `cannot cast "abc" to number`,
+		},
+		{
+			name:    "unknown op",
+			ops:     `[{"op":"explode"}]`,
+			input:   `{}`,
+			wantErr: `unknown op "explode"`,
+		},
+		{
+			name:  "large numbers survive untouched",
+			ops:   `[{"op":"select","path":"id"}]`,
+			input: `{"id":12345678901234567890}`,
+			want:  `12345678901234567890`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := json.RawMessage(`{"ops":` + tc.ops + `}`)
+			out, err := TransformExecutor{}.Execute(context.Background(), Step{Config: cfg, Input: json.RawMessage(tc.input)})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+			if string(out) != tc.want {
+				t.Fatalf("unexpected output:\n got: %s\nwant: %s", out, tc.want)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "$", want: 0},
+		{raw: "$.a.b", want: 2},
+		{raw: "a[0].b", want: 3},
+		{raw: "a[*]", want: 2},
+		{raw: "['x.y'].z", want: 2},
+		{raw: "a[", wantErr: true},
+		{raw: "a[-1]", wantErr: true},
+	}
+	for _, tc := range tests {
+		p, err := parsePath(tc.raw)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("%q: expected error", tc.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", tc.raw, err)
+		}
+		if len(p) != tc.want {
+			t.Fatalf("%q: expected %d segments, got %d", tc.raw, tc.want, len(p))
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/email"
+	"github.com/rs/zerolog/log"
+)
+
+// Account tokens are mailed to users; their purpose is part of the signed payload, so a token for
+// one flow can't be used for the other.
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+
+	// resetMailTimeout bounds mailing a reset token, which outlives the request that asked for it.
+	resetMailTimeout = time.Minute
+)
+
+var (
+	// ErrInvalidAccountToken covers forged, expired, used and wrong-purpose tokens alike.
+	ErrInvalidAccountToken  = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+)
+
+// accountTokenDomain separates account token signatures from other uses of the JWT secret.
+const accountTokenDomain = "potaflow-account-token\x00"
+
+// SetEmailSender replaces where account emails go; by default they're only logged. Links in them
+// point to linkBase + "/reset-password" and "/verify-email" with a token query parameter; with
+// an empty linkBase the emails carry just the token.
+func (s *Service) SetEmailSender(sender email.Sender, linkBase string) {
+	s.mailer = sender
+	s.linkBase = strings.TrimRight(linkBase, "/")
+}
+
+// RequestPasswordReset mails a single-use reset token to email. It succeeds without sending
+// anything for unknown or disabled accounts, so it can't be used to find out which emails exist;
+// for the same reason the token is issued and mailed in the background, so known accounts don't
+// take longer to answer. Requests are rate limited per email and per clientIP (skipped when
+// empty), known or not, and refused with a *ThrottledError past the limit.
+func (s *Service) RequestPasswordReset(ctx context.Context, addr, clientIP string) error {
+	keys := []throttleKey{{ThrottleResetEmail, strings.ToLower(strings.TrimSpace(addr)), s.lockout.ResetsPerEmail}}
+	if clientIP != "" {
+		keys = append(keys, throttleKey{ThrottleResetIP, clientIP, s.lockout.ResetsPerIP})
+	}
+	if err := s.rateLimit(ctx, keys); err != nil {
+		return err
+	}
+	record, err := s.store.GetUserByEmail(ctx, addr)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if record.DisabledAt != nil {
+		return nil
+	}
+	s.mailing.Add(1)
+	go func() {
+		defer s.mailing.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resetMailTimeout)
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, record.User); err != nil {
+			log.Error().Err(err).Str("user_id", record.ID).Msg("failed to send password reset email")
+		}
+	}()
+	return nil
+}
+
+func (s *Service) sendPasswordReset(ctx context.Context, user User) error {
+	token, err := s.issueAccountToken(ctx, user.ID, PurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, email.Outgoing{
+		To:      user.Email,
+		Subject: "Reset your PotaFlow password",
+		Text: "Someone asked to reset the password of your PotaFlow account. If that was you, follow the link\n" +
+			"below within an hour; otherwise you can ignore this email.\n\n" + s.accountLink("/reset-password", token) + "\n",
+	})
+}
+
+// ResetPassword sets a new password with a token from RequestPasswordReset. Every session of the
+// user is revoked, other outstanding reset tokens stop working and a lockout of the account is
+// lifted, so the new password works right away.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if newPassword == "" {
+		return ErrInvalidInput
+	}
+	userID, err := s.useAccountToken(ctx, token, PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.setPassword(ctx, userID, newPassword); err != nil {
+		return err
+	}
+	if err := s.store.DeleteAccountTokens(ctx, userID, PurposePasswordReset); err != nil {
+		return err
+	}
+	key := s.throttleKeys(user.Email, "")[0]
+	if err := s.store.ClearLoginFailures(ctx, key.scope, key.key, s.now().Add(-s.lockout.Window)); err != nil {
+		return err
+	}
+	return s.LogoutAll(ctx, userID)
+}
+
+// ChangePassword replaces the password of userID after checking currentPassword like a login from
+// clientIP: throttled, and a wrong one counts as a failed login. Every existing session is
+// revoked; the returned pair keeps the caller signed in.
+func (s *Service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword, clientIP string) (TokenPair, error) {
+	if newPassword == "" {
+		return TokenPair{}, ErrInvalidInput
+	}
+	user, attempt, err := s.verifyUserPassword(ctx, userID, currentPassword, clientIP)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.succeedAttempt(ctx, attempt); err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.setPassword(ctx, userID, newPassword); err != nil {
+		return TokenPair{}, err
+	}
+	after, err := s.store.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	s.revocations.revokeUser(userID, after)
+
+	// Tokens issued in the millisecond of the cut-off would count as revoked.
+	if wait := time.Until(after.Add(time.Millisecond)); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return TokenPair{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return s.issueTokens(ctx, user)
+}
+
+// SendEmailVerification mails userID a token that confirms they own their email address.
+func (s *Service) SendEmailVerification(ctx context.Context, userID string) error {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	token, err := s.issueAccountToken(ctx, user.ID, PurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, email.Outgoing{
+		To:      user.Email,
+		Subject: "Verify your PotaFlow email address",
+		Text: "Follow the link below within 48 hours to confirm this is your email address.\n\n" +
+			s.accountLink("/verify-email", token) + "\n",
+	})
+}
+
+// VerifyEmail marks the email of the token's user as verified.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.useAccountToken(ctx, token, PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	return s.store.SetEmailVerified(ctx, userID)
+}
+
+// sendWelcomeVerification is SendEmailVerification for a user that just registered; a failure
+// is only logged so registration still succeeds.
+func (s *Service) sendWelcomeVerification(ctx context.Context, userID string) {
+	if err := s.SendEmailVerification(ctx, userID); err != nil {
+		log.Warn().Err(err).Str("user_id", userID).Msg("failed to send verification email")
+	}
+}
+
+func (s *Service) setPassword(ctx context.Context, userID, password string) error {
+	hash, err := HashPassword(password, s.params)
+	if err != nil {
+		return err
+	}
+	return s.store.UpdatePasswordHash(ctx, userID, hash)
+}
+
+func (s *Service) accountLink(path, token string) string {
+	if s.linkBase == "" {
+		return "Token: " + token
+	}
+	return s.linkBase + path + "?token=" + url.QueryEscape(token)
+}
+
+// issueAccountToken signs a token for userID and purpose and stores its hash so it can be used
+// once.
+func (s *Service) issueAccountToken(ctx context.Context, userID, purpose string, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	expiresAt := s.now().Add(ttl)
+	payload := strings.Join([]string{purpose, userID, strconv.FormatInt(expiresAt.Unix(), 10), hex.EncodeToString(nonce)}, ".")
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.signAccountToken(payload))
+	if err := s.store.CreateAccountToken(ctx, userID, purpose, hashToken(token), expiresAt); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// useAccountToken checks token's signature, purpose and expiry, marks it used and returns its
+// user.
+func (s *Service) useAccountToken(ctx context.Context, token, purpose string) (string, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidAccountToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return "", ErrInvalidAccountToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, s.signAccountToken(string(payload))) {
+		return "", ErrInvalidAccountToken
+	}
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 4 || parts[0] != purpose {
+		return "", ErrInvalidAccountToken
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || !s.now().Before(time.Unix(exp, 0)) {
+		return "", ErrInvalidAccountToken
+	}
+
+	userID, err := s.store.UseAccountToken(ctx, hashToken(token), purpose)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", ErrInvalidAccountToken
+		}
+		return "", err
+	}
+	if userID != parts[1] {
+		return "", ErrInvalidAccountToken
+	}
+	return userID, nil
+}
+
+func (s *Service) signAccountToken(payload string) []byte {
+	mac := hmac.New(sha256.New, s.jwtSecret)
+	mac.Write([]byte(accountTokenDomain + payload))
+	return mac.Sum(nil)
+}
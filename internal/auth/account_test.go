@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/email"
+)
+
+type accountToken struct {
+	userID, purpose string
+	expiresAt       time.Time
+	used            bool
+}
+
+// recordingSender keeps outgoing mail instead of sending it.
+type recordingSender struct {
+	sent []email.Outgoing
+}
+
+func (r *recordingSender) Send(ctx context.Context, msg email.Outgoing) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+// lastToken returns the token of the last link mailed.
+func (r *recordingSender) lastToken(t *testing.T) string {
+	t.Helper()
+	if len(r.sent) == 0 {
+		t.Fatal("expected an email")
+	}
+	text := r.sent[len(r.sent)-1].Text
+	i := strings.Index(text, "?token=")
+	if i < 0 {
+		t.Fatalf("expected a link in %q", text)
+	}
+	token, err := url.QueryUnescape(strings.Fields(text[i+len("?token="):])[0])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func (s *refreshStore) GetUserByEmail(ctx context.Context, addr string) (UserWithHash, error) {
+	if !strings.EqualFold(addr, s.userWithHash.Email) {
+		return UserWithHash{}, ErrNotFound
+	}
+	return s.userWithHash, nil
+}
+
+func (s *refreshStore) CreateUser(ctx context.Context, addr, passwordHash string) (User, error) {
+	s.userWithHash = UserWithHash{User: User{ID: "user-1", Email: addr, Role: RoleMember}, PasswordHash: passwordHash}
+	return s.userWithHash.User, nil
+}
+
+func (s *refreshStore) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	if userID != s.userWithHash.ID {
+		return ErrNotFound
+	}
+	s.userWithHash.PasswordHash = passwordHash
+	return nil
+}
+
+func (s *refreshStore) UpgradePasswordHash(ctx context.Context, userID, oldHash, newHash string) error {
+	if userID == s.userWithHash.ID && s.userWithHash.PasswordHash == oldHash {
+		s.userWithHash.PasswordHash = newHash
+	}
+	return nil
+}
+
+func (s *refreshStore) SetEmailVerified(ctx context.Context, userID string) error {
+	now := time.Now()
+	s.userWithHash.EmailVerifiedAt = &now
+	return nil
+}
+
+func (s *refreshStore) CreateAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	s.accountTokens[tokenHash] = &accountToken{userID: userID, purpose: purpose, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *refreshStore) UseAccountToken(ctx context.Context, tokenHash, purpose string) (string, error) {
+	tok, ok := s.accountTokens[tokenHash]
+	if !ok || tok.used || tok.purpose != purpose {
+		return "", ErrNotFound
+	}
+	tok.used = true
+	return tok.userID, nil
+}
+
+func (s *refreshStore) DeleteAccountTokens(ctx context.Context, userID, purpose string) error {
+	for hash, tok := range s.accountTokens {
+		if tok.userID == userID && tok.purpose == purpose && !tok.used {
+			delete(s.accountTokens, hash)
+		}
+	}
+	return nil
+}
+
+func TestServicePasswordReset(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	mail := &recordingSender{}
+	svc.SetEmailSender(mail, "https://potaflow.example.com/")
+	ctx := context.Background()
+
+	// Emails are sent in the background; mailing.Wait waits for them.
+	err := svc.RequestPasswordReset(ctx, "missing@example.com", "192.0.2.1")
+	svc.mailing.Wait()
+	if err != nil || len(mail.sent) != 0 {
+		t.Fatalf("expected unknown emails to be ignored silently, got %v (%d sent)", err, len(mail.sent))
+	}
+	if err := svc.RequestPasswordReset(ctx, "test@example.com", "192.0.2.1"); err != nil {
+		t.Fatalf("RequestPasswordReset error: %v", err)
+	}
+	svc.mailing.Wait()
+	if mail.sent[0].To != "test@example.com" || !strings.Contains(mail.sent[0].Text, "https://potaflow.example.com/reset-password?token=") {
+		t.Fatalf("unexpected email %+v", mail.sent[0])
+	}
+	first := mail.lastToken(t)
+	_ = svc.RequestPasswordReset(ctx, "test@example.com", "192.0.2.1")
+	svc.mailing.Wait()
+	second := mail.lastToken(t)
+
+	_, session, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	if err := svc.ResetPassword(ctx, first, ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for an empty password, got %v", err)
+	}
+	if err := svc.ResetPassword(ctx, first+"x", "new-secret"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected a tampered token to be rejected, got %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := svc.ResetPassword(ctx, first, "new-secret"); err != nil {
+		t.Fatalf("ResetPassword error: %v", err)
+	}
+
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected the old password to stop working, got %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "new-secret", ""); err != nil {
+		t.Fatalf("expected the new password to work, got %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, session.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected existing sessions to be revoked, got %v", err)
+	}
+	if err := svc.ResetPassword(ctx, first, "again"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected a used token to be rejected, got %v", err)
+	}
+	if err := svc.ResetPassword(ctx, second, "again"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected other reset tokens to be invalidated, got %v", err)
+	}
+}
+
+func TestServicePasswordResetLimits(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	policy := testLockoutPolicy
+	policy.ResetsPerEmail = 2
+	svc.SetLockoutPolicy(policy)
+	mail := &recordingSender{}
+	svc.SetEmailSender(mail, "https://potaflow.example.com/")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := svc.RequestPasswordReset(ctx, "test@example.com", "192.0.2.1"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		svc.mailing.Wait()
+	}
+	var throttled *ThrottledError
+	if err := svc.RequestPasswordReset(ctx, " Test@Example.com", "192.0.2.2"); !errors.As(err, &throttled) || throttled.RetryAfter <= 0 {
+		t.Fatalf("expected the third request for the email to be throttled, got %v", err)
+	}
+	if len(mail.sent) != 2 {
+		t.Fatalf("expected two emails, got %d", len(mail.sent))
+	}
+
+	// A reset lifts a lockout of the account.
+	store.failures[ThrottleAccount+"/test@example.com"] = policy.AccountThreshold
+	store.blocks[ThrottleAccount+"/test@example.com"] = time.Now().Add(time.Hour)
+	if err := svc.ResetPassword(ctx, mail.lastToken(t), "new-secret"); err != nil {
+		t.Fatalf("ResetPassword error: %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "new-secret", ""); err != nil {
+		t.Fatalf("expected the reset to lift the lockout, got %v", err)
+	}
+}
+
+func TestServiceAccountTokenChecks(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	reset, err := svc.issueAccountToken(ctx, "user-1", PurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatalf("issueAccountToken error: %v", err)
+	}
+	if err := svc.VerifyEmail(ctx, reset); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected a reset token to be rejected for verification, got %v", err)
+	}
+
+	other := NewService(store, testParams, []byte("other-secret"), time.Minute, time.Hour)
+	if err := other.ResetPassword(ctx, reset, "new-secret"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected a token signed with another secret to be rejected, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := svc.ResetPassword(ctx, reset, "new-secret"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestServiceEmailVerification(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	mail := &recordingSender{}
+	svc.SetEmailSender(mail, "")
+	ctx := context.Background()
+
+	if _, err := svc.Register(ctx, "test@example.com", "secret"); err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+	if len(mail.sent) != 1 || !strings.Contains(mail.sent[0].Text, "Token: ") {
+		t.Fatalf("expected a verification email with a bare token, got %+v", mail.sent)
+	}
+	token := strings.TrimSpace(mail.sent[0].Text[strings.Index(mail.sent[0].Text, "Token: ")+len("Token: "):])
+
+	if err := svc.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail error: %v", err)
+	}
+	if store.userWithHash.EmailVerifiedAt == nil {
+		t.Fatal("expected the email to be verified")
+	}
+	if err := svc.SendEmailVerification(ctx, "user-1"); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Fatalf("expected ErrEmailAlreadyVerified, got %v", err)
+	}
+}
+
+func TestServiceChangePassword(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, old, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	if _, err := svc.ChangePassword(ctx, "user-1", "wrong", "new-secret", "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if store.failures[ThrottleAccount+"/test@example.com"] != 1 || store.failures[ThrottleIP+"/192.0.2.1"] != 1 {
+		t.Fatalf("expected the wrong password to count as a failed login, got %v", store.failures)
+	}
+	pair, err := svc.ChangePassword(ctx, "user-1", "secret", "new-secret", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("ChangePassword error: %v", err)
+	}
+
+	if _, err := svc.ParseAndValidateToken(ctx, old.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected other sessions to be revoked, got %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, pair.AccessToken); err != nil {
+		t.Fatalf("expected the returned session to work, got %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "new-secret", ""); err != nil {
+		t.Fatalf("expected the new password to work, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so the middleware can tell keys from JWTs and secret
+// scanners can spot leaked ones.
+const APIKeyPrefix = "pf_"
+
+// apiKeyHintLength is how much of a key is kept in clear to help users tell keys apart.
+const apiKeyHintLength = len(APIKeyPrefix) + 6
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrInvalidInput  = errors.New("invalid input")
+)
+
+// APIKey is a stored API key; the key itself is only known when it is created. A non-empty
+// WorkflowIDs limits the key to those workflows.
+type APIKey struct {
+	ID          string
+	UserID      string
+	Name        string
+	Prefix      string
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	Scopes      []string
+	WorkflowIDs []string
+}
+
+// NewAPIKey describes a key to create. Nil Scopes grant AllScopes.
+type NewAPIKey struct {
+	Name        string
+	ExpiresAt   *time.Time
+	Scopes      []string
+	WorkflowIDs []string
+}
+
+// CreateAPIKey issues a key for userID and returns it in clear; only its hash is stored.
+func (s *Service) CreateAPIKey(ctx context.Context, userID string, req NewAPIKey) (APIKey, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return APIKey{}, "", ErrInvalidInput
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return APIKey{}, "", ErrInvalidInput
+	}
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = AllScopes
+	}
+	if len(scopes) == 0 {
+		return APIKey{}, "", ErrInvalidInput
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return APIKey{}, "", ErrInvalidInput
+		}
+	}
+	workflowIDs := req.WorkflowIDs
+	if workflowIDs == nil {
+		workflowIDs = []string{}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return APIKey{}, "", err
+	}
+	raw := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	key, err := s.store.CreateAPIKey(ctx, APIKey{
+		UserID:      userID,
+		Name:        name,
+		Prefix:      raw[:apiKeyHintLength],
+		ExpiresAt:   req.ExpiresAt,
+		Scopes:      scopes,
+		WorkflowIDs: workflowIDs,
+	}, hashToken(raw))
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	return key, raw, nil
+}
+
+// ListAPIKeys returns a user's keys, newest first.
+func (s *Service) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	return s.store.ListAPIKeys(ctx, userID)
+}
+
+// DeleteAPIKey revokes one of a user's keys; keys of other users return ErrNotFound.
+func (s *Service) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	return s.store.DeleteAPIKey(ctx, userID, id)
+}
+
+// authenticateAPIKey resolves a pf_ key to the claims of its owner and records its use.
+func (s *Service) authenticateAPIKey(ctx context.Context, raw string) (Claims, error) {
+	key, owner, err := s.store.GetAPIKeyByHash(ctx, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Claims{}, ErrInvalidAPIKey
+		}
+		return Claims{}, err
+	}
+	if key.ExpiresAt != nil && !s.now().Before(*key.ExpiresAt) {
+		return Claims{}, ErrInvalidAPIKey
+	}
+	if owner.DisabledAt != nil {
+		return Claims{}, ErrInvalidAPIKey
+	}
+	if err := s.store.TouchAPIKey(ctx, key.ID); err != nil {
+		return Claims{}, err
+	}
+	return Claims{
+		UserID:      key.UserID,
+		Email:       owner.Email,
+		Role:        owner.Role,
+		APIKeyID:    key.ID,
+		Scopes:      key.Scopes,
+		WorkflowIDs: key.WorkflowIDs,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (s *refreshStore) CreateAPIKey(ctx context.Context, key APIKey, keyHash string) (APIKey, error) {
+	s.seq++
+	key.ID = fmt.Sprintf("key-%d", s.seq)
+	s.apiKeys[keyHash] = &key
+	return key, nil
+}
+
+func (s *refreshStore) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	var keys []APIKey
+	for _, k := range s.apiKeys {
+		if k.UserID == userID {
+			keys = append(keys, *k)
+		}
+	}
+	return keys, nil
+}
+
+func (s *refreshStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, User, error) {
+	k, ok := s.apiKeys[keyHash]
+	if !ok {
+		return APIKey{}, User{}, ErrNotFound
+	}
+	return *k, s.userWithHash.User, nil
+}
+
+func (s *refreshStore) TouchAPIKey(ctx context.Context, id string) error {
+	s.touched++
+	return nil
+}
+
+func (s *refreshStore) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	for hash, k := range s.apiKeys {
+		if k.ID == id && k.UserID == userID {
+			delete(s.apiKeys, hash)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func TestServiceAPIKeys(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	key, raw, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: " ci "})
+	if err != nil {
+		t.Fatalf("CreateAPIKey error: %v", err)
+	}
+	if !strings.HasPrefix(raw, APIKeyPrefix) || key.Name != "ci" || !strings.HasPrefix(raw, key.Prefix) || len(key.Prefix) != apiKeyHintLength {
+		t.Fatalf("unexpected key %+v (%s)", key, raw)
+	}
+	if _, ok := store.apiKeys[raw]; ok {
+		t.Fatalf("expected the key to be stored hashed")
+	}
+
+	claims, err := svc.ParseAndValidateToken(ctx, raw)
+	if err != nil {
+		t.Fatalf("ParseAndValidateToken error: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.Email != "test@example.com" || claims.APIKeyID != key.ID || store.touched != 1 {
+		t.Fatalf("unexpected claims %+v (touched %d)", claims, store.touched)
+	}
+	if len(claims.Scopes) != len(AllScopes) || claims.Restricted() {
+		t.Fatalf("expected an unrestricted key with every scope, got %+v", claims)
+	}
+	if err := svc.Logout(ctx, claims, ""); !errors.Is(err, ErrNotSession) {
+		t.Fatalf("expected ErrNotSession for an api key logout, got %v", err)
+	}
+
+	if _, err := svc.ParseAndValidateToken(ctx, APIKeyPrefix+"unknown"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected ErrInvalidAPIKey, got %v", err)
+	}
+
+	if err := svc.DeleteAPIKey(ctx, "user-2", key.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected another user's delete to fail, got %v", err)
+	}
+	if err := svc.DeleteAPIKey(ctx, "user-1", key.ID); err != nil {
+		t.Fatalf("DeleteAPIKey error: %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, raw); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected a deleted key to be rejected, got %v", err)
+	}
+}
+
+func TestServiceAPIKeyExpiry(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Minute)
+	if _, _, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: "old", ExpiresAt: &past}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for a past expiry, got %v", err)
+	}
+	if _, _, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: "  "}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput without a name, got %v", err)
+	}
+
+	soon := time.Now().Add(time.Hour)
+	_, raw, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: "ci", ExpiresAt: &soon})
+	if err != nil {
+		t.Fatalf("CreateAPIKey error: %v", err)
+	}
+	svc.now = func() time.Time { return soon }
+	if _, err := svc.ParseAndValidateToken(ctx, raw); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected an expired key to be rejected, got %v", err)
+	}
+}
+
+func TestServiceAPIKeyScopes(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	if _, _, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: "ci", Scopes: []string{}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput without scopes, got %v", err)
+	}
+	if _, _, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: "ci", Scopes: []string{"runs:delete"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for an unknown scope, got %v", err)
+	}
+
+	_, raw, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{
+		Name:        "deploy",
+		Scopes:      []string{ScopeRunsTrigger},
+		WorkflowIDs: []string{"wf-1"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey error: %v", err)
+	}
+	claims, err := svc.ParseAndValidateToken(ctx, raw)
+	if err != nil {
+		t.Fatalf("ParseAndValidateToken error: %v", err)
+	}
+	if !claims.HasScope(ScopeRunsTrigger) || claims.HasScope(ScopeWorkflowsWrite) {
+		t.Fatalf("unexpected scopes %v", claims.Scopes)
+	}
+	if !claims.Restricted() || !claims.AllowsWorkflow("wf-1") || claims.AllowsWorkflow("wf-2") {
+		t.Fatalf("unexpected workflow restriction %v", claims.WorkflowIDs)
+	}
+}
@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Failed logins are throttled per account and per client IP; password reset requests are rate
+// limited per email and per client IP.
+const (
+	ThrottleAccount    = "account"
+	ThrottleIP         = "ip"
+	ThrottleResetEmail = "reset_email"
+	ThrottleResetIP    = "reset_ip"
+)
+
+// AuditLoginLocked is recorded when an account or IP is locked out after too many failed logins.
+const AuditLoginLocked = "login.locked"
+
+// ErrLoginThrottled is wrapped by *ThrottledError.
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// ThrottledError rejects a login attempt without checking the password; RetryAfter says when the
+// next attempt is accepted.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string { return ErrLoginThrottled.Error() }
+
+func (e *ThrottledError) Unwrap() error { return ErrLoginThrottled }
+
+// LockoutPolicy decides how long failed logins block further attempts. Failures are counted per
+// account and per IP within Window. After FreeAttempts failures each further attempt has to wait
+// BaseDelay, doubling per failure up to MaxDelay; reaching AccountThreshold (or IPThreshold)
+// failures locks the account (or IP) out for LockoutDuration.
+//
+// Password reset requests are limited to ResetsPerEmail per email and ResetsPerIP per IP within
+// Window; zero disables a limit.
+type LockoutPolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	AccountThreshold int
+	IPThreshold      int
+	Window           time.Duration
+	LockoutDuration  time.Duration
+	ResetsPerEmail   int
+	ResetsPerIP      int
+}
+
+// DefaultLockoutPolicy returns the policy used unless SetLockoutPolicy overrides it.
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         30 * time.Second,
+		AccountThreshold: 10,
+		IPThreshold:      50,
+		Window:           15 * time.Minute,
+		LockoutDuration:  15 * time.Minute,
+		ResetsPerEmail:   3,
+		ResetsPerIP:      20,
+	}
+}
+
+// block returns how long failures (counted against threshold) block further attempts and whether
+// that is a lockout.
+func (p LockoutPolicy) block(failures, threshold int) (time.Duration, bool) {
+	if threshold > 0 && failures >= threshold {
+		return p.LockoutDuration, true
+	}
+	if failures < p.FreeAttempts {
+		return 0, false
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// AuditEvent is an entry of the audit trail. UserID is empty when the event isn't tied to a known
+// user, e.g. a lockout of an IP or of an email without an account.
+type AuditEvent struct {
+	ID        string
+	Event     string
+	UserID    string
+	IP        string
+	Details   json.RawMessage
+	CreatedAt time.Time
+}
+
+// SetLockoutPolicy replaces the login throttling policy.
+func (s *Service) SetLockoutPolicy(p LockoutPolicy) {
+	s.lockout = p
+}
+
+// defaultAuditLimit caps ListAuditEvents when no limit is given.
+const defaultAuditLimit = 100
+
+// ListAuditEvents returns the newest audit events, optionally only those named event.
+func (s *Service) ListAuditEvents(ctx context.Context, event string, limit int32) ([]AuditEvent, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	return s.store.ListAuditEvents(ctx, event, limit)
+}
+
+type throttleKey struct {
+	scope, key string
+	threshold  int
+}
+
+// throttleKeys returns the counters a login attempt is checked against. The account is keyed by
+// email rather than user ID so unknown emails are throttled like real ones.
+func (s *Service) throttleKeys(email, ip string) []throttleKey {
+	keys := []throttleKey{{ThrottleAccount, strings.ToLower(strings.TrimSpace(email)), s.lockout.AccountThreshold}}
+	if ip != "" {
+		keys = append(keys, throttleKey{ThrottleIP, ip, s.lockout.IPThreshold})
+	}
+	return keys
+}
+
+// checkThrottle returns a *ThrottledError if any key is blocked.
+func (s *Service) checkThrottle(ctx context.Context, keys []throttleKey) error {
+	now := s.now()
+	for _, k := range keys {
+		until, err := s.store.GetLoginBlock(ctx, k.scope, k.key)
+		if err != nil {
+			return err
+		}
+		if until.After(now) {
+			return &ThrottledError{RetryAfter: until.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// loginAttempt is an attempt counted against its throttle keys; failures holds the count each key
+// reached with it. It ends with failAttempt or succeedAttempt, or is given back by abandonAttempt.
+type loginAttempt struct {
+	keys     []throttleKey
+	failures []int
+	ip       string
+	settled  bool
+}
+
+// beginAttempt refuses the attempt with a *ThrottledError if any key is blocked, and otherwise
+// counts it against every key before any secret is checked. Each count is one atomic increment,
+// so concurrent guesses can't all pass while the first ones are still being verified: an attempt
+// that takes a key past its threshold is refused too.
+func (s *Service) beginAttempt(ctx context.Context, keys []throttleKey, ip string) (*loginAttempt, error) {
+	if err := s.checkThrottle(ctx, keys); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	a := &loginAttempt{ip: ip}
+	for _, k := range keys {
+		failures, windowStarted, err := s.store.ReserveLoginAttempt(ctx, k.scope, k.key, now, now.Add(-s.lockout.Window))
+		if err != nil {
+			s.abandonAttempt(ctx, a)
+			return nil, err
+		}
+		a.keys = append(a.keys, k)
+		a.failures = append(a.failures, failures)
+		if k.threshold > 0 && failures > k.threshold {
+			s.abandonAttempt(ctx, a)
+			return nil, &ThrottledError{RetryAfter: windowStarted.Add(s.lockout.Window).Sub(now)}
+		}
+	}
+	return a, nil
+}
+
+// rateLimit counts a request against every key and refuses it with a *ThrottledError once a key
+// has counted more than its threshold within the policy's window. Unlike login attempts, requests
+// stay counted whatever their outcome.
+func (s *Service) rateLimit(ctx context.Context, keys []throttleKey) error {
+	a, err := s.beginAttempt(ctx, keys, "")
+	if err != nil {
+		return err
+	}
+	a.settled = true
+	return nil
+}
+
+// abandonAttempt gives back the counts of an attempt that neither failed nor succeeded, e.g. one
+// that ended in an error or a two-factor challenge. It does nothing once the attempt is settled,
+// so it can be deferred.
+func (s *Service) abandonAttempt(ctx context.Context, a *loginAttempt) {
+	if a.settled {
+		return
+	}
+	a.settled = true
+	// The request may be gone; its counts still have to be given back.
+	ctx = context.WithoutCancel(ctx)
+	for _, k := range a.keys {
+		if err := s.store.ReleaseLoginAttempt(ctx, k.scope, k.key); err != nil {
+			log.Warn().Err(err).Str("scope", k.scope).Str("key", k.key).Msg("failed to release login attempt")
+		}
+	}
+}
+
+// succeedAttempt gives back the attempt's counts and forgets the account's failures. Only the
+// account is cleared: one valid account mustn't reset an IP's count.
+func (s *Service) succeedAttempt(ctx context.Context, a *loginAttempt) error {
+	s.abandonAttempt(ctx, a)
+	return s.store.ClearLoginFailures(ctx, ThrottleAccount, a.keys[0].key, s.now().Add(-s.lockout.Window))
+}
+
+// failAttempt keeps the attempt counted, blocks keys per the policy and records lockouts in the
+// audit trail. userID is empty for unknown emails.
+func (s *Service) failAttempt(ctx context.Context, a *loginAttempt, userID string) error {
+	a.settled = true
+	now := s.now()
+	for i, k := range a.keys {
+		failures := a.failures[i]
+		delay, locked := s.lockout.block(failures, k.threshold)
+		if delay <= 0 {
+			continue
+		}
+		until := now.Add(delay)
+		if err := s.store.BlockLogin(ctx, k.scope, k.key, until); err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+		log.Warn().Str("scope", k.scope).Str("key", k.key).Int("failures", failures).Msg("login locked out")
+		details, err := json.Marshal(map[string]any{
+			"scope":        k.scope,
+			"key":          k.key,
+			"failures":     failures,
+			"locked_until": until,
+		})
+		if err != nil {
+			return err
+		}
+		event := AuditEvent{Event: AuditLoginLocked, IP: a.ip, Details: details}
+		if k.scope == ThrottleAccount {
+			event.UserID = userID
+		}
+		if err := s.store.CreateAuditEvent(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyUserPassword checks a signed-in user's password, e.g. before a sensitive account change,
+// as a login attempt from ip: it is throttled like one and a wrong password fails it. On success
+// the attempt is still open for further checks; the caller settles or abandons it.
+func (s *Service) verifyUserPassword(ctx context.Context, userID, password, ip string) (User, *loginAttempt, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return User{}, nil, err
+	}
+	attempt, err := s.beginAttempt(ctx, s.throttleKeys(user.Email, ip), ip)
+	if err != nil {
+		return User{}, nil, err
+	}
+	record, err := s.store.GetUserByEmail(ctx, user.Email)
+	if err != nil {
+		s.abandonAttempt(ctx, attempt)
+		return User{}, nil, err
+	}
+	ok, err := VerifyPassword(password, record.PasswordHash)
+	if err != nil {
+		s.abandonAttempt(ctx, attempt)
+		return User{}, nil, err
+	}
+	if !ok {
+		if err := s.failAttempt(ctx, attempt, userID); err != nil {
+			return User{}, nil, err
+		}
+		return User{}, nil, ErrInvalidCredentials
+	}
+	return user, attempt, nil
+}
+
+// verifyDummyPassword spends as long as verifying a real password, so logins for unknown emails
+// don't answer faster than those for existing accounts.
+func (s *Service) verifyDummyPassword(password string) error {
+	s.dummyOnce.Do(func() {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			s.dummyErr = err
+			return
+		}
+		s.dummyHash, s.dummyErr = HashPassword(base64.RawURLEncoding.EncodeToString(b), s.params)
+	})
+	if s.dummyErr != nil {
+		return s.dummyErr
+	}
+	_, err := VerifyPassword(password, s.dummyHash)
+	return err
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func (s *refreshStore) GetLoginBlock(ctx context.Context, scope, key string) (time.Time, error) {
+	return s.blocks[scope+"/"+key], nil
+}
+
+func (s *refreshStore) ReserveLoginAttempt(ctx context.Context, scope, key string, at, windowStart time.Time) (int, time.Time, error) {
+	s.failures[scope+"/"+key]++
+	return s.failures[scope+"/"+key], at, nil
+}
+
+func (s *refreshStore) ReleaseLoginAttempt(ctx context.Context, scope, key string) error {
+	if s.failures[scope+"/"+key] > 0 {
+		s.failures[scope+"/"+key]--
+	}
+	return nil
+}
+
+func (s *refreshStore) BlockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	s.blocks[scope+"/"+key] = until
+	return nil
+}
+
+func (s *refreshStore) ClearLoginFailures(ctx context.Context, scope, key string, staleBefore time.Time) error {
+	delete(s.failures, scope+"/"+key)
+	delete(s.blocks, scope+"/"+key)
+	return nil
+}
+
+func (s *refreshStore) CreateAuditEvent(ctx context.Context, event AuditEvent) error {
+	s.audits = append(s.audits, event)
+	return nil
+}
+
+func (s *refreshStore) ListAuditEvents(ctx context.Context, event string, limit int32) ([]AuditEvent, error) {
+	return s.audits, nil
+}
+
+var testLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     2,
+	BaseDelay:        time.Second,
+	MaxDelay:         4 * time.Second,
+	AccountThreshold: 5,
+	IPThreshold:      8,
+	Window:           time.Minute,
+	LockoutDuration:  time.Hour,
+}
+
+func TestLockoutPolicyBlock(t *testing.T) {
+	for _, tc := range []struct {
+		failures int
+		delay    time.Duration
+		locked   bool
+	}{
+		{1, 0, false},
+		{2, time.Second, false},
+		{3, 2 * time.Second, false},
+		{4, 4 * time.Second, false},
+		{5, time.Hour, true},
+	} {
+		delay, locked := testLockoutPolicy.block(tc.failures, testLockoutPolicy.AccountThreshold)
+		if delay != tc.delay || locked != tc.locked {
+			t.Errorf("%d failures: expected %v/%v, got %v/%v", tc.failures, tc.delay, tc.locked, delay, locked)
+		}
+	}
+	if delay, _ := testLockoutPolicy.block(100, 0); delay != testLockoutPolicy.MaxDelay {
+		t.Fatalf("expected the delay to be capped without a threshold, got %v", delay)
+	}
+}
+
+func TestServiceLoginThrottling(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	svc.SetLockoutPolicy(testLockoutPolicy)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := svc.Login(ctx, "test@example.com", "wrong", "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+	}
+
+	_, _, err := svc.Login(ctx, "Test@Example.com", "secret", "192.0.2.1")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter != time.Second || !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("expected a one second delay, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1"); err != nil {
+		t.Fatalf("expected login after the delay, got %v", err)
+	}
+	if store.failures[ThrottleAccount+"/test@example.com"] != 0 || store.failures[ThrottleIP+"/192.0.2.1"] != 2 {
+		t.Fatalf("expected only the account counter to be cleared, got %v", store.failures)
+	}
+}
+
+func TestServiceLoginLockout(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	svc.SetLockoutPolicy(testLockoutPolicy)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < testLockoutPolicy.AccountThreshold; i++ {
+		if _, _, err := svc.Login(ctx, "test@example.com", "wrong", "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+		now = now.Add(testLockoutPolicy.MaxDelay)
+	}
+
+	_, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter != time.Hour-testLockoutPolicy.MaxDelay {
+		t.Fatalf("expected the account to be locked out, got %v", err)
+	}
+
+	events, _ := svc.ListAuditEvents(ctx, AuditLoginLocked, 0)
+	if len(events) != 1 || events[0].Event != AuditLoginLocked || events[0].UserID != "user-1" || events[0].IP != "192.0.2.1" {
+		t.Fatalf("expected one lockout event for user-1, got %+v", events)
+	}
+	var details map[string]any
+	if err := json.Unmarshal(events[0].Details, &details); err != nil || details["scope"] != ThrottleAccount {
+		t.Fatalf("unexpected details %s", events[0].Details)
+	}
+
+	// The same IP is still allowed to log into other accounts until its own threshold.
+	if _, _, err := svc.Login(ctx, "other@example.com", "pw", "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for another account, got %v", err)
+	}
+}
+
+func TestServiceLoginThrottlesUnknownEmailsAndIPs(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	policy := testLockoutPolicy
+	policy.FreeAttempts = 100
+	policy.IPThreshold = 3
+	svc.SetLockoutPolicy(policy)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if _, _, err := svc.Login(ctx, email, "pw", "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("%s: expected ErrInvalidCredentials, got %v", email, err)
+		}
+	}
+	if store.failures[ThrottleAccount+"/a@example.com"] != 1 {
+		t.Fatalf("expected unknown emails to be counted, got %v", store.failures)
+	}
+
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1"); !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("expected the IP to be locked out, got %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "198.51.100.7"); err != nil {
+		t.Fatalf("expected other IPs to log in, got %v", err)
+	}
+	if len(store.audits) != 1 || store.audits[0].UserID != "" {
+		t.Fatalf("expected one IP lockout without a user, got %+v", store.audits)
+	}
+}
+
+func TestServiceLoginCountsAttemptsUpFront(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	svc.SetLockoutPolicy(testLockoutPolicy)
+	ctx := context.Background()
+
+	// As many attempts as the threshold allows are still being verified; none has failed yet, so
+	// nothing is blocked, but one more must not be let through.
+	key := ThrottleAccount + "/test@example.com"
+	store.failures[key] = testLockoutPolicy.AccountThreshold
+	_, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter != testLockoutPolicy.Window {
+		t.Fatalf("expected an attempt past the threshold to be refused until the window ends, got %v", err)
+	}
+	if store.failures[key] != testLockoutPolicy.AccountThreshold || store.failures[ThrottleIP+"/192.0.2.1"] != 0 {
+		t.Fatalf("expected the refused attempt to be given back, got %v", store.failures)
+	}
+
+	store.failures[key] = testLockoutPolicy.AccountThreshold - 1
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1"); err != nil {
+		t.Fatalf("expected the last attempt within the threshold to log in, got %v", err)
+	}
+	if len(store.failures) != 1 || store.failures[ThrottleIP+"/192.0.2.1"] != 0 {
+		t.Fatalf("expected a successful login to clear the account and give back the IP's count, got %v", store.failures)
+	}
+}
@@ -103,6 +103,20 @@ func VerifyPassword(password, encodedHash string) (bool, error) {
 	return false, nil
 }
 
+// NeedsRehash reports whether encodedHash was made with weaker settings than p, so it should be
+// replaced by a hash with p the next time the password is known.
+func NeedsRehash(encodedHash string, p Params) (bool, error) {
+	current, _, _, err := decodeHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	return current.Memory < p.Memory ||
+		current.Iterations < p.Iterations ||
+		current.Parallelism < p.Parallelism ||
+		current.SaltLength < p.SaltLength ||
+		current.KeyLength < p.KeyLength, nil
+}
+
 func decodeHash(encoded string) (Params, []byte, []byte, error) {
 	parts := strings.Split(encoded, "$")
 	if len(parts) != 6 {
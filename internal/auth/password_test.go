@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestHashAndVerify_Success(t *testing.T) {
@@ -74,3 +77,114 @@ func TestParamsFromEnv_Invalid(t *testing.T) {
 		t.Fatalf("expected error for invalid override")
 	}
 }
+
+func TestNeedsRehash(t *testing.T) {
+	hash, err := HashPassword("pw", testParams)
+	if err != nil {
+		t.Fatalf("HashPassword error: %v", err)
+	}
+
+	stronger := testParams
+	stronger.Iterations++
+	weaker := testParams
+	weaker.Memory /= 2
+	for name, tc := range map[string]struct {
+		params Params
+		want   bool
+	}{
+		"same":     {testParams, false},
+		"weaker":   {weaker, false},
+		"stronger": {stronger, true},
+	} {
+		got, err := NeedsRehash(hash, tc.params)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: expected %v, got %v (%v)", name, tc.want, got, err)
+		}
+	}
+
+	if _, err := NeedsRehash("not-a-valid-hash", testParams); err == nil {
+		t.Fatalf("expected error for invalid hash encoding")
+	}
+}
+
+func TestServiceLoginUpgradesPasswordHash(t *testing.T) {
+	store := newRefreshStore()
+	stronger := testParams
+	stronger.Iterations = 2
+	svc := NewService(store, stronger, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	if _, _, err := svc.Login(ctx, "test@example.com", "wrong", "192.0.2.1"); err == nil {
+		t.Fatalf("expected wrong password to fail")
+	}
+	if stale, _ := NeedsRehash(store.userWithHash.PasswordHash, stronger); !stale {
+		t.Fatalf("expected a failed login to leave the hash alone")
+	}
+
+	disabled := time.Now()
+	store.userWithHash.DisabledAt = &disabled
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1"); !errors.Is(err, ErrAccountDisabled) {
+		t.Fatalf("expected ErrAccountDisabled, got %v", err)
+	}
+	if stale, _ := NeedsRehash(store.userWithHash.PasswordHash, stronger); !stale {
+		t.Fatalf("expected a refused login to leave the hash alone")
+	}
+	store.userWithHash.DisabledAt = nil
+
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1"); err != nil {
+		t.Fatalf("Login error: %v", err)
+	}
+	upgraded := store.userWithHash.PasswordHash
+	if stale, _ := NeedsRehash(upgraded, stronger); stale {
+		t.Fatalf("expected the hash to be upgraded, got %s", upgraded)
+	}
+	if ok, _ := VerifyPassword("secret", upgraded); !ok {
+		t.Fatalf("expected the upgraded hash to verify")
+	}
+
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1"); err != nil {
+		t.Fatalf("Login error: %v", err)
+	}
+	if store.userWithHash.PasswordHash != upgraded {
+		t.Fatalf("expected a current hash to be kept")
+	}
+
+	// A password changed since the login read the hash is kept.
+	stale, _ := HashPassword("secret", testParams)
+	svc.upgradePasswordHash(ctx, "user-1", stale, svc.rehashPassword("user-1", "secret", stale))
+	if store.userWithHash.PasswordHash != upgraded {
+		t.Fatalf("expected an upgrade of a replaced hash to be skipped")
+	}
+}
+
+func TestServiceLoginTOTPUpgradesPasswordHash(t *testing.T) {
+	store := newRefreshStore()
+	stronger := testParams
+	stronger.Iterations = 2
+	svc := NewService(store, stronger, []byte("secret"), time.Minute, time.Hour)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+	secret, _ := enableTOTP(t, svc)
+
+	_, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1")
+	var required *TOTPRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("expected a login challenge, got %v", err)
+	}
+	if stale, _ := NeedsRehash(store.userWithHash.PasswordHash, stronger); !stale {
+		t.Fatalf("expected the hash to wait for the second factor")
+	}
+
+	now = now.Add(totpPeriod * time.Second)
+	if _, _, err := svc.LoginTOTP(ctx, required.ChallengeToken, currentCode(t, secret, now), "192.0.2.1"); err != nil {
+		t.Fatalf("LoginTOTP error: %v", err)
+	}
+	upgraded := store.userWithHash.PasswordHash
+	if stale, _ := NeedsRehash(upgraded, stronger); stale {
+		t.Fatalf("expected the hash to be upgraded, got %s", upgraded)
+	}
+	if ok, _ := VerifyPassword("secret", upgraded); !ok {
+		t.Fatalf("expected the upgraded hash to verify")
+	}
+}
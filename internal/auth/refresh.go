@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused means a token that was already rotated out was presented again, so
+	// its family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// refreshTokenBytes is the entropy of an opaque refresh token.
+const refreshTokenBytes = 32
+
+// RefreshToken is a stored refresh token. Tokens issued by rotating one another share a FamilyID.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+// TokenPair is what a login or refresh hands to the client: a short-lived access JWT and the
+// opaque refresh token that replaces it once it expires.
+type TokenPair struct {
+	AccessToken      string
+	ExpiresAt        time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Refresh rotates a refresh token: it is marked used and a new pair is issued in the same family.
+// Presenting a token that was already rotated out revokes its whole family, since either the
+// client or an attacker holds a stolen copy.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (User, TokenPair, error) {
+	current, err := s.store.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, TokenPair{}, ErrInvalidRefreshToken
+		}
+		return User{}, TokenPair{}, err
+	}
+	if current.RevokedAt != nil {
+		return User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+	if current.RotatedAt != nil {
+		return User{}, TokenPair{}, s.revokeReused(ctx, current)
+	}
+	if !s.now().Before(current.ExpiresAt) {
+		return User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.store.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, TokenPair{}, ErrInvalidRefreshToken
+		}
+		return User{}, TokenPair{}, err
+	}
+	if user.DisabledAt != nil {
+		return User{}, TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return User{}, TokenPair{}, err
+	}
+	next, err := s.store.RotateRefreshToken(ctx, current, hash, s.now().Add(s.refreshExpiry))
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			// Another request rotated it first: the same token was used twice.
+			return User{}, TokenPair{}, s.revokeReused(ctx, current)
+		}
+		return User{}, TokenPair{}, err
+	}
+
+	pair, err := s.accessToken(user)
+	if err != nil {
+		return User{}, TokenPair{}, err
+	}
+	pair.RefreshToken = raw
+	pair.RefreshExpiresAt = next.ExpiresAt
+	return user, pair, nil
+}
+
+func (s *Service) revokeReused(ctx context.Context, token RefreshToken) error {
+	log.Warn().Str("user_id", token.UserID).Str("family_id", token.FamilyID).Msg("refresh token reused, revoking its family")
+	if err := s.store.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens starts a new refresh token family for user and returns it with an access token.
+func (s *Service) issueTokens(ctx context.Context, user User) (TokenPair, error) {
+	pair, err := s.accessToken(user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	stored, err := s.store.CreateRefreshToken(ctx, user.ID, "", hash, s.now().Add(s.refreshExpiry))
+	if err != nil {
+		return TokenPair{}, err
+	}
+	pair.RefreshToken = raw
+	pair.RefreshExpiresAt = stored.ExpiresAt
+	return pair, nil
+}
+
+func (s *Service) accessToken(user User) (TokenPair, error) {
+	token, err := GenerateToken(user.ID, user.Email, user.Role, s.jwtSecret, s.jwtExpiry)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: token, ExpiresAt: s.now().Add(s.jwtExpiry)}, nil
+}
+
+// newRefreshToken returns a random opaque token and the hash it is stored under.
+func newRefreshToken() (raw, hash string, err error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, hashToken(raw), nil
+}
+
+// hashToken is how opaque tokens are stored: they carry enough entropy that a fast hash suffices.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
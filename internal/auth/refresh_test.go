@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// refreshStore keeps refresh tokens, revocations, API keys and the other per-user auth state in
+// memory on top of a fixed user.
+type refreshStore struct {
+	fakeStoreImpl
+	tokens map[string]*RefreshToken // by hash
+	seq    int
+
+	revoked    map[string]bool
+	validAfter time.Time
+	checks     int
+
+	apiKeys map[string]*APIKey // by hash
+	touched int
+
+	failures map[string]int       // by scope/key
+	blocks   map[string]time.Time // by scope/key
+	audits   []AuditEvent
+
+	totp       *TOTP
+	recovery   map[string]bool // by hash; true once used
+	challenges map[string]LoginChallenge
+
+	accountTokens map[string]*accountToken // by hash
+}
+
+func newRefreshStore() *refreshStore {
+	hash, _ := HashPassword("secret", testParams)
+	return &refreshStore{
+		fakeStoreImpl: fakeStoreImpl{userWithHash: UserWithHash{
+			User:         User{ID: "user-1", Email: "test@example.com", Role: RoleMember},
+			PasswordHash: hash,
+		}},
+		tokens:        make(map[string]*RefreshToken),
+		revoked:       make(map[string]bool),
+		apiKeys:       make(map[string]*APIKey),
+		failures:      make(map[string]int),
+		blocks:        make(map[string]time.Time),
+		recovery:      make(map[string]bool),
+		challenges:    make(map[string]LoginChallenge),
+		accountTokens: make(map[string]*accountToken),
+	}
+}
+
+var testParams = Params{Memory: 32 * 1024, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+
+func (s *refreshStore) GetUserByID(ctx context.Context, id string) (User, error) {
+	if id != s.userWithHash.ID {
+		return User{}, ErrNotFound
+	}
+	return s.userWithHash.User, nil
+}
+
+func (s *refreshStore) CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	s.seq++
+	if familyID == "" {
+		familyID = fmt.Sprintf("family-%d", s.seq)
+	}
+	t := &RefreshToken{ID: fmt.Sprintf("rt-%d", s.seq), UserID: userID, FamilyID: familyID, ExpiresAt: expiresAt}
+	s.tokens[tokenHash] = t
+	return *t, nil
+}
+
+func (s *refreshStore) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	t, ok := s.tokens[tokenHash]
+	if !ok {
+		return RefreshToken{}, ErrNotFound
+	}
+	return *t, nil
+}
+
+func (s *refreshStore) RotateRefreshToken(ctx context.Context, old RefreshToken, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	for _, t := range s.tokens {
+		if t.ID != old.ID {
+			continue
+		}
+		if t.RotatedAt != nil || t.RevokedAt != nil {
+			return RefreshToken{}, ErrRefreshTokenReused
+		}
+		now := time.Now()
+		t.RotatedAt = &now
+	}
+	return s.CreateRefreshToken(ctx, old.UserID, old.FamilyID, tokenHash, expiresAt)
+}
+
+func (s *refreshStore) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, t := range s.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *refreshStore) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	s.revoked[jti] = true
+	return nil
+}
+
+func (s *refreshStore) CheckTokenRevocation(ctx context.Context, jti, userID string) (bool, time.Time, error) {
+	s.checks++
+	if userID != s.userWithHash.ID {
+		return false, time.Time{}, ErrNotFound
+	}
+	return s.revoked[jti], s.validAfter, nil
+}
+
+func (s *refreshStore) RevokeUserSessions(ctx context.Context, userID string) (time.Time, error) {
+	s.validAfter = time.Now()
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &s.validAfter
+		}
+	}
+	return s.validAfter, nil
+}
+
+func TestServiceRefreshRotates(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, first, err := svc.Login(ctx, "test@example.com", "secret", "")
+	if err != nil {
+		t.Fatalf("Login error: %v", err)
+	}
+	user, second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh error: %v", err)
+	}
+	if user.ID != "user-1" || second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatalf("expected a new pair, got %+v", second)
+	}
+	claims, err := svc.ParseAndValidateToken(ctx, second.AccessToken)
+	if err != nil || claims.UserID != "user-1" {
+		t.Fatalf("unexpected claims %+v, err %v", claims, err)
+	}
+	if stored := store.tokens[hashToken(second.RefreshToken)]; stored.FamilyID != store.tokens[hashToken(first.RefreshToken)].FamilyID {
+		t.Fatalf("expected the rotated token to stay in its family")
+	}
+	if _, ok := store.tokens[second.RefreshToken]; ok {
+		t.Fatalf("expected refresh tokens to be stored hashed")
+	}
+
+	if _, third, err := svc.Refresh(ctx, second.RefreshToken); err != nil || third.RefreshToken == "" {
+		t.Fatalf("expected the newest token to keep working, got %v", err)
+	}
+}
+
+func TestServiceRefreshReuseRevokesFamily(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, first, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	_, other, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	_, second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh error: %v", err)
+	}
+
+	if _, _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, _, err := svc.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the family to be revoked, got %v", err)
+	}
+	if _, _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("expected another login's family to be unaffected, got %v", err)
+	}
+}
+
+func TestServiceRefreshInvalid(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	if _, _, err := svc.Refresh(ctx, "unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+	}
+
+	_, pair, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+}
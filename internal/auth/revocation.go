@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTokenRevoked means an otherwise valid access token was logged out.
+var ErrTokenRevoked = errors.New("token revoked")
+
+// revocationCacheTTL is how long a "not revoked" answer from Postgres is trusted. Revocations
+// made through this process apply at once; those made by another API replica within this window.
+const revocationCacheTTL = 10 * time.Second
+
+// revocationCache remembers revocation checks so authenticating a request doesn't always cost a
+// query. Revoked tokens stay cached until they expire; everything else for revocationCacheTTL.
+type revocationCache struct {
+	mu         sync.Mutex
+	tokens     map[string]revocationEntry // by jti
+	validAfter map[string]time.Time       // by user ID
+	lastSweep  time.Time
+}
+
+type revocationEntry struct {
+	revoked    bool
+	validAfter time.Time
+	until      time.Time
+}
+
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		tokens:     make(map[string]revocationEntry),
+		validAfter: make(map[string]time.Time),
+	}
+}
+
+// lookup returns the cached verdict for a token, if any.
+func (c *revocationCache) lookup(claims Claims, now time.Time) (revoked, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if after, found := c.validAfter[claims.UserID]; found && claims.IssuedAt.Before(after) {
+		return true, true
+	}
+	e, found := c.tokens[claims.ID]
+	if !found || now.After(e.until) {
+		return false, false
+	}
+	return e.revoked || claims.IssuedAt.Before(e.validAfter), true
+}
+
+// store caches the verdict Postgres gave for a token.
+func (c *revocationCache) store(claims Claims, revoked bool, validAfter, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until := now.Add(revocationCacheTTL)
+	if revoked {
+		until = claims.ExpiresAt
+	}
+	c.tokens[claims.ID] = revocationEntry{revoked: revoked, validAfter: validAfter, until: until}
+	c.sweep(now)
+}
+
+// revokeToken marks a single token revoked until it expires.
+func (c *revocationCache) revokeToken(claims Claims, now time.Time) {
+	c.store(claims, true, time.Time{}, now)
+}
+
+// revokeUser rejects every token of a user issued before after.
+func (c *revocationCache) revokeUser(userID string, after time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validAfter[userID] = after
+}
+
+// sweep drops stale entries at most once per TTL. A validAfter entry only has to outlive the
+// token entries cached before it was set; after that Postgres answers for it.
+func (c *revocationCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < revocationCacheTTL {
+		return
+	}
+	c.lastSweep = now
+	for jti, e := range c.tokens {
+		if now.After(e.until) {
+			delete(c.tokens, jti)
+		}
+	}
+	for userID, after := range c.validAfter {
+		if now.Sub(after) > revocationCacheTTL {
+			delete(c.validAfter, userID)
+		}
+	}
+}
+
+// checkRevoked reports whether claims belong to a logged-out token or session. Tokens of users
+// that no longer exist count as revoked.
+func (s *Service) checkRevoked(ctx context.Context, claims Claims) error {
+	now := s.now()
+	if revoked, ok := s.revocations.lookup(claims, now); ok {
+		if revoked {
+			return ErrTokenRevoked
+		}
+		return nil
+	}
+	revoked, validAfter, err := s.store.CheckTokenRevocation(ctx, claims.ID, claims.UserID)
+	if errors.Is(err, ErrNotFound) {
+		revoked, err = true, nil
+	}
+	if err != nil {
+		return err
+	}
+	s.revocations.store(claims, revoked, validAfter, now)
+	if revoked || claims.IssuedAt.Before(validAfter) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// ErrNotSession is returned when an API key tries to end a session; revoke the key instead.
+var ErrNotSession = errors.New("not a session token")
+
+// Logout revokes the access token described by claims and, when refreshToken is given, the
+// refresh token family it belongs to.
+func (s *Service) Logout(ctx context.Context, claims Claims, refreshToken string) error {
+	if claims.APIKeyID != "" {
+		return ErrNotSession
+	}
+	if err := s.store.RevokeToken(ctx, claims.ID, claims.UserID, claims.ExpiresAt); err != nil {
+		return err
+	}
+	s.revocations.revokeToken(claims, s.now())
+
+	if refreshToken == "" {
+		return nil
+	}
+	rt, err := s.store.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if rt.UserID != claims.UserID {
+		return nil
+	}
+	return s.store.RevokeRefreshTokenFamily(ctx, rt.FamilyID)
+}
+
+// LogoutAll ends every session of a user: access tokens issued so far stop working and all
+// refresh tokens are revoked.
+func (s *Service) LogoutAll(ctx context.Context, userID string) error {
+	after, err := s.store.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	s.revocations.revokeUser(userID, after)
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServiceLogout(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, pair, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	_, other, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	claims, err := svc.ParseAndValidateToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ParseAndValidateToken error: %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, pair.AccessToken); err != nil || store.checks != 1 {
+		t.Fatalf("expected the second check to be cached, got %d queries (err %v)", store.checks, err)
+	}
+
+	if err := svc.Logout(ctx, claims, pair.RefreshToken); err != nil {
+		t.Fatalf("Logout error: %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked after logout, got %v", err)
+	}
+	if _, _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the refresh token to be revoked, got %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, other.AccessToken); err != nil {
+		t.Fatalf("expected another session to survive, got %v", err)
+	}
+
+	// A fresh process (empty cache) asks Postgres.
+	svc2 := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	if _, err := svc2.ParseAndValidateToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected the revocation to be persisted, got %v", err)
+	}
+}
+
+func TestServiceLogoutAll(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, pair, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	if _, err := svc.ParseAndValidateToken(ctx, pair.AccessToken); err != nil {
+		t.Fatalf("ParseAndValidateToken error: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := svc.LogoutAll(ctx, "user-1"); err != nil {
+		t.Fatalf("LogoutAll error: %v", err)
+	}
+	// The cached "not revoked" verdict must not outlive the logout.
+	if _, err := svc.ParseAndValidateToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked after logout-all, got %v", err)
+	}
+	if _, _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected refresh tokens to be revoked, got %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	_, fresh, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	if _, err := svc.ParseAndValidateToken(ctx, fresh.AccessToken); err != nil {
+		t.Fatalf("expected a new login to work, got %v", err)
+	}
+}
+
+func TestServiceTokenOfDeletedUser(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	token, _ := GenerateToken("gone", "gone@example.com", RoleMember, []byte("secret"), time.Minute)
+	if _, err := svc.ParseAndValidateToken(context.Background(), token); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked for a deleted user, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Roles a user can have. Members manage their own workflows; admins may also manage users and see
+// every workflow on the instance.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ErrAccountDisabled is returned when a disabled user signs in with the right password.
+var ErrAccountDisabled = errors.New("account disabled")
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember
+}
+
+// ListUsers returns every user, oldest first.
+func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
+	return s.store.ListUsers(ctx)
+}
+
+// SetUserRole changes a user's role and ends their sessions, so no token keeps the old role.
+func (s *Service) SetUserRole(ctx context.Context, userID, role string) (User, error) {
+	if !ValidRole(role) {
+		return User{}, ErrInvalidInput
+	}
+	user, err := s.store.SetUserRole(ctx, userID, role)
+	if err != nil {
+		return User{}, err
+	}
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// DisableUser blocks a user from signing in and ends their sessions. Their API keys stop working
+// until the user is enabled again.
+func (s *Service) DisableUser(ctx context.Context, userID string) (User, error) {
+	user, err := s.store.SetUserDisabled(ctx, userID, true)
+	if err != nil {
+		return User{}, err
+	}
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// EnableUser lets a disabled user sign in again.
+func (s *Service) EnableUser(ctx context.Context, userID string) (User, error) {
+	return s.store.SetUserDisabled(ctx, userID, false)
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func (s *refreshStore) ListUsers(ctx context.Context) ([]User, error) {
+	return []User{s.userWithHash.User}, nil
+}
+
+func (s *refreshStore) SetUserRole(ctx context.Context, id, role string) (User, error) {
+	if id != s.userWithHash.ID {
+		return User{}, ErrNotFound
+	}
+	s.userWithHash.Role = role
+	return s.userWithHash.User, nil
+}
+
+func (s *refreshStore) SetUserDisabled(ctx context.Context, id string, disabled bool) (User, error) {
+	if id != s.userWithHash.ID {
+		return User{}, ErrNotFound
+	}
+	s.userWithHash.DisabledAt = nil
+	if disabled {
+		now := time.Now()
+		s.userWithHash.DisabledAt = &now
+	}
+	return s.userWithHash.User, nil
+}
+
+func TestServiceSetUserRole(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	if _, err := svc.SetUserRole(ctx, "user-1", "owner"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for an unknown role, got %v", err)
+	}
+	if _, err := svc.SetUserRole(ctx, "user-2", RoleAdmin); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown user, got %v", err)
+	}
+
+	_, before, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	time.Sleep(2 * time.Millisecond)
+	user, err := svc.SetUserRole(ctx, "user-1", RoleAdmin)
+	if err != nil || user.Role != RoleAdmin {
+		t.Fatalf("unexpected user %+v, err %v", user, err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, before.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected tokens with the old role to be revoked, got %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	_, after, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	claims, err := svc.ParseAndValidateToken(ctx, after.AccessToken)
+	if err != nil || claims.Role != RoleAdmin {
+		t.Fatalf("expected an admin token, got %+v (err %v)", claims, err)
+	}
+}
+
+func TestServiceDisableUser(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	_, pair, _ := svc.Login(ctx, "test@example.com", "secret", "")
+	_, key, err := svc.CreateAPIKey(ctx, "user-1", NewAPIKey{Name: "ci"})
+	if err != nil {
+		t.Fatalf("CreateAPIKey error: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := svc.DisableUser(ctx, "user-1"); err != nil {
+		t.Fatalf("DisableUser error: %v", err)
+	}
+
+	if _, err := svc.ParseAndValidateToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked, got %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected ErrInvalidAPIKey, got %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", ""); !errors.Is(err, ErrAccountDisabled) {
+		t.Fatalf("expected ErrAccountDisabled, got %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "wrong", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected a wrong password to stay ErrInvalidCredentials, got %v", err)
+	}
+
+	if _, err := svc.EnableUser(ctx, "user-1"); err != nil {
+		t.Fatalf("EnableUser error: %v", err)
+	}
+	if _, _, err := svc.Login(ctx, "test@example.com", "secret", ""); err != nil {
+		t.Fatalf("expected login to work again, got %v", err)
+	}
+	if _, err := svc.ParseAndValidateToken(ctx, key); err != nil {
+		t.Fatalf("expected the api key to work again, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import "slices"
+
+// Scopes limit what a token or API key may do on the user's behalf.
+const (
+	ScopeWorkflowsRead  = "workflows:read"
+	ScopeWorkflowsWrite = "workflows:write"
+	ScopeRunsTrigger    = "runs:trigger"
+	ScopeRunsRead       = "runs:read"
+)
+
+// AllScopes is what a login session, and an API key created without scopes, may do.
+var AllScopes = []string{ScopeWorkflowsRead, ScopeWorkflowsWrite, ScopeRunsTrigger, ScopeRunsRead}
+
+// ValidScope reports whether s is a known scope.
+func ValidScope(s string) bool {
+	return slices.Contains(AllScopes, s)
+}
+
+// HasScope reports whether the caller was granted scope.
+func (c Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// Restricted reports whether the caller may only touch the workflows in WorkflowIDs.
+func (c Claims) Restricted() bool {
+	return len(c.WorkflowIDs) > 0
+}
+
+// AllowsWorkflow reports whether the caller may touch workflow id.
+func (c Claims) AllowsWorkflow(id string) bool {
+	return !c.Restricted() || slices.Contains(c.WorkflowIDs, id)
+}
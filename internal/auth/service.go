@@ -3,15 +3,25 @@ package auth
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/email"
+	"github.com/rs/zerolog/log"
 )
 
-// User represents a sanitized view of the users table without the password hash.
+// User represents a sanitized view of the users table without the password hash. Disabled users
+// have a DisabledAt and can't sign in. EmailVerifiedAt is set once the user followed a
+// verification email.
 type User struct {
-	ID        string
-	Email     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID              string
+	Email           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Role            string
+	DisabledAt      *time.Time
+	EmailVerifiedAt *time.Time
 }
 
 // UserWithHash holds the stored hash for credential verification.
@@ -30,23 +40,121 @@ type Store interface {
 	CreateUser(ctx context.Context, email, passwordHash string) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (UserWithHash, error)
 	GetUserByID(ctx context.Context, id string) (User, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	SetUserRole(ctx context.Context, id, role string) (User, error)
+	SetUserDisabled(ctx context.Context, id string, disabled bool) (User, error)
+
+	// CreateRefreshToken stores a refresh token hash; an empty familyID starts a new family.
+	CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// RotateRefreshToken marks old as used and stores its successor in the same family. It
+	// returns ErrRefreshTokenReused if old was already used or revoked.
+	RotateRefreshToken(ctx context.Context, old RefreshToken, tokenHash string, expiresAt time.Time) (RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+
+	RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	// CheckTokenRevocation reports whether jti was revoked and the user's tokens_valid_after
+	// (zero if unset). It returns ErrNotFound if the user doesn't exist.
+	CheckTokenRevocation(ctx context.Context, jti, userID string) (bool, time.Time, error)
+	// RevokeUserSessions sets the user's tokens_valid_after to now, revokes all their refresh
+	// tokens and returns the new tokens_valid_after.
+	RevokeUserSessions(ctx context.Context, userID string) (time.Time, error)
+
+	// CreateAPIKey stores key under keyHash. It returns ErrInvalidInput for malformed workflow IDs.
+	CreateAPIKey(ctx context.Context, key APIKey, keyHash string) (APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	// GetAPIKeyByHash returns a key and its owner; only the owner's ID, Email, Role and
+	// DisabledAt are set.
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, User, error)
+	// TouchAPIKey records that a key was used; it may skip the write if it was used moments ago.
+	TouchAPIKey(ctx context.Context, id string) error
+	DeleteAPIKey(ctx context.Context, userID, id string) error
+
+	// GetLoginBlock returns until when login attempts for key in scope are refused; zero if never.
+	GetLoginBlock(ctx context.Context, scope, key string) (time.Time, error)
+	// ReserveLoginAttempt atomically counts an attempt at at and returns the attempts counted
+	// since windowStart and when that window started; a key whose window started earlier starts
+	// counting from one again. Attempts that didn't fail are given back with ReleaseLoginAttempt.
+	ReserveLoginAttempt(ctx context.Context, scope, key string, at, windowStart time.Time) (int, time.Time, error)
+	ReleaseLoginAttempt(ctx context.Context, scope, key string) error
+	BlockLogin(ctx context.Context, scope, key string, until time.Time) error
+	// ClearLoginFailures forgets the failures of key and prunes other keys that have been idle
+	// and unblocked since staleBefore.
+	ClearLoginFailures(ctx context.Context, scope, key string, staleBefore time.Time) error
+
+	CreateAuditEvent(ctx context.Context, event AuditEvent) error
+	// ListAuditEvents returns the newest events first; an empty event matches every event.
+	ListAuditEvents(ctx context.Context, event string, limit int32) ([]AuditEvent, error)
+
+	// GetTOTP returns ErrNotFound if the user never enrolled.
+	GetTOTP(ctx context.Context, userID string) (TOTP, error)
+	// SaveTOTPSecret stores a pending secret; it returns ErrTOTPAlreadyEnabled if TOTP is enabled.
+	SaveTOTPSecret(ctx context.Context, userID, secret string) error
+	// EnableTOTP enables the pending secret, accepting codes after step, and replaces the
+	// recovery codes. It returns ErrTOTPNotEnabled if no secret is pending.
+	EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	// UseTOTPStep advances the last accepted step; it reports false if step isn't newer.
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	// UseRecoveryCode marks a recovery code used; it reports false if there was no unused one.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	// DeleteTOTP removes the secret and recovery codes.
+	DeleteTOTP(ctx context.Context, userID string) error
+
+	// CreateLoginChallenge stores a challenge and prunes expired ones.
+	CreateLoginChallenge(ctx context.Context, tokenHash string, c LoginChallenge) error
+	GetLoginChallenge(ctx context.Context, tokenHash string) (LoginChallenge, error)
+	// DeleteLoginChallenge reports whether the challenge still existed.
+	DeleteLoginChallenge(ctx context.Context, tokenHash string) (bool, error)
+
+	// UpdatePasswordHash returns ErrNotFound if the user doesn't exist.
+	UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error
+	// UpgradePasswordHash replaces the hash only while it is still oldHash; otherwise it does
+	// nothing.
+	UpgradePasswordHash(ctx context.Context, userID, oldHash, newHash string) error
+	SetEmailVerified(ctx context.Context, userID string) error
+	// CreateAccountToken stores the hash of a mailed token and prunes expired ones.
+	CreateAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error
+	// UseAccountToken marks an unused, unexpired token used and returns its user; ErrNotFound
+	// otherwise.
+	UseAccountToken(ctx context.Context, tokenHash, purpose string) (string, error)
+	DeleteAccountTokens(ctx context.Context, userID, purpose string) error
 }
 
 // Service coordinates password hashing and user persistence.
 type Service struct {
-	store     Store
-	params    Params
-	jwtSecret []byte
-	jwtExpiry time.Duration
+	store         Store
+	params        Params
+	jwtSecret     []byte
+	jwtExpiry     time.Duration
+	refreshExpiry time.Duration
+	revocations   *revocationCache
+	lockout       LockoutPolicy
+	mailer        email.Sender
+	linkBase      string
+	now           func() time.Time
+
+	// mailing tracks password reset emails still being sent.
+	mailing sync.WaitGroup
+
+	// dummyHash is verified against for unknown emails; see verifyDummyPassword.
+	dummyOnce sync.Once
+	dummyHash string
+	dummyErr  error
 }
 
-// NewService constructs a Service with the provided store and Argon2 parameters.
-func NewService(store Store, params Params, jwtSecret []byte, jwtExpiry time.Duration) *Service {
+// NewService constructs a Service with the provided store and Argon2 parameters. Access tokens
+// live for jwtExpiry and refresh tokens for refreshExpiry.
+func NewService(store Store, params Params, jwtSecret []byte, jwtExpiry, refreshExpiry time.Duration) *Service {
 	return &Service{
-		store:     store,
-		params:    params,
-		jwtSecret: jwtSecret,
-		jwtExpiry: jwtExpiry,
+		store:         store,
+		params:        params,
+		jwtSecret:     jwtSecret,
+		jwtExpiry:     jwtExpiry,
+		refreshExpiry: refreshExpiry,
+		revocations:   newRevocationCache(),
+		lockout:       DefaultLockoutPolicy(),
+		mailer:        email.LogSender{},
+		now:           time.Now,
 	}
 }
 
@@ -57,38 +165,121 @@ func (s *Service) Register(ctx context.Context, email, password string) (User, e
 		return User{}, err
 	}
 
-	return s.store.CreateUser(ctx, email, hash)
+	user, err := s.store.CreateUser(ctx, email, hash)
+	if err != nil {
+		return User{}, err
+	}
+	s.sendWelcomeVerification(ctx, user.ID)
+	return user, nil
 }
 
-// Login verifies credentials and returns the user and a signed JWT.
-func (s *Service) Login(ctx context.Context, email, password string) (User, string, error) {
+// Login verifies credentials and returns the user with an access token and a new refresh token.
+// Failed attempts are throttled per account and per clientIP (skipped when empty); a throttled
+// attempt fails with a *ThrottledError before the password is checked. Accounts with two-factor
+// authentication get a *TOTPRequiredError instead of tokens; see LoginTOTP.
+func (s *Service) Login(ctx context.Context, email, password, clientIP string) (User, TokenPair, error) {
+	attempt, err := s.beginAttempt(ctx, s.throttleKeys(email, clientIP), clientIP)
+	if err != nil {
+		return User{}, TokenPair{}, err
+	}
+	defer s.abandonAttempt(ctx, attempt)
+
 	record, err := s.store.GetUserByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, ErrNotFound) {
-			return User{}, "", ErrInvalidCredentials
+		if !errors.Is(err, ErrNotFound) {
+			return User{}, TokenPair{}, err
+		}
+		if err := s.verifyDummyPassword(password); err != nil {
+			return User{}, TokenPair{}, err
 		}
-		return User{}, "", err
+		if err := s.failAttempt(ctx, attempt, ""); err != nil {
+			return User{}, TokenPair{}, err
+		}
+		return User{}, TokenPair{}, ErrInvalidCredentials
 	}
 
 	ok, err := VerifyPassword(password, record.PasswordHash)
 	if err != nil {
-		return User{}, "", err
+		return User{}, TokenPair{}, err
 	}
 	if !ok {
-		return User{}, "", ErrInvalidCredentials
+		if err := s.failAttempt(ctx, attempt, record.ID); err != nil {
+			return User{}, TokenPair{}, err
+		}
+		return User{}, TokenPair{}, ErrInvalidCredentials
 	}
+	rehash := s.rehashPassword(record.ID, password, record.PasswordHash)
 
-	token, err := GenerateToken(record.ID, record.Email, s.jwtSecret, s.jwtExpiry)
+	// With two-factor authentication the failures stay counted until LoginTOTP succeeds, so
+	// the password can't be used to reset them while guessing codes.
+	t, err := s.store.GetTOTP(ctx, record.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return User{}, TokenPair{}, err
+	}
+	if err == nil && t.EnabledAt != nil {
+		return User{}, TokenPair{}, s.challengeTOTP(ctx, LoginChallenge{
+			UserID:          record.ID,
+			OldPasswordHash: record.PasswordHash,
+			NewPasswordHash: rehash,
+		})
+	}
+	if err := s.succeedAttempt(ctx, attempt); err != nil {
+		return User{}, TokenPair{}, err
+	}
+	if record.DisabledAt != nil {
+		return User{}, TokenPair{}, ErrAccountDisabled
+	}
+	s.upgradePasswordHash(ctx, record.ID, record.PasswordHash, rehash)
+
+	pair, err := s.issueTokens(ctx, record.User)
 	if err != nil {
-		return User{}, "", err
+		return User{}, TokenPair{}, err
 	}
 
-	return record.User, token, nil
+	return record.User, pair, nil
 }
 
-// ParseAndValidateToken parses a JWT and returns the claims (user ID/email).
-func (s *Service) ParseAndValidateToken(tokenStr string) (Claims, error) {
-	return ParseToken(tokenStr, s.jwtSecret)
+// rehashPassword hashes a just-verified password again if its stored encodedHash predates the
+// current Params, and returns "" otherwise. Failures are only logged: the login itself is fine
+// and the next one tries again.
+func (s *Service) rehashPassword(userID, password, encodedHash string) string {
+	stale, err := NeedsRehash(encodedHash, s.params)
+	if err != nil || !stale {
+		return ""
+	}
+	hash, err := HashPassword(password, s.params)
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", userID).Msg("failed to upgrade password hash")
+		return ""
+	}
+	return hash
+}
+
+// upgradePasswordHash stores newHash from rehashPassword once a login completes. The hash is only
+// replaced while it is still oldHash, so a password changed in the meantime is kept.
+func (s *Service) upgradePasswordHash(ctx context.Context, userID, oldHash, newHash string) {
+	if newHash == "" {
+		return
+	}
+	if err := s.store.UpgradePasswordHash(ctx, userID, oldHash, newHash); err != nil {
+		log.Warn().Err(err).Str("user_id", userID).Msg("failed to upgrade password hash")
+	}
+}
+
+// ParseAndValidateToken authenticates a bearer credential: a pf_ API key, or a JWT that hasn't
+// been revoked. It returns the caller's claims.
+func (s *Service) ParseAndValidateToken(ctx context.Context, tokenStr string) (Claims, error) {
+	if strings.HasPrefix(tokenStr, APIKeyPrefix) {
+		return s.authenticateAPIKey(ctx, tokenStr)
+	}
+	claims, err := ParseToken(tokenStr, s.jwtSecret)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := s.checkRevoked(ctx, claims); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
 }
 
 // GetUser fetches a user by ID.
@@ -40,8 +40,156 @@ func (f fakeStore) GetUserByID(ctx context.Context, id string) (User, error) {
 	return User{}, ErrNotFound
 }
 
+func (f fakeStore) ListUsers(ctx context.Context) ([]User, error) {
+	return nil, nil
+}
+
+func (f fakeStore) SetUserRole(ctx context.Context, id, role string) (User, error) {
+	return User{}, ErrNotFound
+}
+
+func (f fakeStore) SetUserDisabled(ctx context.Context, id string, disabled bool) (User, error) {
+	return User{}, ErrNotFound
+}
+
+func (f fakeStore) CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	return RefreshToken{ID: "rt-1", UserID: userID, FamilyID: "family-1", ExpiresAt: expiresAt}, nil
+}
+
+func (f fakeStore) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	return RefreshToken{}, ErrNotFound
+}
+
+func (f fakeStore) RotateRefreshToken(ctx context.Context, old RefreshToken, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	return RefreshToken{}, ErrRefreshTokenReused
+}
+
+func (f fakeStore) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (f fakeStore) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	return nil
+}
+
+func (f fakeStore) CheckTokenRevocation(ctx context.Context, jti, userID string) (bool, time.Time, error) {
+	return false, time.Time{}, nil
+}
+
+func (f fakeStore) RevokeUserSessions(ctx context.Context, userID string) (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (f fakeStore) CreateAPIKey(ctx context.Context, key APIKey, keyHash string) (APIKey, error) {
+	return APIKey{}, nil
+}
+
+func (f fakeStore) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	return nil, nil
+}
+
+func (f fakeStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, User, error) {
+	return APIKey{}, User{}, ErrNotFound
+}
+
+func (f fakeStore) TouchAPIKey(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f fakeStore) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	return ErrNotFound
+}
+
+func (f fakeStore) GetLoginBlock(ctx context.Context, scope, key string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (f fakeStore) ReserveLoginAttempt(ctx context.Context, scope, key string, at, windowStart time.Time) (int, time.Time, error) {
+	return 1, at, nil
+}
+
+func (f fakeStore) ReleaseLoginAttempt(ctx context.Context, scope, key string) error {
+	return nil
+}
+
+func (f fakeStore) BlockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	return nil
+}
+
+func (f fakeStore) ClearLoginFailures(ctx context.Context, scope, key string, staleBefore time.Time) error {
+	return nil
+}
+
+func (f fakeStore) CreateAuditEvent(ctx context.Context, event AuditEvent) error {
+	return nil
+}
+
+func (f fakeStore) ListAuditEvents(ctx context.Context, event string, limit int32) ([]AuditEvent, error) {
+	return nil, nil
+}
+
+func (f fakeStore) GetTOTP(ctx context.Context, userID string) (TOTP, error) {
+	return TOTP{}, ErrNotFound
+}
+
+func (f fakeStore) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	return nil
+}
+
+func (f fakeStore) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	return nil
+}
+
+func (f fakeStore) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	return false, nil
+}
+
+func (f fakeStore) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func (f fakeStore) DeleteTOTP(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (f fakeStore) CreateLoginChallenge(ctx context.Context, tokenHash string, c LoginChallenge) error {
+	return nil
+}
+
+func (f fakeStore) GetLoginChallenge(ctx context.Context, tokenHash string) (LoginChallenge, error) {
+	return LoginChallenge{}, ErrNotFound
+}
+
+func (f fakeStore) DeleteLoginChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	return false, nil
+}
+
+func (f fakeStore) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	return nil
+}
+
+func (f fakeStore) UpgradePasswordHash(ctx context.Context, userID, oldHash, newHash string) error {
+	return nil
+}
+
+func (f fakeStore) SetEmailVerified(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (f fakeStore) CreateAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+
+func (f fakeStore) UseAccountToken(ctx context.Context, tokenHash, purpose string) (string, error) {
+	return "", ErrNotFound
+}
+
+func (f fakeStore) DeleteAccountTokens(ctx context.Context, userID, purpose string) error {
+	return nil
+}
+
 func TestServiceRegister_Success(t *testing.T) {
-	svc := NewService(fakeStore{}, DefaultParams(), []byte("secret"), time.Hour)
+	svc := NewService(fakeStore{}, DefaultParams(), []byte("secret"), time.Hour, 24*time.Hour)
 
 	u, err := svc.Register(context.Background(), "test@example.com", "password123")
 	if err != nil {
@@ -53,7 +201,7 @@ func TestServiceRegister_Success(t *testing.T) {
 }
 
 func TestServiceRegister_EmailExists(t *testing.T) {
-	svc := NewService(fakeStore{err: ErrEmailExists}, DefaultParams(), []byte("secret"), time.Hour)
+	svc := NewService(fakeStore{err: ErrEmailExists}, DefaultParams(), []byte("secret"), time.Hour, 24*time.Hour)
 
 	_, err := svc.Register(context.Background(), "dup@example.com", "password123")
 	if !errors.Is(err, ErrEmailExists) {
@@ -63,7 +211,7 @@ func TestServiceRegister_EmailExists(t *testing.T) {
 
 func TestServiceRegister_StoreError(t *testing.T) {
 	wantErr := errors.New("store failure")
-	svc := NewService(fakeStore{err: wantErr}, DefaultParams(), []byte("secret"), time.Hour)
+	svc := NewService(fakeStore{err: wantErr}, DefaultParams(), []byte("secret"), time.Hour, 24*time.Hour)
 
 	if _, err := svc.Register(context.Background(), "dup@example.com", "password123"); !errors.Is(err, wantErr) {
 		t.Fatalf("expected store error, got %v", err)
@@ -71,18 +219,18 @@ func TestServiceRegister_StoreError(t *testing.T) {
 }
 
 func TestServiceLogin_NotFound(t *testing.T) {
-	svc := NewService(fakeStore{}, DefaultParams(), []byte("secret"), time.Hour)
+	svc := NewService(fakeStore{}, DefaultParams(), []byte("secret"), time.Hour, 24*time.Hour)
 
-	if _, _, err := svc.Login(context.Background(), "missing@example.com", "pw"); !errors.Is(err, ErrInvalidCredentials) {
+	if _, _, err := svc.Login(context.Background(), "missing@example.com", "pw", ""); !errors.Is(err, ErrInvalidCredentials) {
 		t.Fatalf("expected ErrInvalidCredentials for missing user, got %v", err)
 	}
 }
 
 func TestServiceLogin_StoreError(t *testing.T) {
 	store := fakeStore{err: errors.New("store failure")}
-	svc := NewService(store, DefaultParams(), []byte("secret"), time.Hour)
+	svc := NewService(store, DefaultParams(), []byte("secret"), time.Hour, 24*time.Hour)
 
-	if _, _, err := svc.Login(context.Background(), "test@example.com", "pw"); !errors.Is(err, store.err) {
+	if _, _, err := svc.Login(context.Background(), "test@example.com", "pw", ""); !errors.Is(err, store.err) {
 		t.Fatalf("expected store error, got %v", err)
 	}
 }
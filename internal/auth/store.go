@@ -3,11 +3,13 @@ package auth
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -38,12 +40,7 @@ func (s *StorePG) CreateUser(ctx context.Context, email, passwordHash string) (U
 		return User{}, err
 	}
 
-	return User{
-		ID:        row.ID,
-		Email:     row.Email,
-		CreatedAt: row.CreatedAt.Time,
-		UpdatedAt: row.UpdatedAt.Time,
-	}, nil
+	return userFromRow(sqlc.GetUserByIDRow(row)), nil
 }
 
 func (s *StorePG) GetUserByEmail(ctx context.Context, email string) (UserWithHash, error) {
@@ -57,10 +54,13 @@ func (s *StorePG) GetUserByEmail(ctx context.Context, email string) (UserWithHas
 
 	return UserWithHash{
 		User: User{
-			ID:        row.ID,
-			Email:     row.Email,
-			CreatedAt: row.CreatedAt.Time,
-			UpdatedAt: row.UpdatedAt.Time,
+			ID:              row.ID,
+			Email:           row.Email,
+			CreatedAt:       row.CreatedAt.Time,
+			UpdatedAt:       row.UpdatedAt.Time,
+			Role:            row.Role,
+			DisabledAt:      timePtr(row.DisabledAt),
+			EmailVerifiedAt: timePtr(row.EmailVerifiedAt),
 		},
 		PasswordHash: row.PasswordHash,
 	}, nil
@@ -75,10 +75,534 @@ func (s *StorePG) GetUserByID(ctx context.Context, id string) (User, error) {
 		return User{}, err
 	}
 
+	return userFromRow(row), nil
+}
+
+func (s *StorePG) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.queries.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, userFromRow(sqlc.GetUserByIDRow(row)))
+	}
+	return users, nil
+}
+
+func (s *StorePG) SetUserRole(ctx context.Context, id, role string) (User, error) {
+	row, err := s.queries.SetUserRole(ctx, sqlc.SetUserRoleParams{ID: id, Role: role})
+	if err != nil {
+		if missingRow(err) {
+			return User{}, ErrNotFound
+		}
+		return User{}, err
+	}
+	return userFromRow(sqlc.GetUserByIDRow(row)), nil
+}
+
+func (s *StorePG) SetUserDisabled(ctx context.Context, id string, disabled bool) (User, error) {
+	row, err := s.queries.SetUserDisabled(ctx, sqlc.SetUserDisabledParams{ID: id, Disabled: disabled})
+	if err != nil {
+		if missingRow(err) {
+			return User{}, ErrNotFound
+		}
+		return User{}, err
+	}
+	return userFromRow(sqlc.GetUserByIDRow(row)), nil
+}
+
+// missingRow reports whether a lookup by ID found nothing; a malformed ID can't match a row either.
+func missingRow(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == pgerrcode.InvalidTextRepresentation)
+}
+
+// userFromRow maps a users row; the other user queries return the same columns.
+func userFromRow(row sqlc.GetUserByIDRow) User {
 	return User{
+		ID:              row.ID,
+		Email:           row.Email,
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+		Role:            row.Role,
+		DisabledAt:      timePtr(row.DisabledAt),
+		EmailVerifiedAt: timePtr(row.EmailVerifiedAt),
+	}
+}
+
+func (s *StorePG) CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	return createRefreshToken(ctx, s.queries, userID, familyID, tokenHash, expiresAt)
+}
+
+func (s *StorePG) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row, err := s.queries.GetRefreshTokenByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RefreshToken{}, ErrNotFound
+		}
+		return RefreshToken{}, err
+	}
+
+	return RefreshToken{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		FamilyID:  row.FamilyID,
+		ExpiresAt: row.ExpiresAt.Time,
+		RotatedAt: timePtr(row.RotatedAt),
+		RevokedAt: timePtr(row.RevokedAt),
+	}, nil
+}
+
+func (s *StorePG) RotateRefreshToken(ctx context.Context, old RefreshToken, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	defer tx.Rollback(ctx)
+	q := s.queries.WithTx(tx)
+
+	n, err := q.MarkRefreshTokenRotated(ctx, old.ID)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	if n == 0 {
+		return RefreshToken{}, ErrRefreshTokenReused
+	}
+	next, err := createRefreshToken(ctx, q, old.UserID, old.FamilyID, tokenHash, expiresAt)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	return next, tx.Commit(ctx)
+}
+
+func (s *StorePG) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return s.queries.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (s *StorePG) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	if err := s.queries.RevokeToken(ctx, sqlc.RevokeTokenParams{
+		Jti:       jti,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}); err != nil {
+		return err
+	}
+	// Expired tokens are rejected anyway; keep the table small.
+	_, err := s.queries.PruneRevokedTokens(ctx)
+	return err
+}
+
+func (s *StorePG) CheckTokenRevocation(ctx context.Context, jti, userID string) (bool, time.Time, error) {
+	row, err := s.queries.CheckTokenRevocation(ctx, sqlc.CheckTokenRevocationParams{Jti: jti, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, time.Time{}, ErrNotFound
+		}
+		return false, time.Time{}, err
+	}
+	return row.Revoked, row.TokensValidAfter.Time, nil
+}
+
+func (s *StorePG) RevokeUserSessions(ctx context.Context, userID string) (time.Time, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback(ctx)
+	q := s.queries.WithTx(tx)
+
+	after, err := q.SetUserTokensValidAfter(ctx, userID)
+	if err != nil {
+		if missingRow(err) {
+			return time.Time{}, ErrNotFound
+		}
+		return time.Time{}, err
+	}
+	if err := q.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+	return after.Time, tx.Commit(ctx)
+}
+
+func (s *StorePG) CreateAPIKey(ctx context.Context, key APIKey, keyHash string) (APIKey, error) {
+	row, err := s.queries.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
+		UserID:      key.UserID,
+		Name:        key.Name,
+		HashedKey:   keyHash,
+		KeyPrefix:   key.Prefix,
+		ExpiresAt:   timestamptz(key.ExpiresAt),
+		Scopes:      key.Scopes,
+		WorkflowIds: key.WorkflowIDs,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.InvalidTextRepresentation {
+			return APIKey{}, ErrInvalidInput
+		}
+		return APIKey{}, err
+	}
+	return APIKey{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Name:        row.Name,
+		Prefix:      row.KeyPrefix,
+		CreatedAt:   row.CreatedAt.Time,
+		ExpiresAt:   timePtr(row.ExpiresAt),
+		LastUsedAt:  timePtr(row.LastUsedAt),
+		Scopes:      row.Scopes,
+		WorkflowIDs: row.WorkflowIds,
+	}, nil
+}
+
+func (s *StorePG) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	rows, err := s.queries.ListAPIKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, APIKey{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			Name:        row.Name,
+			Prefix:      row.KeyPrefix,
+			CreatedAt:   row.CreatedAt.Time,
+			ExpiresAt:   timePtr(row.ExpiresAt),
+			LastUsedAt:  timePtr(row.LastUsedAt),
+			Scopes:      row.Scopes,
+			WorkflowIDs: row.WorkflowIds,
+		})
+	}
+	return keys, nil
+}
+
+func (s *StorePG) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, User, error) {
+	row, err := s.queries.GetAPIKeyByHash(ctx, keyHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return APIKey{}, User{}, ErrNotFound
+		}
+		return APIKey{}, User{}, err
+	}
+	return APIKey{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Name:        row.Name,
+		Prefix:      row.KeyPrefix,
+		CreatedAt:   row.CreatedAt.Time,
+		ExpiresAt:   timePtr(row.ExpiresAt),
+		LastUsedAt:  timePtr(row.LastUsedAt),
+		Scopes:      row.Scopes,
+		WorkflowIDs: row.WorkflowIds,
+	}, User{ID: row.UserID, Email: row.Email, Role: row.Role, DisabledAt: timePtr(row.DisabledAt)}, nil
+}
+
+func (s *StorePG) TouchAPIKey(ctx context.Context, id string) error {
+	return s.queries.TouchAPIKey(ctx, id)
+}
+
+func (s *StorePG) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	n, err := s.queries.DeleteAPIKey(ctx, sqlc.DeleteAPIKeyParams{ID: id, UserID: userID})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.InvalidTextRepresentation {
+			return ErrNotFound
+		}
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func createRefreshToken(ctx context.Context, q *sqlc.Queries, userID, familyID, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	var family pgtype.UUID
+	if familyID != "" {
+		if err := family.Scan(familyID); err != nil {
+			return RefreshToken{}, err
+		}
+	}
+	row, err := q.CreateRefreshToken(ctx, sqlc.CreateRefreshTokenParams{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	return RefreshToken{
 		ID:        row.ID,
-		Email:     row.Email,
-		CreatedAt: row.CreatedAt.Time,
-		UpdatedAt: row.UpdatedAt.Time,
+		UserID:    userID,
+		FamilyID:  row.FamilyID,
+		ExpiresAt: expiresAt,
 	}, nil
 }
+
+func (s *StorePG) GetLoginBlock(ctx context.Context, scope, key string) (time.Time, error) {
+	row, err := s.queries.GetLoginThrottle(ctx, sqlc.GetLoginThrottleParams{Scope: scope, Key: key})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return row.BlockedUntil.Time, nil
+}
+
+func (s *StorePG) ReserveLoginAttempt(ctx context.Context, scope, key string, at, windowStart time.Time) (int, time.Time, error) {
+	row, err := s.queries.ReserveLoginAttempt(ctx, sqlc.ReserveLoginAttemptParams{
+		Scope:       scope,
+		Key:         key,
+		AttemptedAt: pgtype.Timestamptz{Time: at, Valid: true},
+		WindowStart: pgtype.Timestamptz{Time: windowStart, Valid: true},
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return int(row.Failures), row.WindowStartedAt.Time, nil
+}
+
+func (s *StorePG) ReleaseLoginAttempt(ctx context.Context, scope, key string) error {
+	return s.queries.ReleaseLoginAttempt(ctx, sqlc.ReleaseLoginAttemptParams{Scope: scope, Key: key})
+}
+
+func (s *StorePG) BlockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	return s.queries.BlockLogin(ctx, sqlc.BlockLoginParams{
+		Scope:        scope,
+		Key:          key,
+		BlockedUntil: pgtype.Timestamptz{Time: until, Valid: true},
+	})
+}
+
+func (s *StorePG) ClearLoginFailures(ctx context.Context, scope, key string, staleBefore time.Time) error {
+	if err := s.queries.ClearLoginFailures(ctx, sqlc.ClearLoginFailuresParams{Scope: scope, Key: key}); err != nil {
+		return err
+	}
+	// Counters of attackers that gave up would otherwise pile up.
+	_, err := s.queries.PruneLoginThrottles(ctx, pgtype.Timestamptz{Time: staleBefore, Valid: true})
+	return err
+}
+
+func (s *StorePG) CreateAuditEvent(ctx context.Context, event AuditEvent) error {
+	var userID pgtype.UUID
+	if event.UserID != "" {
+		if err := userID.Scan(event.UserID); err != nil {
+			return err
+		}
+	}
+	details := []byte(event.Details)
+	if len(details) == 0 {
+		details = []byte(`{}`)
+	}
+	return s.queries.CreateAuditEvent(ctx, sqlc.CreateAuditEventParams{
+		Event:   event.Event,
+		UserID:  userID,
+		Ip:      event.IP,
+		Details: details,
+	})
+}
+
+func (s *StorePG) ListAuditEvents(ctx context.Context, event string, limit int32) ([]AuditEvent, error) {
+	rows, err := s.queries.ListAuditEvents(ctx, sqlc.ListAuditEventsParams{
+		Event:    pgtype.Text{String: event, Valid: event != ""},
+		RowLimit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, AuditEvent{
+			ID:        row.ID,
+			Event:     row.Event,
+			UserID:    row.UserID,
+			IP:        row.Ip,
+			Details:   row.Details,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+	return events, nil
+}
+
+func (s *StorePG) GetTOTP(ctx context.Context, userID string) (TOTP, error) {
+	row, err := s.queries.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if missingRow(err) {
+			return TOTP{}, ErrNotFound
+		}
+		return TOTP{}, err
+	}
+	return TOTP{Secret: row.Secret, EnabledAt: timePtr(row.EnabledAt), LastStep: row.LastStep}, nil
+}
+
+func (s *StorePG) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	n, err := s.queries.SaveTOTPSecret(ctx, sqlc.SaveTOTPSecretParams{UserID: userID, Secret: secret})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+func (s *StorePG) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := s.queries.WithTx(tx)
+
+	n, err := q.EnableTOTP(ctx, sqlc.EnableTOTPParams{UserID: userID, LastStep: step})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTOTPNotEnabled
+	}
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	for _, h := range recoveryCodeHashes {
+		if err := q.CreateRecoveryCode(ctx, sqlc.CreateRecoveryCodeParams{UserID: userID, CodeHash: h}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *StorePG) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	n, err := s.queries.UseTOTPStep(ctx, sqlc.UseTOTPStepParams{UserID: userID, LastStep: step})
+	return n > 0, err
+}
+
+func (s *StorePG) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	n, err := s.queries.UseRecoveryCode(ctx, sqlc.UseRecoveryCodeParams{UserID: userID, CodeHash: codeHash})
+	return n > 0, err
+}
+
+func (s *StorePG) DeleteTOTP(ctx context.Context, userID string) error {
+	// Recovery codes cascade.
+	_, err := s.queries.DeleteUserTOTP(ctx, userID)
+	return err
+}
+
+func (s *StorePG) CreateLoginChallenge(ctx context.Context, tokenHash string, c LoginChallenge) error {
+	if err := s.queries.CreateLoginChallenge(ctx, sqlc.CreateLoginChallengeParams{
+		UserID:          c.UserID,
+		TokenHash:       tokenHash,
+		ExpiresAt:       pgtype.Timestamptz{Time: c.ExpiresAt, Valid: true},
+		OldPasswordHash: c.OldPasswordHash,
+		NewPasswordHash: c.NewPasswordHash,
+	}); err != nil {
+		return err
+	}
+	_, err := s.queries.PruneLoginChallenges(ctx, pgtype.Timestamptz{Time: time.Now(), Valid: true})
+	return err
+}
+
+func (s *StorePG) GetLoginChallenge(ctx context.Context, tokenHash string) (LoginChallenge, error) {
+	row, err := s.queries.GetLoginChallenge(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return LoginChallenge{}, ErrNotFound
+		}
+		return LoginChallenge{}, err
+	}
+	return LoginChallenge{
+		UserID:          row.UserID,
+		ExpiresAt:       row.ExpiresAt.Time,
+		OldPasswordHash: row.OldPasswordHash,
+		NewPasswordHash: row.NewPasswordHash,
+	}, nil
+}
+
+func (s *StorePG) DeleteLoginChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	n, err := s.queries.DeleteLoginChallenge(ctx, tokenHash)
+	return n > 0, err
+}
+
+func (s *StorePG) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	n, err := s.queries.UpdatePasswordHash(ctx, sqlc.UpdatePasswordHashParams{ID: userID, PasswordHash: passwordHash})
+	if err != nil {
+		if missingRow(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *StorePG) UpgradePasswordHash(ctx context.Context, userID, oldHash, newHash string) error {
+	err := s.queries.UpgradePasswordHash(ctx, sqlc.UpgradePasswordHashParams{ID: userID, OldHash: oldHash, NewHash: newHash})
+	if missingRow(err) {
+		// The user is gone; there is nothing to upgrade.
+		return nil
+	}
+	return err
+}
+
+func (s *StorePG) SetEmailVerified(ctx context.Context, userID string) error {
+	n, err := s.queries.SetEmailVerified(ctx, userID)
+	if err != nil {
+		if missingRow(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *StorePG) CreateAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	if err := s.queries.CreateAccountToken(ctx, sqlc.CreateAccountTokenParams{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}); err != nil {
+		return err
+	}
+	_, err := s.queries.PruneAccountTokens(ctx, pgtype.Timestamptz{Time: time.Now(), Valid: true})
+	return err
+}
+
+func (s *StorePG) UseAccountToken(ctx context.Context, tokenHash, purpose string) (string, error) {
+	userID, err := s.queries.UseAccountToken(ctx, sqlc.UseAccountTokenParams{TokenHash: tokenHash, Purpose: purpose})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+func (s *StorePG) DeleteAccountTokens(ctx context.Context, userID, purpose string) error {
+	return s.queries.DeleteAccountTokens(ctx, sqlc.DeleteAccountTokensParams{UserID: userID, Purpose: purpose})
+}
+
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+func timePtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
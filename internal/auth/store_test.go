@@ -41,6 +41,154 @@ func (f fakeStoreImpl) GetUserByID(ctx context.Context, id string) (User, error)
 	return User{}, ErrNotFound
 }
 
+func (f fakeStoreImpl) ListUsers(ctx context.Context) ([]User, error) {
+	return nil, nil
+}
+
+func (f fakeStoreImpl) SetUserRole(ctx context.Context, id, role string) (User, error) {
+	return User{}, ErrNotFound
+}
+
+func (f fakeStoreImpl) SetUserDisabled(ctx context.Context, id string, disabled bool) (User, error) {
+	return User{}, ErrNotFound
+}
+
+func (f fakeStoreImpl) CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	return RefreshToken{ID: "rt-1", UserID: userID, FamilyID: "family-1", ExpiresAt: expiresAt}, nil
+}
+
+func (f fakeStoreImpl) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	return RefreshToken{}, ErrNotFound
+}
+
+func (f fakeStoreImpl) RotateRefreshToken(ctx context.Context, old RefreshToken, tokenHash string, expiresAt time.Time) (RefreshToken, error) {
+	return RefreshToken{}, ErrRefreshTokenReused
+}
+
+func (f fakeStoreImpl) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	return nil
+}
+
+func (f fakeStoreImpl) CheckTokenRevocation(ctx context.Context, jti, userID string) (bool, time.Time, error) {
+	return false, time.Time{}, nil
+}
+
+func (f fakeStoreImpl) RevokeUserSessions(ctx context.Context, userID string) (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (f fakeStoreImpl) CreateAPIKey(ctx context.Context, key APIKey, keyHash string) (APIKey, error) {
+	return APIKey{}, nil
+}
+
+func (f fakeStoreImpl) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	return nil, nil
+}
+
+func (f fakeStoreImpl) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, User, error) {
+	return APIKey{}, User{}, ErrNotFound
+}
+
+func (f fakeStoreImpl) TouchAPIKey(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	return ErrNotFound
+}
+
+func (f fakeStoreImpl) GetLoginBlock(ctx context.Context, scope, key string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (f fakeStoreImpl) ReserveLoginAttempt(ctx context.Context, scope, key string, at, windowStart time.Time) (int, time.Time, error) {
+	return 1, at, nil
+}
+
+func (f fakeStoreImpl) ReleaseLoginAttempt(ctx context.Context, scope, key string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) BlockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	return nil
+}
+
+func (f fakeStoreImpl) ClearLoginFailures(ctx context.Context, scope, key string, staleBefore time.Time) error {
+	return nil
+}
+
+func (f fakeStoreImpl) CreateAuditEvent(ctx context.Context, event AuditEvent) error {
+	return nil
+}
+
+func (f fakeStoreImpl) ListAuditEvents(ctx context.Context, event string, limit int32) ([]AuditEvent, error) {
+	return nil, nil
+}
+
+func (f fakeStoreImpl) GetTOTP(ctx context.Context, userID string) (TOTP, error) {
+	return TOTP{}, ErrNotFound
+}
+
+func (f fakeStoreImpl) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	return false, nil
+}
+
+func (f fakeStoreImpl) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func (f fakeStoreImpl) DeleteTOTP(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) CreateLoginChallenge(ctx context.Context, tokenHash string, c LoginChallenge) error {
+	return nil
+}
+
+func (f fakeStoreImpl) GetLoginChallenge(ctx context.Context, tokenHash string) (LoginChallenge, error) {
+	return LoginChallenge{}, ErrNotFound
+}
+
+func (f fakeStoreImpl) DeleteLoginChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	return false, nil
+}
+
+func (f fakeStoreImpl) UpdatePasswordHash(ctx context.Context, userID, passwordHash string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) UpgradePasswordHash(ctx context.Context, userID, oldHash, newHash string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) SetEmailVerified(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (f fakeStoreImpl) CreateAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+
+func (f fakeStoreImpl) UseAccountToken(ctx context.Context, tokenHash, purpose string) (string, error) {
+	return "", ErrNotFound
+}
+
+func (f fakeStoreImpl) DeleteAccountTokens(ctx context.Context, userID, purpose string) error {
+	return nil
+}
+
 func TestServiceLoginSuccess(t *testing.T) {
 	params := Params{
 		Memory:      32 * 1024,
@@ -61,17 +209,17 @@ func TestServiceLoginSuccess(t *testing.T) {
 			PasswordHash: hash,
 		},
 	}
-	svc := NewService(store, params, []byte("secret"), time.Hour)
+	svc := NewService(store, params, []byte("secret"), time.Hour, 24*time.Hour)
 
-	user, token, err := svc.Login(context.Background(), "test@example.com", "secret")
+	user, tokens, err := svc.Login(context.Background(), "test@example.com", "secret", "")
 	if err != nil {
 		t.Fatalf("Login error: %v", err)
 	}
 	if user.Email != "test@example.com" {
 		t.Fatalf("unexpected email: %s", user.Email)
 	}
-	if token == "" {
-		t.Fatalf("expected token")
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected access and refresh tokens, got %+v", tokens)
 	}
 }
 
@@ -84,18 +232,18 @@ func TestServiceLoginWrongPassword(t *testing.T) {
 			PasswordHash: hash,
 		},
 	}
-	svc := NewService(store, params, []byte("secret"), time.Hour)
+	svc := NewService(store, params, []byte("secret"), time.Hour, 24*time.Hour)
 
-	_, _, err := svc.Login(context.Background(), "test@example.com", "wrong")
+	_, _, err := svc.Login(context.Background(), "test@example.com", "wrong", "")
 	if !errors.Is(err, ErrInvalidCredentials) {
 		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
 	}
 }
 
 func TestServiceLoginNotFound(t *testing.T) {
-	svc := NewService(fakeStoreImpl{}, DefaultParams(), []byte("secret"), time.Hour)
+	svc := NewService(fakeStoreImpl{}, DefaultParams(), []byte("secret"), time.Hour, 24*time.Hour)
 
-	_, _, err := svc.Login(context.Background(), "missing@example.com", "pw")
+	_, _, err := svc.Login(context.Background(), "missing@example.com", "pw", "")
 	if !errors.Is(err, ErrInvalidCredentials) {
 		t.Fatalf("expected ErrInvalidCredentials for missing user, got %v", err)
 	}
@@ -1,24 +1,50 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Claims holds the user fields embedded in JWTs.
+// Claims holds the user fields embedded in JWTs. ID (the jti) identifies the token so it can be
+// revoked before it expires. Requests authenticated with an API key carry its APIKeyID instead.
+// Scopes say what the caller may do; a non-empty WorkflowIDs limits it to those workflows. Role is
+// the user's role when the token was issued.
 type Claims struct {
-	UserID string
-	Email  string
+	UserID      string
+	Email       string
+	Role        string
+	ID          string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+	APIKeyID    string
+	Scopes      []string
+	WorkflowIDs []string
 }
 
-// GenerateToken issues a signed JWT containing the user ID and email.
-func GenerateToken(userID, email string, secret []byte, expiry time.Duration) (string, error) {
+// GenerateToken issues a signed JWT containing the user ID, email, role, scopes (AllScopes if none
+// are given) and a random jti. iat has millisecond precision so a token issued right after a
+// logout-everywhere stays valid.
+func GenerateToken(userID, email, role string, secret []byte, expiry time.Duration, scopes ...string) (string, error) {
+	if len(scopes) == 0 {
+		scopes = AllScopes
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub":   userID,
-		"email": email,
-		"exp":   time.Now().Add(expiry).Unix(),
-		"iat":   time.Now().Unix(),
+		"sub":    userID,
+		"email":  email,
+		"role":   role,
+		"jti":    hex.EncodeToString(jti),
+		"scopes": scopes,
+		"exp":    now.Add(expiry).Unix(),
+		"iat":    float64(now.UnixMilli()) / 1000,
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(secret)
@@ -47,9 +73,50 @@ func ParseToken(tokenStr string, secret []byte) (Claims, error) {
 
 	sub, _ := mapClaims["sub"].(string)
 	email, _ := mapClaims["email"].(string)
-	if sub == "" || email == "" {
+	jti, _ := mapClaims["jti"].(string)
+	role, _ := mapClaims["role"].(string)
+	if sub == "" || email == "" || jti == "" || role == "" {
+		return Claims{}, jwt.ErrTokenInvalidClaims
+	}
+	// GetIssuedAt would truncate iat to whole seconds.
+	iat, ok := mapClaims["iat"].(float64)
+	if !ok {
+		return Claims{}, jwt.ErrTokenInvalidClaims
+	}
+	exp, err := mapClaims.GetExpirationTime()
+	if err != nil || exp == nil {
 		return Claims{}, jwt.ErrTokenInvalidClaims
 	}
 
-	return Claims{UserID: sub, Email: email}, nil
+	scopes, ok := stringList(mapClaims["scopes"])
+	if !ok {
+		return Claims{}, jwt.ErrTokenInvalidClaims
+	}
+
+	return Claims{
+		UserID:    sub,
+		Email:     email,
+		Role:      role,
+		ID:        jti,
+		IssuedAt:  time.UnixMilli(int64(math.Round(iat * 1000))),
+		ExpiresAt: exp.Time,
+		Scopes:    scopes,
+	}, nil
+}
+
+// stringList converts a decoded JSON array of strings.
+func stringList(v any) ([]string, bool) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
 }
@@ -6,7 +6,7 @@ import (
 )
 
 func TestGenerateAndParseToken(t *testing.T) {
-	token, err := GenerateToken("user-1", "test@example.com", []byte("secret"), time.Minute)
+	token, err := GenerateToken("user-1", "test@example.com", RoleMember, []byte("secret"), time.Minute)
 	if err != nil {
 		t.Fatalf("GenerateToken error: %v", err)
 	}
@@ -14,13 +14,35 @@ func TestGenerateAndParseToken(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ParseToken error: %v", err)
 	}
-	if claims.UserID != "user-1" || claims.Email != "test@example.com" {
+	if claims.UserID != "user-1" || claims.Email != "test@example.com" || claims.Role != RoleMember || len(claims.ID) != 32 {
 		t.Fatalf("unexpected claims: %+v", claims)
 	}
+	if d := time.Since(claims.IssuedAt); d < 0 || d > 100*time.Millisecond {
+		t.Fatalf("expected a millisecond iat, got %s (%s ago)", claims.IssuedAt, d)
+	}
+
+	other, _ := GenerateToken("user-1", "test@example.com", RoleMember, []byte("secret"), time.Minute)
+	if otherClaims, _ := ParseToken(other, []byte("secret")); otherClaims.ID == claims.ID {
+		t.Fatalf("expected every token to get its own jti")
+	}
+}
+
+func TestGenerateToken_Scopes(t *testing.T) {
+	token, _ := GenerateToken("user-1", "test@example.com", RoleMember, []byte("secret"), time.Minute)
+	claims, err := ParseToken(token, []byte("secret"))
+	if err != nil || len(claims.Scopes) != len(AllScopes) {
+		t.Fatalf("expected every scope by default, got %v (err %v)", claims.Scopes, err)
+	}
+
+	token, _ = GenerateToken("user-1", "test@example.com", RoleMember, []byte("secret"), time.Minute, ScopeRunsRead)
+	claims, err = ParseToken(token, []byte("secret"))
+	if err != nil || !claims.HasScope(ScopeRunsRead) || claims.HasScope(ScopeRunsTrigger) {
+		t.Fatalf("expected only runs:read, got %v (err %v)", claims.Scopes, err)
+	}
 }
 
 func TestParseToken_Expired(t *testing.T) {
-	token, err := GenerateToken("user-1", "test@example.com", []byte("secret"), -1*time.Minute)
+	token, err := GenerateToken("user-1", "test@example.com", RoleMember, []byte("secret"), -1*time.Minute)
 	if err != nil {
 		t.Fatalf("GenerateToken error: %v", err)
 	}
@@ -30,7 +52,7 @@ func TestParseToken_Expired(t *testing.T) {
 }
 
 func TestParseToken_WrongSecret(t *testing.T) {
-	token, err := GenerateToken("user-1", "test@example.com", []byte("secret"), time.Minute)
+	token, err := GenerateToken("user-1", "test@example.com", RoleMember, []byte("secret"), time.Minute)
 	if err != nil {
 		t.Fatalf("GenerateToken error: %v", err)
 	}
@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP codes follow RFC 6238 with the parameters authenticator apps assume: HMAC-SHA1, six digits
+// and 30-second steps. One step either side of now is accepted for clock drift.
+const (
+	totpIssuer      = "PotaFlow"
+	totpPeriod      = 30
+	totpDigits      = 6
+	totpSkew        = 1
+	totpSecretBytes = 20
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+
+	// challengeTTL is how long a login challenge waits for the second factor.
+	challengeTTL = 5 * time.Minute
+)
+
+var (
+	// ErrTOTPRequired is wrapped by *TOTPRequiredError.
+	ErrTOTPRequired       = errors.New("two-factor code required")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTOTPNotEnabled means there is no enrollment to confirm or disable.
+	ErrTOTPNotEnabled   = errors.New("two-factor authentication not enabled")
+	ErrInvalidTOTPCode  = errors.New("invalid two-factor code")
+	ErrInvalidChallenge = errors.New("invalid login challenge")
+)
+
+// TOTPRequiredError is what Login returns for accounts with two-factor authentication once the
+// password is correct: ChallengeToken and a code have to be passed to LoginTOTP before ExpiresAt.
+type TOTPRequiredError struct {
+	ChallengeToken string
+	ExpiresAt      time.Time
+}
+
+func (e *TOTPRequiredError) Error() string { return ErrTOTPRequired.Error() }
+
+func (e *TOTPRequiredError) Unwrap() error { return ErrTOTPRequired }
+
+// LoginChallenge is a login waiting for its second factor. NewPasswordHash, if set, re-hashes the
+// password with the current Params and replaces OldPasswordHash once the challenge is passed.
+type LoginChallenge struct {
+	UserID          string
+	ExpiresAt       time.Time
+	OldPasswordHash string
+	NewPasswordHash string
+}
+
+// TOTP is a user's stored TOTP secret; it is pending until EnabledAt is set. LastStep is the
+// newest time step a code was accepted for, so codes can't be replayed.
+type TOTP struct {
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+}
+
+// TOTPEnrollment is handed to the user to set up an authenticator app: URI is the otpauth://
+// provisioning URI to render as a QR code, Secret the same key for manual entry.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTOTP generates a new secret for userID. Two-factor authentication stays off until
+// ConfirmTOTP sees a code for it; enrolling again replaces a pending secret.
+func (s *Service) EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	key := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(key); err != nil {
+		return TOTPEnrollment{}, err
+	}
+	secret := totpEncoding.EncodeToString(key)
+	if err := s.store.SaveTOTPSecret(ctx, userID, secret); err != nil {
+		return TOTPEnrollment{}, err
+	}
+	return TOTPEnrollment{Secret: secret, URI: provisioningURI(user.Email, secret)}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once code matches the pending secret and returns
+// the recovery codes, which are only stored hashed.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	t, err := s.store.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrTOTPNotEnabled
+		}
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	step, ok := matchTOTP(t.Secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:]
+		hashes[i] = hashToken(raw)
+	}
+	if err := s.store.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It needs the user's password and, for an
+// enabled one, a current code or a recovery code; a pending enrollment only needs the password.
+// Both are checked like a login from clientIP: throttled, and wrong ones count as failed logins.
+func (s *Service) DisableTOTP(ctx context.Context, userID, password, code, clientIP string) error {
+	t, err := s.store.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrTOTPNotEnabled
+		}
+		return err
+	}
+	_, attempt, err := s.verifyUserPassword(ctx, userID, password, clientIP)
+	if err != nil {
+		return err
+	}
+	defer s.abandonAttempt(ctx, attempt)
+	if t.EnabledAt != nil {
+		if err := s.verifySecondFactor(ctx, userID, t, code); err != nil {
+			if errors.Is(err, ErrInvalidTOTPCode) {
+				if err := s.failAttempt(ctx, attempt, userID); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+	}
+	if err := s.succeedAttempt(ctx, attempt); err != nil {
+		return err
+	}
+	return s.store.DeleteTOTP(ctx, userID)
+}
+
+// LoginTOTP completes a login that Login answered with a *TOTPRequiredError. code is a current
+// TOTP code or an unused recovery code. Wrong codes count as failed logins of the account.
+func (s *Service) LoginTOTP(ctx context.Context, challengeToken, code, clientIP string) (User, TokenPair, error) {
+	tokenHash := hashToken(challengeToken)
+	challenge, err := s.store.GetLoginChallenge(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, TokenPair{}, ErrInvalidChallenge
+		}
+		return User{}, TokenPair{}, err
+	}
+	userID := challenge.UserID
+	if !s.now().Before(challenge.ExpiresAt) {
+		return User{}, TokenPair{}, ErrInvalidChallenge
+	}
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, TokenPair{}, ErrInvalidChallenge
+		}
+		return User{}, TokenPair{}, err
+	}
+
+	attempt, err := s.beginAttempt(ctx, s.throttleKeys(user.Email, clientIP), clientIP)
+	if err != nil {
+		return User{}, TokenPair{}, err
+	}
+	defer s.abandonAttempt(ctx, attempt)
+	t, err := s.store.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, TokenPair{}, ErrInvalidChallenge
+		}
+		return User{}, TokenPair{}, err
+	}
+	if t.EnabledAt == nil {
+		return User{}, TokenPair{}, ErrInvalidChallenge
+	}
+	if err := s.verifySecondFactor(ctx, userID, t, code); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			if err := s.failAttempt(ctx, attempt, userID); err != nil {
+				return User{}, TokenPair{}, err
+			}
+		}
+		return User{}, TokenPair{}, err
+	}
+
+	deleted, err := s.store.DeleteLoginChallenge(ctx, tokenHash)
+	if err != nil {
+		return User{}, TokenPair{}, err
+	}
+	if !deleted {
+		return User{}, TokenPair{}, ErrInvalidChallenge
+	}
+	if err := s.succeedAttempt(ctx, attempt); err != nil {
+		return User{}, TokenPair{}, err
+	}
+	if user.DisabledAt != nil {
+		return User{}, TokenPair{}, ErrAccountDisabled
+	}
+	s.upgradePasswordHash(ctx, userID, challenge.OldPasswordHash, challenge.NewPasswordHash)
+
+	pair, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return User{}, TokenPair{}, err
+	}
+	return user, pair, nil
+}
+
+// challengeTOTP stores c, expiring challengeTTL from now, and returns it as a *TOTPRequiredError.
+func (s *Service) challengeTOTP(ctx context.Context, c LoginChallenge) error {
+	// Challenge tokens are opaque like refresh tokens and stored the same way.
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return err
+	}
+	c.ExpiresAt = s.now().Add(challengeTTL)
+	if err := s.store.CreateLoginChallenge(ctx, hash, c); err != nil {
+		return err
+	}
+	return &TOTPRequiredError{ChallengeToken: raw, ExpiresAt: c.ExpiresAt}
+}
+
+// verifySecondFactor accepts a TOTP code whose step is newer than any accepted before, or an
+// unused recovery code, which is used up.
+func (s *Service) verifySecondFactor(ctx context.Context, userID string, t TOTP, code string) error {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) == totpDigits {
+		step, ok := matchTOTP(t.Secret, code, s.now())
+		if !ok || step <= t.LastStep {
+			return ErrInvalidTOTPCode
+		}
+		used, err := s.store.UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTOTPCode
+		}
+		return nil
+	}
+	if code == "" {
+		return ErrInvalidTOTPCode
+	}
+	used, err := s.store.UseRecoveryCode(ctx, userID, hashToken(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// matchTOTP returns the time step around now whose code is code.
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step, sha1.New, totpDigits)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode is the HOTP value (RFC 4226) of key for counter step, truncated to digits.
+func totpCode(key []byte, step int64, h func() hash.Hash, digits int) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(h, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// provisioningURI is the otpauth:// URI authenticator apps scan from a QR code.
+func provisioningURI(account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + totpIssuer + ":" + account, RawQuery: q.Encode()}
+	return u.String()
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (s *refreshStore) GetTOTP(ctx context.Context, userID string) (TOTP, error) {
+	if s.totp == nil || userID != s.userWithHash.ID {
+		return TOTP{}, ErrNotFound
+	}
+	return *s.totp, nil
+}
+
+func (s *refreshStore) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	if s.totp != nil && s.totp.EnabledAt != nil {
+		return ErrTOTPAlreadyEnabled
+	}
+	s.totp = &TOTP{Secret: secret}
+	return nil
+}
+
+func (s *refreshStore) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	if s.totp == nil || s.totp.EnabledAt != nil {
+		return ErrTOTPNotEnabled
+	}
+	now := time.Now()
+	s.totp.EnabledAt = &now
+	s.totp.LastStep = step
+	s.recovery = make(map[string]bool)
+	for _, h := range recoveryCodeHashes {
+		s.recovery[h] = false
+	}
+	return nil
+}
+
+func (s *refreshStore) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	if s.totp == nil || step <= s.totp.LastStep {
+		return false, nil
+	}
+	s.totp.LastStep = step
+	return true, nil
+}
+
+func (s *refreshStore) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	used, ok := s.recovery[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	s.recovery[codeHash] = true
+	return true, nil
+}
+
+func (s *refreshStore) DeleteTOTP(ctx context.Context, userID string) error {
+	s.totp = nil
+	s.recovery = make(map[string]bool)
+	return nil
+}
+
+func (s *refreshStore) CreateLoginChallenge(ctx context.Context, tokenHash string, c LoginChallenge) error {
+	s.challenges[tokenHash] = c
+	return nil
+}
+
+func (s *refreshStore) GetLoginChallenge(ctx context.Context, tokenHash string) (LoginChallenge, error) {
+	c, ok := s.challenges[tokenHash]
+	if !ok {
+		return LoginChallenge{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (s *refreshStore) DeleteLoginChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	_, ok := s.challenges[tokenHash]
+	delete(s.challenges, tokenHash)
+	return ok, nil
+}
+
+// currentCode returns the TOTP code of secret at t.
+func currentCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	return totpCode(key, at.Unix()/totpPeriod, sha1.New, totpDigits)
+}
+
+// enableTOTP enrolls and confirms TOTP for the store's user and returns the secret and recovery codes.
+func enableTOTP(t *testing.T, svc *Service) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	enrollment, err := svc.EnrollTOTP(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	codes, err := svc.ConfirmTOTP(ctx, "user-1", currentCode(t, enrollment.Secret, svc.now()))
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	return enrollment.Secret, codes
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	keys := map[string]struct {
+		key string
+		h   func() hash.Hash
+	}{
+		"SHA1":   {"12345678901234567890", sha1.New},
+		"SHA256": {"12345678901234567890123456789012", sha256.New},
+		"SHA512": {"1234567890123456789012345678901234567890123456789012345678901234", sha512.New},
+	}
+	for _, tc := range []struct {
+		unix                 int64
+		sha1, sha256, sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	} {
+		for alg, want := range map[string]string{"SHA1": tc.sha1, "SHA256": tc.sha256, "SHA512": tc.sha512} {
+			k := keys[alg]
+			if got := totpCode([]byte(k.key), tc.unix/totpPeriod, k.h, 8); got != want {
+				t.Errorf("%s at %d: expected %s, got %s", alg, tc.unix, want, got)
+			}
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+	// The six-digit code is the eight-digit RFC vector's low digits.
+	if step, ok := matchTOTP(secret, "081804", now); !ok || step != 1111111109/totpPeriod {
+		t.Fatalf("expected the current step to match, got %d %v", step, ok)
+	}
+	if _, ok := matchTOTP(secret, "081804", now.Add(totpPeriod*time.Second)); !ok {
+		t.Fatal("expected the previous step to be accepted for clock drift")
+	}
+	if _, ok := matchTOTP(secret, "081804", now.Add(2*totpPeriod*time.Second)); ok {
+		t.Fatal("expected a code two steps old to be rejected")
+	}
+	if _, ok := matchTOTP(secret, "000000", now); ok {
+		t.Fatal("expected a wrong code to be rejected")
+	}
+}
+
+func TestServiceTOTPEnrollment(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	if _, err := svc.ConfirmTOTP(ctx, "user-1", "123456"); !errors.Is(err, ErrTOTPNotEnabled) {
+		t.Fatalf("expected ErrTOTPNotEnabled without an enrollment, got %v", err)
+	}
+	enrollment, err := svc.EnrollTOTP(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/PotaFlow:test@example.com?") || !strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Fatalf("unexpected provisioning URI %s", enrollment.URI)
+	}
+	if _, err := svc.ConfirmTOTP(ctx, "user-1", "abcdef"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected ErrInvalidTOTPCode, got %v", err)
+	}
+
+	codes, err := svc.ConfirmTOTP(ctx, "user-1", currentCode(t, enrollment.Secret, time.Now()))
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %v (%v)", recoveryCodeCount, codes, err)
+	}
+	for _, code := range codes {
+		if _, stored := store.recovery[code]; stored {
+			t.Fatal("expected recovery codes to be stored hashed")
+		}
+	}
+	if _, err := svc.EnrollTOTP(ctx, "user-1"); !errors.Is(err, ErrTOTPAlreadyEnabled) {
+		t.Fatalf("expected ErrTOTPAlreadyEnabled, got %v", err)
+	}
+}
+
+func TestServiceLoginTOTP(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+	secret, recovery := enableTOTP(t, svc)
+
+	_, _, err := svc.Login(ctx, "test@example.com", "secret", "192.0.2.1")
+	var required *TOTPRequiredError
+	if !errors.As(err, &required) || required.ChallengeToken == "" || !required.ExpiresAt.Equal(now.Add(challengeTTL)) {
+		t.Fatalf("expected a login challenge, got %v", err)
+	}
+
+	if _, _, err := svc.LoginTOTP(ctx, "bogus", "123456", ""); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("expected ErrInvalidChallenge, got %v", err)
+	}
+	// The code used to confirm the enrollment can't be replayed.
+	if _, _, err := svc.LoginTOTP(ctx, required.ChallengeToken, currentCode(t, secret, now), "192.0.2.1"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected a replayed code to be rejected, got %v", err)
+	}
+	if store.failures[ThrottleAccount+"/test@example.com"] != 1 {
+		t.Fatalf("expected the wrong code to count as a failed login, got %v", store.failures)
+	}
+
+	now = now.Add(totpPeriod * time.Second)
+	user, pair, err := svc.LoginTOTP(ctx, required.ChallengeToken, currentCode(t, secret, now), "192.0.2.1")
+	if err != nil || user.ID != "user-1" || pair.AccessToken == "" {
+		t.Fatalf("expected tokens, got %+v %v", user, err)
+	}
+	if store.failures[ThrottleAccount+"/test@example.com"] != 0 {
+		t.Fatalf("expected the account's failures to be cleared, got %v", store.failures)
+	}
+	if _, _, err := svc.LoginTOTP(ctx, required.ChallengeToken, recovery[0], "192.0.2.1"); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("expected a used challenge to be rejected, got %v", err)
+	}
+
+	_, _, err = svc.Login(ctx, "test@example.com", "secret", "")
+	if !errors.As(err, &required) {
+		t.Fatalf("expected a login challenge, got %v", err)
+	}
+	if _, _, err := svc.LoginTOTP(ctx, required.ChallengeToken, strings.ToUpper(recovery[0]), ""); err != nil {
+		t.Fatalf("expected a recovery code to work, got %v", err)
+	}
+	_, _, err = svc.Login(ctx, "test@example.com", "secret", "")
+	if !errors.As(err, &required) {
+		t.Fatalf("expected a login challenge, got %v", err)
+	}
+	if _, _, err := svc.LoginTOTP(ctx, required.ChallengeToken, recovery[0], ""); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected a used recovery code to be rejected, got %v", err)
+	}
+
+	now = now.Add(challengeTTL)
+	if _, _, err := svc.LoginTOTP(ctx, required.ChallengeToken, recovery[1], ""); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("expected an expired challenge to be rejected, got %v", err)
+	}
+}
+
+func TestServiceDisableTOTP(t *testing.T) {
+	store := newRefreshStore()
+	svc := NewService(store, testParams, []byte("secret"), time.Minute, time.Hour)
+	ctx := context.Background()
+
+	if err := svc.DisableTOTP(ctx, "user-1", "secret", "", "192.0.2.1"); !errors.Is(err, ErrTOTPNotEnabled) {
+		t.Fatalf("expected ErrTOTPNotEnabled, got %v", err)
+	}
+	_, recovery := enableTOTP(t, svc)
+	if err := svc.DisableTOTP(ctx, "user-1", "wrong", recovery[2], "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected a stolen session without the password to be refused, got %v", err)
+	}
+	if err := svc.DisableTOTP(ctx, "user-1", "secret", "000000", "192.0.2.1"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected ErrInvalidTOTPCode, got %v", err)
+	}
+	if store.failures[ThrottleAccount+"/test@example.com"] != 2 || store.failures[ThrottleIP+"/192.0.2.1"] != 2 {
+		t.Fatalf("expected the wrong password and code to count as failed logins, got %v", store.failures)
+	}
+	if err := svc.DisableTOTP(ctx, "user-1", "secret", recovery[2], "192.0.2.1"); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if _, pair, err := svc.Login(ctx, "test@example.com", "secret", ""); err != nil || pair.AccessToken == "" {
+		t.Fatalf("expected a plain login after disabling, got %v", err)
+	}
+}
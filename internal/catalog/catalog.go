@@ -0,0 +1,175 @@
+// Package catalog lists the trigger and action types PotaFlow knows about and validates their
+// configs against each type's JSON schema.
+package catalog
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+)
+
+// Kind says whether a type is a trigger or an action.
+type Kind string
+
+const (
+	KindTrigger Kind = "trigger"
+	KindAction  Kind = "action"
+)
+
+// Entry describes one trigger or action type. Plugin is set for types contributed by a plugin.
+type Entry struct {
+	Kind         Kind            `json:"kind"`
+	Type         string          `json:"type"`
+	Description  string          `json:"description,omitempty"`
+	Plugin       string          `json:"plugin,omitempty"`
+	ConfigSchema json.RawMessage `json:"config_schema"`
+
+	schema *Schema
+}
+
+// ValidationError lists why a type or config was rejected.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+" "+f.Message)
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+//go:embed builtin/*.json
+var builtinFS embed.FS
+
+// builtins are parsed once at init; a broken schema file is a programming error.
+var builtins = mustLoadBuiltins()
+
+func mustLoadBuiltins() map[Kind]map[string]Entry {
+	files, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		panic(err)
+	}
+	out := map[Kind]map[string]Entry{KindTrigger: {}, KindAction: {}}
+	for _, f := range files {
+		raw, err := builtinFS.ReadFile(path.Join("builtin", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			panic(fmt.Sprintf("catalog: %s: %v", f.Name(), err))
+		}
+		if e.schema, err = ParseSchema(e.ConfigSchema); err != nil {
+			panic(fmt.Sprintf("catalog: %s: %v", f.Name(), err))
+		}
+		out[e.Kind][e.Type] = e
+	}
+	return out
+}
+
+// IsBuiltin reports whether typ is a built-in type of kind, which plugins can't override.
+func IsBuiltin(kind Kind, typ string) bool {
+	_, ok := builtins[kind][typ]
+	return ok
+}
+
+// Catalog combines the built-in types with the ones published by worker plugins.
+type Catalog struct {
+	plugins pluginSource
+}
+
+type pluginSource interface {
+	ListPluginTypes(ctx context.Context) ([]sqlc.ListPluginTypesRow, error)
+}
+
+// New builds a Catalog that reads plugin types through source (e.g., sqlc.New(db)).
+func New(source pluginSource) *Catalog {
+	return &Catalog{plugins: source}
+}
+
+// List returns every known type, built-ins first, each group sorted by kind and type.
+func (c *Catalog) List(ctx context.Context) ([]Entry, error) {
+	var out []Entry
+	for _, kind := range []Kind{KindTrigger, KindAction} {
+		for _, e := range builtins[kind] {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind > out[j].Kind // triggers before actions
+		}
+		return out[i].Type < out[j].Type
+	})
+
+	rows, err := c.plugins.ListPluginTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		kind := Kind(row.Kind)
+		if _, ok := builtins[kind][row.Type]; ok {
+			continue
+		}
+		schema := json.RawMessage(row.ConfigSchema)
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		out = append(out, Entry{
+			Kind:         kind,
+			Type:         row.Type,
+			Description:  row.Description,
+			Plugin:       row.Plugin,
+			ConfigSchema: schema,
+		})
+	}
+	return out, nil
+}
+
+// Validate checks that typ is a known type of kind and that config matches its schema. It
+// returns a *ValidationError for bad input and other errors for lookup failures.
+func (c *Catalog) Validate(ctx context.Context, kind Kind, typ string, config []byte) error {
+	entry, ok := builtins[kind][typ]
+	if !ok {
+		var err error
+		if entry, ok, err = c.pluginEntry(ctx, kind, typ); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return &ValidationError{Fields: []FieldError{{Field: "type", Message: fmt.Sprintf("unknown %s type %q", kind, typ)}}}
+	}
+	if errs := entry.schema.Validate("config", config); len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+func (c *Catalog) pluginEntry(ctx context.Context, kind Kind, typ string) (Entry, bool, error) {
+	rows, err := c.plugins.ListPluginTypes(ctx)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, row := range rows {
+		if Kind(row.Kind) != kind || row.Type != typ {
+			continue
+		}
+		schema := &Schema{Type: "object"}
+		if len(row.ConfigSchema) > 0 {
+			// A plugin that publishes an unusable schema only gets the object check.
+			if parsed, err := ParseSchema(row.ConfigSchema); err == nil {
+				schema = parsed
+			}
+		}
+		return Entry{Kind: kind, Type: typ, Plugin: row.Plugin, schema: schema}, true, nil
+	}
+	return Entry{}, false, nil
+}
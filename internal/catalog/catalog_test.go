@@ -0,0 +1,203 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+)
+
+type fakePlugins struct {
+	rows []sqlc.ListPluginTypesRow
+	err  error
+}
+
+func (f fakePlugins) ListPluginTypes(ctx context.Context) ([]sqlc.ListPluginTypesRow, error) {
+	return f.rows, f.err
+}
+
+func fields(err error) map[string]string {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return nil
+	}
+	out := make(map[string]string, len(verr.Fields))
+	for _, f := range verr.Fields {
+		out[f.Field] = f.Message
+	}
+	return out
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["name", "count"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+			"count": {"type": "integer", "minimum": 1, "maximum": 10},
+			"mode": {"enum": ["a", "b"]},
+			"tags": {"type": "array", "minItems": 1, "items": {"type": "string"}},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"url": {"type": "string", "format": "uri"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSchema error: %v", err)
+	}
+
+	if errs := schema.Validate("config", []byte(`{"name":"ok","count":3,"mode":"b","tags":["x"],"labels":{"k":"v"},"url":"https://example.com"}`)); len(errs) != 0 {
+		t.Fatalf("expected valid document, got %+v", errs)
+	}
+
+	errs := schema.Validate("config", []byte(`{"name":"NO","count":2.5,"mode":"c","tags":[1],"labels":{"k":1},"url":"/relative","extra":true}`))
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Field] = e.Message
+	}
+	want := map[string]string{
+		"config.name":     "must match ^[a-z]+$",
+		"config.count":    "must be an integer",
+		"config.mode":     `must be one of "a", "b"`,
+		"config.tags[0]":  "must be a string",
+		"config.labels.k": "must be a string",
+		"config.url":      "must be an absolute URL",
+		"config.extra":    "is not allowed",
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Fatalf("field %s: expected %q, got %q (all: %+v)", field, msg, got[field], errs)
+		}
+	}
+
+	errs = schema.Validate("config", []byte(`{"count":11,"tags":[]}`))
+	got = map[string]string{}
+	for _, e := range errs {
+		got[e.Field] = e.Message
+	}
+	if got["config.name"] != "is required" || got["config.count"] != "must be <= 10" || got["config.tags"] != "must have at least 1 item(s)" {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+
+	for _, doc := range []string{`null`, `[]`, `not json`, `{} {}`} {
+		if errs := schema.Validate("config", []byte(doc)); len(errs) != 1 || errs[0].Field != "config" {
+			t.Fatalf("expected a single root error for %s, got %+v", doc, errs)
+		}
+	}
+}
+
+func TestParseSchemaRejectsBadSchemas(t *testing.T) {
+	for _, raw := range []string{`{"pattern":"("}`, `{"format":"zip-code"}`, `{"properties":{"a":{"format":"nope"}}}`, `[]`} {
+		if _, err := ParseSchema([]byte(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestCatalogValidateBuiltins(t *testing.T) {
+	c := New(fakePlugins{})
+	ctx := context.Background()
+
+	valid := []struct {
+		kind   Kind
+		typ    string
+		config string
+	}{
+		{KindTrigger, "webhook", `{}`},
+		{KindTrigger, "webhook", `{"webhook_secret":"s3cret","signature":{"preset":"github"},"response_mode":"sync"}`},
+		{KindTrigger, "cron", `{"cron_expr":"0 9 * * 1-5","timezone":"Europe/Berlin","misfire_policy":"fire_all","max_catchup":3}`},
+		{KindTrigger, "interval", `{"every":"90s","start_at":"2026-11-01T09:00:00Z"}`},
+		{KindTrigger, "at", `{"at":"2026-11-01T09:00:00Z"}`},
+		{KindTrigger, "poll", `{"url":"https://example.com/feed.json","every":"10m","items_path":"data","headers":{"Accept":"application/json"}}`},
+		{KindTrigger, "file", `{"path":"inbox","pattern":"*.csv","events":["create"],"include_content":true,"processed_dir":"done"}`},
+		{KindTrigger, "workflow_event", `{"workflow_id":"6f1c2b7e-1d2a-4a8e-9c43-2f0d7f3b9a10","status":"failed"}`},
+		{KindTrigger, "email", `{"allowed_senders":["*@example.com","boss@corp.test"]}`},
+		{KindAction, "http", `{"url":"https://example.com","method":"post","headers":{"X-Test":"1"},"body":{"a":1}}`},
+		{KindAction, "sql", `{"credential":"db","query":"SELECT 1","params":[1,"a"]}`},
+		{KindAction, "transform", `{"ops":[{"op":"filter","path":"items","where":[{"field":"n","op":"gt","value":1}]}]}`},
+		{KindAction, "call_workflow", `{"workflow_id":"6f1c2b7e-1d2a-4a8e-9c43-2f0d7f3b9a10","wait":true}`},
+	}
+	for _, tc := range valid {
+		if err := c.Validate(ctx, tc.kind, tc.typ, []byte(tc.config)); err != nil {
+			t.Fatalf("%s %s: unexpected error %v", tc.kind, tc.typ, err)
+		}
+	}
+
+	invalid := []struct {
+		kind   Kind
+		typ    string
+		config string
+		field  string
+	}{
+		{KindTrigger, "cron", `{}`, "config.cron_expr"},
+		{KindTrigger, "cron", `{"cron_expr":"0 25 * * *"}`, "config.cron_expr"},
+		{KindTrigger, "cron", `{"cron_expr":"@daily","timezone":"Nowhere/Land"}`, "config.timezone"},
+		{KindTrigger, "webhook", `{"response_mode":"later"}`, "config.response_mode"},
+		{KindTrigger, "webhook", `{"secret":"typo"}`, "config.secret"},
+		{KindTrigger, "interval", `{"every":"500ms"}`, "config.every"},
+		{KindTrigger, "at", `{"at":"next tuesday"}`, "config.at"},
+		{KindTrigger, "at", `{"at":"2026-11-01T09:00:00Z","misfire_policy":"fire_all"}`, "config.misfire_policy"},
+		{KindTrigger, "poll", `{"url":"https://example.com/feed","format":"csv"}`, "config.format"},
+		{KindTrigger, "poll", `{"every":"5m"}`, "config.url"},
+		{KindTrigger, "file", `{"path":"inbox","events":["delete"]}`, "config.events[0]"},
+		{KindTrigger, "workflow_event", `{"workflow_id":"6f1c2b7e-1d2a-4a8e-9c43-2f0d7f3b9a10","status":"done"}`, "config.status"},
+		{KindTrigger, "email", `{"allowed_senders":"*@example.com"}`, "config.allowed_senders"},
+		{KindAction, "http", `{"url":"example.com"}`, "config.url"},
+		{KindAction, "sql", `{"credential":"","query":"SELECT 1"}`, "config.credential"},
+		{KindAction, "transform", `{"ops":[{"op":"explode"}]}`, "config.ops[0].op"},
+		{KindAction, "call_workflow", `{"workflow_id":"wf-1"}`, "config.workflow_id"},
+		{KindAction, "cron", `{"cron_expr":"@daily"}`, "type"},
+	}
+	for _, tc := range invalid {
+		err := c.Validate(ctx, tc.kind, tc.typ, []byte(tc.config))
+		if _, ok := fields(err)[tc.field]; !ok {
+			t.Fatalf("%s %s %s: expected error on %s, got %v", tc.kind, tc.typ, tc.config, tc.field, err)
+		}
+	}
+}
+
+func TestCatalogPluginTypes(t *testing.T) {
+	c := New(fakePlugins{rows: []sqlc.ListPluginTypesRow{
+		{Kind: "action", Type: "echo", Plugin: "echo-plugin", Description: "Echoes its input."},
+		{Kind: "action", Type: "slack", Plugin: "slack-plugin", ConfigSchema: []byte(`{"type":"object","required":["channel"]}`)},
+		{Kind: "action", Type: "http", Plugin: "shadow", ConfigSchema: []byte(`{}`)},
+	}})
+	ctx := context.Background()
+
+	if err := c.Validate(ctx, KindAction, "echo", []byte(`{"anything":true}`)); err != nil {
+		t.Fatalf("expected schemaless plugin type to accept any object, got %v", err)
+	}
+	if err := c.Validate(ctx, KindAction, "echo", []byte(`"text"`)); fields(err)["config"] == "" {
+		t.Fatalf("expected plugin config to be an object, got %v", err)
+	}
+	if err := c.Validate(ctx, KindAction, "slack", []byte(`{}`)); fields(err)["config.channel"] != "is required" {
+		t.Fatalf("expected plugin schema to apply, got %v", err)
+	}
+	if err := c.Validate(ctx, KindTrigger, "echo", []byte(`{}`)); fields(err)["type"] == "" {
+		t.Fatalf("expected plugin action type to be unknown as a trigger, got %v", err)
+	}
+
+	entries, err := c.List(ctx)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	seen := map[string]Entry{}
+	for _, e := range entries {
+		seen[string(e.Kind)+"/"+e.Type] = e
+	}
+	if seen["trigger/cron"].ConfigSchema == nil || seen["action/echo"].Plugin != "echo-plugin" || seen["action/http"].Plugin != "" {
+		t.Fatalf("unexpected catalog: %+v", entries)
+	}
+	if entries[0].Kind != KindTrigger {
+		t.Fatalf("expected triggers first, got %+v", entries[0])
+	}
+
+	failing := New(fakePlugins{err: errors.New("db down")})
+	if err := failing.Validate(ctx, KindAction, "echo", []byte(`{}`)); err == nil || fields(err) != nil {
+		t.Fatalf("expected lookup error, got %v", err)
+	}
+	if err := failing.Validate(ctx, KindAction, "http", []byte(`{"url":"https://example.com"}`)); err != nil {
+		t.Fatalf("built-ins must not need the plugin table, got %v", err)
+	}
+}
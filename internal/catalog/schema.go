@@ -0,0 +1,322 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/groovypotato/PotaFlow/internal/scheduler"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Schema is the subset of JSON Schema used to describe trigger and action configs: type,
+// properties, required, additionalProperties, items, enum, minimum/maximum,
+// minLength/maxLength, minItems, pattern and format. Annotations (description, default,
+// title) are kept so clients can render forms but are not validated.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Additional is the value of additionalProperties: either a boolean or a schema that every
+// property not listed in properties must match.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+// UnmarshalJSON accepts true, false or a schema object.
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		a.Schema = nil
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// MarshalJSON writes the boolean or schema form back out.
+func (a Additional) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// formats maps the supported "format" values to their checks.
+var formats = map[string]func(string) error{
+	"uri": func(s string) error {
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL")
+		}
+		return nil
+	},
+	"uuid": func(s string) error {
+		var u pgtype.UUID
+		if err := u.Scan(s); err != nil {
+			return errors.New("must be a UUID")
+		}
+		return nil
+	},
+	"timezone": func(s string) error {
+		if _, err := time.LoadLocation(s); err != nil {
+			return errors.New("must be an IANA time zone")
+		}
+		return nil
+	},
+	"cron": func(s string) error {
+		if err := scheduler.ValidateCronExpr(s); err != nil {
+			return fmt.Errorf("must be a cron expression: %v", err)
+		}
+		return nil
+	},
+	"duration": func(s string) error {
+		if d, err := time.ParseDuration(s); err != nil || d < time.Second {
+			return errors.New("must be a duration of at least 1s such as 90s or 5m")
+		}
+		return nil
+	},
+	"date-time": func(s string) error {
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return errors.New("must be an RFC 3339 timestamp")
+		}
+		return nil
+	},
+}
+
+// ParseSchema decodes a schema and compiles its patterns.
+func ParseSchema(raw []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid schema pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	if s.Format != "" {
+		if _, ok := formats[s.Format]; !ok {
+			return fmt.Errorf("invalid schema: unknown format %q", s.Format)
+		}
+	}
+	children := make([]*Schema, 0, len(s.Properties)+2)
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	if s.Items != nil {
+		children = append(children, s.Items)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		children = append(children, s.AdditionalProperties.Schema)
+	}
+	for _, c := range children {
+		if err := c.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FieldError is a single validation failure. Field is a path such as "config.ops[2].op".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validate checks doc against the schema and returns every failure, rooted at path.
+func (s *Schema) Validate(path string, doc []byte) []FieldError {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return []FieldError{{Field: path, Message: "must be valid JSON"}}
+	}
+	var errs []FieldError
+	s.validate(path, v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v any, errs *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.Type != "" && !hasType(v, s.Type) {
+		fail("must be %s", article(s.Type))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(v, s.Enum) {
+		fail("must be one of %s", enumList(s.Enum))
+		return
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				*errs = append(*errs, FieldError{Field: join(path, name), Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				prop.validate(join(path, k), val[k], errs)
+				continue
+			}
+			if ap := s.AdditionalProperties; ap != nil {
+				if !ap.Allowed {
+					*errs = append(*errs, FieldError{Field: join(path, k), Message: "is not allowed"})
+				} else if ap.Schema != nil {
+					ap.Schema.validate(join(path, k), val[k], errs)
+				}
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			fail("must have at least %d item(s)", *s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(path+"["+strconv.Itoa(i)+"]", item, errs)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			fail("must match %s", s.Pattern)
+		}
+		if check := formats[s.Format]; check != nil && val != "" {
+			if err := check(val); err != nil {
+				fail("%v", err)
+			}
+		}
+	case json.Number:
+		f, _ := val.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be >= %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be <= %s", formatNumber(*s.Maximum))
+		}
+	}
+}
+
+func hasType(v any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+func inEnum(v any, enum []any) bool {
+	got, _ := json.Marshal(v)
+	for _, e := range enum {
+		want, _ := json.Marshal(e)
+		if bytes.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	var buf bytes.Buffer
+	for i, e := range enum {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		b, _ := json.Marshal(e)
+		buf.Write(b)
+	}
+	return buf.String()
+}
+
+func article(typ string) string {
+	switch typ {
+	case "object", "array", "integer":
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
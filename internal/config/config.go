@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -19,6 +20,50 @@ type Config struct {
 	DEBUGMODE bool
 	JWTSecret string
 	JWTExpiry time.Duration
+
+	// RefreshTokenTTL is how long a refresh token stays usable; each refresh issues a new one.
+	RefreshTokenTTL time.Duration
+
+	// LoginLockoutThreshold and LoginIPLockoutThreshold are the failed logins per account and per
+	// client IP that trigger a lockout of LoginLockoutDuration.
+	LoginLockoutThreshold   int
+	LoginIPLockoutThreshold int
+	LoginLockoutDuration    time.Duration
+
+	// TrustedProxies are the reverse proxies (IPs or CIDRs) whose X-Forwarded-For header names the
+	// client; connections from anywhere else are counted against their own address.
+	TrustedProxies []netip.Prefix
+
+	// PluginDir is scanned by the worker for plugin executables; empty disables plugins.
+	PluginDir            string
+	PluginHealthInterval time.Duration
+
+	// StepTimeout is how long the worker lets a single action step run.
+	StepTimeout time.Duration
+
+	// SchedulerEnabled runs the cron scheduler inside the worker; replicas elect a single leader.
+	SchedulerEnabled        bool
+	SchedulerReloadInterval time.Duration
+
+	// FileTriggerRoot is the directory file triggers may watch inside; empty disables them.
+	FileTriggerRoot string
+
+	// SMTPAddr is where the API listens for mail to email triggers; empty disables the listener.
+	SMTPAddr            string
+	SMTPDomain          string
+	SMTPMaxMessageBytes int64
+	SMTPMaxConnections  int
+	SMTPSessionTimeout  time.Duration
+
+	// MailSender is where account emails go: "log" (the default), "file" into MailFileDir, or
+	// "smtp" through MailSMTPAddr. AppURL is the base of the links in them.
+	MailSender       string
+	MailFrom         string
+	MailFileDir      string
+	MailSMTPAddr     string
+	MailSMTPUsername string
+	MailSMTPPassword string
+	AppURL           string
 }
 
 // Load reads environment variables (optionally from .env) and returns a validated Config.
@@ -31,7 +76,21 @@ func Load() (Config, error) {
 	v := viper.New()
 	v.AutomaticEnv()
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	v.SetDefault("JWT_EXP_MINUTES", 60)
+	v.SetDefault("JWT_EXP_MINUTES", 15)
+	v.SetDefault("REFRESH_TOKEN_TTL_HOURS", 720)
+	v.SetDefault("LOGIN_LOCKOUT_THRESHOLD", 10)
+	v.SetDefault("LOGIN_IP_LOCKOUT_THRESHOLD", 50)
+	v.SetDefault("LOGIN_LOCKOUT_MINUTES", 15)
+	v.SetDefault("PLUGIN_HEALTH_INTERVAL_SECONDS", 30)
+	v.SetDefault("STEP_TIMEOUT_SECONDS", 600)
+	v.SetDefault("SCHEDULER_ENABLED", true)
+	v.SetDefault("SCHEDULER_RELOAD_SECONDS", 60)
+	v.SetDefault("SMTP_MAX_MESSAGE_BYTES", 10<<20)
+	v.SetDefault("SMTP_MAX_CONNECTIONS", 100)
+	v.SetDefault("SMTP_SESSION_TIMEOUT_MINUTES", 10)
+	v.SetDefault("MAIL_SENDER", "log")
+	v.SetDefault("MAIL_FROM", "PotaFlow <no-reply@localhost>")
+	v.SetDefault("MAIL_FILE_DIR", "mail")
 
 	requireEnv := func(key string) (string, error) {
 		val := v.GetString(key)
@@ -55,6 +114,42 @@ func Load() (Config, error) {
 
 	jwtExpMinutes := v.GetInt("JWT_EXP_MINUTES")
 	jwtExpiry := time.Duration(jwtExpMinutes) * time.Minute
+	refreshTokenTTL := time.Duration(v.GetInt("REFRESH_TOKEN_TTL_HOURS")) * time.Hour
+
+	loginLockoutThreshold := v.GetInt("LOGIN_LOCKOUT_THRESHOLD")
+	loginIPLockoutThreshold := v.GetInt("LOGIN_IP_LOCKOUT_THRESHOLD")
+	loginLockoutDuration := time.Duration(v.GetInt("LOGIN_LOCKOUT_MINUTES")) * time.Minute
+	trustedProxies, err := parsePrefixes(v.GetString("TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	pluginDir := v.GetString("PLUGIN_DIR")
+	pluginHealthInterval := time.Duration(v.GetInt("PLUGIN_HEALTH_INTERVAL_SECONDS")) * time.Second
+	stepTimeout := time.Duration(v.GetInt("STEP_TIMEOUT_SECONDS")) * time.Second
+
+	schedulerEnabled := v.GetBool("SCHEDULER_ENABLED")
+	schedulerReloadInterval := time.Duration(v.GetInt("SCHEDULER_RELOAD_SECONDS")) * time.Second
+
+	fileTriggerRoot := v.GetString("FILE_TRIGGER_ROOT")
+
+	smtpAddr := v.GetString("SMTP_ADDR")
+	smtpDomain := v.GetString("SMTP_DOMAIN")
+	smtpMaxMessageBytes := v.GetInt64("SMTP_MAX_MESSAGE_BYTES")
+	smtpMaxConnections := v.GetInt("SMTP_MAX_CONNECTIONS")
+	smtpSessionTimeout := time.Duration(v.GetInt("SMTP_SESSION_TIMEOUT_MINUTES")) * time.Minute
+
+	mailSender := strings.ToLower(v.GetString("MAIL_SENDER"))
+	mailSMTPAddr := v.GetString("MAIL_SMTP_ADDR")
+	switch mailSender {
+	case "log", "file":
+	case "smtp":
+		if mailSMTPAddr == "" {
+			return Config{}, fmt.Errorf("missing required environment variable MAIL_SMTP_ADDR")
+		}
+	default:
+		return Config{}, fmt.Errorf("unknown mail sender: %s (expected log, file or smtp)", mailSender)
+	}
 
 	var (
 		dbURL     string
@@ -87,5 +182,62 @@ func Load() (Config, error) {
 		DEBUGMODE: debugMode,
 		JWTSecret: jwtSecret,
 		JWTExpiry: jwtExpiry,
+
+		RefreshTokenTTL: refreshTokenTTL,
+
+		LoginLockoutThreshold:   loginLockoutThreshold,
+		LoginIPLockoutThreshold: loginIPLockoutThreshold,
+		LoginLockoutDuration:    loginLockoutDuration,
+
+		TrustedProxies: trustedProxies,
+
+		PluginDir:            pluginDir,
+		PluginHealthInterval: pluginHealthInterval,
+
+		StepTimeout: stepTimeout,
+
+		SchedulerEnabled:        schedulerEnabled,
+		SchedulerReloadInterval: schedulerReloadInterval,
+
+		FileTriggerRoot: fileTriggerRoot,
+
+		SMTPAddr:            smtpAddr,
+		SMTPDomain:          smtpDomain,
+		SMTPMaxMessageBytes: smtpMaxMessageBytes,
+		SMTPMaxConnections:  smtpMaxConnections,
+		SMTPSessionTimeout:  smtpSessionTimeout,
+
+		MailSender:       mailSender,
+		MailFrom:         v.GetString("MAIL_FROM"),
+		MailFileDir:      v.GetString("MAIL_FILE_DIR"),
+		MailSMTPAddr:     mailSMTPAddr,
+		MailSMTPUsername: v.GetString("MAIL_SMTP_USERNAME"),
+		MailSMTPPassword: v.GetString("MAIL_SMTP_PASSWORD"),
+		AppURL:           v.GetString("APP_URL"),
 	}, nil
 }
+
+// parsePrefixes parses a comma-separated list of IPs and CIDRs; an IP stands for itself alone.
+func parsePrefixes(raw string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return out, nil
+}
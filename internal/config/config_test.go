@@ -56,8 +56,29 @@ func TestLoadProdDefaults(t *testing.T) {
 	if cfg.DBDSN != "postgres://user:pass@db/prod?sslmode=disable" {
 		t.Fatalf("unexpected DBDSN: %s", cfg.DBDSN)
 	}
-	if cfg.JWTExpiry != 60*time.Minute {
-		t.Fatalf("expected default JWT expiry 60m, got %s", cfg.JWTExpiry)
+	if cfg.JWTExpiry != 15*time.Minute {
+		t.Fatalf("expected default JWT expiry 15m, got %s", cfg.JWTExpiry)
+	}
+	if cfg.RefreshTokenTTL != 30*24*time.Hour {
+		t.Fatalf("expected default refresh token TTL 30d, got %s", cfg.RefreshTokenTTL)
+	}
+	if cfg.LoginLockoutThreshold != 10 || cfg.LoginIPLockoutThreshold != 50 || cfg.LoginLockoutDuration != 15*time.Minute {
+		t.Fatalf("unexpected lockout defaults: account=%d ip=%d duration=%s", cfg.LoginLockoutThreshold, cfg.LoginIPLockoutThreshold, cfg.LoginLockoutDuration)
+	}
+	if cfg.PluginDir != "" || cfg.PluginHealthInterval != 30*time.Second {
+		t.Fatalf("unexpected plugin defaults: dir=%q interval=%s", cfg.PluginDir, cfg.PluginHealthInterval)
+	}
+	if !cfg.SchedulerEnabled || cfg.SchedulerReloadInterval != time.Minute {
+		t.Fatalf("unexpected scheduler defaults: enabled=%v reload=%s", cfg.SchedulerEnabled, cfg.SchedulerReloadInterval)
+	}
+	if cfg.FileTriggerRoot != "" {
+		t.Fatalf("expected file triggers to be disabled by default, got root %q", cfg.FileTriggerRoot)
+	}
+	if cfg.MailSender != "log" || cfg.MailFileDir != "mail" || cfg.MailFrom == "" {
+		t.Fatalf("unexpected mail defaults: sender=%q dir=%q from=%q", cfg.MailSender, cfg.MailFileDir, cfg.MailFrom)
+	}
+	if cfg.SMTPAddr != "" || cfg.SMTPMaxMessageBytes != 10<<20 || cfg.SMTPMaxConnections != 100 || cfg.SMTPSessionTimeout != 10*time.Minute {
+		t.Fatalf("unexpected smtp defaults: addr=%q max=%d conns=%d session=%s", cfg.SMTPAddr, cfg.SMTPMaxMessageBytes, cfg.SMTPMaxConnections, cfg.SMTPSessionTimeout)
 	}
 }
 
@@ -69,3 +90,39 @@ func TestLoadUnknownEnv(t *testing.T) {
 		t.Fatalf("expected error for unsupported APP_ENV")
 	}
 }
+
+func TestLoadSMTPMailSenderRequiresAddr(t *testing.T) {
+	t.Setenv("APP_ENV", "PROD")
+	t.Setenv("DB_URL", "postgres://user:pass@db/prod?sslmode=disable")
+	t.Setenv("JWT_SECRET", "supersecret")
+	t.Setenv("MAIL_SENDER", "smtp")
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("expected error for smtp mail sender without MAIL_SMTP_ADDR")
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	t.Setenv("APP_ENV", "PROD")
+	t.Setenv("DB_URL", "postgres://user:pass@db/prod?sslmode=disable")
+	t.Setenv("JWT_SECRET", "supersecret")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.7,2001:db8::/32")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.7/32", "2001:db8::/32"}
+	if len(cfg.TrustedProxies) != len(want) {
+		t.Fatalf("unexpected trusted proxies %v", cfg.TrustedProxies)
+	}
+	for i, p := range cfg.TrustedProxies {
+		if p.String() != want[i] {
+			t.Fatalf("unexpected trusted proxies %v", cfg.TrustedProxies)
+		}
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/33")
+	if _, err := Load(); err == nil {
+		t.Fatalf("expected error for an invalid TRUSTED_PROXIES entry")
+	}
+}
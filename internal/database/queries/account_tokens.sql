@@ -0,0 +1,17 @@
+-- name: CreateAccountToken :exec
+INSERT INTO account_tokens (user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: UseAccountToken :one
+UPDATE account_tokens
+SET used_at = now()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+RETURNING user_id::text;
+
+-- name: DeleteAccountTokens :exec
+DELETE FROM account_tokens
+WHERE user_id = $1 AND purpose = $2;
+
+-- name: PruneAccountTokens :execrows
+DELETE FROM account_tokens
+WHERE expires_at < $1;
@@ -1,13 +1,25 @@
 -- name: CreateAPIKey :one
-INSERT INTO api_keys (user_id, name, hashed_key)
-VALUES ($1, $2, $3)
-RETURNING id::text, user_id::text, name, hashed_key, created_at;
+INSERT INTO api_keys (user_id, name, hashed_key, key_prefix, expires_at, scopes, workflow_ids)
+VALUES ($1, $2, $3, $4, $5, $6, sqlc.arg(workflow_ids)::text[]::uuid[])
+RETURNING id::text, user_id::text, name, key_prefix, created_at, expires_at, last_used_at, scopes, workflow_ids::text[];
 
 -- name: ListAPIKeysByUser :many
-SELECT id::text, user_id::text, name, hashed_key, created_at
+SELECT id::text, user_id::text, name, key_prefix, created_at, expires_at, last_used_at, scopes, workflow_ids::text[]
 FROM api_keys
 WHERE user_id = $1
 ORDER BY created_at DESC;
 
--- name: DeleteAPIKey :exec
-DELETE FROM api_keys WHERE id = $1;
+-- name: GetAPIKeyByHash :one
+SELECT k.id::text, k.user_id::text, k.name, k.key_prefix, k.created_at, k.expires_at, k.last_used_at, k.scopes, k.workflow_ids::text[],
+       u.email, u.role, u.disabled_at
+FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.hashed_key = $1;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+
+-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,10 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (event, user_id, ip, details)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListAuditEvents :many
+SELECT id::text, event, COALESCE(user_id::text, '') AS user_id, ip, details, created_at
+FROM audit_events
+WHERE (sqlc.narg(event)::text IS NULL OR event = sqlc.narg(event))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,31 @@
+-- name: GetLoginThrottle :one
+SELECT failures, blocked_until
+FROM login_throttles
+WHERE scope = $1 AND key = $2;
+
+-- name: ReserveLoginAttempt :one
+INSERT INTO login_throttles (scope, key, failures, window_started_at)
+VALUES (sqlc.arg(scope), sqlc.arg(key), 1, sqlc.arg(attempted_at))
+ON CONFLICT (scope, key) DO UPDATE
+SET failures = CASE WHEN login_throttles.window_started_at < sqlc.arg(window_start) THEN 1 ELSE login_throttles.failures + 1 END,
+    window_started_at = CASE WHEN login_throttles.window_started_at < sqlc.arg(window_start) THEN sqlc.arg(attempted_at) ELSE login_throttles.window_started_at END
+RETURNING failures, window_started_at;
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_throttles
+SET failures = greatest(failures - 1, 0)
+WHERE scope = $1 AND key = $2;
+
+-- name: BlockLogin :exec
+UPDATE login_throttles
+SET blocked_until = $3
+WHERE scope = $1 AND key = $2;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_throttles
+WHERE scope = $1 AND key = $2;
+
+-- name: PruneLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE window_started_at < sqlc.arg(stale_before)
+  AND (blocked_until IS NULL OR blocked_until < sqlc.arg(stale_before));
@@ -0,0 +1,96 @@
+-- name: CreateOrganization :one
+WITH org AS (
+    INSERT INTO organizations (name)
+    VALUES (sqlc.arg(name))
+    RETURNING id, name, created_at
+), owner AS (
+    INSERT INTO organization_members (org_id, user_id, role)
+    SELECT id, sqlc.arg(user_id), 'owner' FROM org
+)
+SELECT id::text, name, created_at
+FROM org;
+
+-- name: GetDefaultOrganization :one
+SELECT o.id::text
+FROM organizations o
+JOIN organization_members m ON m.org_id = o.id
+WHERE m.user_id = $1 AND m.role = 'owner'
+ORDER BY o.created_at
+LIMIT 1;
+
+-- name: ListOrganizationsForUser :many
+SELECT o.id::text, o.name, o.created_at, m.role
+FROM organizations o
+JOIN organization_members m ON m.org_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.created_at;
+
+-- name: GetOrganizationForMember :one
+SELECT o.id::text, o.name, o.created_at, m.role
+FROM organizations o
+JOIN organization_members m ON m.org_id = o.id
+WHERE o.id = $1 AND m.user_id = $2;
+
+-- name: ListOrganizationMembers :many
+SELECT m.user_id::text, u.email, m.role, m.created_at
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.org_id = $1
+ORDER BY m.created_at;
+
+-- name: UpdateOrganizationMemberRole :execrows
+-- An owner is only demoted while another owner remains. The owner rows are locked first, so two
+-- concurrent demotions can't both see the other owner.
+WITH owners AS (
+    SELECT user_id
+    FROM organization_members
+    WHERE org_id = $1 AND role = 'owner'
+    FOR UPDATE
+)
+UPDATE organization_members
+SET role = $3
+WHERE org_id = $1 AND user_id = $2
+  AND (role <> 'owner' OR $3 = 'owner' OR (SELECT count(*) FROM owners) > 1);
+
+-- name: DeleteOrganizationMember :execrows
+-- Like UpdateOrganizationMemberRole, the last owner is never removed.
+WITH owners AS (
+    SELECT user_id
+    FROM organization_members
+    WHERE org_id = $1 AND role = 'owner'
+    FOR UPDATE
+)
+DELETE FROM organization_members
+WHERE org_id = $1 AND user_id = $2
+  AND (role <> 'owner' OR (SELECT count(*) FROM owners) > 1);
+
+-- name: CreateOrganizationInvitation :one
+INSERT INTO organization_invitations (org_id, email, role, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id::text, org_id::text, email, role, COALESCE(invited_by::text, '') AS invited_by, expires_at, accepted_at, created_at;
+
+-- name: ListOrganizationInvitations :many
+SELECT id::text, org_id::text, email, role, COALESCE(invited_by::text, '') AS invited_by, expires_at, accepted_at, created_at
+FROM organization_invitations
+WHERE org_id = $1 AND accepted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetOrganizationInvitationByHash :one
+SELECT id::text, org_id::text, email, role, COALESCE(invited_by::text, '') AS invited_by, expires_at, accepted_at, created_at
+FROM organization_invitations
+WHERE token_hash = $1;
+
+-- name: DeleteOrganizationInvitation :execrows
+DELETE FROM organization_invitations
+WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL;
+
+-- name: AcceptOrganizationInvitation :execrows
+WITH inv AS (
+    UPDATE organization_invitations
+    SET accepted_at = now()
+    WHERE id = sqlc.arg(id) AND accepted_at IS NULL
+    RETURNING org_id, role
+)
+INSERT INTO organization_members (org_id, user_id, role)
+SELECT org_id, sqlc.arg(user_id), role FROM inv
+ON CONFLICT (org_id, user_id) DO NOTHING;
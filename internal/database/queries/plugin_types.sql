@@ -0,0 +1,13 @@
+-- name: UpsertPluginType :exec
+INSERT INTO plugin_types (kind, type, plugin, description, config_schema)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (kind, type) DO UPDATE
+SET plugin = EXCLUDED.plugin,
+    description = EXCLUDED.description,
+    config_schema = EXCLUDED.config_schema,
+    updated_at = now();
+
+-- name: ListPluginTypes :many
+SELECT kind, type, plugin, description, config_schema
+FROM plugin_types
+ORDER BY kind, type;
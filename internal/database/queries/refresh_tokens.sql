@@ -0,0 +1,25 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+VALUES ($1, COALESCE($2::uuid, gen_random_uuid()), $3, $4)
+RETURNING id::text, family_id::text;
+
+-- name: GetRefreshTokenByHash :one
+SELECT id::text, user_id::text, family_id::text, expires_at, rotated_at, revoked_at, created_at
+FROM refresh_tokens
+WHERE token_hash = $1;
+
+-- name: MarkRefreshTokenRotated :execrows
+UPDATE refresh_tokens
+SET rotated_at = now()
+WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,14 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: PruneRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < now();
+
+-- name: CheckTokenRevocation :one
+SELECT (disabled_at IS NOT NULL OR EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = sqlc.arg(jti)))::bool AS revoked,
+       tokens_valid_after
+FROM users
+WHERE id = sqlc.arg(user_id);
@@ -0,0 +1,55 @@
+-- name: GetUserTOTP :one
+SELECT secret, enabled_at, last_step
+FROM user_totp
+WHERE user_id = $1;
+
+-- name: SaveTOTPSecret :execrows
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+WHERE user_totp.enabled_at IS NULL;
+
+-- name: EnableTOTP :execrows
+UPDATE user_totp
+SET enabled_at = now(), last_step = $2
+WHERE user_id = $1 AND enabled_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET last_step = $2
+WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_step < $2;
+
+-- name: DeleteUserTOTP :execrows
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (user_id, token_hash, expires_at, old_password_hash, new_password_hash)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetLoginChallenge :one
+SELECT user_id::text, expires_at, old_password_hash, new_password_hash
+FROM login_challenges
+WHERE token_hash = $1;
+
+-- name: DeleteLoginChallenge :execrows
+DELETE FROM login_challenges
+WHERE token_hash = $1;
+
+-- name: PruneLoginChallenges :execrows
+DELETE FROM login_challenges
+WHERE expires_at < $1;
@@ -0,0 +1,11 @@
+-- name: MarkTriggerItemSeen :one
+INSERT INTO trigger_seen_items (trigger_id, item_id)
+VALUES ($1, $2)
+ON CONFLICT (trigger_id, item_id) DO UPDATE SET last_seen_at = now()
+RETURNING (xmax = 0)::bool AS inserted;
+
+-- name: ForgetTriggerItem :exec
+DELETE FROM trigger_seen_items WHERE trigger_id = $1 AND item_id = $2;
+
+-- name: PruneTriggerSeenItems :exec
+DELETE FROM trigger_seen_items WHERE trigger_id = $1 AND last_seen_at < $2;
@@ -9,7 +9,7 @@ FROM triggers
 WHERE id = $1 AND workflow_id = $2;
 
 -- name: ListTriggersByWorkflow :many
-SELECT id::text, workflow_id::text, type, config, created_at
+SELECT id::text, workflow_id::text, type, config, created_at, rejected_count, last_rejected_at, last_fired_at
 FROM triggers
 WHERE workflow_id = $1
 ORDER BY created_at;
@@ -25,3 +25,55 @@ DELETE FROM triggers WHERE workflow_id = $1;
 
 -- name: DeleteTrigger :exec
 DELETE FROM triggers WHERE id = $1 AND workflow_id = $2;
+
+-- name: GetTriggerWithWorkflow :one
+SELECT t.id::text, t.workflow_id::text, t.type, t.config, t.created_at, w.is_enabled
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+WHERE t.id = $1;
+
+-- name: RecordTriggerRejection :exec
+UPDATE triggers
+SET rejected_count = rejected_count + 1, last_rejected_at = now()
+WHERE id = $1;
+
+-- name: ListScheduledTriggers :many
+SELECT t.id::text, t.workflow_id::text, t.type, t.config, t.created_at, t.last_fired_at
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+WHERE t.type IN ('cron', 'interval', 'at', 'poll') AND w.is_enabled
+ORDER BY t.created_at;
+
+-- name: UpdateTriggerLastFiredAt :exec
+UPDATE triggers
+SET last_fired_at = $2
+WHERE id = $1;
+
+-- name: DeleteTriggerByID :exec
+DELETE FROM triggers WHERE id = $1;
+
+-- name: GetTriggerLastPolledAt :one
+SELECT last_polled_at FROM triggers WHERE id = $1;
+
+-- name: UpdateTriggerLastPolledAt :exec
+UPDATE triggers
+SET last_polled_at = $2
+WHERE id = $1;
+
+-- name: ListEnabledTriggersByType :many
+SELECT t.id::text, t.workflow_id::text, w.org_id::text, t.config
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+WHERE t.type = $1 AND w.is_enabled
+ORDER BY t.created_at;
+
+-- name: ListWorkflowEventTriggers :many
+SELECT t.id::text, t.workflow_id::text, t.config
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+JOIN workflows src ON src.id = $1
+WHERE t.type = 'workflow_event'
+  AND t.config->>'workflow_id' = $1::text
+  AND w.is_enabled
+  AND w.org_id = src.org_id
+ORDER BY t.created_at;
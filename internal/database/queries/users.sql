@@ -1,14 +1,54 @@
 -- name: CreateUser :one
 INSERT INTO users (email, password_hash)
 VALUES ($1, $2)
-RETURNING id::text, email, created_at, updated_at;
+RETURNING id::text, email, created_at, updated_at, role, disabled_at, email_verified_at;
 
 -- name: GetUserByEmail :one
-SELECT id::text, email, password_hash, created_at, updated_at
+SELECT id::text, email, password_hash, created_at, updated_at, role, disabled_at, email_verified_at
 FROM users
 WHERE email = $1;
 
 -- name: GetUserByID :one
-SELECT id::text, email, created_at, updated_at
+SELECT id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
 FROM users
 WHERE id = $1;
+
+-- name: ListUsers :many
+SELECT id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
+FROM users
+ORDER BY created_at;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = now()
+WHERE id = $1
+RETURNING id::text, email, created_at, updated_at, role, disabled_at, email_verified_at;
+
+-- name: SetUserDisabled :one
+UPDATE users
+SET disabled_at = CASE WHEN sqlc.arg(disabled)::bool THEN COALESCE(disabled_at, now()) END,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING id::text, email, created_at, updated_at, role, disabled_at, email_verified_at;
+
+-- name: SetUserTokensValidAfter :one
+UPDATE users
+SET tokens_valid_after = now()
+WHERE id = $1
+RETURNING tokens_valid_after;
+
+-- name: UpdatePasswordHash :execrows
+UPDATE users
+SET password_hash = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: UpgradePasswordHash :exec
+-- Replaces the hash only if it is still old_hash, so a concurrent password change wins.
+UPDATE users
+SET password_hash = sqlc.arg(new_hash), updated_at = now()
+WHERE id = sqlc.arg(id) AND password_hash = sqlc.arg(old_hash);
+
+-- name: SetEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+WHERE id = $1;
@@ -1,10 +1,26 @@
 -- name: CreateWorkflowRun :one
-INSERT INTO workflow_runs (workflow_id, status, trigger_type, started_at)
-VALUES ($1, $2, $3, $4)
+INSERT INTO workflow_runs (workflow_id, status, trigger_type, started_at, input)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at;
 
+-- name: CreateChildWorkflowRun :one
+INSERT INTO workflow_runs (workflow_id, status, trigger_type, started_at, parent_run_id, depth, input, chain_depth)
+VALUES ($1, $2, 'workflow', $3, $4::uuid, $5, $6,
+        COALESCE((SELECT chain_depth FROM workflow_runs WHERE id = $4::uuid), 0))
+RETURNING id::text;
+
+-- name: CreateChainedWorkflowRun :one
+INSERT INTO workflow_runs (workflow_id, status, trigger_type, parent_run_id, chain_depth, input)
+VALUES ($1, 'pending', 'workflow_event', $2::uuid, $3, $4)
+RETURNING id::text;
+
+-- name: GetWorkflowRun :one
+SELECT id::text, workflow_id::text, status, parent_run_id, depth, output, chain_depth
+FROM workflow_runs
+WHERE id = $1;
+
 -- name: ListPendingWorkflowRuns :many
-SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at
+SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at, depth, input
 FROM workflow_runs
 WHERE status = 'pending'
 ORDER BY created_at
@@ -18,12 +34,20 @@ RETURNING id::text, workflow_id::text, status, trigger_type, started_at, finishe
 
 -- name: UpdateWorkflowRunStatus :one
 UPDATE workflow_runs
-SET status = $2, finished_at = $3
+SET status = $2, finished_at = $3, output = $4
 WHERE id = $1
 RETURNING id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at;
 
 -- name: ListWorkflowRunsByWorkflow :many
-SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at
+SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at, parent_run_id, depth
 FROM workflow_runs
 WHERE workflow_id = $1
 ORDER BY created_at DESC;
+
+-- name: ListRecentWorkflowRuns :many
+SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at, parent_run_id, depth
+FROM workflow_runs
+WHERE (sqlc.narg(workflow_id)::text IS NULL OR workflow_id::text = sqlc.narg(workflow_id))
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
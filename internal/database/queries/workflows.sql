@@ -1,26 +1,48 @@
 -- name: CreateWorkflow :one
-INSERT INTO workflows (user_id, name)
-VALUES ($1, $2)
-RETURNING id::text, user_id::text, name, is_enabled, created_at, updated_at;
+INSERT INTO workflows (user_id, org_id, name)
+VALUES ($1, $2, $3)
+RETURNING id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled, created_at, updated_at;
 
 -- name: GetWorkflow :one
-SELECT id::text, user_id::text, name, is_enabled, created_at, updated_at
-FROM workflows
-WHERE id = $1 AND user_id = $2;
+SELECT w.id::text, COALESCE(w.user_id::text, '') AS user_id, w.org_id::text, w.name, w.is_enabled, w.created_at, w.updated_at, m.role
+FROM workflows w
+JOIN organization_members m ON m.org_id = w.org_id
+WHERE w.id = $1 AND m.user_id = $2;
 
--- name: ListWorkflowsByUser :many
-SELECT id::text, user_id::text, name, is_enabled, created_at, updated_at
-FROM workflows
-WHERE user_id = $1
-ORDER BY created_at DESC;
+-- name: ListWorkflowsForMember :many
+SELECT w.id::text, COALESCE(w.user_id::text, '') AS user_id, w.org_id::text, w.name, w.is_enabled, w.created_at, w.updated_at
+FROM workflows w
+JOIN organization_members m ON m.org_id = w.org_id
+WHERE m.user_id = $1
+ORDER BY w.created_at DESC;
 
 -- name: UpdateWorkflow :one
 UPDATE workflows
 SET name = $2, is_enabled = $3, updated_at = now()
-WHERE id = $1 AND user_id = $4
-RETURNING id::text, user_id::text, name, is_enabled, created_at, updated_at;
+WHERE id = $1
+RETURNING id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled, created_at, updated_at;
 
--- name: DeleteWorkflow :one
+-- name: DeleteWorkflow :execrows
 DELETE FROM workflows
-WHERE id = $1 AND user_id = $2
-RETURNING id::text;
+WHERE id = $1;
+
+-- name: GetWorkflowSigningSecret :one
+SELECT signing_secret
+FROM workflows
+WHERE id = $1;
+
+-- name: RotateWorkflowSigningSecret :one
+UPDATE workflows
+SET signing_secret = encode(gen_random_bytes(32), 'hex'), updated_at = now()
+WHERE id = $1
+RETURNING signing_secret;
+
+-- name: GetWorkflowByID :one
+SELECT id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled
+FROM workflows
+WHERE id = $1;
+
+-- name: ListAllWorkflows :many
+SELECT id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled, created_at, updated_at
+FROM workflows
+ORDER BY created_at DESC;
@@ -12,60 +12,135 @@ import (
 )
 
 const createAPIKey = `-- name: CreateAPIKey :one
-INSERT INTO api_keys (user_id, name, hashed_key)
-VALUES ($1, $2, $3)
-RETURNING id::text, user_id::text, name, hashed_key, created_at
+INSERT INTO api_keys (user_id, name, hashed_key, key_prefix, expires_at, scopes, workflow_ids)
+VALUES ($1, $2, $3, $4, $5, $6, $1::text[]::uuid[])
+RETURNING id::text, user_id::text, name, key_prefix, created_at, expires_at, last_used_at, scopes, workflow_ids::text[]
 `
 
 type CreateAPIKeyParams struct {
-	UserID    string `json:"user_id"`
-	Name      string `json:"name"`
-	HashedKey string `json:"hashed_key"`
+	UserID      string             `json:"user_id"`
+	Name        string             `json:"name"`
+	HashedKey   string             `json:"hashed_key"`
+	KeyPrefix   string             `json:"key_prefix"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	Scopes      []string           `json:"scopes"`
+	WorkflowIds []string           `json:"workflow_ids"`
 }
 
 type CreateAPIKeyRow struct {
-	ID        string             `json:"id"`
-	UserID    string             `json:"user_id"`
-	Name      string             `json:"name"`
-	HashedKey string             `json:"hashed_key"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	Name        string             `json:"name"`
+	KeyPrefix   string             `json:"key_prefix"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	Scopes      []string           `json:"scopes"`
+	WorkflowIds []string           `json:"workflow_ids"`
 }
 
 func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (CreateAPIKeyRow, error) {
-	row := q.db.QueryRow(ctx, createAPIKey, arg.UserID, arg.Name, arg.HashedKey)
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.UserID,
+		arg.Name,
+		arg.HashedKey,
+		arg.KeyPrefix,
+		arg.ExpiresAt,
+		arg.Scopes,
+		arg.WorkflowIds,
+	)
 	var i CreateAPIKeyRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.Name,
-		&i.HashedKey,
+		&i.KeyPrefix,
 		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.Scopes,
+		&i.WorkflowIds,
 	)
 	return i, err
 }
 
-const deleteAPIKey = `-- name: DeleteAPIKey :exec
-DELETE FROM api_keys WHERE id = $1
+const deleteAPIKey = `-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys WHERE id = $1 AND user_id = $2
 `
 
-func (q *Queries) DeleteAPIKey(ctx context.Context, id string) error {
-	_, err := q.db.Exec(ctx, deleteAPIKey, id)
-	return err
+type DeleteAPIKeyParams struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAPIKey, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT k.id::text, k.user_id::text, k.name, k.key_prefix, k.created_at, k.expires_at, k.last_used_at, k.scopes, k.workflow_ids::text[],
+       u.email, u.role, u.disabled_at
+FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.hashed_key = $1
+`
+
+type GetAPIKeyByHashRow struct {
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	Name        string             `json:"name"`
+	KeyPrefix   string             `json:"key_prefix"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	Scopes      []string           `json:"scopes"`
+	WorkflowIds []string           `json:"workflow_ids"`
+	Email       string             `json:"email"`
+	Role        string             `json:"role"`
+	DisabledAt  pgtype.Timestamptz `json:"disabled_at"`
+}
+
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, hashedKey string) (GetAPIKeyByHashRow, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByHash, hashedKey)
+	var i GetAPIKeyByHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.KeyPrefix,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.Scopes,
+		&i.WorkflowIds,
+		&i.Email,
+		&i.Role,
+		&i.DisabledAt,
+	)
+	return i, err
 }
 
 const listAPIKeysByUser = `-- name: ListAPIKeysByUser :many
-SELECT id::text, user_id::text, name, hashed_key, created_at
+SELECT id::text, user_id::text, name, key_prefix, created_at, expires_at, last_used_at, scopes, workflow_ids::text[]
 FROM api_keys
 WHERE user_id = $1
 ORDER BY created_at DESC
 `
 
 type ListAPIKeysByUserRow struct {
-	ID        string             `json:"id"`
-	UserID    string             `json:"user_id"`
-	Name      string             `json:"name"`
-	HashedKey string             `json:"hashed_key"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	Name        string             `json:"name"`
+	KeyPrefix   string             `json:"key_prefix"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	Scopes      []string           `json:"scopes"`
+	WorkflowIds []string           `json:"workflow_ids"`
 }
 
 func (q *Queries) ListAPIKeysByUser(ctx context.Context, userID string) ([]ListAPIKeysByUserRow, error) {
@@ -81,8 +156,12 @@ func (q *Queries) ListAPIKeysByUser(ctx context.Context, userID string) ([]ListA
 			&i.ID,
 			&i.UserID,
 			&i.Name,
-			&i.HashedKey,
+			&i.KeyPrefix,
 			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.Scopes,
+			&i.WorkflowIds,
 		); err != nil {
 			return nil, err
 		}
@@ -93,3 +172,14 @@ func (q *Queries) ListAPIKeysByUser(ctx context.Context, userID string) ([]ListA
 	}
 	return items, nil
 }
+
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+`
+
+func (q *Queries) TouchAPIKey(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, touchAPIKey, id)
+	return err
+}
@@ -8,6 +8,16 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccountToken struct {
+	ID        string             `json:"id"`
+	UserID    string             `json:"user_id"`
+	Purpose   string             `json:"purpose"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Action struct {
 	ID         string             `json:"id"`
 	WorkflowID string             `json:"workflow_id"`
@@ -18,36 +28,151 @@ type Action struct {
 }
 
 type ApiKey struct {
+	ID          string             `json:"id"`
+	UserID      string             `json:"user_id"`
+	Name        string             `json:"name"`
+	HashedKey   string             `json:"hashed_key"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	KeyPrefix   string             `json:"key_prefix"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	Scopes      []string           `json:"scopes"`
+	WorkflowIds []pgtype.UUID      `json:"workflow_ids"`
+}
+
+type AuditEvent struct {
+	ID        string             `json:"id"`
+	Event     string             `json:"event"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Ip        string             `json:"ip"`
+	Details   []byte             `json:"details"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type LoginChallenge struct {
 	ID        string             `json:"id"`
 	UserID    string             `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Scope           string             `json:"scope"`
+	Key             string             `json:"key"`
+	Failures        int32              `json:"failures"`
+	WindowStartedAt pgtype.Timestamptz `json:"window_started_at"`
+	BlockedUntil    pgtype.Timestamptz `json:"blocked_until"`
+}
+
+type Organization struct {
+	ID        string             `json:"id"`
 	Name      string             `json:"name"`
-	HashedKey string             `json:"hashed_key"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
-type Trigger struct {
+type OrganizationInvitation struct {
 	ID         string             `json:"id"`
-	WorkflowID string             `json:"workflow_id"`
-	Type       string             `json:"type"`
-	Config     []byte             `json:"config"`
+	OrgID      string             `json:"org_id"`
+	Email      string             `json:"email"`
+	Role       string             `json:"role"`
+	TokenHash  string             `json:"token_hash"`
+	InvitedBy  pgtype.UUID        `json:"invited_by"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	AcceptedAt pgtype.Timestamptz `json:"accepted_at"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
 
-type User struct {
-	ID           string             `json:"id"`
-	Email        string             `json:"email"`
-	PasswordHash string             `json:"password_hash"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+type OrganizationMember struct {
+	OrgID     string             `json:"org_id"`
+	UserID    string             `json:"user_id"`
+	Role      string             `json:"role"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type PluginType struct {
+	Kind         string             `json:"kind"`
+	Type         string             `json:"type"`
+	Plugin       string             `json:"plugin"`
+	Description  string             `json:"description"`
+	ConfigSchema []byte             `json:"config_schema"`
 	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
 }
 
-type Workflow struct {
+type RecoveryCode struct {
 	ID        string             `json:"id"`
 	UserID    string             `json:"user_id"`
-	Name      string             `json:"name"`
-	IsEnabled bool               `json:"is_enabled"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RefreshToken struct {
+	ID        string             `json:"id"`
+	UserID    string             `json:"user_id"`
+	FamilyID  string             `json:"family_id"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RotatedAt pgtype.Timestamptz `json:"rotated_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type RevokedToken struct {
+	Jti       string             `json:"jti"`
+	UserID    string             `json:"user_id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type Trigger struct {
+	ID             string             `json:"id"`
+	WorkflowID     string             `json:"workflow_id"`
+	Type           string             `json:"type"`
+	Config         []byte             `json:"config"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	RejectedCount  int64              `json:"rejected_count"`
+	LastRejectedAt pgtype.Timestamptz `json:"last_rejected_at"`
+	LastFiredAt    pgtype.Timestamptz `json:"last_fired_at"`
+	LastPolledAt   pgtype.Timestamptz `json:"last_polled_at"`
+}
+
+type TriggerSeenItem struct {
+	TriggerID   string             `json:"trigger_id"`
+	ItemID      string             `json:"item_id"`
+	FirstSeenAt pgtype.Timestamptz `json:"first_seen_at"`
+	LastSeenAt  pgtype.Timestamptz `json:"last_seen_at"`
+}
+
+type User struct {
+	ID               string             `json:"id"`
+	Email            string             `json:"email"`
+	PasswordHash     string             `json:"password_hash"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	TokensValidAfter pgtype.Timestamptz `json:"tokens_valid_after"`
+	Role             string             `json:"role"`
+	DisabledAt       pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt  pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+type UserTotp struct {
+	UserID    string             `json:"user_id"`
+	Secret    string             `json:"secret"`
+	EnabledAt pgtype.Timestamptz `json:"enabled_at"`
+	LastStep  int64              `json:"last_step"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Workflow struct {
+	ID            string             `json:"id"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	Name          string             `json:"name"`
+	IsEnabled     bool               `json:"is_enabled"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	SigningSecret string             `json:"signing_secret"`
+	OrgID         string             `json:"org_id"`
 }
 
 type WorkflowRun struct {
@@ -58,6 +183,11 @@ type WorkflowRun struct {
 	StartedAt   pgtype.Timestamptz `json:"started_at"`
 	FinishedAt  pgtype.Timestamptz `json:"finished_at"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ParentRunID pgtype.UUID        `json:"parent_run_id"`
+	Depth       int32              `json:"depth"`
+	Input       []byte             `json:"input"`
+	Output      []byte             `json:"output"`
+	ChainDepth  int32              `json:"chain_depth"`
 }
 
 type WorkflowRunLog struct {
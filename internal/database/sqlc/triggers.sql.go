@@ -58,6 +58,15 @@ func (q *Queries) DeleteTrigger(ctx context.Context, arg DeleteTriggerParams) er
 	return err
 }
 
+const deleteTriggerByID = `-- name: DeleteTriggerByID :exec
+DELETE FROM triggers WHERE id = $1
+`
+
+func (q *Queries) DeleteTriggerByID(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, deleteTriggerByID, id)
+	return err
+}
+
 const deleteTriggersByWorkflow = `-- name: DeleteTriggersByWorkflow :exec
 DELETE FROM triggers WHERE workflow_id = $1
 `
@@ -99,19 +108,147 @@ func (q *Queries) GetTrigger(ctx context.Context, arg GetTriggerParams) (GetTrig
 	return i, err
 }
 
-const listTriggersByWorkflow = `-- name: ListTriggersByWorkflow :many
-SELECT id::text, workflow_id::text, type, config, created_at
-FROM triggers
-WHERE workflow_id = $1
-ORDER BY created_at
+const getTriggerLastPolledAt = `-- name: GetTriggerLastPolledAt :one
+SELECT last_polled_at FROM triggers WHERE id = $1
 `
 
-type ListTriggersByWorkflowRow struct {
+func (q *Queries) GetTriggerLastPolledAt(ctx context.Context, id string) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getTriggerLastPolledAt, id)
+	var last_polled_at pgtype.Timestamptz
+	err := row.Scan(&last_polled_at)
+	return last_polled_at, err
+}
+
+const getTriggerWithWorkflow = `-- name: GetTriggerWithWorkflow :one
+SELECT t.id::text, t.workflow_id::text, t.type, t.config, t.created_at, w.is_enabled
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+WHERE t.id = $1
+`
+
+type GetTriggerWithWorkflowRow struct {
 	ID         string             `json:"id"`
 	WorkflowID string             `json:"workflow_id"`
 	Type       string             `json:"type"`
 	Config     []byte             `json:"config"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	IsEnabled  bool               `json:"is_enabled"`
+}
+
+func (q *Queries) GetTriggerWithWorkflow(ctx context.Context, id string) (GetTriggerWithWorkflowRow, error) {
+	row := q.db.QueryRow(ctx, getTriggerWithWorkflow, id)
+	var i GetTriggerWithWorkflowRow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.Type,
+		&i.Config,
+		&i.CreatedAt,
+		&i.IsEnabled,
+	)
+	return i, err
+}
+
+const listEnabledTriggersByType = `-- name: ListEnabledTriggersByType :many
+SELECT t.id::text, t.workflow_id::text, w.org_id::text, t.config
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+WHERE t.type = $1 AND w.is_enabled
+ORDER BY t.created_at
+`
+
+type ListEnabledTriggersByTypeRow struct {
+	ID         string `json:"id"`
+	WorkflowID string `json:"workflow_id"`
+	OrgID      string `json:"org_id"`
+	Config     []byte `json:"config"`
+}
+
+func (q *Queries) ListEnabledTriggersByType(ctx context.Context, type_ string) ([]ListEnabledTriggersByTypeRow, error) {
+	rows, err := q.db.Query(ctx, listEnabledTriggersByType, type_)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnabledTriggersByTypeRow
+	for rows.Next() {
+		var i ListEnabledTriggersByTypeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.OrgID,
+			&i.Config,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTriggers = `-- name: ListScheduledTriggers :many
+SELECT t.id::text, t.workflow_id::text, t.type, t.config, t.created_at, t.last_fired_at
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+WHERE t.type IN ('cron', 'interval', 'at', 'poll') AND w.is_enabled
+ORDER BY t.created_at
+`
+
+type ListScheduledTriggersRow struct {
+	ID          string             `json:"id"`
+	WorkflowID  string             `json:"workflow_id"`
+	Type        string             `json:"type"`
+	Config      []byte             `json:"config"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastFiredAt pgtype.Timestamptz `json:"last_fired_at"`
+}
+
+func (q *Queries) ListScheduledTriggers(ctx context.Context) ([]ListScheduledTriggersRow, error) {
+	rows, err := q.db.Query(ctx, listScheduledTriggers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListScheduledTriggersRow
+	for rows.Next() {
+		var i ListScheduledTriggersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.Type,
+			&i.Config,
+			&i.CreatedAt,
+			&i.LastFiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTriggersByWorkflow = `-- name: ListTriggersByWorkflow :many
+SELECT id::text, workflow_id::text, type, config, created_at, rejected_count, last_rejected_at, last_fired_at
+FROM triggers
+WHERE workflow_id = $1
+ORDER BY created_at
+`
+
+type ListTriggersByWorkflowRow struct {
+	ID             string             `json:"id"`
+	WorkflowID     string             `json:"workflow_id"`
+	Type           string             `json:"type"`
+	Config         []byte             `json:"config"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	RejectedCount  int64              `json:"rejected_count"`
+	LastRejectedAt pgtype.Timestamptz `json:"last_rejected_at"`
+	LastFiredAt    pgtype.Timestamptz `json:"last_fired_at"`
 }
 
 func (q *Queries) ListTriggersByWorkflow(ctx context.Context, workflowID string) ([]ListTriggersByWorkflowRow, error) {
@@ -129,6 +266,51 @@ func (q *Queries) ListTriggersByWorkflow(ctx context.Context, workflowID string)
 			&i.Type,
 			&i.Config,
 			&i.CreatedAt,
+			&i.RejectedCount,
+			&i.LastRejectedAt,
+			&i.LastFiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkflowEventTriggers = `-- name: ListWorkflowEventTriggers :many
+SELECT t.id::text, t.workflow_id::text, t.config
+FROM triggers t
+JOIN workflows w ON w.id = t.workflow_id
+JOIN workflows src ON src.id = $1
+WHERE t.type = 'workflow_event'
+  AND t.config->>'workflow_id' = $1::text
+  AND w.is_enabled
+  AND w.org_id = src.org_id
+ORDER BY t.created_at
+`
+
+type ListWorkflowEventTriggersRow struct {
+	ID         string `json:"id"`
+	WorkflowID string `json:"workflow_id"`
+	Config     []byte `json:"config"`
+}
+
+func (q *Queries) ListWorkflowEventTriggers(ctx context.Context, workflowID string) ([]ListWorkflowEventTriggersRow, error) {
+	rows, err := q.db.Query(ctx, listWorkflowEventTriggers, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkflowEventTriggersRow
+	for rows.Next() {
+		var i ListWorkflowEventTriggersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.Config,
 		); err != nil {
 			return nil, err
 		}
@@ -140,6 +322,17 @@ func (q *Queries) ListTriggersByWorkflow(ctx context.Context, workflowID string)
 	return items, nil
 }
 
+const recordTriggerRejection = `-- name: RecordTriggerRejection :exec
+UPDATE triggers
+SET rejected_count = rejected_count + 1, last_rejected_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) RecordTriggerRejection(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, recordTriggerRejection, id)
+	return err
+}
+
 const updateTrigger = `-- name: UpdateTrigger :one
 UPDATE triggers
 SET type = $2, config = $3
@@ -179,3 +372,35 @@ func (q *Queries) UpdateTrigger(ctx context.Context, arg UpdateTriggerParams) (U
 	)
 	return i, err
 }
+
+const updateTriggerLastFiredAt = `-- name: UpdateTriggerLastFiredAt :exec
+UPDATE triggers
+SET last_fired_at = $2
+WHERE id = $1
+`
+
+type UpdateTriggerLastFiredAtParams struct {
+	ID          string             `json:"id"`
+	LastFiredAt pgtype.Timestamptz `json:"last_fired_at"`
+}
+
+func (q *Queries) UpdateTriggerLastFiredAt(ctx context.Context, arg UpdateTriggerLastFiredAtParams) error {
+	_, err := q.db.Exec(ctx, updateTriggerLastFiredAt, arg.ID, arg.LastFiredAt)
+	return err
+}
+
+const updateTriggerLastPolledAt = `-- name: UpdateTriggerLastPolledAt :exec
+UPDATE triggers
+SET last_polled_at = $2
+WHERE id = $1
+`
+
+type UpdateTriggerLastPolledAtParams struct {
+	ID           string             `json:"id"`
+	LastPolledAt pgtype.Timestamptz `json:"last_polled_at"`
+}
+
+func (q *Queries) UpdateTriggerLastPolledAt(ctx context.Context, arg UpdateTriggerLastPolledAtParams) error {
+	_, err := q.db.Exec(ctx, updateTriggerLastPolledAt, arg.ID, arg.LastPolledAt)
+	return err
+}
@@ -14,7 +14,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, password_hash)
 VALUES ($1, $2)
-RETURNING id::text, email, created_at, updated_at
+RETURNING id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -23,10 +23,13 @@ type CreateUserParams struct {
 }
 
 type CreateUserRow struct {
-	ID        string             `json:"id"`
-	Email     string             `json:"email"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID              string             `json:"id"`
+	Email           string             `json:"email"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
@@ -37,22 +40,28 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id::text, email, password_hash, created_at, updated_at
+SELECT id::text, email, password_hash, created_at, updated_at, role, disabled_at, email_verified_at
 FROM users
 WHERE email = $1
 `
 
 type GetUserByEmailRow struct {
-	ID           string             `json:"id"`
-	Email        string             `json:"email"`
-	PasswordHash string             `json:"password_hash"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID              string             `json:"id"`
+	Email           string             `json:"email"`
+	PasswordHash    string             `json:"password_hash"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
 }
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error) {
@@ -64,21 +73,27 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id::text, email, created_at, updated_at
+SELECT id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
 FROM users
 WHERE id = $1
 `
 
 type GetUserByIDRow struct {
-	ID        string             `json:"id"`
-	Email     string             `json:"email"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID              string             `json:"id"`
+	Email           string             `json:"email"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, error) {
@@ -89,6 +104,193 @@ func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, e
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
+
+const listUsers = `-- name: ListUsers :many
+SELECT id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
+FROM users
+ORDER BY created_at
+`
+
+type ListUsersRow struct {
+	ID              string             `json:"id"`
+	Email           string             `json:"email"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
+	rows, err := q.db.Query(ctx, listUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Role,
+			&i.DisabledAt,
+			&i.EmailVerifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setEmailVerified = `-- name: SetEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) SetEmailVerified(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, setEmailVerified, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :one
+UPDATE users
+SET disabled_at = CASE WHEN $1::bool THEN COALESCE(disabled_at, now()) END,
+    updated_at = now()
+WHERE id = $2
+RETURNING id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
+`
+
+type SetUserDisabledParams struct {
+	Disabled bool   `json:"disabled"`
+	ID       string `json:"id"`
+}
+
+type SetUserDisabledRow struct {
+	ID              string             `json:"id"`
+	Email           string             `json:"email"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (SetUserDisabledRow, error) {
+	row := q.db.QueryRow(ctx, setUserDisabled, arg.Disabled, arg.ID)
+	var i SetUserDisabledRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = now()
+WHERE id = $1
+RETURNING id::text, email, created_at, updated_at, role, disabled_at, email_verified_at
+`
+
+type SetUserRoleParams struct {
+	ID   string `json:"id"`
+	Role string `json:"role"`
+}
+
+type SetUserRoleRow struct {
+	ID              string             `json:"id"`
+	Email           string             `json:"email"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	DisabledAt      pgtype.Timestamptz `json:"disabled_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (SetUserRoleRow, error) {
+	row := q.db.QueryRow(ctx, setUserRole, arg.ID, arg.Role)
+	var i SetUserRoleRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserTokensValidAfter = `-- name: SetUserTokensValidAfter :one
+UPDATE users
+SET tokens_valid_after = now()
+WHERE id = $1
+RETURNING tokens_valid_after
+`
+
+func (q *Queries) SetUserTokensValidAfter(ctx context.Context, id string) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, setUserTokensValidAfter, id)
+	var tokens_valid_after pgtype.Timestamptz
+	err := row.Scan(&tokens_valid_after)
+	return tokens_valid_after, err
+}
+
+const updatePasswordHash = `-- name: UpdatePasswordHash :execrows
+UPDATE users
+SET password_hash = $2, updated_at = now()
+WHERE id = $1
+`
+
+type UpdatePasswordHashParams struct {
+	ID           string `json:"id"`
+	PasswordHash string `json:"password_hash"`
+}
+
+func (q *Queries) UpdatePasswordHash(ctx context.Context, arg UpdatePasswordHashParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePasswordHash, arg.ID, arg.PasswordHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upgradePasswordHash = `-- name: UpgradePasswordHash :exec
+UPDATE users
+SET password_hash = $1, updated_at = now()
+WHERE id = $2 AND password_hash = $3
+`
+
+type UpgradePasswordHashParams struct {
+	NewHash string `json:"new_hash"`
+	ID      string `json:"id"`
+	OldHash string `json:"old_hash"`
+}
+
+// Replaces the hash only if it is still old_hash, so a concurrent password change wins.
+func (q *Queries) UpgradePasswordHash(ctx context.Context, arg UpgradePasswordHashParams) error {
+	_, err := q.db.Exec(ctx, upgradePasswordHash, arg.NewHash, arg.ID, arg.OldHash)
+	return err
+}
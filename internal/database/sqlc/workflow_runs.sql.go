@@ -11,9 +11,64 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createChainedWorkflowRun = `-- name: CreateChainedWorkflowRun :one
+INSERT INTO workflow_runs (workflow_id, status, trigger_type, parent_run_id, chain_depth, input)
+VALUES ($1, 'pending', 'workflow_event', $2::uuid, $3, $4)
+RETURNING id::text
+`
+
+type CreateChainedWorkflowRunParams struct {
+	WorkflowID  string `json:"workflow_id"`
+	ParentRunID string `json:"parent_run_id"`
+	ChainDepth  int32  `json:"chain_depth"`
+	Input       []byte `json:"input"`
+}
+
+func (q *Queries) CreateChainedWorkflowRun(ctx context.Context, arg CreateChainedWorkflowRunParams) (string, error) {
+	row := q.db.QueryRow(ctx, createChainedWorkflowRun,
+		arg.WorkflowID,
+		arg.ParentRunID,
+		arg.ChainDepth,
+		arg.Input,
+	)
+	var id string
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createChildWorkflowRun = `-- name: CreateChildWorkflowRun :one
+INSERT INTO workflow_runs (workflow_id, status, trigger_type, started_at, parent_run_id, depth, input, chain_depth)
+VALUES ($1, $2, 'workflow', $3, $4::uuid, $5, $6,
+        COALESCE((SELECT chain_depth FROM workflow_runs WHERE id = $4::uuid), 0))
+RETURNING id::text
+`
+
+type CreateChildWorkflowRunParams struct {
+	WorkflowID  string             `json:"workflow_id"`
+	Status      string             `json:"status"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	ParentRunID string             `json:"parent_run_id"`
+	Depth       int32              `json:"depth"`
+	Input       []byte             `json:"input"`
+}
+
+func (q *Queries) CreateChildWorkflowRun(ctx context.Context, arg CreateChildWorkflowRunParams) (string, error) {
+	row := q.db.QueryRow(ctx, createChildWorkflowRun,
+		arg.WorkflowID,
+		arg.Status,
+		arg.StartedAt,
+		arg.ParentRunID,
+		arg.Depth,
+		arg.Input,
+	)
+	var id string
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createWorkflowRun = `-- name: CreateWorkflowRun :one
-INSERT INTO workflow_runs (workflow_id, status, trigger_type, started_at)
-VALUES ($1, $2, $3, $4)
+INSERT INTO workflow_runs (workflow_id, status, trigger_type, started_at, input)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at
 `
 
@@ -22,6 +77,7 @@ type CreateWorkflowRunParams struct {
 	Status      string             `json:"status"`
 	TriggerType string             `json:"trigger_type"`
 	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	Input       []byte             `json:"input"`
 }
 
 type CreateWorkflowRunRow struct {
@@ -40,6 +96,7 @@ func (q *Queries) CreateWorkflowRun(ctx context.Context, arg CreateWorkflowRunPa
 		arg.Status,
 		arg.TriggerType,
 		arg.StartedAt,
+		arg.Input,
 	)
 	var i CreateWorkflowRunRow
 	err := row.Scan(
@@ -54,8 +111,39 @@ func (q *Queries) CreateWorkflowRun(ctx context.Context, arg CreateWorkflowRunPa
 	return i, err
 }
 
+const getWorkflowRun = `-- name: GetWorkflowRun :one
+SELECT id::text, workflow_id::text, status, parent_run_id, depth, output, chain_depth
+FROM workflow_runs
+WHERE id = $1
+`
+
+type GetWorkflowRunRow struct {
+	ID          string      `json:"id"`
+	WorkflowID  string      `json:"workflow_id"`
+	Status      string      `json:"status"`
+	ParentRunID pgtype.UUID `json:"parent_run_id"`
+	Depth       int32       `json:"depth"`
+	Output      []byte      `json:"output"`
+	ChainDepth  int32       `json:"chain_depth"`
+}
+
+func (q *Queries) GetWorkflowRun(ctx context.Context, id string) (GetWorkflowRunRow, error) {
+	row := q.db.QueryRow(ctx, getWorkflowRun, id)
+	var i GetWorkflowRunRow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.Status,
+		&i.ParentRunID,
+		&i.Depth,
+		&i.Output,
+		&i.ChainDepth,
+	)
+	return i, err
+}
+
 const listPendingWorkflowRuns = `-- name: ListPendingWorkflowRuns :many
-SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at
+SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at, depth, input
 FROM workflow_runs
 WHERE status = 'pending'
 ORDER BY created_at
@@ -70,6 +158,8 @@ type ListPendingWorkflowRunsRow struct {
 	StartedAt   pgtype.Timestamptz `json:"started_at"`
 	FinishedAt  pgtype.Timestamptz `json:"finished_at"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	Depth       int32              `json:"depth"`
+	Input       []byte             `json:"input"`
 }
 
 func (q *Queries) ListPendingWorkflowRuns(ctx context.Context, limit int32) ([]ListPendingWorkflowRunsRow, error) {
@@ -89,6 +179,65 @@ func (q *Queries) ListPendingWorkflowRuns(ctx context.Context, limit int32) ([]L
 			&i.StartedAt,
 			&i.FinishedAt,
 			&i.CreatedAt,
+			&i.Depth,
+			&i.Input,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentWorkflowRuns = `-- name: ListRecentWorkflowRuns :many
+SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at, parent_run_id, depth
+FROM workflow_runs
+WHERE ($1::text IS NULL OR workflow_id::text = $1)
+  AND ($2::text IS NULL OR status = $2)
+ORDER BY created_at DESC
+LIMIT $3
+`
+
+type ListRecentWorkflowRunsParams struct {
+	WorkflowID pgtype.Text `json:"workflow_id"`
+	Status     pgtype.Text `json:"status"`
+	RowLimit   int32       `json:"row_limit"`
+}
+
+type ListRecentWorkflowRunsRow struct {
+	ID          string             `json:"id"`
+	WorkflowID  string             `json:"workflow_id"`
+	Status      string             `json:"status"`
+	TriggerType string             `json:"trigger_type"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	FinishedAt  pgtype.Timestamptz `json:"finished_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ParentRunID pgtype.UUID        `json:"parent_run_id"`
+	Depth       int32              `json:"depth"`
+}
+
+func (q *Queries) ListRecentWorkflowRuns(ctx context.Context, arg ListRecentWorkflowRunsParams) ([]ListRecentWorkflowRunsRow, error) {
+	rows, err := q.db.Query(ctx, listRecentWorkflowRuns, arg.WorkflowID, arg.Status, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecentWorkflowRunsRow
+	for rows.Next() {
+		var i ListRecentWorkflowRunsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.Status,
+			&i.TriggerType,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CreatedAt,
+			&i.ParentRunID,
+			&i.Depth,
 		); err != nil {
 			return nil, err
 		}
@@ -101,7 +250,7 @@ func (q *Queries) ListPendingWorkflowRuns(ctx context.Context, limit int32) ([]L
 }
 
 const listWorkflowRunsByWorkflow = `-- name: ListWorkflowRunsByWorkflow :many
-SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at
+SELECT id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at, parent_run_id, depth
 FROM workflow_runs
 WHERE workflow_id = $1
 ORDER BY created_at DESC
@@ -115,6 +264,8 @@ type ListWorkflowRunsByWorkflowRow struct {
 	StartedAt   pgtype.Timestamptz `json:"started_at"`
 	FinishedAt  pgtype.Timestamptz `json:"finished_at"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ParentRunID pgtype.UUID        `json:"parent_run_id"`
+	Depth       int32              `json:"depth"`
 }
 
 func (q *Queries) ListWorkflowRunsByWorkflow(ctx context.Context, workflowID string) ([]ListWorkflowRunsByWorkflowRow, error) {
@@ -134,6 +285,8 @@ func (q *Queries) ListWorkflowRunsByWorkflow(ctx context.Context, workflowID str
 			&i.StartedAt,
 			&i.FinishedAt,
 			&i.CreatedAt,
+			&i.ParentRunID,
+			&i.Depth,
 		); err != nil {
 			return nil, err
 		}
@@ -179,7 +332,7 @@ func (q *Queries) StartWorkflowRun(ctx context.Context, id string) (StartWorkflo
 
 const updateWorkflowRunStatus = `-- name: UpdateWorkflowRunStatus :one
 UPDATE workflow_runs
-SET status = $2, finished_at = $3
+SET status = $2, finished_at = $3, output = $4
 WHERE id = $1
 RETURNING id::text, workflow_id::text, status, trigger_type, started_at, finished_at, created_at
 `
@@ -188,6 +341,7 @@ type UpdateWorkflowRunStatusParams struct {
 	ID         string             `json:"id"`
 	Status     string             `json:"status"`
 	FinishedAt pgtype.Timestamptz `json:"finished_at"`
+	Output     []byte             `json:"output"`
 }
 
 type UpdateWorkflowRunStatusRow struct {
@@ -201,7 +355,12 @@ type UpdateWorkflowRunStatusRow struct {
 }
 
 func (q *Queries) UpdateWorkflowRunStatus(ctx context.Context, arg UpdateWorkflowRunStatusParams) (UpdateWorkflowRunStatusRow, error) {
-	row := q.db.QueryRow(ctx, updateWorkflowRunStatus, arg.ID, arg.Status, arg.FinishedAt)
+	row := q.db.QueryRow(ctx, updateWorkflowRunStatus,
+		arg.ID,
+		arg.Status,
+		arg.FinishedAt,
+		arg.Output,
+	)
 	var i UpdateWorkflowRunStatusRow
 	err := row.Scan(
 		&i.ID,
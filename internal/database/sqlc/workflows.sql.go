@@ -12,19 +12,21 @@ import (
 )
 
 const createWorkflow = `-- name: CreateWorkflow :one
-INSERT INTO workflows (user_id, name)
-VALUES ($1, $2)
-RETURNING id::text, user_id::text, name, is_enabled, created_at, updated_at
+INSERT INTO workflows (user_id, org_id, name)
+VALUES ($1, $2, $3)
+RETURNING id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled, created_at, updated_at
 `
 
 type CreateWorkflowParams struct {
 	UserID string `json:"user_id"`
+	OrgID  string `json:"org_id"`
 	Name   string `json:"name"`
 }
 
 type CreateWorkflowRow struct {
 	ID        string             `json:"id"`
 	UserID    string             `json:"user_id"`
+	OrgID     string             `json:"org_id"`
 	Name      string             `json:"name"`
 	IsEnabled bool               `json:"is_enabled"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
@@ -32,11 +34,12 @@ type CreateWorkflowRow struct {
 }
 
 func (q *Queries) CreateWorkflow(ctx context.Context, arg CreateWorkflowParams) (CreateWorkflowRow, error) {
-	row := q.db.QueryRow(ctx, createWorkflow, arg.UserID, arg.Name)
+	row := q.db.QueryRow(ctx, createWorkflow, arg.UserID, arg.OrgID, arg.Name)
 	var i CreateWorkflowRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
+		&i.OrgID,
 		&i.Name,
 		&i.IsEnabled,
 		&i.CreatedAt,
@@ -45,28 +48,24 @@ func (q *Queries) CreateWorkflow(ctx context.Context, arg CreateWorkflowParams)
 	return i, err
 }
 
-const deleteWorkflow = `-- name: DeleteWorkflow :one
+const deleteWorkflow = `-- name: DeleteWorkflow :execrows
 DELETE FROM workflows
-WHERE id = $1 AND user_id = $2
-RETURNING id::text
+WHERE id = $1
 `
 
-type DeleteWorkflowParams struct {
-	ID     string `json:"id"`
-	UserID string `json:"user_id"`
-}
-
-func (q *Queries) DeleteWorkflow(ctx context.Context, arg DeleteWorkflowParams) (string, error) {
-	row := q.db.QueryRow(ctx, deleteWorkflow, arg.ID, arg.UserID)
-	var id string
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) DeleteWorkflow(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWorkflow, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getWorkflow = `-- name: GetWorkflow :one
-SELECT id::text, user_id::text, name, is_enabled, created_at, updated_at
-FROM workflows
-WHERE id = $1 AND user_id = $2
+SELECT w.id::text, COALESCE(w.user_id::text, '') AS user_id, w.org_id::text, w.name, w.is_enabled, w.created_at, w.updated_at, m.role
+FROM workflows w
+JOIN organization_members m ON m.org_id = w.org_id
+WHERE w.id = $1 AND m.user_id = $2
 `
 
 type GetWorkflowParams struct {
@@ -77,10 +76,12 @@ type GetWorkflowParams struct {
 type GetWorkflowRow struct {
 	ID        string             `json:"id"`
 	UserID    string             `json:"user_id"`
+	OrgID     string             `json:"org_id"`
 	Name      string             `json:"name"`
 	IsEnabled bool               `json:"is_enabled"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Role      string             `json:"role"`
 }
 
 func (q *Queries) GetWorkflow(ctx context.Context, arg GetWorkflowParams) (GetWorkflowRow, error) {
@@ -89,42 +90,131 @@ func (q *Queries) GetWorkflow(ctx context.Context, arg GetWorkflowParams) (GetWo
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
+		&i.OrgID,
 		&i.Name,
 		&i.IsEnabled,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
-const listWorkflowsByUser = `-- name: ListWorkflowsByUser :many
-SELECT id::text, user_id::text, name, is_enabled, created_at, updated_at
+const getWorkflowByID = `-- name: GetWorkflowByID :one
+SELECT id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled
+FROM workflows
+WHERE id = $1
+`
+
+type GetWorkflowByIDRow struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	OrgID     string `json:"org_id"`
+	Name      string `json:"name"`
+	IsEnabled bool   `json:"is_enabled"`
+}
+
+func (q *Queries) GetWorkflowByID(ctx context.Context, id string) (GetWorkflowByIDRow, error) {
+	row := q.db.QueryRow(ctx, getWorkflowByID, id)
+	var i GetWorkflowByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OrgID,
+		&i.Name,
+		&i.IsEnabled,
+	)
+	return i, err
+}
+
+const getWorkflowSigningSecret = `-- name: GetWorkflowSigningSecret :one
+SELECT signing_secret
+FROM workflows
+WHERE id = $1
+`
+
+func (q *Queries) GetWorkflowSigningSecret(ctx context.Context, id string) (string, error) {
+	row := q.db.QueryRow(ctx, getWorkflowSigningSecret, id)
+	var signing_secret string
+	err := row.Scan(&signing_secret)
+	return signing_secret, err
+}
+
+const listAllWorkflows = `-- name: ListAllWorkflows :many
+SELECT id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled, created_at, updated_at
 FROM workflows
-WHERE user_id = $1
 ORDER BY created_at DESC
 `
 
-type ListWorkflowsByUserRow struct {
+type ListAllWorkflowsRow struct {
+	ID        string             `json:"id"`
+	UserID    string             `json:"user_id"`
+	OrgID     string             `json:"org_id"`
+	Name      string             `json:"name"`
+	IsEnabled bool               `json:"is_enabled"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) ListAllWorkflows(ctx context.Context) ([]ListAllWorkflowsRow, error) {
+	rows, err := q.db.Query(ctx, listAllWorkflows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAllWorkflowsRow
+	for rows.Next() {
+		var i ListAllWorkflowsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.OrgID,
+			&i.Name,
+			&i.IsEnabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkflowsForMember = `-- name: ListWorkflowsForMember :many
+SELECT w.id::text, COALESCE(w.user_id::text, '') AS user_id, w.org_id::text, w.name, w.is_enabled, w.created_at, w.updated_at
+FROM workflows w
+JOIN organization_members m ON m.org_id = w.org_id
+WHERE m.user_id = $1
+ORDER BY w.created_at DESC
+`
+
+type ListWorkflowsForMemberRow struct {
 	ID        string             `json:"id"`
 	UserID    string             `json:"user_id"`
+	OrgID     string             `json:"org_id"`
 	Name      string             `json:"name"`
 	IsEnabled bool               `json:"is_enabled"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
-func (q *Queries) ListWorkflowsByUser(ctx context.Context, userID string) ([]ListWorkflowsByUserRow, error) {
-	rows, err := q.db.Query(ctx, listWorkflowsByUser, userID)
+func (q *Queries) ListWorkflowsForMember(ctx context.Context, userID string) ([]ListWorkflowsForMemberRow, error) {
+	rows, err := q.db.Query(ctx, listWorkflowsForMember, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListWorkflowsByUserRow
+	var items []ListWorkflowsForMemberRow
 	for rows.Next() {
-		var i ListWorkflowsByUserRow
+		var i ListWorkflowsForMemberRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
+			&i.OrgID,
 			&i.Name,
 			&i.IsEnabled,
 			&i.CreatedAt,
@@ -140,23 +230,37 @@ func (q *Queries) ListWorkflowsByUser(ctx context.Context, userID string) ([]Lis
 	return items, nil
 }
 
+const rotateWorkflowSigningSecret = `-- name: RotateWorkflowSigningSecret :one
+UPDATE workflows
+SET signing_secret = encode(gen_random_bytes(32), 'hex'), updated_at = now()
+WHERE id = $1
+RETURNING signing_secret
+`
+
+func (q *Queries) RotateWorkflowSigningSecret(ctx context.Context, id string) (string, error) {
+	row := q.db.QueryRow(ctx, rotateWorkflowSigningSecret, id)
+	var signing_secret string
+	err := row.Scan(&signing_secret)
+	return signing_secret, err
+}
+
 const updateWorkflow = `-- name: UpdateWorkflow :one
 UPDATE workflows
 SET name = $2, is_enabled = $3, updated_at = now()
-WHERE id = $1 AND user_id = $4
-RETURNING id::text, user_id::text, name, is_enabled, created_at, updated_at
+WHERE id = $1
+RETURNING id::text, COALESCE(user_id::text, '') AS user_id, org_id::text, name, is_enabled, created_at, updated_at
 `
 
 type UpdateWorkflowParams struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
 	IsEnabled bool   `json:"is_enabled"`
-	UserID    string `json:"user_id"`
 }
 
 type UpdateWorkflowRow struct {
 	ID        string             `json:"id"`
 	UserID    string             `json:"user_id"`
+	OrgID     string             `json:"org_id"`
 	Name      string             `json:"name"`
 	IsEnabled bool               `json:"is_enabled"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
@@ -164,16 +268,12 @@ type UpdateWorkflowRow struct {
 }
 
 func (q *Queries) UpdateWorkflow(ctx context.Context, arg UpdateWorkflowParams) (UpdateWorkflowRow, error) {
-	row := q.db.QueryRow(ctx, updateWorkflow,
-		arg.ID,
-		arg.Name,
-		arg.IsEnabled,
-		arg.UserID,
-	)
+	row := q.db.QueryRow(ctx, updateWorkflow, arg.ID, arg.Name, arg.IsEnabled)
 	var i UpdateWorkflowRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
+		&i.OrgID,
 		&i.Name,
 		&i.IsEnabled,
 		&i.CreatedAt,
@@ -0,0 +1,246 @@
+// Package email receives mail for "email" triggers: a small SMTP listener and a MIME parser that
+// turns a message into a run input. It also sends the mail the API writes itself, through a
+// pluggable Sender.
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPartDepth bounds nested multiparts so a hostile message can't recurse forever.
+const maxPartDepth = 10
+
+// Message is a parsed email, as handed to a run.
+type Message struct {
+	MessageID   string            `json:"message_id,omitempty"`
+	Subject     string            `json:"subject"`
+	From        *Address          `json:"from,omitempty"`
+	To          []Address         `json:"to,omitempty"`
+	Cc          []Address         `json:"cc,omitempty"`
+	ReplyTo     []Address         `json:"reply_to,omitempty"`
+	Date        *time.Time        `json:"date,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Attachments []Attachment      `json:"attachments"`
+	Headers     map[string]string `json:"headers"`
+}
+
+// Address is a mailbox with an optional display name.
+type Address struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+// Attachment is a non-body part of a message; ContentBase64 holds its decoded bytes.
+type Attachment struct {
+	Filename      string `json:"filename,omitempty"`
+	ContentType   string `json:"content_type"`
+	ContentID     string `json:"content_id,omitempty"`
+	Inline        bool   `json:"inline,omitempty"`
+	Size          int    `json:"size"`
+	ContentBase64 string `json:"content_base64"`
+}
+
+var headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse decodes a raw RFC 5322 message. The first text/plain and text/html parts that aren't
+// attachments become Text and HTML; every other leaf part is an attachment.
+func Parse(raw []byte) (Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Message{}, fmt.Errorf("email: %w", err)
+	}
+
+	msg := Message{
+		MessageID:   strings.Trim(m.Header.Get("Message-Id"), "<> "),
+		Subject:     decodeHeader(m.Header.Get("Subject")),
+		Attachments: []Attachment{},
+		Headers:     make(map[string]string, len(m.Header)),
+	}
+	for name, vals := range m.Header {
+		decoded := make([]string, len(vals))
+		for i, v := range vals {
+			decoded[i] = decodeHeader(v)
+		}
+		msg.Headers[name] = strings.Join(decoded, ", ")
+	}
+	if from := parseAddresses(m.Header, "From"); len(from) > 0 {
+		msg.From = &from[0]
+	}
+	msg.To = parseAddresses(m.Header, "To")
+	msg.Cc = parseAddresses(m.Header, "Cc")
+	msg.ReplyTo = parseAddresses(m.Header, "Reply-To")
+	if date, err := m.Header.Date(); err == nil {
+		date = date.UTC()
+		msg.Date = &date
+	}
+
+	if err := msg.addPart(mailHeader(m.Header), m.Body, 0); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+// partHeader is the subset of header access shared by mail.Header and multipart parts.
+type partHeader interface {
+	Get(key string) string
+}
+
+type mailHeader mail.Header
+
+func (h mailHeader) Get(key string) string { return mail.Header(h).Get(key) }
+
+func (msg *Message) addPart(h partHeader, body io.Reader, depth int) error {
+	if depth > maxPartDepth {
+		return errors.New("email: message nests too deeply")
+	}
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return errors.New("email: multipart message without boundary")
+		}
+		mr := multipart.NewReader(body, boundary)
+		for {
+			// NextRawPart leaves the transfer encoding to decode, which handles base64 too.
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("email: %w", err)
+			}
+			if err := msg.addPart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decode(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	isAttachment := disposition == "attachment" || filename != ""
+
+	switch {
+	case mediaType == "text/plain" && !isAttachment && msg.Text == "":
+		msg.Text = toUTF8(data, params["charset"])
+	case mediaType == "text/html" && !isAttachment && msg.HTML == "":
+		msg.HTML = toUTF8(data, params["charset"])
+	default:
+		msg.Attachments = append(msg.Attachments, Attachment{
+			Filename:      filename,
+			ContentType:   mediaType,
+			ContentID:     strings.Trim(h.Get("Content-Id"), "<> "),
+			Inline:        disposition == "inline",
+			Size:          len(data),
+			ContentBase64: base64.StdEncoding.EncodeToString(data),
+		})
+	}
+	return nil
+}
+
+func decode(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineStripper drops CR, LF and spaces, which base64 bodies wrap with but the decoder rejects.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+func parseAddresses(h mail.Header, key string) []Address {
+	list, err := (&mail.AddressParser{WordDecoder: headerDecoder}).ParseList(h.Get(key))
+	if err != nil {
+		return nil
+	}
+	out := make([]Address, 0, len(list))
+	for _, a := range list {
+		out = append(out, Address{Name: a.Name, Address: a.Address})
+	}
+	return out
+}
+
+func decodeHeader(v string) string {
+	decoded, err := headerDecoder.DecodeHeader(v)
+	if err != nil {
+		return v
+	}
+	return decoded
+}
+
+// toUTF8 converts a text body to UTF-8. Latin-1 and Windows-1252 are common in older mailers;
+// other charsets are assumed to be UTF-8 already and invalid bytes are replaced.
+func toUTF8(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		return latin1(data)
+	}
+	return strings.ToValidUTF8(string(data), string(utf8.RuneError))
+}
+
+func latin1(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "us-ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(latin1(data)), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestParseMultipart(t *testing.T) {
+	raw := strings.ReplaceAll(`From: =?UTF-8?Q?Jos=C3=A9?= <jose@example.com>
+To: a@mail.example.com, "B" <b@mail.example.com>
+Subject: =?UTF-8?B?SMOpbGxv?= world
+Message-ID: <abc@example.com>
+Date: Mon, 19 Oct 2026 10:00:00 +0200
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Caf=C3=A9 =
+time
+--inner
+Content-Type: text/html; charset=iso-8859-1
+
+<p>Caf`+"\xe9"+`</p>
+--inner--
+--outer
+Content-Type: application/pdf; name="report.pdf"
+Content-Disposition: attachment; filename="report.pdf"
+Content-Transfer-Encoding: base64
+
+aGVs
+bG8=
+--outer--
+`, "\n", "\r\n")
+
+	msg, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if msg.Subject != "Héllo world" || msg.MessageID != "abc@example.com" {
+		t.Fatalf("unexpected headers: subject %q id %q", msg.Subject, msg.MessageID)
+	}
+	if msg.From == nil || msg.From.Name != "José" || msg.From.Address != "jose@example.com" {
+		t.Fatalf("unexpected from %+v", msg.From)
+	}
+	if len(msg.To) != 2 || msg.To[1].Name != "B" {
+		t.Fatalf("unexpected to %+v", msg.To)
+	}
+	if msg.Date == nil || msg.Date.Hour() != 8 {
+		t.Fatalf("expected date in UTC, got %v", msg.Date)
+	}
+	if strings.TrimSpace(msg.Text) != "Café time" {
+		t.Fatalf("unexpected text %q", msg.Text)
+	}
+	if strings.TrimSpace(msg.HTML) != "<p>Café</p>" {
+		t.Fatalf("unexpected html %q", msg.HTML)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("expected one attachment, got %+v", msg.Attachments)
+	}
+	att := msg.Attachments[0]
+	if att.Filename != "report.pdf" || att.ContentType != "application/pdf" || att.Size != 5 ||
+		att.ContentBase64 != base64.StdEncoding.EncodeToString([]byte("hello")) {
+		t.Fatalf("unexpected attachment %+v", att)
+	}
+}
+
+func TestParsePlain(t *testing.T) {
+	msg, err := Parse([]byte("Subject: hi\r\n\r\njust text\r\n"))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if msg.Text != "just text\r\n" || msg.HTML != "" || len(msg.Attachments) != 0 || msg.Headers["Subject"] != "hi" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	if _, err := Parse([]byte("not a message")); err == nil {
+		t.Fatalf("expected error for a message without headers")
+	}
+	if _, err := Parse([]byte("Content-Type: multipart/mixed\r\n\r\nbody")); err == nil {
+		t.Fatalf("expected error for a multipart without boundary")
+	}
+}
@@ -0,0 +1,171 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/groovypotato/PotaFlow/internal/workflows"
+	"github.com/rs/zerolog/log"
+)
+
+// Config is the config of an "email" trigger. AllowedSenders, when set, lists glob patterns
+// (e.g. "*@example.com") the envelope sender must match; mail from anyone else is dropped.
+// The envelope sender is whatever the client claims, so this filters noise but is no access
+// control: anyone who knows the trigger's address can send as an allowed sender.
+type Config struct {
+	AllowedSenders []string `json:"allowed_senders,omitempty"`
+}
+
+// ParseConfig decodes and validates an email trigger config.
+func ParseConfig(raw []byte) (Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return Config{}, fmt.Errorf("invalid email config: %w", err)
+		}
+	}
+	for i, p := range cfg.AllowedSenders {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			return Config{}, fmt.Errorf("invalid email config: allowed_senders[%d] is not a valid pattern", i)
+		}
+		cfg.AllowedSenders[i] = p
+	}
+	return cfg, nil
+}
+
+// Allows reports whether mail from the envelope sender may fire the trigger.
+func (c Config) Allows(sender string) bool {
+	if len(c.AllowedSenders) == 0 {
+		return true
+	}
+	sender = strings.ToLower(sender)
+	for _, p := range c.AllowedSenders {
+		if ok, _ := path.Match(p, sender); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TriggerManager is the part of the workflow service the Receiver needs.
+type TriggerManager interface {
+	EmailTrigger(ctx context.Context, triggerID string) (workflows.Trigger, error)
+	EnqueueTriggeredRun(ctx context.Context, trigger workflows.Trigger, input []byte) (workflows.WorkflowRun, error)
+}
+
+// Receiver is the Backend that routes mail for <trigger_id>@<domain> to email triggers.
+type Receiver struct {
+	triggers TriggerManager
+	domain   string
+}
+
+// NewReceiver builds a Receiver accepting mail for domain; an empty domain accepts any.
+func NewReceiver(triggers TriggerManager, domain string) *Receiver {
+	return &Receiver{triggers: triggers, domain: strings.ToLower(domain)}
+}
+
+// runInput is the input of a run fired by mail: the parsed message plus who sent it to which trigger.
+type runInput struct {
+	TriggerID string        `json:"trigger_id"`
+	Envelope  envelopeInput `json:"envelope"`
+	Message
+}
+
+type envelopeInput struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Recipient accepts addresses whose local part is the ID of an email trigger of an enabled workflow.
+func (r *Receiver) Recipient(ctx context.Context, addr string) error {
+	_, err := r.trigger(ctx, addr)
+	return err
+}
+
+// Deliver parses the message once and enqueues a run for every recipient trigger whose
+// allowed_senders admit the envelope sender. Every recipient is resolved before any run is
+// enqueued. An error makes the sender retry the whole message, so it is only returned while
+// nothing has been enqueued; later enqueue failures are logged and that recipient is skipped.
+func (r *Receiver) Deliver(ctx context.Context, env Envelope) error {
+	msg, err := Parse(env.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMessageRejected, err)
+	}
+
+	type delivery struct {
+		trigger workflows.Trigger
+		input   []byte
+	}
+	var deliveries []delivery
+	fired := make(map[string]bool, len(env.To))
+	for _, rcpt := range env.To {
+		trigger, err := r.trigger(ctx, rcpt)
+		if errors.Is(err, ErrUnknownRecipient) {
+			// Removed or disabled since RCPT; nothing to do for this one.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if fired[trigger.ID] {
+			continue
+		}
+		fired[trigger.ID] = true
+
+		cfg, err := ParseConfig(trigger.Config)
+		if err != nil {
+			log.Warn().Err(err).Str("trigger_id", trigger.ID).Msg("skipping email trigger")
+			continue
+		}
+		if !cfg.Allows(env.From) {
+			log.Info().Str("trigger_id", trigger.ID).Str("from", env.From).Msg("email trigger ignored mail from a sender not in allowed_senders")
+			continue
+		}
+
+		input, err := json.Marshal(runInput{
+			TriggerID: trigger.ID,
+			Envelope:  envelopeInput{From: env.From, To: rcpt},
+			Message:   msg,
+		})
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, delivery{trigger: trigger, input: input})
+	}
+
+	for i, d := range deliveries {
+		run, err := r.triggers.EnqueueTriggeredRun(ctx, d.trigger, d.input)
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			log.Error().Err(err).Str("trigger_id", d.trigger.ID).Str("from", env.From).Msg("failed to enqueue email trigger run; dropping it")
+			continue
+		}
+		log.Info().Str("trigger_id", d.trigger.ID).Str("run_id", run.ID).Str("from", env.From).Msg("email trigger fired")
+	}
+	return nil
+}
+
+// trigger resolves a recipient address to its trigger, returning ErrUnknownRecipient for
+// addresses outside the domain, unknown triggers and triggers of disabled workflows.
+func (r *Receiver) trigger(ctx context.Context, addr string) (workflows.Trigger, error) {
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return workflows.Trigger{}, ErrUnknownRecipient
+	}
+	local, domain := addr[:at], strings.ToLower(addr[at+1:])
+	if r.domain != "" && domain != r.domain {
+		return workflows.Trigger{}, ErrUnknownRecipient
+	}
+	trigger, err := r.triggers.EmailTrigger(ctx, strings.ToLower(local))
+	if errors.Is(err, workflows.ErrTriggerNotFound) || errors.Is(err, workflows.ErrWorkflowDisabled) {
+		return workflows.Trigger{}, ErrUnknownRecipient
+	}
+	return trigger, err
+}
@@ -0,0 +1,160 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/groovypotato/PotaFlow/internal/workflows"
+)
+
+const (
+	openID       = "6f1c2a9e-0d4b-4a57-9b8e-1c2d3e4f5a6b"
+	restrictedID = "7a2d3b0f-1e5c-4b68-8c9f-2d3e4f5a6b7c"
+	disabledID   = "8b3e4c1a-2f6d-4c79-9d0a-3e4f5a6b7c8d"
+)
+
+type fakeTriggers struct {
+	triggers map[string]workflows.Trigger
+	runs     map[string][]byte
+	failing  map[string]bool
+}
+
+func (f *fakeTriggers) EmailTrigger(ctx context.Context, triggerID string) (workflows.Trigger, error) {
+	if triggerID == disabledID {
+		return workflows.Trigger{}, workflows.ErrWorkflowDisabled
+	}
+	tr, ok := f.triggers[triggerID]
+	if !ok {
+		return workflows.Trigger{}, workflows.ErrTriggerNotFound
+	}
+	return tr, nil
+}
+
+func (f *fakeTriggers) EnqueueTriggeredRun(ctx context.Context, trigger workflows.Trigger, input []byte) (workflows.WorkflowRun, error) {
+	if f.failing[trigger.ID] {
+		return workflows.WorkflowRun{}, errors.New("db down")
+	}
+	f.runs[trigger.ID] = input
+	return workflows.WorkflowRun{ID: "run-" + trigger.ID}, nil
+}
+
+func newFakeTriggers() *fakeTriggers {
+	return &fakeTriggers{
+		triggers: map[string]workflows.Trigger{
+			openID:       {ID: openID, WorkflowID: "wf-1", Type: "email"},
+			restrictedID: {ID: restrictedID, WorkflowID: "wf-2", Type: "email", Config: []byte(`{"allowed_senders":["*@Example.com"]}`)},
+		},
+		runs: make(map[string][]byte),
+	}
+}
+
+func TestReceiverRecipient(t *testing.T) {
+	r := NewReceiver(newFakeTriggers(), "Mail.Example.com")
+	ctx := context.Background()
+
+	if err := r.Recipient(ctx, openID+"@mail.example.COM"); err != nil {
+		t.Fatalf("expected trigger address to be accepted, got %v", err)
+	}
+	for _, addr := range []string{
+		openID + "@other.example.com",
+		"unknown@mail.example.com",
+		disabledID + "@mail.example.com",
+		"no-at-sign",
+	} {
+		if err := r.Recipient(ctx, addr); !errors.Is(err, ErrUnknownRecipient) {
+			t.Fatalf("%s: expected ErrUnknownRecipient, got %v", addr, err)
+		}
+	}
+
+	if err := NewReceiver(newFakeTriggers(), "").Recipient(ctx, openID+"@anything.test"); err != nil {
+		t.Fatalf("expected any domain to be accepted without SMTP_DOMAIN, got %v", err)
+	}
+}
+
+func TestReceiverDeliver(t *testing.T) {
+	ft := newFakeTriggers()
+	r := NewReceiver(ft, "mail.example.com")
+	ctx := context.Background()
+	env := Envelope{
+		From: "someone@elsewhere.org",
+		To:   []string{openID + "@mail.example.com", restrictedID + "@mail.example.com", openID + "@MAIL.example.com"},
+		Data: []byte("Subject: Order 42\r\nFrom: someone@elsewhere.org\r\n\r\nplease ship\r\n"),
+	}
+	if err := r.Deliver(ctx, env); err != nil {
+		t.Fatalf("Deliver error: %v", err)
+	}
+	if len(ft.runs) != 1 {
+		t.Fatalf("expected only the unrestricted trigger to fire once, got %v", ft.runs)
+	}
+	var input struct {
+		TriggerID string `json:"trigger_id"`
+		Subject   string `json:"subject"`
+		Text      string `json:"text"`
+		Envelope  struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"envelope"`
+	}
+	if err := json.Unmarshal(ft.runs[openID], &input); err != nil {
+		t.Fatalf("invalid input: %v", err)
+	}
+	if input.TriggerID != openID || input.Subject != "Order 42" || input.Text != "please ship\r\n" ||
+		input.Envelope.From != "someone@elsewhere.org" || input.Envelope.To != openID+"@mail.example.com" {
+		t.Fatalf("unexpected input %+v", input)
+	}
+
+	env.From = "Boss@example.com"
+	if err := r.Deliver(ctx, env); err != nil {
+		t.Fatalf("Deliver error: %v", err)
+	}
+	if _, ok := ft.runs[restrictedID]; !ok {
+		t.Fatalf("expected an allowed sender to fire the restricted trigger")
+	}
+
+	env.Data = []byte("garbage")
+	if err := r.Deliver(ctx, env); !errors.Is(err, ErrMessageRejected) {
+		t.Fatalf("expected ErrMessageRejected for an unparseable message, got %v", err)
+	}
+}
+
+func TestReceiverDeliverEnqueueFailure(t *testing.T) {
+	ft := newFakeTriggers()
+	ft.failing = map[string]bool{restrictedID: true}
+	r := NewReceiver(ft, "")
+	ctx := context.Background()
+	env := Envelope{
+		From: "boss@example.com",
+		To:   []string{openID + "@mail.example.com", restrictedID + "@mail.example.com"},
+		Data: []byte("Subject: hi\r\n\r\nbody\r\n"),
+	}
+
+	// A retry would enqueue the first recipient's run again, so the failure is only logged.
+	if err := r.Deliver(ctx, env); err != nil {
+		t.Fatalf("expected a failure after the first run to be dropped, got %v", err)
+	}
+	if _, ok := ft.runs[openID]; !ok || len(ft.runs) != 1 {
+		t.Fatalf("expected only the first recipient's run, got %v", ft.runs)
+	}
+
+	// Nothing enqueued yet: the sender is asked to retry.
+	ft.runs = make(map[string][]byte)
+	env.To = []string{restrictedID + "@mail.example.com", openID + "@mail.example.com"}
+	if err := r.Deliver(ctx, env); err == nil || len(ft.runs) != 0 {
+		t.Fatalf("expected an error and no runs when the first enqueue fails, got %v and %v", err, ft.runs)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(nil)
+	if err != nil || !cfg.Allows("anyone@anywhere") {
+		t.Fatalf("expected an empty config to allow anyone, got %+v, %v", cfg, err)
+	}
+	if _, err := ParseConfig([]byte(`{"allowed_senders":["[bad"]}`)); err == nil {
+		t.Fatalf("expected error for an invalid pattern")
+	}
+	if _, err := ParseConfig([]byte(`{"allowed_senders":"x"}`)); err == nil {
+		t.Fatalf("expected error for a non-array allowed_senders")
+	}
+}
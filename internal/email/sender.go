@@ -0,0 +1,123 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Outgoing is a plain-text message the application sends, such as a password reset.
+type Outgoing struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Sender delivers outgoing mail.
+type Sender interface {
+	Send(ctx context.Context, msg Outgoing) error
+}
+
+// ErrInvalidHeader rejects a recipient or subject containing a line break.
+var ErrInvalidHeader = errors.New("invalid header value")
+
+// LogSender logs messages instead of sending them, for development.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Outgoing) error {
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("text", msg.Text).Msg("outgoing email")
+	return nil
+}
+
+// FileSender writes every message as an .eml file into Dir, for development.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func (s FileSender) Send(ctx context.Context, msg Outgoing) error {
+	now := time.Now()
+	raw, err := formatMessage(s.From, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(s.Dir, name), raw, 0o600)
+}
+
+// SMTPSender relays messages through an SMTP server, authenticating with PLAIN when Username is
+// set. net/smtp only allows PLAIN over TLS or to localhost.
+type SMTPSender struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (s SMTPSender) Send(ctx context.Context, msg Outgoing) error {
+	raw, err := formatMessage(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	var a smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		a = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	envelopeFrom := s.From
+	if addr, err := mail.ParseAddress(s.From); err == nil {
+		envelopeFrom = addr.Address
+	}
+	return smtp.SendMail(s.Addr, a, envelopeFrom, []string{msg.To}, raw)
+}
+
+// formatMessage renders msg as a quoted-printable UTF-8 text message.
+func formatMessage(from string, msg Outgoing, date time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.Text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	s := FileSender{Dir: dir, From: "PotaFlow <no-reply@example.com>"}
+	msg := Outgoing{To: "a@example.com", Subject: "Réinitialiser", Text: "Hello\nhttps://example.com/reset?token=abc=def"}
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one message file, got %v", files)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	parsed, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if parsed.Subject != msg.Subject || parsed.Text != "Hello\r\nhttps://example.com/reset?token=abc=def" {
+		t.Fatalf("unexpected message %q %q", parsed.Subject, parsed.Text)
+	}
+	if parsed.From == nil || parsed.From.Address != "no-reply@example.com" || len(parsed.To) != 1 || parsed.To[0].Address != "a@example.com" {
+		t.Fatalf("unexpected addresses %+v %+v", parsed.From, parsed.To)
+	}
+}
+
+func TestFormatMessageRejectsHeaderInjection(t *testing.T) {
+	for _, msg := range []Outgoing{
+		{To: "a@example.com\r\nBcc: b@example.com", Subject: "hi"},
+		{To: "a@example.com", Subject: "hi\nBcc: b@example.com"},
+	} {
+		if err := (FileSender{Dir: t.TempDir()}).Send(context.Background(), msg); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%+v: expected ErrInvalidHeader, got %v", msg, err)
+		}
+	}
+}
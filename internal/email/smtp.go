@@ -0,0 +1,369 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultMaxMessageBytes caps a message when Server.MaxMessageBytes is unset.
+	DefaultMaxMessageBytes = 10 << 20
+	// DefaultMaxConnections caps concurrent sessions when Server.MaxConnections is unset.
+	DefaultMaxConnections = 100
+	// DefaultSessionTimeout caps a whole session when Server.SessionTimeout is unset.
+	DefaultSessionTimeout = 10 * time.Minute
+	maxRecipients         = 100
+	commandTimeout        = 5 * time.Minute
+	maxLineBytes          = 4096
+)
+
+// Envelope is one accepted message: the SMTP sender, the accepted recipients and the raw data.
+type Envelope struct {
+	RemoteAddr string
+	From       string
+	To         []string
+	Data       []byte
+}
+
+// Backend decides which recipients a Server accepts and delivers accepted messages.
+type Backend interface {
+	// Recipient is called for every RCPT TO; an error rejects that recipient.
+	Recipient(ctx context.Context, addr string) error
+	// Deliver is called once per message; an error tells the client to retry later.
+	Deliver(ctx context.Context, env Envelope) error
+}
+
+var (
+	// ErrUnknownRecipient rejects a recipient permanently (550); other Recipient errors are temporary.
+	ErrUnknownRecipient = errors.New("unknown recipient")
+	// ErrMessageRejected rejects a message permanently (554); other Deliver errors are temporary.
+	ErrMessageRejected = errors.New("message rejected")
+)
+
+// Server is a receive-only SMTP server (RFC 5321): it supports EHLO/HELO, MAIL, RCPT, DATA,
+// RSET, NOOP and QUIT, and never relays. It offers neither STARTTLS nor AUTH, so expose it only
+// behind a relay or firewall you trust.
+//
+// At most MaxConnections sessions are served at once; further clients get a 421 and are
+// disconnected. A session is closed once it has lasted SessionTimeout, however active it is.
+type Server struct {
+	Addr            string
+	Hostname        string
+	MaxMessageBytes int64
+	MaxConnections  int
+	SessionTimeout  time.Duration
+	Backend         Backend
+}
+
+// ListenAndServe listens on s.Addr and serves until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	sessions := make(chan struct{}, s.maxConnections())
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		select {
+		case sessions <- struct{}{}:
+			go func() {
+				defer func() { <-sessions }()
+				s.serveConn(ctx, conn)
+			}()
+		default:
+			log.Warn().Str("remote_addr", conn.RemoteAddr().String()).Msg("smtp: too many connections")
+			_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_, _ = io.WriteString(conn, "421 4.3.2 too many connections, try again later\r\n")
+			conn.Close()
+		}
+	}
+}
+
+func (s *Server) maxConnections() int {
+	if s.MaxConnections <= 0 {
+		return DefaultMaxConnections
+	}
+	return s.MaxConnections
+}
+
+func (s *Server) sessionTimeout() time.Duration {
+	if s.SessionTimeout <= 0 {
+		return DefaultSessionTimeout
+	}
+	return s.SessionTimeout
+}
+
+func (s *Server) maxMessageBytes() int64 {
+	if s.MaxMessageBytes <= 0 {
+		return DefaultMaxMessageBytes
+	}
+	return s.MaxMessageBytes
+}
+
+func (s *Server) hostname() string {
+	if s.Hostname == "" {
+		return "localhost"
+	}
+	return s.Hostname
+}
+
+// session is the state of one SMTP connection.
+type session struct {
+	srv     *Server
+	conn    net.Conn
+	tp      *textproto.Conn
+	expires time.Time
+
+	helo bool
+	from *string
+	to   []string
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	expires := time.Now().Add(s.sessionTimeout())
+	ctx, cancel := context.WithDeadline(ctx, expires)
+	defer cancel()
+	sess := &session{
+		srv:     s,
+		conn:    conn,
+		tp:      textproto.NewConn(conn),
+		expires: expires,
+	}
+	// textproto's reader has no line limit; put one in front of it.
+	sess.tp.Reader = *textproto.NewReader(bufio.NewReader(&lineLimiter{r: conn, max: maxLineBytes}))
+	sess.reply(220, s.hostname()+" PotaFlow ESMTP ready")
+
+	for {
+		_ = conn.SetReadDeadline(sess.deadline())
+		line, err := sess.tp.ReadLine()
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				sess.reply(500, "line too long")
+			}
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			sess.reset()
+			sess.helo = true
+			sess.tp.PrintfLine("250-%s", s.hostname())
+			sess.tp.PrintfLine("250-SIZE %d", s.maxMessageBytes())
+			sess.tp.PrintfLine("250-8BITMIME")
+			sess.reply(250, "ENHANCEDSTATUSCODES")
+		case "HELO":
+			sess.reset()
+			sess.helo = true
+			sess.reply(250, s.hostname())
+		case "MAIL":
+			sess.mail(arg)
+		case "RCPT":
+			sess.rcpt(ctx, arg)
+		case "DATA":
+			if !sess.data(ctx) {
+				return
+			}
+		case "RSET":
+			sess.reset()
+			sess.reply(250, "2.0.0 OK")
+		case "NOOP":
+			sess.reply(250, "2.0.0 OK")
+		case "VRFY":
+			sess.reply(252, "2.5.0 cannot verify, send some mail")
+		case "QUIT":
+			sess.reply(221, "2.0.0 bye")
+			return
+		default:
+			sess.reply(502, "5.5.1 command not implemented")
+		}
+	}
+}
+
+// deadline is when the current command times out: after commandTimeout, or at the end of the
+// session if that comes first.
+func (sess *session) deadline() time.Time {
+	d := time.Now().Add(commandTimeout)
+	if sess.expires.Before(d) {
+		return sess.expires
+	}
+	return d
+}
+
+func (sess *session) reply(code int, msg string) {
+	_ = sess.conn.SetWriteDeadline(sess.deadline())
+	_ = sess.tp.PrintfLine("%d %s", code, msg)
+}
+
+func (sess *session) reset() {
+	sess.from = nil
+	sess.to = nil
+}
+
+func (sess *session) mail(arg string) {
+	if !sess.helo {
+		sess.reply(503, "5.5.1 say EHLO first")
+		return
+	}
+	if sess.from != nil {
+		sess.reply(503, "5.5.1 sender already given")
+		return
+	}
+	addr, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, p := range params {
+		key, val, _ := strings.Cut(p, "=")
+		if strings.EqualFold(key, "SIZE") {
+			if size, err := strconv.ParseInt(val, 10, 64); err == nil && size > sess.srv.maxMessageBytes() {
+				sess.reply(552, "5.3.4 message too big")
+				return
+			}
+		}
+	}
+	sess.from = &addr
+	sess.reply(250, "2.1.0 OK")
+}
+
+func (sess *session) rcpt(ctx context.Context, arg string) {
+	if sess.from == nil {
+		sess.reply(503, "5.5.1 need MAIL first")
+		return
+	}
+	addr, _, ok := parsePath(arg, "TO:")
+	if !ok || addr == "" {
+		sess.reply(501, "5.5.4 syntax: RCPT TO:<address>")
+		return
+	}
+	if len(sess.to) >= maxRecipients {
+		sess.reply(452, "4.5.3 too many recipients")
+		return
+	}
+	if err := sess.srv.Backend.Recipient(ctx, addr); err != nil {
+		if errors.Is(err, ErrUnknownRecipient) {
+			sess.reply(550, "5.1.1 no such mailbox")
+		} else {
+			log.Error().Err(err).Str("rcpt", addr).Msg("smtp recipient lookup failed")
+			sess.reply(451, "4.3.0 try again later")
+		}
+		return
+	}
+	sess.to = append(sess.to, addr)
+	sess.reply(250, "2.1.5 OK")
+}
+
+// data reads a message and hands it to the backend. It returns false when the connection should
+// be closed.
+func (sess *session) data(ctx context.Context) bool {
+	if sess.from == nil || len(sess.to) == 0 {
+		sess.reply(503, "5.5.1 need MAIL and RCPT first")
+		return true
+	}
+	sess.reply(354, "end data with <CR><LF>.<CR><LF>")
+
+	_ = sess.conn.SetReadDeadline(sess.deadline())
+	max := sess.srv.maxMessageBytes()
+	dr := sess.tp.DotReader()
+	data, err := io.ReadAll(io.LimitReader(dr, max+1))
+	if err != nil {
+		return false
+	}
+	if int64(len(data)) > max {
+		// Drain the rest so the connection stays in sync, then refuse.
+		if _, err := io.Copy(io.Discard, dr); err != nil {
+			return false
+		}
+		sess.reset()
+		sess.reply(552, "5.3.4 message too big")
+		return true
+	}
+
+	env := Envelope{RemoteAddr: sess.conn.RemoteAddr().String(), From: *sess.from, To: sess.to, Data: data}
+	sess.reset()
+	if err := sess.srv.Backend.Deliver(ctx, env); err != nil {
+		if errors.Is(err, ErrMessageRejected) {
+			log.Warn().Err(err).Str("from", env.From).Msg("smtp message rejected")
+			sess.reply(554, "5.6.0 message rejected")
+			return true
+		}
+		log.Error().Err(err).Str("from", env.From).Msg("smtp delivery failed")
+		sess.reply(451, "4.3.0 try again later")
+		return true
+	}
+	sess.reply(250, "2.0.0 OK queued")
+	return true
+}
+
+// parsePath parses "FROM:<addr> PARAMS..." or "TO:<addr> ...". MAIL FROM:<> (a bounce) yields "".
+func parsePath(arg, prefix string) (addr string, params []string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+	return rest[1:end], strings.Fields(rest[end+1:]), true
+}
+
+var errLineTooLong = errors.New("smtp: line too long")
+
+// lineLimiter fails reads once a line grows past max bytes. Message data is read through the
+// same limiter, so message lines share the limit (RFC 5321 allows 1000).
+type lineLimiter struct {
+	r    io.Reader
+	max  int
+	line int
+}
+
+func (l *lineLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			l.line = 0
+			continue
+		}
+		l.line++
+		if l.line > l.max {
+			return 0, errLineTooLong
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,239 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	mu         sync.Mutex
+	delivered  []Envelope
+	deliverErr error
+}
+
+func (b *fakeBackend) Recipient(ctx context.Context, addr string) error {
+	switch {
+	case strings.HasPrefix(addr, "ok"):
+		return nil
+	case strings.HasPrefix(addr, "down"):
+		return errors.New("database down")
+	}
+	return ErrUnknownRecipient
+}
+
+func (b *fakeBackend) Deliver(ctx context.Context, env Envelope) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.deliverErr != nil {
+		return b.deliverErr
+	}
+	b.delivered = append(b.delivered, env)
+	return nil
+}
+
+func startServer(t *testing.T, backend Backend, maxBytes int64) string {
+	t.Helper()
+	return serve(t, &Server{Hostname: "mail.example.com", MaxMessageBytes: maxBytes, Backend: backend})
+}
+
+func serve(t *testing.T, srv *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go srv.Serve(ctx, ln)
+	return ln.Addr().String()
+}
+
+func TestServerDelivers(t *testing.T) {
+	backend := &fakeBackend{}
+	addr := startServer(t, backend, 0)
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if err := c.Hello("client.example.com"); err != nil {
+		t.Fatalf("EHLO: %v", err)
+	}
+	if ok, size := c.Extension("SIZE"); !ok || size != "10485760" {
+		t.Fatalf("expected SIZE extension, got %v %q", ok, size)
+	}
+	if err := c.Mail("sender@example.com"); err != nil {
+		t.Fatalf("MAIL: %v", err)
+	}
+	if err := c.Rcpt("ok-1@mail.example.com"); err != nil {
+		t.Fatalf("RCPT: %v", err)
+	}
+	if err := c.Rcpt("nobody@mail.example.com"); err == nil || !strings.HasPrefix(err.Error(), "550") {
+		t.Fatalf("expected 550 for an unknown recipient, got %v", err)
+	}
+	if err := c.Rcpt("down@mail.example.com"); err == nil || !strings.HasPrefix(err.Error(), "451") {
+		t.Fatalf("expected 451 for a failed lookup, got %v", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		t.Fatalf("DATA: %v", err)
+	}
+	if _, err := w.Write([]byte("Subject: hi\r\n\r\n.leading dot\r\nbody\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("end of data: %v", err)
+	}
+	if err := c.Quit(); err != nil {
+		t.Fatalf("QUIT: %v", err)
+	}
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if len(backend.delivered) != 1 {
+		t.Fatalf("expected one delivery, got %d", len(backend.delivered))
+	}
+	env := backend.delivered[0]
+	if env.From != "sender@example.com" || len(env.To) != 1 || env.To[0] != "ok-1@mail.example.com" {
+		t.Fatalf("unexpected envelope %+v", env)
+	}
+	if string(env.Data) != "Subject: hi\n\n.leading dot\nbody\n" {
+		t.Fatalf("unexpected data %q", env.Data)
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	backend := &fakeBackend{}
+	addr := startServer(t, backend, 64)
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	if err := c.Hello("client.example.com"); err != nil {
+		t.Fatalf("EHLO: %v", err)
+	}
+	if err := c.Rcpt("ok@mail.example.com"); err == nil {
+		t.Fatalf("expected RCPT before MAIL to fail")
+	}
+
+	// Too big: the data is drained and refused, and the session carries on.
+	if err := c.Mail("sender@example.com"); err != nil {
+		t.Fatalf("MAIL: %v", err)
+	}
+	if err := c.Rcpt("ok@mail.example.com"); err != nil {
+		t.Fatalf("RCPT: %v", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		t.Fatalf("DATA: %v", err)
+	}
+	w.Write([]byte("Subject: big\r\n\r\n" + strings.Repeat("x", 200) + "\r\n"))
+	if err := w.Close(); err == nil || !strings.HasPrefix(err.Error(), "552") {
+		t.Fatalf("expected 552 for an oversized message, got %v", err)
+	}
+
+	backend.mu.Lock()
+	backend.deliverErr = ErrMessageRejected
+	backend.mu.Unlock()
+	if err := c.Mail("sender@example.com"); err != nil {
+		t.Fatalf("MAIL after refusal: %v", err)
+	}
+	if err := c.Rcpt("ok@mail.example.com"); err != nil {
+		t.Fatalf("RCPT: %v", err)
+	}
+	w, err = c.Data()
+	if err != nil {
+		t.Fatalf("DATA: %v", err)
+	}
+	w.Write([]byte("Subject: small\r\n\r\nhi\r\n"))
+	if err := w.Close(); err == nil || !strings.HasPrefix(err.Error(), "554") {
+		t.Fatalf("expected 554 for a rejected message, got %v", err)
+	}
+	if len(backend.delivered) != 0 {
+		t.Fatalf("expected nothing delivered, got %d", len(backend.delivered))
+	}
+}
+
+func TestServerLimitsConnections(t *testing.T) {
+	addr := serve(t, &Server{Hostname: "mail.example.com", MaxConnections: 1, SessionTimeout: 300 * time.Millisecond, Backend: &fakeBackend{}})
+
+	first, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer first.Close()
+	firstTP := textproto.NewConn(first)
+	if _, _, err := firstTP.ReadResponse(220); err != nil {
+		t.Fatalf("expected a greeting, got %v", err)
+	}
+
+	second, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer second.Close()
+	if _, _, err := textproto.NewConn(second).ReadResponse(220); err == nil || !strings.HasPrefix(err.Error(), "421") {
+		t.Fatalf("expected 421 over the connection limit, got %v", err)
+	}
+
+	// The first session is cut off once its time is up, even while the client keeps talking.
+	_ = first.SetDeadline(time.Now().Add(5 * time.Second))
+	closed := false
+	for i := 0; i < 20 && !closed; i++ {
+		if _, err := firstTP.Cmd("NOOP"); err != nil {
+			closed = true
+			break
+		}
+		if _, _, err := firstTP.ReadResponse(250); err != nil {
+			closed = true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !closed {
+		t.Fatalf("expected the session to end after SessionTimeout")
+	}
+
+	// Its slot is free again, once the server has finished closing it.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		third, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		_ = third.SetDeadline(deadline)
+		_, _, err = textproto.NewConn(third).ReadResponse(220)
+		third.Close()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a greeting once the slot is free, got %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	addr, params, ok := parsePath("FROM:<a@b.c> SIZE=100 BODY=8BITMIME", "FROM:")
+	if !ok || addr != "a@b.c" || len(params) != 2 {
+		t.Fatalf("unexpected parse %q %v %v", addr, params, ok)
+	}
+	if addr, _, ok := parsePath("from: <>", "FROM:"); !ok || addr != "" {
+		t.Fatalf("expected null reverse path, got %q %v", addr, ok)
+	}
+	for _, arg := range []string{"TO:<a@b.c>", "FROM:a@b.c", "FROM:<a@b.c"} {
+		if _, _, ok := parsePath(arg, "FROM:"); ok {
+			t.Fatalf("expected %q to be rejected", arg)
+		}
+	}
+}
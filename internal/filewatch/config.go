@@ -0,0 +1,99 @@
+package filewatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// File events a trigger can fire on.
+const (
+	EventCreate = "create"
+	EventModify = "modify"
+)
+
+const (
+	defaultDebounce        = 500 * time.Millisecond
+	maxDebounce            = time.Minute
+	defaultMaxContentBytes = 1 << 20
+	maxMaxContentBytes     = 10 << 20
+)
+
+// Config is the config of a "file" trigger. Path is a directory relative to the organization's
+// directory, FILE_TRIGGER_ROOT/<organization ID>, on the worker; Pattern is a glob matched against file names in it (default "*").
+//
+// Events for the same file within DebounceMS of each other fire a single run, so a file that is
+// still being written is only picked up once it has settled. With ProcessedDir set (relative to
+// Path), the file is moved there after its run is enqueued.
+type Config struct {
+	Path            string   `json:"path"`
+	Pattern         string   `json:"pattern,omitempty"`
+	Events          []string `json:"events,omitempty"`
+	DebounceMS      int      `json:"debounce_ms,omitempty"`
+	IncludeContent  bool     `json:"include_content,omitempty"`
+	MaxContentBytes int64    `json:"max_content_bytes,omitempty"`
+	ProcessedDir    string   `json:"processed_dir,omitempty"`
+}
+
+// ParseConfig decodes and validates a file trigger config and applies defaults.
+func ParseConfig(raw []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid file config: %w", err)
+	}
+	if cfg.Path == "" {
+		return Config{}, errors.New("invalid file config: path is required")
+	}
+	if !filepath.IsLocal(cfg.Path) {
+		return Config{}, errors.New("invalid file config: path must be relative and stay inside the trigger root")
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = "*"
+	}
+	if _, err := filepath.Match(cfg.Pattern, ""); err != nil {
+		return Config{}, fmt.Errorf("invalid file config: pattern: %w", err)
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = []string{EventCreate, EventModify}
+	}
+	for _, ev := range cfg.Events {
+		if ev != EventCreate && ev != EventModify {
+			return Config{}, fmt.Errorf("invalid file config: unknown event %q", ev)
+		}
+	}
+	if cfg.DebounceMS < 0 || time.Duration(cfg.DebounceMS)*time.Millisecond > maxDebounce {
+		return Config{}, fmt.Errorf("invalid file config: debounce_ms must be between 0 and %d", maxDebounce.Milliseconds())
+	}
+	if cfg.MaxContentBytes < 0 || cfg.MaxContentBytes > maxMaxContentBytes {
+		return Config{}, fmt.Errorf("invalid file config: max_content_bytes must be at most %d", maxMaxContentBytes)
+	}
+	if cfg.MaxContentBytes == 0 {
+		cfg.MaxContentBytes = defaultMaxContentBytes
+	}
+	if cfg.ProcessedDir != "" && (!filepath.IsLocal(cfg.ProcessedDir) || filepath.Clean(cfg.ProcessedDir) == ".") {
+		return Config{}, errors.New("invalid file config: processed_dir must be a subdirectory of path")
+	}
+	return cfg, nil
+}
+
+// Debounce returns how long a file must be quiet before it fires.
+func (c Config) Debounce() time.Duration {
+	if c.DebounceMS == 0 {
+		return defaultDebounce
+	}
+	return time.Duration(c.DebounceMS) * time.Millisecond
+}
+
+// Matches reports whether a file name matches the trigger's pattern.
+func (c Config) Matches(name string) bool {
+	ok, _ := filepath.Match(c.Pattern, name)
+	return ok
+}
+
+// Wants reports whether the trigger fires on event.
+func (c Config) Wants(event string) bool {
+	return slices.Contains(c.Events, event)
+}
@@ -0,0 +1,440 @@
+// Package filewatch fires "file" triggers when files matching a glob are created or modified in
+// a watched directory.
+package filewatch
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/internal/scheduler"
+	"github.com/rs/zerolog/log"
+)
+
+// TriggerType is the trigger type handled by the Watcher.
+const TriggerType = "file"
+
+// LockKey is the advisory lock key watcher replicas compete for, so each file fires once even
+// when several workers share the watched storage.
+const LockKey = scheduler.LockKey + 1
+
+// leaderCheckInterval is how often a follower tries to take over watching.
+const leaderCheckInterval = 5 * time.Second
+
+// Watcher watches the directories of the file triggers of enabled workflows and enqueues a run
+// for each matching file once its events have settled. Only the current Leader watches.
+type Watcher struct {
+	queries        watcherQueries
+	leader         scheduler.Leader
+	root           string
+	reloadInterval time.Duration
+	now            func() time.Time
+
+	fsw      *fsnotify.Watcher
+	leading  bool
+	triggers map[string]*watch
+	dirs     map[string]bool
+	pending  map[pendingKey]*pending
+	due      chan dueFile
+	done     chan struct{}
+}
+
+type watch struct {
+	triggerID  string
+	workflowID string
+	scope      string // the real path of the organization's directory under root
+	dir        string
+	cfg        Config
+}
+
+type pendingKey struct {
+	triggerID string
+	path      string
+}
+
+// pending is a file waiting for its events to settle. gen tells a stale timer from the current one.
+type pending struct {
+	event string
+	gen   int
+	timer *time.Timer
+}
+
+type dueFile struct {
+	key pendingKey
+	gen int
+}
+
+type watcherQueries interface {
+	ListEnabledTriggersByType(ctx context.Context, type_ string) ([]sqlc.ListEnabledTriggersByTypeRow, error)
+	CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error)
+}
+
+// New builds a Watcher for directories under root that also reloads its triggers every
+// reloadInterval, as a fallback for missed change notifications. Each organization's triggers are
+// confined to root/<organization ID>, which an operator creates to enable file triggers for it.
+func New(db sqlc.DBTX, leader scheduler.Leader, root string, reloadInterval time.Duration) (*Watcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("file trigger root: %w", err)
+	}
+	return newWatcher(sqlc.New(db), leader, root, reloadInterval), nil
+}
+
+func newWatcher(queries watcherQueries, leader scheduler.Leader, root string, reloadInterval time.Duration) *Watcher {
+	return &Watcher{
+		queries:        queries,
+		leader:         leader,
+		root:           root,
+		reloadInterval: reloadInterval,
+		now:            time.Now,
+		triggers:       make(map[string]*watch),
+		dirs:           make(map[string]bool),
+		pending:        make(map[pendingKey]*pending),
+		due:            make(chan dueFile, 64),
+		done:           make(chan struct{}),
+	}
+}
+
+// Run watches until ctx is cancelled. A value on changes (see scheduler.Listen) triggers an
+// immediate reload.
+func (w *Watcher) Run(ctx context.Context, changes <-chan struct{}) error {
+	defer w.leader.Release(context.Background())
+	defer close(w.done)
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+	w.fsw = fsw
+	defer w.stop()
+
+	w.checkLeader(ctx)
+	check := time.NewTicker(leaderCheckInterval)
+	defer check.Stop()
+	reload := time.NewTicker(w.reloadInterval)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-check.C:
+			w.checkLeader(ctx)
+		case <-changes:
+			w.reload(ctx)
+		case <-reload.C:
+			w.reload(ctx)
+		case ev, ok := <-fsw.Events:
+			if !ok {
+				return errors.New("file watcher closed")
+			}
+			w.handle(ev)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return errors.New("file watcher closed")
+			}
+			log.Error().Err(err).Msg("file watcher error")
+		case d := <-w.due:
+			w.flush(ctx, d)
+		}
+	}
+}
+
+func (w *Watcher) checkLeader(ctx context.Context) {
+	leader, err := w.leader.Acquire(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("file watcher leader election failed")
+	}
+	switch {
+	case leader && !w.leading:
+		w.leading = true
+		w.reload(ctx)
+	case !leader && w.leading:
+		w.leading = false
+		w.stop()
+	}
+}
+
+func (w *Watcher) reload(ctx context.Context) {
+	if !w.leading {
+		return
+	}
+	if err := w.load(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to load file triggers")
+	}
+}
+
+// load replaces the watched triggers with the current file triggers and adjusts the watched
+// directories. Invalid configs and missing directories are logged and skipped.
+func (w *Watcher) load(ctx context.Context) error {
+	rows, err := w.queries.ListEnabledTriggersByType(ctx, TriggerType)
+	if err != nil {
+		return err
+	}
+	triggers := make(map[string]*watch, len(rows))
+	dirs := make(map[string]bool)
+	for _, row := range rows {
+		cfg, err := ParseConfig(row.Config)
+		if err != nil {
+			log.Warn().Err(err).Str("trigger_id", row.ID).Msg("skipping file trigger")
+			continue
+		}
+		scope, err := resolve(w.root, row.OrgID)
+		if err != nil {
+			log.Warn().Err(err).Str("trigger_id", row.ID).Msg("skipping file trigger: no directory for its organization")
+			continue
+		}
+		dir, err := resolve(scope, cfg.Path)
+		if err != nil {
+			log.Warn().Err(err).Str("trigger_id", row.ID).Msg("skipping file trigger")
+			continue
+		}
+		triggers[row.ID] = &watch{triggerID: row.ID, workflowID: row.WorkflowID, scope: scope, dir: dir, cfg: cfg}
+		dirs[dir] = true
+	}
+
+	for dir := range dirs {
+		if w.dirs[dir] || w.fsw == nil {
+			continue
+		}
+		if err := w.fsw.Add(dir); err != nil {
+			log.Error().Err(err).Str("dir", dir).Msg("failed to watch directory")
+			delete(dirs, dir)
+		}
+	}
+	for dir := range w.dirs {
+		if !dirs[dir] && w.fsw != nil {
+			_ = w.fsw.Remove(dir)
+		}
+	}
+	for key, p := range w.pending {
+		if _, ok := triggers[key.triggerID]; !ok {
+			p.timer.Stop()
+			delete(w.pending, key)
+		}
+	}
+	w.triggers = triggers
+	w.dirs = dirs
+	return nil
+}
+
+// resolve returns the real path of a directory under scope, refusing symlinks that lead out of it.
+func resolve(scope, path string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Join(scope, path))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(scope, dir); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the file trigger root", path)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+	return dir, nil
+}
+
+// stop forgets every trigger and pending file and unwatches all directories.
+func (w *Watcher) stop() {
+	for dir := range w.dirs {
+		if w.fsw != nil {
+			_ = w.fsw.Remove(dir)
+		}
+	}
+	for _, p := range w.pending {
+		p.timer.Stop()
+	}
+	w.triggers = make(map[string]*watch)
+	w.dirs = make(map[string]bool)
+	w.pending = make(map[pendingKey]*pending)
+}
+
+// handle (re)starts the debounce timer of every trigger the event's file matches. A file keeps
+// the first event seen within its debounce window, so a new file that is then written fires as
+// "create".
+func (w *Watcher) handle(ev fsnotify.Event) {
+	var event string
+	switch {
+	case ev.Has(fsnotify.Create):
+		event = EventCreate
+	case ev.Has(fsnotify.Write):
+		event = EventModify
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		w.cancel(ev.Name)
+		return
+	default:
+		return
+	}
+
+	dir, name := filepath.Split(ev.Name)
+	dir = filepath.Clean(dir)
+	for _, t := range w.triggers {
+		if t.dir != dir || !t.cfg.Matches(name) {
+			continue
+		}
+		key := pendingKey{triggerID: t.triggerID, path: ev.Name}
+		p, ok := w.pending[key]
+		if !ok {
+			p = &pending{event: event}
+			w.pending[key] = p
+		} else {
+			p.timer.Stop()
+		}
+		p.gen++
+		d := dueFile{key: key, gen: p.gen}
+		p.timer = time.AfterFunc(t.cfg.Debounce(), func() {
+			select {
+			case w.due <- d:
+			case <-w.done:
+			}
+		})
+	}
+}
+
+func (w *Watcher) cancel(path string) {
+	for key, p := range w.pending {
+		if key.path == path {
+			p.timer.Stop()
+			delete(w.pending, key)
+		}
+	}
+}
+
+// flush fires a settled file, unless it changed again since its timer was set.
+func (w *Watcher) flush(ctx context.Context, d dueFile) {
+	p, ok := w.pending[d.key]
+	if !ok || p.gen != d.gen {
+		return
+	}
+	delete(w.pending, d.key)
+	t, ok := w.triggers[d.key.triggerID]
+	if !ok || !t.cfg.Wants(p.event) {
+		return
+	}
+	if err := w.fire(ctx, t, d.key.path, p.event); err != nil {
+		log.Error().Err(err).Str("trigger_id", t.triggerID).Str("path", d.key.path).Msg("failed to fire file trigger")
+	}
+}
+
+// fire enqueues a run for a file and then moves it to the trigger's processed_dir, if any.
+// Files that are gone or aren't regular files (e.g. symlinks) are ignored.
+func (w *Watcher) fire(ctx context.Context, t *watch, path, event string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	input := map[string]any{
+		"trigger_id":  t.triggerID,
+		"event":       event,
+		"path":        relative(t.scope, path),
+		"name":        info.Name(),
+		"size":        info.Size(),
+		"modified_at": info.ModTime().UTC().Format(time.RFC3339Nano),
+	}
+	if t.cfg.IncludeContent {
+		if info.Size() > t.cfg.MaxContentBytes {
+			input["content_omitted"] = true
+		} else {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if utf8.Valid(content) {
+				input["content"] = string(content)
+			} else {
+				input["content_base64"] = base64.StdEncoding.EncodeToString(content)
+			}
+		}
+	}
+	var target string
+	if t.cfg.ProcessedDir != "" {
+		if target, err = w.processedPath(t, info.Name()); err != nil {
+			return err
+		}
+		input["processed_path"] = relative(t.scope, target)
+	}
+
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	run, err := w.queries.CreateWorkflowRun(ctx, sqlc.CreateWorkflowRunParams{
+		WorkflowID:  t.workflowID,
+		Status:      "pending",
+		TriggerType: TriggerType,
+		Input:       raw,
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Str("trigger_id", t.triggerID).Str("run_id", run.ID).Str("path", path).Str("event", event).Msg("file trigger fired")
+
+	if target != "" {
+		if err := os.Rename(path, target); err != nil {
+			return fmt.Errorf("move to processed_dir: %w", err)
+		}
+	}
+	return nil
+}
+
+// processedPath returns where a fired file is moved, prefixing the name with a timestamp when a
+// file of that name was already processed. The processed_dir is created through an os.Root and
+// resolved again before use, so a symlink can't send files outside the organization's directory.
+func (w *Watcher) processedPath(t *watch, name string) (string, error) {
+	rel, err := filepath.Rel(t.scope, filepath.Join(t.dir, t.cfg.ProcessedDir))
+	if err != nil {
+		return "", err
+	}
+	root, err := os.OpenRoot(t.scope)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+	if err := root.MkdirAll(rel, 0o755); err != nil {
+		return "", fmt.Errorf("processed_dir: %w", err)
+	}
+	dir, err := resolve(t.scope, rel)
+	if err != nil {
+		return "", fmt.Errorf("processed_dir: %w", err)
+	}
+	target := filepath.Join(dir, name)
+	if _, err := os.Lstat(target); err == nil {
+		target = filepath.Join(dir, w.now().UTC().Format("20060102T150405.000000000")+"-"+name)
+	}
+	return target, nil
+}
+
+// relative returns path as the user sees it: relative to the organization's directory.
+func relative(scope, path string) string {
+	rel, err := filepath.Rel(scope, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
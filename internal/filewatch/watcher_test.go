@@ -0,0 +1,283 @@
+package filewatch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+)
+
+type fakeQueries struct {
+	mu       sync.Mutex
+	triggers []sqlc.ListEnabledTriggersByTypeRow
+	runs     []sqlc.CreateWorkflowRunParams
+	fired    chan struct{}
+}
+
+func (f *fakeQueries) ListEnabledTriggersByType(ctx context.Context, type_ string) ([]sqlc.ListEnabledTriggersByTypeRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.triggers, nil
+}
+
+func (f *fakeQueries) CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error) {
+	f.mu.Lock()
+	f.runs = append(f.runs, arg)
+	f.mu.Unlock()
+	if f.fired != nil {
+		f.fired <- struct{}{}
+	}
+	return sqlc.CreateWorkflowRunRow{ID: "run-1", WorkflowID: arg.WorkflowID}, nil
+}
+
+type fakeLeader struct{ leader bool }
+
+func (f *fakeLeader) Acquire(ctx context.Context) (bool, error) { return f.leader, nil }
+func (f *fakeLeader) Release(ctx context.Context)               {}
+
+func runInput(t *testing.T, run sqlc.CreateWorkflowRunParams) map[string]any {
+	t.Helper()
+	var input map[string]any
+	if err := json.Unmarshal(run.Input, &input); err != nil {
+		t.Fatalf("invalid run input %s: %v", run.Input, err)
+	}
+	return input
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`{"path":"inbox"}`))
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if cfg.Pattern != "*" || !cfg.Wants(EventCreate) || !cfg.Wants(EventModify) || cfg.Debounce() != defaultDebounce || cfg.MaxContentBytes != defaultMaxContentBytes {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+
+	cfg, err = ParseConfig([]byte(`{"path":"inbox/csv","pattern":"*.csv","events":["create"],"debounce_ms":50}`))
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if !cfg.Matches("report.csv") || cfg.Matches("report.txt") || cfg.Wants(EventModify) || cfg.Debounce() != 50*time.Millisecond {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	for _, raw := range []string{
+		`{}`,
+		`{"path":"/etc"}`,
+		`{"path":"../outside"}`,
+		`{"path":"inbox","pattern":"[x"}`,
+		`{"path":"inbox","events":["delete"]}`,
+		`{"path":"inbox","debounce_ms":600000}`,
+		`{"path":"inbox","max_content_bytes":104857600}`,
+		`{"path":"inbox","processed_dir":"../done"}`,
+		`{"path":"inbox","processed_dir":"."}`,
+	} {
+		if _, err := ParseConfig([]byte(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestWatcherLoadSkipsInvalidTriggers(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, dir := range []string{"org-1/inbox", "org-2/inbox"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "org-1", "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "org-2", "inbox"), filepath.Join(root, "org-1", "neighbour")); err != nil {
+		t.Fatal(err)
+	}
+	fq := &fakeQueries{triggers: []sqlc.ListEnabledTriggersByTypeRow{
+		{ID: "tr-ok", WorkflowID: "wf-1", OrgID: "org-1", Config: []byte(`{"path":"inbox"}`)},
+		{ID: "tr-missing", WorkflowID: "wf-1", OrgID: "org-1", Config: []byte(`{"path":"nope"}`)},
+		{ID: "tr-escape", WorkflowID: "wf-1", OrgID: "org-1", Config: []byte(`{"path":"escape"}`)},
+		{ID: "tr-neighbour", WorkflowID: "wf-1", OrgID: "org-1", Config: []byte(`{"path":"neighbour"}`)},
+		{ID: "tr-bad", WorkflowID: "wf-1", OrgID: "org-1", Config: []byte(`{"path":"/tmp"}`)},
+		{ID: "tr-no-org-dir", WorkflowID: "wf-3", OrgID: "org-3", Config: []byte(`{"path":"inbox"}`)},
+	}}
+	w := newWatcher(fq, &fakeLeader{leader: true}, root, time.Minute)
+	if err := w.load(context.Background()); err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if len(w.triggers) != 1 || w.triggers["tr-ok"] == nil {
+		t.Fatalf("expected only the valid trigger to load, got %v", w.triggers)
+	}
+	if want := filepath.Join(w.root, "org-1", "inbox"); w.triggers["tr-ok"].dir != want {
+		t.Fatalf("expected the path under the organization's directory %s, got %s", want, w.triggers["tr-ok"].dir)
+	}
+}
+
+func TestWatcherFireIncludesContentAndMovesFile(t *testing.T) {
+	scope := t.TempDir()
+	inbox := filepath.Join(scope, "inbox")
+	if err := os.Mkdir(inbox, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fq := &fakeQueries{}
+	w := newWatcher(fq, &fakeLeader{leader: true}, filepath.Dir(scope), time.Minute)
+	cfg, _ := ParseConfig([]byte(`{"path":"inbox","include_content":true,"processed_dir":"done"}`))
+	tr := &watch{triggerID: "tr-1", workflowID: "wf-1", scope: scope, dir: inbox, cfg: cfg}
+	ctx := context.Background()
+
+	for i, content := range []string{"a,b\n1,2\n", "again"} {
+		path := filepath.Join(inbox, "data.csv")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.fire(ctx, tr, path, EventCreate); err != nil {
+			t.Fatalf("fire error: %v", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected file to be moved out of the inbox")
+		}
+		input := runInput(t, fq.runs[i])
+		if input["path"] != "inbox/data.csv" || input["name"] != "data.csv" || input["content"] != content || input["event"] != EventCreate {
+			t.Fatalf("unexpected run input %v", input)
+		}
+		moved, _ := input["processed_path"].(string)
+		if _, err := os.Stat(filepath.Join(scope, moved)); err != nil {
+			t.Fatalf("expected file at processed_path %q: %v", moved, err)
+		}
+	}
+	if runInput(t, fq.runs[0])["processed_path"] == runInput(t, fq.runs[1])["processed_path"] {
+		t.Fatalf("expected a second file of the same name not to overwrite the first")
+	}
+
+	// Binary content is base64 encoded; large files are announced without content.
+	binary := filepath.Join(inbox, "blob.bin")
+	_ = os.WriteFile(binary, []byte{0xff, 0xfe, 0x00}, 0o644)
+	tr.cfg.ProcessedDir = ""
+	if err := w.fire(ctx, tr, binary, EventModify); err != nil {
+		t.Fatalf("fire error: %v", err)
+	}
+	if input := runInput(t, fq.runs[2]); input["content_base64"] != "//4A" {
+		t.Fatalf("unexpected binary input %v", input)
+	}
+	tr.cfg.MaxContentBytes = 1
+	if err := w.fire(ctx, tr, binary, EventModify); err != nil {
+		t.Fatalf("fire error: %v", err)
+	}
+	if input := runInput(t, fq.runs[3]); input["content_omitted"] != true || input["content_base64"] != nil {
+		t.Fatalf("expected content to be omitted, got %v", input)
+	}
+
+	if err := w.fire(ctx, tr, filepath.Join(inbox, "gone.csv"), EventCreate); err != nil || len(fq.runs) != 4 {
+		t.Fatalf("expected a vanished file to be ignored, err %v", err)
+	}
+}
+
+func TestWatcherProcessedDirStaysInsideOrganization(t *testing.T) {
+	scope := t.TempDir()
+	outside := t.TempDir()
+	inbox := filepath.Join(scope, "inbox")
+	if err := os.Mkdir(inbox, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(inbox, "done")); err != nil {
+		t.Fatal(err)
+	}
+	fq := &fakeQueries{}
+	w := newWatcher(fq, &fakeLeader{leader: true}, filepath.Dir(scope), time.Minute)
+	cfg, _ := ParseConfig([]byte(`{"path":"inbox","processed_dir":"done/nested"}`))
+	tr := &watch{triggerID: "tr-1", workflowID: "wf-1", scope: scope, dir: inbox, cfg: cfg}
+
+	path := filepath.Join(inbox, "data.csv")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.fire(context.Background(), tr, path, EventCreate); err == nil {
+		t.Fatalf("expected a processed_dir symlinked out of the organization's directory to fail")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the file to stay in place: %v", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 || len(fq.runs) != 0 {
+		t.Fatalf("expected nothing to be created outside or enqueued, got %v and %d runs", entries, len(fq.runs))
+	}
+}
+
+func TestWatcherDebouncesEvents(t *testing.T) {
+	root := t.TempDir()
+	inbox := filepath.Join(root, "org-1", "inbox")
+	if err := os.MkdirAll(inbox, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fq := &fakeQueries{
+		triggers: []sqlc.ListEnabledTriggersByTypeRow{
+			{ID: "tr-1", WorkflowID: "wf-1", OrgID: "org-1", Config: []byte(`{"path":"inbox","pattern":"*.csv","debounce_ms":200}`)},
+		},
+		fired: make(chan struct{}, 10),
+	}
+	w := newWatcher(fq, &fakeLeader{leader: true}, root, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = w.Run(ctx, nil)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Wait for the directory to be watched before writing.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_ = os.WriteFile(filepath.Join(inbox, "probe.csv"), []byte("x"), 0o644)
+		select {
+		case <-fq.fired:
+		case <-time.After(300 * time.Millisecond):
+		}
+		fq.mu.Lock()
+		n := len(fq.runs)
+		fq.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("watcher never fired")
+		}
+	}
+	time.Sleep(300 * time.Millisecond)
+	fq.mu.Lock()
+	fq.runs = nil
+	fq.mu.Unlock()
+
+	path := filepath.Join(inbox, "report.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		_, _ = f.WriteString("row\n")
+		time.Sleep(20 * time.Millisecond)
+	}
+	f.Close()
+	_ = os.WriteFile(filepath.Join(inbox, "ignored.txt"), []byte("x"), 0o644)
+
+	select {
+	case <-fq.fired:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the settled file to fire")
+	}
+	time.Sleep(400 * time.Millisecond)
+
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	if len(fq.runs) != 1 {
+		t.Fatalf("expected a single debounced run, got %d", len(fq.runs))
+	}
+	if input := runInput(t, fq.runs[0]); input["name"] != "report.csv" || input["event"] != EventCreate || input["size"] != float64(20) {
+		t.Fatalf("unexpected run input %v", input)
+	}
+}
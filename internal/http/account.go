@@ -0,0 +1,162 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/groovypotato/PotaFlow/internal/auth"
+)
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// RequestPasswordResetHandler mails a reset token. It answers 202 whether or not the email has
+// an account, and 429 with Retry-After when the email or client asks too often.
+func RequestPasswordResetHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req passwordResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email is required"})
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.RequestPasswordReset(ctx, req.Email, clientIP(r)); err != nil {
+			if writeThrottled(w, err) {
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ResetPasswordHandler sets a new password with a mailed reset token and signs the user out
+// everywhere.
+func ResetPasswordHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req resetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.Password == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token and password are required"})
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.ResetPassword(ctx, req.Token, req.Password); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ChangePasswordHandler replaces the caller's password after checking the current one. Every
+// other session is signed out; the response carries fresh tokens for this one. Wrong passwords
+// count as failed logins, so it can be throttled with 429.
+func ChangePasswordHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req changePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "current_password and new_password are required"})
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		tokens, err := authSvc.ChangePassword(ctx, claims.UserID, req.CurrentPassword, req.NewPassword, clientIP(r))
+		if err != nil {
+			if writeThrottled(w, err) {
+				return
+			}
+			writeAccountError(w, err)
+			return
+		}
+		user, err := authSvc.GetUser(ctx, claims.UserID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, newLoginResponse(user, tokens))
+	}
+}
+
+// VerifyEmailHandler confirms an email address with a mailed verification token.
+func VerifyEmailHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req verifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token is required"})
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.VerifyEmail(ctx, req.Token); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ResendVerificationHandler mails the caller a new verification token.
+func ResendVerificationHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.SendEmailVerification(ctx, claims.UserID); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidAccountToken):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid or expired token"})
+	case errors.Is(err, auth.ErrInvalidInput):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid password"})
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "current password is incorrect"})
+	case errors.Is(err, auth.ErrEmailAlreadyVerified):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "email already verified"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	}
+}
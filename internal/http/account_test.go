@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/auth"
+)
+
+func (f fakeAuthService) RequestPasswordReset(ctx context.Context, email, clientIP string) error {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "reset:"+email+":"+clientIP)
+	}
+	return f.err
+}
+
+func (f fakeAuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "reset-confirm:"+token+":"+newPassword)
+	}
+	return f.err
+}
+
+func (f fakeAuthService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword, clientIP string) (auth.TokenPair, error) {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "change:"+userID+":"+currentPassword+":"+newPassword+":"+clientIP)
+	}
+	return auth.TokenPair{AccessToken: f.token}, f.err
+}
+
+func (f fakeAuthService) SendEmailVerification(ctx context.Context, userID string) error {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "verify-resend:"+userID)
+	}
+	return f.err
+}
+
+func (f fakeAuthService) VerifyEmail(ctx context.Context, token string) error {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "verify:"+token)
+	}
+	return f.err
+}
+
+func TestPasswordResetHandlers(t *testing.T) {
+	var calls []string
+	svc := fakeAuthService{loggedOut: &calls}
+
+	rr := httptest.NewRecorder()
+	RequestPasswordResetHandler(svc).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/password-reset", bytes.NewBufferString(`{"email":"a@example.com"}`)))
+	if rr.Code != nethttp.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ResetPasswordHandler(svc).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/password-reset/confirm", bytes.NewBufferString(`{"token":"tok","password":"new"}`)))
+	if rr.Code != nethttp.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if len(calls) != 2 || calls[0] != "reset:a@example.com:192.0.2.1" || calls[1] != "reset-confirm:tok:new" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	rr = httptest.NewRecorder()
+	RequestPasswordResetHandler(fakeAuthService{err: &auth.ThrottledError{RetryAfter: 90 * time.Second}}).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/password-reset", bytes.NewBufferString(`{"email":"a@example.com"}`)))
+	if rr.Code != nethttp.StatusTooManyRequests || rr.Header().Get("Retry-After") != "90" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	for name, tc := range map[string]struct {
+		body string
+		err  error
+		want int
+	}{
+		"missing password": {`{"token":"tok"}`, nil, nethttp.StatusBadRequest},
+		"invalid token":    {`{"token":"tok","password":"new"}`, auth.ErrInvalidAccountToken, nethttp.StatusBadRequest},
+		"internal":         {`{"token":"tok","password":"new"}`, context.DeadlineExceeded, nethttp.StatusInternalServerError},
+	} {
+		rr := httptest.NewRecorder()
+		ResetPasswordHandler(fakeAuthService{err: tc.err}).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/password-reset/confirm", bytes.NewBufferString(tc.body)))
+		if rr.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rr.Code)
+		}
+	}
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	var calls []string
+	svc := fakeAuthService{token: "fresh-token", user: auth.User{ID: "u1", Email: "a@example.com"}, loggedOut: &calls}
+	session := auth.Claims{UserID: "u1", ID: "jti"}
+
+	rr := httptest.NewRecorder()
+	ChangePasswordHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/change-password", `{"current_password":"old","new_password":"new"}`, session))
+	var resp map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != nethttp.StatusOK || resp["token"] != "fresh-token" {
+		t.Fatalf("unexpected response %d %v", rr.Code, resp)
+	}
+	if len(calls) != 1 || calls[0] != "change:u1:old:new:192.0.2.1" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	rr = httptest.NewRecorder()
+	ChangePasswordHandler(fakeAuthService{err: auth.ErrInvalidCredentials}).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/change-password", `{"current_password":"bad","new_password":"new"}`, session))
+	if rr.Code != nethttp.StatusForbidden {
+		t.Fatalf("expected 403 for wrong password, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ChangePasswordHandler(fakeAuthService{err: &auth.ThrottledError{RetryAfter: time.Second}}).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/change-password", `{"current_password":"bad","new_password":"new"}`, session))
+	if rr.Code != nethttp.StatusTooManyRequests {
+		t.Fatalf("expected 429 when throttled, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ChangePasswordHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/change-password", `{"current_password":"old","new_password":"new"}`, auth.Claims{UserID: "u1", APIKeyID: "k1"}))
+	if rr.Code != nethttp.StatusForbidden {
+		t.Fatalf("expected 403 for api key, got %d", rr.Code)
+	}
+}
+
+func TestVerifyEmailHandlers(t *testing.T) {
+	var calls []string
+	svc := fakeAuthService{loggedOut: &calls}
+
+	rr := httptest.NewRecorder()
+	VerifyEmailHandler(svc).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/verify-email", bytes.NewBufferString(`{"token":"tok"}`)))
+	if rr.Code != nethttp.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ResendVerificationHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/verify-email/resend", "", auth.Claims{UserID: "u1", ID: "jti"}))
+	if rr.Code != nethttp.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+	if len(calls) != 2 || calls[0] != "verify:tok" || calls[1] != "verify-resend:u1" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	rr = httptest.NewRecorder()
+	ResendVerificationHandler(fakeAuthService{err: auth.ErrEmailAlreadyVerified}).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/verify-email/resend", "", auth.Claims{UserID: "u1", ID: "jti"}))
+	if rr.Code != nethttp.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	VerifyEmailHandler(fakeAuthService{err: auth.ErrInvalidAccountToken}).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/verify-email", bytes.NewBufferString(`{"token":"tok"}`)))
+	if rr.Code != nethttp.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
+	"github.com/groovypotato/PotaFlow/internal/workflows"
+)
+
+type adminUserResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	DisabledAt *time.Time `json:"disabled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func toAdminUserResponse(u auth.User) adminUserResponse {
+	return adminUserResponse{
+		ID:         u.ID,
+		Email:      u.Email,
+		Role:       u.Role,
+		DisabledAt: u.DisabledAt,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func writeAdminUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, auth.ErrInvalidInput):
+		http.Error(w, "role must be admin or member", http.StatusBadRequest)
+	default:
+		http.Error(w, "failed to update user", http.StatusInternalServerError)
+	}
+}
+
+// notSelf writes 400 when an admin targets their own account, so the last admin can't lock
+// everyone out.
+func notSelf(w http.ResponseWriter, claims auth.Claims, userID string) bool {
+	if userID == claims.UserID {
+		http.Error(w, "admins cannot change their own role or status", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// ListUsersHandler returns every user on the instance.
+func ListUsersHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		users, err := authSvc.ListUsers(ctx)
+		if err != nil {
+			http.Error(w, "failed to list users", http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]adminUserResponse, 0, len(users))
+		for _, u := range users {
+			resp = append(resp, toAdminUserResponse(u))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// SetUserRoleHandler changes the role of another user.
+func SetUserRoleHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireClaims(w, r)
+		if !ok {
+			return
+		}
+		userID := chi.URLParam(r, "id")
+		if !notSelf(w, claims, userID) {
+			return
+		}
+
+		var req setRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		user, err := authSvc.SetUserRole(ctx, userID, req.Role)
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toAdminUserResponse(user))
+	}
+}
+
+// DisableUserHandler blocks another user from signing in and ends their sessions.
+func DisableUserHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireClaims(w, r)
+		if !ok {
+			return
+		}
+		userID := chi.URLParam(r, "id")
+		if !notSelf(w, claims, userID) {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		user, err := authSvc.DisableUser(ctx, userID)
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toAdminUserResponse(user))
+	}
+}
+
+// EnableUserHandler lets a disabled user sign in again.
+func EnableUserHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		user, err := authSvc.EnableUser(ctx, chi.URLParam(r, "id"))
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toAdminUserResponse(user))
+	}
+}
+
+// LogoutUserHandler ends every session of a user, e.g. after their credentials leaked.
+func LogoutUserHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.LogoutAll(ctx, chi.URLParam(r, "id")); err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListAllWorkflowsHandler returns the workflows of every user.
+func ListAllWorkflowsHandler(svc WorkflowService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		wfs, err := svc.ListAllWorkflows(ctx)
+		if err != nil {
+			http.Error(w, "failed to list workflows", http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]workflowResponse, 0, len(wfs))
+		for _, wf := range wfs {
+			resp = append(resp, toWorkflowResponse(wf))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// ListAllRunsHandler returns the newest runs across every workflow, optionally filtered by the
+// workflow_id and status query parameters; limit defaults to 100.
+func ListAllRunsHandler(svc WorkflowService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := workflows.RunFilter{
+			WorkflowID: r.URL.Query().Get("workflow_id"),
+			Status:     r.URL.Query().Get("status"),
+		}
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			limit, err := strconv.ParseInt(raw, 10, 32)
+			if err != nil || limit <= 0 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = int32(limit)
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		runs, err := svc.ListAllRuns(ctx, filter)
+		if err != nil {
+			http.Error(w, "failed to list runs", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, runs)
+	}
+}
+
+type auditEventResponse struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	UserID    string          `json:"user_id,omitempty"`
+	IP        string          `json:"ip"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ListAuditEventsHandler returns the newest audit events, optionally filtered by the event query
+// parameter; limit defaults to 100.
+func ListAuditEventsHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var limit int32
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 32)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = int32(parsed)
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		events, err := authSvc.ListAuditEvents(ctx, r.URL.Query().Get("event"), limit)
+		if err != nil {
+			http.Error(w, "failed to list audit events", http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]auditEventResponse, 0, len(events))
+		for _, e := range events {
+			resp = append(resp, auditEventResponse{
+				ID:        e.ID,
+				Event:     e.Event,
+				UserID:    e.UserID,
+				IP:        e.IP,
+				Details:   e.Details,
+				CreatedAt: e.CreatedAt,
+			})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
+	"github.com/groovypotato/PotaFlow/internal/workflows"
+)
+
+func (f fakeAuthService) ListUsers(ctx context.Context) ([]auth.User, error) {
+	return []auth.User{f.user}, f.err
+}
+
+func (f fakeAuthService) SetUserRole(ctx context.Context, userID, role string) (auth.User, error) {
+	if !auth.ValidRole(role) {
+		return auth.User{}, auth.ErrInvalidInput
+	}
+	u := f.user
+	u.Role = role
+	return u, f.err
+}
+
+func (f fakeAuthService) DisableUser(ctx context.Context, userID string) (auth.User, error) {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "disabled:"+userID)
+	}
+	return f.user, f.err
+}
+
+func (f fakeAuthService) EnableUser(ctx context.Context, userID string) (auth.User, error) {
+	return f.user, f.err
+}
+
+func (f fakeAuthService) ListAuditEvents(ctx context.Context, event string, limit int32) ([]auth.AuditEvent, error) {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, fmt.Sprintf("audit:%s:%d", event, limit))
+	}
+	return []auth.AuditEvent{{ID: "ev-1", Event: auth.AuditLoginLocked, IP: "192.0.2.1", Details: json.RawMessage(`{"scope":"ip"}`)}}, f.err
+}
+
+func (f fakeWorkflowService) ListAllWorkflows(ctx context.Context) ([]workflows.Workflow, error) {
+	return []workflows.Workflow{f.wf}, f.err
+}
+
+func (f fakeWorkflowService) ListAllRuns(ctx context.Context, filter workflows.RunFilter) ([]workflows.WorkflowRun, error) {
+	if f.filter != nil {
+		*f.filter = filter
+	}
+	return []workflows.WorkflowRun{f.run}, f.err
+}
+
+func adminRouter(authSvc AuthService, wfSvc WorkflowService) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/admin/users", ListUsersHandler(authSvc))
+	r.Put("/admin/users/{id}/role", SetUserRoleHandler(authSvc))
+	r.Post("/admin/users/{id}/disable", DisableUserHandler(authSvc))
+	r.Post("/admin/users/{id}/logout-all", LogoutUserHandler(authSvc))
+	r.Get("/admin/runs", ListAllRunsHandler(wfSvc))
+	r.Get("/admin/audit-events", ListAuditEventsHandler(authSvc))
+	return r
+}
+
+func TestAdminUserHandlers(t *testing.T) {
+	var calls []string
+	svc := fakeAuthService{user: auth.User{ID: "u2", Email: "b@example.com", Role: auth.RoleMember}, loggedOut: &calls}
+	router := adminRouter(svc, fakeWorkflowService{})
+	admin := auth.Claims{UserID: "u1", Role: auth.RoleAdmin}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/admin/users", "", admin))
+	var users []map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&users)
+	if rr.Code != nethttp.StatusOK || len(users) != 1 || users[0]["role"] != auth.RoleMember {
+		t.Fatalf("unexpected list response %d %v", rr.Code, users)
+	}
+
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{nethttp.MethodPut, "/admin/users/u2/role", `{"role":"admin"}`, nethttp.StatusOK},
+		{nethttp.MethodPut, "/admin/users/u2/role", `{"role":"owner"}`, nethttp.StatusBadRequest},
+		{nethttp.MethodPut, "/admin/users/u1/role", `{"role":"member"}`, nethttp.StatusBadRequest},
+		{nethttp.MethodPost, "/admin/users/u1/disable", "", nethttp.StatusBadRequest},
+		{nethttp.MethodPost, "/admin/users/u2/disable", "", nethttp.StatusOK},
+		{nethttp.MethodPost, "/admin/users/u2/logout-all", "", nethttp.StatusNoContent},
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, apiKeyRequest(tc.method, tc.target, tc.body, admin))
+		if rr.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.target, tc.want, rr.Code)
+		}
+	}
+	if len(calls) != 2 || calls[0] != "disabled:u2" || calls[1] != "all:u2" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+}
+
+func TestListAllRunsHandler(t *testing.T) {
+	var filter workflows.RunFilter
+	router := adminRouter(fakeAuthService{}, fakeWorkflowService{run: workflows.WorkflowRun{ID: "run-1"}, filter: &filter})
+	admin := auth.Claims{UserID: "u1", Role: auth.RoleAdmin}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/admin/runs?workflow_id=wf-1&status=failed&limit=5", "", admin))
+	if rr.Code != nethttp.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if filter != (workflows.RunFilter{WorkflowID: "wf-1", Status: "failed", Limit: 5}) {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/admin/runs?limit=zero", "", admin))
+	if rr.Code != nethttp.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad limit, got %d", rr.Code)
+	}
+}
+
+func TestListAuditEventsHandler(t *testing.T) {
+	var calls []string
+	router := adminRouter(fakeAuthService{loggedOut: &calls}, fakeWorkflowService{})
+	admin := auth.Claims{UserID: "u1", Role: auth.RoleAdmin}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/admin/audit-events?event=login.locked&limit=5", "", admin))
+	var events []map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&events)
+	if rr.Code != nethttp.StatusOK || len(events) != 1 || events[0]["event"] != auth.AuditLoginLocked {
+		t.Fatalf("unexpected response %d %v", rr.Code, events)
+	}
+	if details, ok := events[0]["details"].(map[string]any); !ok || details["scope"] != "ip" {
+		t.Fatalf("expected details to be embedded as JSON, got %v", events[0]["details"])
+	}
+	if len(calls) != 1 || calls[0] != "audit:login.locked:5" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/admin/audit-events?limit=-1", "", admin))
+	if rr.Code != nethttp.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad limit, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
+)
+
+type apiKeyResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	Scopes      []string   `json:"scopes"`
+	WorkflowIDs []string   `json:"workflow_ids"`
+}
+
+// createAPIKeyResponse includes the key itself, which is never shown again.
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	Key string `json:"key"`
+}
+
+func toAPIKeyResponse(k auth.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:          k.ID,
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		CreatedAt:   k.CreatedAt,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		Scopes:      k.Scopes,
+		WorkflowIDs: k.WorkflowIDs,
+	}
+}
+
+// createAPIKeyRequest grants every scope when scopes is omitted and every workflow when
+// workflow_ids is.
+type createAPIKeyRequest struct {
+	Name        string     `json:"name"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Scopes      []string   `json:"scopes"`
+	WorkflowIDs []string   `json:"workflow_ids"`
+}
+
+// requireSession is requireClaims for endpoints an API key may not use, so a leaked key can't
+// mint more keys, outlive its own revocation or change organization memberships.
+func requireSession(w http.ResponseWriter, r *http.Request) (auth.Claims, bool) {
+	claims, ok := requireClaims(w, r)
+	if !ok {
+		return auth.Claims{}, false
+	}
+	if claims.APIKeyID != "" {
+		http.Error(w, "api keys cannot be used for this endpoint", http.StatusForbidden)
+		return auth.Claims{}, false
+	}
+	return claims, true
+}
+
+// CreateAPIKeyHandler issues an API key for the authenticated user and returns it once.
+func CreateAPIKeyHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req createAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		key, raw, err := authSvc.CreateAPIKey(ctx, claims.UserID, auth.NewAPIKey{
+			Name:        req.Name,
+			ExpiresAt:   req.ExpiresAt,
+			Scopes:      req.Scopes,
+			WorkflowIDs: req.WorkflowIDs,
+		})
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidInput) {
+				http.Error(w, "name is required, expires_at must be in the future, scopes must be known and workflow_ids must be UUIDs", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "failed to create api key", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, createAPIKeyResponse{apiKeyResponse: toAPIKeyResponse(key), Key: raw})
+	}
+}
+
+// ListAPIKeysHandler returns the authenticated user's API keys without the keys themselves.
+func ListAPIKeysHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		keys, err := authSvc.ListAPIKeys(ctx, claims.UserID)
+		if err != nil {
+			http.Error(w, "failed to list api keys", http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]apiKeyResponse, 0, len(keys))
+		for _, k := range keys {
+			resp = append(resp, toAPIKeyResponse(k))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DeleteAPIKeyHandler revokes one of the authenticated user's API keys.
+func DeleteAPIKeyHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.DeleteAPIKey(ctx, claims.UserID, chi.URLParam(r, "id")); err != nil {
+			if errors.Is(err, auth.ErrNotFound) {
+				http.Error(w, "api key not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "failed to delete api key", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
+)
+
+func (f fakeAuthService) CreateAPIKey(ctx context.Context, userID string, req auth.NewAPIKey) (auth.APIKey, string, error) {
+	if req.Name == "" {
+		return auth.APIKey{}, "", auth.ErrInvalidInput
+	}
+	key := auth.APIKey{
+		ID:          "key-1",
+		UserID:      userID,
+		Name:        req.Name,
+		Prefix:      "pf_abcdef",
+		ExpiresAt:   req.ExpiresAt,
+		Scopes:      req.Scopes,
+		WorkflowIDs: req.WorkflowIDs,
+	}
+	f.apiKeys[key.ID] = key
+	return key, "pf_abcdef-secret", nil
+}
+
+func (f fakeAuthService) ListAPIKeys(ctx context.Context, userID string) ([]auth.APIKey, error) {
+	var keys []auth.APIKey
+	for _, k := range f.apiKeys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (f fakeAuthService) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	k, ok := f.apiKeys[id]
+	if !ok || k.UserID != userID {
+		return auth.ErrNotFound
+	}
+	delete(f.apiKeys, id)
+	return nil
+}
+
+func apiKeyRequest(method, target, body string, claims auth.Claims) *nethttp.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	return req.WithContext(context.WithValue(req.Context(), userCtxKey, claims))
+}
+
+func TestAPIKeyHandlers(t *testing.T) {
+	svc := fakeAuthService{apiKeys: make(map[string]auth.APIKey)}
+	session := auth.Claims{UserID: "u1", ID: "jti"}
+
+	rr := httptest.NewRecorder()
+	CreateAPIKeyHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/api-keys", `{"name":"ci","expires_at":"2030-01-01T00:00:00Z","scopes":["runs:trigger"],"workflow_ids":["wf-1"]}`, session))
+	if rr.Code != nethttp.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body)
+	}
+	var created map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&created)
+	if created["key"] != "pf_abcdef-secret" || created["prefix"] != "pf_abcdef" || created["expires_at"] != "2030-01-01T00:00:00Z" {
+		t.Fatalf("unexpected create response %v", created)
+	}
+	if scopes, _ := created["scopes"].([]any); len(scopes) != 1 || scopes[0] != "runs:trigger" {
+		t.Fatalf("unexpected scopes %v", created["scopes"])
+	}
+	if ids, _ := created["workflow_ids"].([]any); len(ids) != 1 || ids[0] != "wf-1" {
+		t.Fatalf("unexpected workflow_ids %v", created["workflow_ids"])
+	}
+
+	rr = httptest.NewRecorder()
+	CreateAPIKeyHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/api-keys", `{}`, session))
+	if rr.Code != nethttp.StatusBadRequest {
+		t.Fatalf("expected 400 without a name, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ListAPIKeysHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/api-keys", "", session))
+	var listed []map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&listed)
+	if rr.Code != nethttp.StatusOK || len(listed) != 1 || listed[0]["key"] != nil {
+		t.Fatalf("unexpected list response %d %v", rr.Code, listed)
+	}
+
+	// API keys can't manage keys.
+	rr = httptest.NewRecorder()
+	CreateAPIKeyHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/api-keys", `{"name":"x"}`, auth.Claims{UserID: "u1", APIKeyID: "key-1"}))
+	if rr.Code != nethttp.StatusForbidden {
+		t.Fatalf("expected 403 for an api key, got %d", rr.Code)
+	}
+
+	router := chi.NewRouter()
+	router.Delete("/api-keys/{id}", DeleteAPIKeyHandler(svc))
+	for _, tc := range []struct {
+		claims auth.Claims
+		want   int
+	}{
+		{auth.Claims{UserID: "u2", ID: "jti"}, nethttp.StatusNotFound},
+		{session, nethttp.StatusNoContent},
+		{session, nethttp.StatusNotFound},
+	} {
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodDelete, "/api-keys/key-1", "", tc.claims))
+		if rr.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.claims.UserID, tc.want, rr.Code)
+		}
+	}
+}
@@ -3,7 +3,10 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	nethttp "net/http"
+	"strconv"
 	"time"
 
 	"github.com/groovypotato/PotaFlow/internal/auth"
@@ -27,19 +30,46 @@ type loginRequest struct {
 }
 
 type loginResponse struct {
-	Token     string    `json:"token"`
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	ID               string    `json:"id"`
+	Email            string    `json:"email"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // AuthService defines the Register capability needed by the handler.
 type AuthService interface {
 	Register(ctx context.Context, email, password string) (auth.User, error)
-	Login(ctx context.Context, email, password string) (auth.User, string, error)
-	ParseAndValidateToken(tokenStr string) (auth.Claims, error)
+	Login(ctx context.Context, email, password, clientIP string) (auth.User, auth.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (auth.User, auth.TokenPair, error)
+	ParseAndValidateToken(ctx context.Context, tokenStr string) (auth.Claims, error)
+	Logout(ctx context.Context, claims auth.Claims, refreshToken string) error
+	LogoutAll(ctx context.Context, userID string) error
+	CreateAPIKey(ctx context.Context, userID string, req auth.NewAPIKey) (auth.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]auth.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id string) error
 	GetUser(ctx context.Context, id string) (auth.User, error)
+	ListUsers(ctx context.Context) ([]auth.User, error)
+	SetUserRole(ctx context.Context, userID, role string) (auth.User, error)
+	DisableUser(ctx context.Context, userID string) (auth.User, error)
+	EnableUser(ctx context.Context, userID string) (auth.User, error)
+	ListAuditEvents(ctx context.Context, event string, limit int32) ([]auth.AuditEvent, error)
+	LoginTOTP(ctx context.Context, challengeToken, code, clientIP string) (auth.User, auth.TokenPair, error)
+	EnrollTOTP(ctx context.Context, userID string) (auth.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, password, code, clientIP string) error
+	RequestPasswordReset(ctx context.Context, email, clientIP string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword, clientIP string) (auth.TokenPair, error)
+	SendEmailVerification(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, token string) error
 }
 
 // RegisterHandler registers a new user after hashing the password.
@@ -86,7 +116,9 @@ func RegisterHandler(authSvc AuthService) nethttp.HandlerFunc {
 	}
 }
 
-// LoginHandler authenticates a user and returns a JWT.
+// LoginHandler authenticates a user and returns a JWT. Throttled attempts get 429 with a
+// Retry-After header. Accounts with two-factor authentication get a challenge token for
+// LoginTOTPHandler instead.
 func LoginHandler(authSvc AuthService) nethttp.HandlerFunc {
 	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -107,12 +139,28 @@ func LoginHandler(authSvc AuthService) nethttp.HandlerFunc {
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		user, token, err := authSvc.Login(ctx, req.Email, req.Password)
+		user, tokens, err := authSvc.Login(ctx, req.Email, req.Password, clientIP(r))
 		if err != nil {
+			if writeThrottled(w, err) {
+				return
+			}
+			var required *auth.TOTPRequiredError
+			if errors.As(err, &required) {
+				w.WriteHeader(nethttp.StatusOK)
+				_ = json.NewEncoder(w).Encode(totpChallengeResponse{
+					TwoFactorRequired: true,
+					ChallengeToken:    required.ChallengeToken,
+					ExpiresAt:         required.ExpiresAt,
+				})
+				return
+			}
 			switch err {
 			case auth.ErrInvalidCredentials:
 				w.WriteHeader(nethttp.StatusUnauthorized)
 				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid credentials"})
+			case auth.ErrAccountDisabled:
+				w.WriteHeader(nethttp.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "account disabled"})
 			default:
 				w.WriteHeader(nethttp.StatusInternalServerError)
 				_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
@@ -121,12 +169,132 @@ func LoginHandler(authSvc AuthService) nethttp.HandlerFunc {
 		}
 
 		w.WriteHeader(nethttp.StatusOK)
-		_ = json.NewEncoder(w).Encode(loginResponse{
-			Token:     token,
-			ID:        user.ID,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		})
+		_ = json.NewEncoder(w).Encode(newLoginResponse(user, tokens))
+	}
+}
+
+// RefreshHandler exchanges a refresh token for a new access token and refresh token. The old
+// refresh token stops working; presenting it again revokes every token descended from the login.
+func RefreshHandler(authSvc AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(nethttp.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON body"})
+			return
+		}
+
+		if req.RefreshToken == "" {
+			w.WriteHeader(nethttp.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "refresh_token is required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, tokens, err := authSvc.Refresh(ctx, req.RefreshToken)
+		if err != nil {
+			switch err {
+			case auth.ErrInvalidRefreshToken, auth.ErrRefreshTokenReused:
+				w.WriteHeader(nethttp.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid refresh token"})
+			default:
+				w.WriteHeader(nethttp.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+			}
+			return
+		}
+
+		w.WriteHeader(nethttp.StatusOK)
+		_ = json.NewEncoder(w).Encode(newLoginResponse(user, tokens))
+	}
+}
+
+// LogoutHandler revokes the caller's access token and, if the body names one, its refresh token.
+func LogoutHandler(authSvc AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		claims, ok := UserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		// The body is optional.
+		var req refreshRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON body"})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := authSvc.Logout(ctx, claims, req.RefreshToken); err != nil {
+			if err == auth.ErrNotSession {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "api keys cannot log out; delete the key instead"})
+				return
+			}
+			w.WriteHeader(nethttp.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+			return
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}
+}
+
+// LogoutAllHandler ends every session of the caller, on every device. It needs a session: an API
+// key, however narrowly scoped, can't sign its owner out everywhere.
+func LogoutAllHandler(authSvc AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := authSvc.LogoutAll(ctx, claims.UserID); err != nil {
+			w.WriteHeader(nethttp.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+			return
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}
+}
+
+// writeThrottled answers a *auth.ThrottledError with 429 and reports whether err was one.
+func writeThrottled(w nethttp.ResponseWriter, err error) bool {
+	var throttled *auth.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	w.WriteHeader(nethttp.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": "too many failed login attempts"})
+	return true
+}
+
+func newLoginResponse(user auth.User, tokens auth.TokenPair) loginResponse {
+	return loginResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.ExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		ID:               user.ID,
+		Email:            user.Email,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
 	}
 }
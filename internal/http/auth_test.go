@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	nethttp "net/http"
 	"net/http/httptest"
 	"testing"
@@ -14,9 +15,11 @@ import (
 )
 
 type fakeAuthService struct {
-	user  auth.User
-	token string
-	err   error
+	user      auth.User
+	token     string
+	err       error
+	loggedOut *[]string
+	apiKeys   map[string]auth.APIKey
 }
 
 func (f fakeAuthService) Register(ctx context.Context, email, password string) (auth.User, error) {
@@ -36,7 +39,7 @@ func (f fakeAuthService) Register(ctx context.Context, email, password string) (
 	return u, f.err
 }
 
-func (f fakeAuthService) Login(ctx context.Context, email, password string) (auth.User, string, error) {
+func (f fakeAuthService) Login(ctx context.Context, email, password, clientIP string) (auth.User, auth.TokenPair, error) {
 	u := f.user
 	if u.Email == "" {
 		u.Email = email
@@ -50,16 +53,37 @@ func (f fakeAuthService) Login(ctx context.Context, email, password string) (aut
 	if u.UpdatedAt.IsZero() {
 		u.UpdatedAt = u.CreatedAt
 	}
-	return u, f.token, f.err
+	return u, auth.TokenPair{AccessToken: f.token, RefreshToken: "refresh-" + f.token}, f.err
 }
 
-func (f fakeAuthService) ParseAndValidateToken(tokenStr string) (auth.Claims, error) {
+func (f fakeAuthService) Refresh(ctx context.Context, refreshToken string) (auth.User, auth.TokenPair, error) {
+	if f.err != nil {
+		return auth.User{}, auth.TokenPair{}, f.err
+	}
+	return f.user, auth.TokenPair{AccessToken: f.token, RefreshToken: "rotated-" + refreshToken}, nil
+}
+
+func (f fakeAuthService) ParseAndValidateToken(ctx context.Context, tokenStr string) (auth.Claims, error) {
 	if f.err != nil {
 		return auth.Claims{}, f.err
 	}
 	return auth.Claims{UserID: "fake-id", Email: "test@example.com"}, nil
 }
 
+func (f fakeAuthService) Logout(ctx context.Context, claims auth.Claims, refreshToken string) error {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, claims.ID+":"+refreshToken)
+	}
+	return f.err
+}
+
+func (f fakeAuthService) LogoutAll(ctx context.Context, userID string) error {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "all:"+userID)
+	}
+	return f.err
+}
+
 func (f fakeAuthService) GetUser(ctx context.Context, id string) (auth.User, error) {
 	return f.user, f.err
 }
@@ -130,8 +154,43 @@ func TestLoginHandler_Success(t *testing.T) {
 	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
 		t.Fatalf("decode response: %v", err)
 	}
-	if resp["token"] != "signed-token" {
-		t.Fatalf("unexpected token: %v", resp["token"])
+	if resp["token"] != "signed-token" || resp["refresh_token"] != "refresh-signed-token" {
+		t.Fatalf("unexpected tokens: %v", resp)
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	req := httptest.NewRequest(nethttp.MethodPost, "/auth/refresh", bytes.NewBufferString(`{"refresh_token":"old"}`))
+	rr := httptest.NewRecorder()
+	RefreshHandler(fakeAuthService{token: "new-access"}).ServeHTTP(rr, req)
+
+	if rr.Code != nethttp.StatusOK {
+		t.Fatalf("expected status %d, got %d", nethttp.StatusOK, rr.Code)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp["token"] != "new-access" || resp["refresh_token"] != "rotated-old" {
+		t.Fatalf("unexpected tokens: %v", resp)
+	}
+
+	for _, tc := range []struct {
+		body string
+		err  error
+		want int
+	}{
+		{`{}`, nil, nethttp.StatusBadRequest},
+		{`{"refresh_token":"old"}`, auth.ErrInvalidRefreshToken, nethttp.StatusUnauthorized},
+		{`{"refresh_token":"old"}`, auth.ErrRefreshTokenReused, nethttp.StatusUnauthorized},
+		{`{"refresh_token":"old"}`, errors.New("boom"), nethttp.StatusInternalServerError},
+	} {
+		req := httptest.NewRequest(nethttp.MethodPost, "/auth/refresh", bytes.NewBufferString(tc.body))
+		rr := httptest.NewRecorder()
+		RefreshHandler(fakeAuthService{err: tc.err}).ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("%s (%v): expected status %d, got %d", tc.body, tc.err, tc.want, rr.Code)
+		}
 	}
 }
 
@@ -148,6 +207,35 @@ func TestLoginHandler_InvalidCredentials(t *testing.T) {
 	}
 }
 
+func TestLoginHandler_AccountDisabled(t *testing.T) {
+	body := `{"email":"test@example.com","password":"secret"}`
+	req := httptest.NewRequest(nethttp.MethodPost, "/auth/login", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	handler := LoginHandler(fakeAuthService{err: auth.ErrAccountDisabled})
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != nethttp.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", nethttp.StatusForbidden, rr.Code)
+	}
+}
+
+func TestLoginHandler_Throttled(t *testing.T) {
+	body := `{"email":"test@example.com","password":"wrong"}`
+	req := httptest.NewRequest(nethttp.MethodPost, "/auth/login", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	handler := LoginHandler(fakeAuthService{err: &auth.ThrottledError{RetryAfter: 1500 * time.Millisecond}})
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != nethttp.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", nethttp.StatusTooManyRequests, rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("expected Retry-After to round up to 2, got %q", got)
+	}
+}
+
 func TestRegisterHandler_MissingFields(t *testing.T) {
 	body := `{"email":"","password":""}`
 	req := httptest.NewRequest(nethttp.MethodPost, "/auth/register", bytes.NewBufferString(body))
@@ -186,3 +274,42 @@ func TestLoginHandler_InternalError(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", nethttp.StatusInternalServerError, rr.Code)
 	}
 }
+
+func TestLogoutHandlers(t *testing.T) {
+	var calls []string
+	svc := fakeAuthService{loggedOut: &calls}
+	claims := auth.Claims{UserID: "u1", ID: "jti-1"}
+
+	for _, tc := range []struct {
+		handler nethttp.HandlerFunc
+		body    string
+	}{
+		{LogoutHandler(svc), ""},
+		{LogoutHandler(svc), `{"refresh_token":"rt"}`},
+		{LogoutAllHandler(svc), ""},
+	} {
+		req := httptest.NewRequest(nethttp.MethodPost, "/auth/logout", bytes.NewBufferString(tc.body))
+		req = req.WithContext(context.WithValue(req.Context(), userCtxKey, claims))
+		rr := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rr, req)
+		if rr.Code != nethttp.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", nethttp.StatusNoContent, rr.Code)
+		}
+	}
+	if want := []string{"jti-1:", "jti-1:rt", "all:u1"}; fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Fatalf("expected calls %v, got %v", want, calls)
+	}
+
+	rr := httptest.NewRecorder()
+	LogoutHandler(svc).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/logout", nil))
+	if rr.Code != nethttp.StatusUnauthorized {
+		t.Fatalf("expected 401 without claims, got %d", rr.Code)
+	}
+
+	calls = nil
+	rr = httptest.NewRecorder()
+	LogoutAllHandler(svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/logout-all", "", auth.Claims{UserID: "u1", APIKeyID: "key-1"}))
+	if rr.Code != nethttp.StatusForbidden || len(calls) != 0 {
+		t.Fatalf("expected api keys to be refused, got %d and calls %v", rr.Code, calls)
+	}
+}
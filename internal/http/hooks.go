@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/webhooks"
+	"github.com/groovypotato/PotaFlow/internal/workflows"
+	"github.com/rs/zerolog/log"
+)
+
+const maxHookBodyBytes = 1 << 20
+
+// hookPollInterval is how often a sync webhook request checks on its run.
+var hookPollInterval = 250 * time.Millisecond
+
+// hookInput is the run input of a webhook-triggered run. Body holds the decoded JSON payload,
+// or the raw text when the payload isn't JSON.
+type hookInput struct {
+	Body    any               `json:"body"`
+	Headers map[string]string `json:"headers"`
+	Query   map[string]string `json:"query"`
+}
+
+type hookResponse struct {
+	RunID  string          `json:"run_id"`
+	Status string          `json:"status"`
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// WebhookHandler is the public ingress for webhook triggers. Requests to triggers with a
+// webhook_secret must carry a valid signature; rejections are counted on the trigger. Accepted
+// requests queue a run of the trigger's workflow with the request as input and, depending on the
+// trigger config, return 202 with the run ID or wait for the run to finish and return its output.
+func WebhookHandler(svc WorkflowService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		triggerID := chi.URLParam(r, "triggerID")
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+			}
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		trigger, err := svc.WebhookTrigger(ctx, triggerID)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		cfg, err := webhooks.ParseConfig(trigger.Config)
+		if err != nil {
+			log.Error().Err(err).Str("trigger_id", trigger.ID).Msg("invalid webhook trigger config")
+			http.Error(w, "invalid webhook trigger config", http.StatusInternalServerError)
+			return
+		}
+		if err := cfg.Verify(r.Header, body, time.Now()); err != nil {
+			if recErr := svc.RecordRejection(ctx, trigger.ID); recErr != nil {
+				log.Error().Err(recErr).Str("trigger_id", trigger.ID).Msg("failed to record webhook rejection")
+			}
+			log.Warn().Err(err).Str("trigger_id", trigger.ID).Msg("webhook rejected")
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		input, err := json.Marshal(newHookInput(r, body))
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		run, err := svc.EnqueueTriggeredRun(ctx, trigger, input)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+
+		if cfg.ResponseMode != webhooks.ResponseSync {
+			writeJSON(w, http.StatusAccepted, hookResponse{RunID: run.ID, Status: run.Status})
+			return
+		}
+		status, resp := waitForRun(r.Context(), svc, run, cfg.SyncTimeout())
+		writeJSON(w, status, resp)
+	}
+}
+
+func newHookInput(r *http.Request, body []byte) hookInput {
+	in := hookInput{
+		Body:    string(body),
+		Headers: make(map[string]string, len(r.Header)),
+		Query:   make(map[string]string),
+	}
+	var decoded any
+	if json.Unmarshal(body, &decoded) == nil {
+		in.Body = decoded
+	}
+	for name, vals := range r.Header {
+		in.Headers[name] = strings.Join(vals, ", ")
+	}
+	for name, vals := range r.URL.Query() {
+		in.Query[name] = strings.Join(vals, ",")
+	}
+	return in
+}
+
+// waitForRun polls the run until it finishes or timeout passes. Runs that are still going when
+// the timeout hits are reported with 202 so the caller can tell them apart from finished ones.
+func waitForRun(ctx context.Context, svc WorkflowService, run workflows.WorkflowRun, timeout time.Duration) (int, hookResponse) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(hookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return http.StatusAccepted, hookResponse{RunID: run.ID, Status: run.Status}
+		case <-ticker.C:
+		}
+		current, err := svc.GetRun(ctx, run.ID)
+		if err != nil {
+			return http.StatusAccepted, hookResponse{RunID: run.ID, Status: run.Status}
+		}
+		run = current
+		if run.Status == "success" || run.Status == "failed" {
+			return http.StatusOK, hookResponse{RunID: run.ID, Status: run.Status, Output: run.Output}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/workflows"
+)
+
+func hookRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/trig-1?source=test", bytes.NewBufferString(body))
+	req.Header.Set("X-Event", "created")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("triggerID", "trig-1")
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestWebhookHandler_Async(t *testing.T) {
+	var input []byte
+	svc := fakeWorkflowService{
+		trigger: workflows.Trigger{ID: "trig-1", WorkflowID: "wf-1", Type: "webhook"},
+		input:   &input,
+	}
+	rr := httptest.NewRecorder()
+
+	WebhookHandler(svc).ServeHTTP(rr, hookRequest(`{"id":7}`))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+	var resp hookResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || resp.RunID != "run-1" {
+		t.Fatalf("unexpected response %+v, err %v", resp, err)
+	}
+
+	var got hookInput
+	if err := json.Unmarshal(input, &got); err != nil {
+		t.Fatalf("decode run input: %v", err)
+	}
+	if got.Body.(map[string]any)["id"] != float64(7) || got.Headers["X-Event"] != "created" || got.Query["source"] != "test" {
+		t.Fatalf("unexpected run input: %+v", got)
+	}
+}
+
+func TestWebhookHandler_Sync(t *testing.T) {
+	hookPollInterval = time.Millisecond
+	svc := fakeWorkflowService{
+		trigger: workflows.Trigger{ID: "trig-1", WorkflowID: "wf-1", Type: "webhook", Config: []byte(`{"response_mode":"sync"}`)},
+		run:     workflows.WorkflowRun{ID: "run-1", Status: "success", Output: json.RawMessage(`{"ok":true}`)},
+	}
+	rr := httptest.NewRecorder()
+
+	WebhookHandler(svc).ServeHTTP(rr, hookRequest("plain text"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var resp hookResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Status != "success" || string(resp.Output) != `{"ok":true}` {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestWebhookHandler_SyncTimeout(t *testing.T) {
+	hookPollInterval = time.Millisecond
+	svc := fakeWorkflowService{
+		trigger: workflows.Trigger{ID: "trig-1", WorkflowID: "wf-1", Type: "webhook", Config: []byte(`{"response_mode":"sync","timeout_ms":20}`)},
+		run:     workflows.WorkflowRun{ID: "run-1", Status: "running"},
+	}
+	rr := httptest.NewRecorder()
+
+	WebhookHandler(svc).ServeHTTP(rr, hookRequest("{}"))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 after timeout, got %d", rr.Code)
+	}
+}
+
+func TestWebhookHandler_RejectsBadSignature(t *testing.T) {
+	var input []byte
+	rejects := 0
+	svc := fakeWorkflowService{
+		trigger: workflows.Trigger{ID: "trig-1", WorkflowID: "wf-1", Type: "webhook", Config: []byte(`{"webhook_secret":"s3cret","signature":{"preset":"github"}}`)},
+		input:   &input,
+		rejects: &rejects,
+	}
+	req := hookRequest(`{"id":7}`)
+	req.Header.Set("X-Hub-Signature-256", "sha256=deadbeef")
+	rr := httptest.NewRecorder()
+
+	WebhookHandler(svc).ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+	if rejects != 1 || input != nil {
+		t.Fatalf("expected a recorded rejection and no run, got %d rejections, input %s", rejects, input)
+	}
+}
+
+func TestWebhookHandler_Errors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: workflows.ErrTriggerNotFound, want: http.StatusNotFound},
+		{err: workflows.ErrWorkflowDisabled, want: http.StatusConflict},
+	}
+	for _, tc := range tests {
+		rr := httptest.NewRecorder()
+		WebhookHandler(fakeWorkflowService{err: tc.err}).ServeHTTP(rr, hookRequest("{}"))
+		if rr.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.err, tc.want, rr.Code)
+		}
+	}
+}
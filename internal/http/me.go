@@ -12,10 +12,12 @@ type UserProvider interface {
 }
 
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // MeHandler returns the current authenticated user.
@@ -42,10 +44,12 @@ func MeHandler(authSvc AuthService) nethttp.HandlerFunc {
 
 		w.WriteHeader(nethttp.StatusOK)
 		_ = json.NewEncoder(w).Encode(UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			ID:            user.ID,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			Role:          user.Role,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
 		})
 	}
 }
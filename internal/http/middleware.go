@@ -3,8 +3,10 @@ package http
 import (
 	"context"
 	"net/http"
+	"net/netip"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/groovypotato/PotaFlow/internal/auth"
 )
 
@@ -22,7 +24,7 @@ func AuthMiddleware(authSvc AuthService) func(http.Handler) http.Handler {
 				return
 			}
 			token := strings.TrimSpace(authHeader[len("bearer "):])
-			claims, err := authSvc.ParseAndValidateToken(token)
+			claims, err := authSvc.ParseAndValidateToken(r.Context(), token)
 			if err != nil {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
@@ -34,6 +36,114 @@ func AuthMiddleware(authSvc AuthService) func(http.Handler) http.Handler {
 	}
 }
 
+// RequireScope rejects callers without scope. Callers restricted to some workflows may only reach
+// those through the {id} or {workflowID} URL parameter, and may only read routes without one.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := UserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !claims.HasScope(scope) {
+				http.Error(w, "missing scope "+scope, http.StatusForbidden)
+				return
+			}
+			if claims.Restricted() {
+				id := chi.URLParam(r, "id")
+				if id == "" {
+					id = chi.URLParam(r, "workflowID")
+				}
+				if id == "" && r.Method != http.MethodGet {
+					http.Error(w, "restricted to specific workflows", http.StatusForbidden)
+					return
+				}
+				if id != "" && !claims.AllowsWorkflow(id) {
+					http.Error(w, "workflow not found", http.StatusNotFound)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole rejects callers whose role isn't role. API keys are rejected too, so a leaked key
+// can't be used to administer the instance.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := UserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if claims.Role != role || claims.APIKeyID != "" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TrustProxies sets r.RemoteAddr to the client address reported by trusted reverse proxies, so
+// login throttling counts the client rather than the proxy. For a connection from a trusted proxy,
+// X-Forwarded-For is read right to left and the first address that isn't a trusted proxy is the
+// client; entries left of it were sent by the client and are ignored. Other connections keep their
+// remote address, so clients can't choose the address they are throttled as.
+func TrustProxies(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trusted) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isTrusted(trusted, clientIP(r)) {
+				if client, ok := forwardedClient(trusted, r.Header.Values("X-Forwarded-For")); ok {
+					r.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the rightmost X-Forwarded-For address that isn't a trusted proxy.
+func forwardedClient(trusted []netip.Prefix, headers []string) (netip.Addr, bool) {
+	var hops []string
+	for _, h := range headers {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Garbage where a proxy should have written an address: don't believe any of it.
+			return netip.Addr{}, false
+		}
+		client = addr.Unmap()
+		if !isTrusted(trusted, client.String()) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+func isTrusted(trusted []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // UserFromContext extracts auth claims from the request context.
 func UserFromContext(ctx context.Context) (auth.Claims, bool) {
 	val := ctx.Value(userCtxKey)
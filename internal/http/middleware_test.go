@@ -5,8 +5,10 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/groovypotato/PotaFlow/internal/auth"
 )
 
@@ -18,16 +20,66 @@ type fakeAuthSvc struct {
 func (f fakeAuthSvc) Register(ctx context.Context, email, password string) (auth.User, error) {
 	return auth.User{}, nil
 }
-func (f fakeAuthSvc) Login(ctx context.Context, email, password string) (auth.User, string, error) {
-	return auth.User{}, "", nil
+func (f fakeAuthSvc) Login(ctx context.Context, email, password, clientIP string) (auth.User, auth.TokenPair, error) {
+	return auth.User{}, auth.TokenPair{}, nil
 }
-func (f fakeAuthSvc) ParseAndValidateToken(tokenStr string) (auth.Claims, error) {
+func (f fakeAuthSvc) Refresh(ctx context.Context, refreshToken string) (auth.User, auth.TokenPair, error) {
+	return auth.User{}, auth.TokenPair{}, nil
+}
+func (f fakeAuthSvc) ParseAndValidateToken(ctx context.Context, tokenStr string) (auth.Claims, error) {
 	if f.err != nil {
 		return auth.Claims{}, f.err
 	}
 	return f.claims, nil
 }
-func (f fakeAuthSvc) GetUser(_ context.Context, _ string) (auth.User, error) { return auth.User{}, nil }
+func (f fakeAuthSvc) Logout(ctx context.Context, claims auth.Claims, refreshToken string) error {
+	return nil
+}
+func (f fakeAuthSvc) LogoutAll(ctx context.Context, userID string) error { return nil }
+func (f fakeAuthSvc) CreateAPIKey(ctx context.Context, userID string, req auth.NewAPIKey) (auth.APIKey, string, error) {
+	return auth.APIKey{}, "", nil
+}
+func (f fakeAuthSvc) ListAPIKeys(ctx context.Context, userID string) ([]auth.APIKey, error) {
+	return nil, nil
+}
+func (f fakeAuthSvc) DeleteAPIKey(ctx context.Context, userID, id string) error { return nil }
+func (f fakeAuthSvc) GetUser(_ context.Context, _ string) (auth.User, error)    { return auth.User{}, nil }
+func (f fakeAuthSvc) ListUsers(ctx context.Context) ([]auth.User, error)        { return nil, nil }
+func (f fakeAuthSvc) SetUserRole(ctx context.Context, userID, role string) (auth.User, error) {
+	return auth.User{}, nil
+}
+func (f fakeAuthSvc) DisableUser(ctx context.Context, userID string) (auth.User, error) {
+	return auth.User{}, nil
+}
+func (f fakeAuthSvc) EnableUser(ctx context.Context, userID string) (auth.User, error) {
+	return auth.User{}, nil
+}
+func (f fakeAuthSvc) ListAuditEvents(ctx context.Context, event string, limit int32) ([]auth.AuditEvent, error) {
+	return nil, nil
+}
+func (f fakeAuthSvc) LoginTOTP(ctx context.Context, challengeToken, code, clientIP string) (auth.User, auth.TokenPair, error) {
+	return auth.User{}, auth.TokenPair{}, nil
+}
+func (f fakeAuthSvc) EnrollTOTP(ctx context.Context, userID string) (auth.TOTPEnrollment, error) {
+	return auth.TOTPEnrollment{}, nil
+}
+func (f fakeAuthSvc) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	return nil, nil
+}
+func (f fakeAuthSvc) DisableTOTP(ctx context.Context, userID, password, code, clientIP string) error {
+	return nil
+}
+func (f fakeAuthSvc) RequestPasswordReset(ctx context.Context, email, clientIP string) error {
+	return nil
+}
+func (f fakeAuthSvc) ResetPassword(ctx context.Context, token, newPassword string) error {
+	return nil
+}
+func (f fakeAuthSvc) ChangePassword(ctx context.Context, userID, currentPassword, newPassword, clientIP string) (auth.TokenPair, error) {
+	return auth.TokenPair{}, nil
+}
+func (f fakeAuthSvc) SendEmailVerification(ctx context.Context, userID string) error { return nil }
+func (f fakeAuthSvc) VerifyEmail(ctx context.Context, token string) error            { return nil }
 
 func TestAuthMiddleware_MissingHeader(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
@@ -70,3 +122,98 @@ func TestAuthMiddleware_SetsClaims(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestRequireScope(t *testing.T) {
+	router := chi.NewRouter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	router.With(RequireScope(auth.ScopeWorkflowsRead)).Get("/workflows", ok)
+	router.With(RequireScope(auth.ScopeWorkflowsWrite)).Post("/workflows", ok)
+	router.With(RequireScope(auth.ScopeRunsTrigger)).Post("/workflows/{id}/run", ok)
+
+	session := auth.Claims{UserID: "u1", Scopes: auth.AllScopes}
+	restricted := auth.Claims{UserID: "u1", Scopes: auth.AllScopes, WorkflowIDs: []string{"wf-1"}}
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		claims auth.Claims
+		want   int
+	}{
+		{"session", http.MethodPost, "/workflows/wf-2/run", session, http.StatusNoContent},
+		{"missing scope", http.MethodPost, "/workflows/wf-1/run", auth.Claims{UserID: "u1", Scopes: []string{auth.ScopeRunsRead}}, http.StatusForbidden},
+		{"allowed workflow", http.MethodPost, "/workflows/wf-1/run", restricted, http.StatusNoContent},
+		{"other workflow", http.MethodPost, "/workflows/wf-2/run", restricted, http.StatusNotFound},
+		{"restricted list", http.MethodGet, "/workflows", restricted, http.StatusNoContent},
+		{"restricted create", http.MethodPost, "/workflows", restricted, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userCtxKey, tc.claims))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rr.Code)
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	for _, tc := range []struct {
+		name   string
+		claims auth.Claims
+		want   int
+	}{
+		{"admin", auth.Claims{UserID: "u1", Role: auth.RoleAdmin}, http.StatusNoContent},
+		{"member", auth.Claims{UserID: "u1", Role: auth.RoleMember}, http.StatusForbidden},
+		{"admin api key", auth.Claims{UserID: "u1", Role: auth.RoleAdmin, APIKeyID: "key-1"}, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userCtxKey, tc.claims))
+		rr := httptest.NewRecorder()
+		RequireRole(auth.RoleAdmin)(ok).ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rr.Code)
+		}
+	}
+}
+
+func TestTrustProxies(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::1/128")}
+	for _, tc := range []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct client", "203.0.113.9:5000", []string{"198.51.100.1"}, "203.0.113.9"},
+		{"through proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed left of the real client", "10.0.0.2:5000", []string{"192.0.2.66, 198.51.100.1"}, "198.51.100.1"},
+		{"chained proxies", "10.0.0.2:5000", []string{"198.51.100.1, 10.0.0.7", "10.0.0.3"}, "198.51.100.1"},
+		{"ipv6 proxy", "[2001:db8::1]:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"garbage", "10.0.0.2:5000", []string{"198.51.100.1, nonsense"}, "10.0.0.2"},
+		{"no header", "10.0.0.2:5000", nil, "10.0.0.2"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.RemoteAddr = tc.remote
+		for _, h := range tc.forwarded {
+			req.Header.Add("X-Forwarded-For", h)
+		}
+		var got string
+		TrustProxies(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got = clientIP(r)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+		if got != tc.want {
+			t.Errorf("%s: expected client %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	// Without trusted proxies the header is never read.
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	TrustProxies(nil)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if ip := clientIP(r); ip != "10.0.0.2" {
+			t.Errorf("expected the remote address without trusted proxies, got %s", ip)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
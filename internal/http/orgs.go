@@ -0,0 +1,322 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/orgs"
+)
+
+// OrgService captures the organization operations the handlers depend on.
+type OrgService interface {
+	Create(ctx context.Context, userID, name string) (orgs.Organization, error)
+	List(ctx context.Context, userID string) ([]orgs.Organization, error)
+	ListMembers(ctx context.Context, userID, orgID string) ([]orgs.Member, error)
+	SetMemberRole(ctx context.Context, userID, orgID, memberID, role string) error
+	RemoveMember(ctx context.Context, userID, orgID, memberID string) error
+	Invite(ctx context.Context, userID, orgID, email, role string) (orgs.Invitation, string, error)
+	ListInvitations(ctx context.Context, userID, orgID string) ([]orgs.Invitation, error)
+	RevokeInvitation(ctx context.Context, userID, orgID, invitationID string) error
+	AcceptInvitation(ctx context.Context, userID, email, token string) (orgs.Organization, error)
+}
+
+type orgResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toOrgResponse(o orgs.Organization) orgResponse {
+	return orgResponse{ID: o.ID, Name: o.Name, Role: o.Role, CreatedAt: o.CreatedAt}
+}
+
+type orgMemberResponse struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// invitationResponse carries Token only in the response to the invite that created it.
+type invitationResponse struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"org_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Token     string    `json:"token,omitempty"`
+}
+
+func toInvitationResponse(inv orgs.Invitation) invitationResponse {
+	return invitationResponse{
+		ID:        inv.ID,
+		OrgID:     inv.OrgID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		InvitedBy: inv.InvitedBy,
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+}
+
+type createOrgRequest struct {
+	Name string `json:"name"`
+}
+
+type inviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+func writeOrgError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, orgs.ErrNotFound), errors.Is(err, orgs.ErrMemberNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, orgs.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, orgs.ErrInvalidInput):
+		http.Error(w, "role must be owner, editor, runner or viewer", http.StatusBadRequest)
+	case errors.Is(err, orgs.ErrLastOwner), errors.Is(err, orgs.ErrAlreadyMember):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, orgs.ErrInvalidInvitation):
+		http.Error(w, "invitation not found or expired", http.StatusNotFound)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// CreateOrgHandler creates an organization owned by the authenticated user.
+func CreateOrgHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req createOrgRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		org, err := svc.Create(ctx, claims.UserID, req.Name)
+		if err != nil {
+			if errors.Is(err, orgs.ErrInvalidInput) {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toOrgResponse(org))
+	}
+}
+
+// ListOrgsHandler returns the organizations the authenticated user belongs to with their role.
+func ListOrgsHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		list, err := svc.List(ctx, claims.UserID)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		resp := make([]orgResponse, 0, len(list))
+		for _, o := range list {
+			resp = append(resp, toOrgResponse(o))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// ListOrgMembersHandler returns the members of an organization.
+func ListOrgMembersHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		members, err := svc.ListMembers(ctx, claims.UserID, chi.URLParam(r, "orgID"))
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		resp := make([]orgMemberResponse, 0, len(members))
+		for _, m := range members {
+			resp = append(resp, orgMemberResponse{UserID: m.UserID, Email: m.Email, Role: m.Role, CreatedAt: m.CreatedAt})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// SetOrgMemberRoleHandler changes a member's role; owners only.
+func SetOrgMemberRoleHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req setRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := svc.SetMemberRole(ctx, claims.UserID, chi.URLParam(r, "orgID"), chi.URLParam(r, "userID"), req.Role); err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RemoveOrgMemberHandler removes a member, or lets the authenticated user leave.
+func RemoveOrgMemberHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := svc.RemoveMember(ctx, claims.UserID, chi.URLParam(r, "orgID"), chi.URLParam(r, "userID")); err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CreateInvitationHandler invites an email address to an organization and returns the
+// invitation token once; delivering it is up to the caller.
+func CreateInvitationHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req inviteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		inv, token, err := svc.Invite(ctx, claims.UserID, chi.URLParam(r, "orgID"), req.Email, req.Role)
+		if err != nil {
+			if errors.Is(err, orgs.ErrInvalidInput) {
+				http.Error(w, "a valid email and role are required", http.StatusBadRequest)
+				return
+			}
+			writeOrgError(w, err)
+			return
+		}
+		resp := toInvitationResponse(inv)
+		resp.Token = token
+		writeJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// ListInvitationsHandler returns an organization's pending invitations; owners only.
+func ListInvitationsHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		invs, err := svc.ListInvitations(ctx, claims.UserID, chi.URLParam(r, "orgID"))
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		resp := make([]invitationResponse, 0, len(invs))
+		for _, inv := range invs {
+			resp = append(resp, toInvitationResponse(inv))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// RevokeInvitationHandler deletes a pending invitation; owners only.
+func RevokeInvitationHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := svc.RevokeInvitation(ctx, claims.UserID, chi.URLParam(r, "orgID"), chi.URLParam(r, "invitationID")); err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AcceptInvitationHandler adds the authenticated user to the organization of an invitation
+// addressed to their email.
+func AcceptInvitationHandler(svc OrgService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req acceptInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		org, err := svc.AcceptInvitation(ctx, claims.UserID, claims.Email, req.Token)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toOrgResponse(org))
+	}
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
+	"github.com/groovypotato/PotaFlow/internal/orgs"
+)
+
+type fakeOrgService struct {
+	org      orgs.Organization
+	accepted *[]string
+	err      error
+}
+
+func (f fakeOrgService) Create(ctx context.Context, userID, name string) (orgs.Organization, error) {
+	if name == "" {
+		return orgs.Organization{}, orgs.ErrInvalidInput
+	}
+	return orgs.Organization{ID: "org-2", Name: name, Role: orgs.RoleOwner}, f.err
+}
+
+func (f fakeOrgService) List(ctx context.Context, userID string) ([]orgs.Organization, error) {
+	return []orgs.Organization{f.org}, f.err
+}
+
+func (f fakeOrgService) ListMembers(ctx context.Context, userID, orgID string) ([]orgs.Member, error) {
+	return []orgs.Member{{UserID: userID, Email: "a@example.com", Role: f.org.Role}}, f.err
+}
+
+func (f fakeOrgService) SetMemberRole(ctx context.Context, userID, orgID, memberID, role string) error {
+	if !orgs.ValidRole(role) {
+		return orgs.ErrInvalidInput
+	}
+	return f.err
+}
+
+func (f fakeOrgService) RemoveMember(ctx context.Context, userID, orgID, memberID string) error {
+	return f.err
+}
+
+func (f fakeOrgService) Invite(ctx context.Context, userID, orgID, email, role string) (orgs.Invitation, string, error) {
+	return orgs.Invitation{ID: "inv-1", OrgID: orgID, Email: email, Role: role}, "invite-token", f.err
+}
+
+func (f fakeOrgService) ListInvitations(ctx context.Context, userID, orgID string) ([]orgs.Invitation, error) {
+	return []orgs.Invitation{{ID: "inv-1", OrgID: orgID}}, f.err
+}
+
+func (f fakeOrgService) RevokeInvitation(ctx context.Context, userID, orgID, invitationID string) error {
+	return f.err
+}
+
+func (f fakeOrgService) AcceptInvitation(ctx context.Context, userID, email, token string) (orgs.Organization, error) {
+	if f.accepted != nil {
+		*f.accepted = append(*f.accepted, email+":"+token)
+	}
+	return f.org, f.err
+}
+
+func orgRouter(svc OrgService) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/orgs", ListOrgsHandler(svc))
+	r.Post("/orgs", CreateOrgHandler(svc))
+	r.Get("/orgs/{orgID}/members", ListOrgMembersHandler(svc))
+	r.Put("/orgs/{orgID}/members/{userID}", SetOrgMemberRoleHandler(svc))
+	r.Delete("/orgs/{orgID}/members/{userID}", RemoveOrgMemberHandler(svc))
+	r.Get("/orgs/{orgID}/invitations", ListInvitationsHandler(svc))
+	r.Post("/orgs/{orgID}/invitations", CreateInvitationHandler(svc))
+	r.Delete("/orgs/{orgID}/invitations/{invitationID}", RevokeInvitationHandler(svc))
+	r.Post("/invitations/accept", AcceptInvitationHandler(svc))
+	return r
+}
+
+func TestOrgHandlers(t *testing.T) {
+	var accepted []string
+	router := orgRouter(fakeOrgService{org: orgs.Organization{ID: "org-1", Name: "Acme", Role: orgs.RoleOwner}, accepted: &accepted})
+	session := auth.Claims{UserID: "u1", Email: "a@example.com", ID: "jti"}
+
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{nethttp.MethodGet, "/orgs", "", nethttp.StatusOK},
+		{nethttp.MethodPost, "/orgs", `{"name":"Acme"}`, nethttp.StatusCreated},
+		{nethttp.MethodPost, "/orgs", `{"name":""}`, nethttp.StatusBadRequest},
+		{nethttp.MethodGet, "/orgs/org-1/members", "", nethttp.StatusOK},
+		{nethttp.MethodPut, "/orgs/org-1/members/u2", `{"role":"runner"}`, nethttp.StatusNoContent},
+		{nethttp.MethodPut, "/orgs/org-1/members/u2", `{"role":"admin"}`, nethttp.StatusBadRequest},
+		{nethttp.MethodDelete, "/orgs/org-1/members/u2", "", nethttp.StatusNoContent},
+		{nethttp.MethodGet, "/orgs/org-1/invitations", "", nethttp.StatusOK},
+		{nethttp.MethodDelete, "/orgs/org-1/invitations/inv-1", "", nethttp.StatusNoContent},
+		{nethttp.MethodPost, "/invitations/accept", `{}`, nethttp.StatusBadRequest},
+		{nethttp.MethodPost, "/invitations/accept", `{"token":"invite-token"}`, nethttp.StatusOK},
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, apiKeyRequest(tc.method, tc.target, tc.body, session))
+		if rr.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.target, tc.want, rr.Code)
+		}
+	}
+	if len(accepted) != 1 || accepted[0] != "a@example.com:invite-token" {
+		t.Fatalf("expected the invitation to be accepted with the session's email, got %v", accepted)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/orgs/org-1/invitations", `{"email":"b@example.com","role":"editor"}`, session))
+	var inv map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&inv)
+	if rr.Code != nethttp.StatusCreated || inv["token"] != "invite-token" || inv["role"] != "editor" {
+		t.Fatalf("unexpected invite response %d %v", rr.Code, inv)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodGet, "/orgs/org-1/invitations", "", session))
+	var invs []map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&invs)
+	if len(invs) != 1 || invs[0]["token"] != nil {
+		t.Fatalf("expected listed invitations to omit the token, got %v", invs)
+	}
+}
+
+func TestOrgHandlers_Errors(t *testing.T) {
+	session := auth.Claims{UserID: "u1", Email: "a@example.com", ID: "jti"}
+	for err, want := range map[error]int{
+		orgs.ErrNotFound:          nethttp.StatusNotFound,
+		orgs.ErrForbidden:         nethttp.StatusForbidden,
+		orgs.ErrLastOwner:         nethttp.StatusConflict,
+		orgs.ErrAlreadyMember:     nethttp.StatusConflict,
+		orgs.ErrInvalidInvitation: nethttp.StatusNotFound,
+	} {
+		rr := httptest.NewRecorder()
+		orgRouter(fakeOrgService{err: err}).ServeHTTP(rr, apiKeyRequest(nethttp.MethodDelete, "/orgs/org-1/members/u2", "", session))
+		if rr.Code != want {
+			t.Errorf("%v: expected %d, got %d", err, want, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	key := auth.Claims{UserID: "u1", APIKeyID: "key-1"}
+	orgRouter(fakeOrgService{}).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/invitations/accept", `{"token":"invite-token"}`, key))
+	if rr.Code != nethttp.StatusForbidden {
+		t.Fatalf("expected api keys to be rejected, got %d", rr.Code)
+	}
+}
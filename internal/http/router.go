@@ -2,38 +2,84 @@ package http
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // NewRouter wires all HTTP routes for the API.
-func NewRouter(db *pgxpool.Pool, authSvc AuthService, wfSvc WorkflowService) chi.Router {
+func NewRouter(db *pgxpool.Pool, authSvc AuthService, wfSvc WorkflowService, orgSvc OrgService) chi.Router {
 	r := chi.NewRouter()
 	r.Get("/health", HealthHandler(db))
 	r.Post("/auth/register", RegisterHandler(authSvc))
 	r.Post("/auth/login", LoginHandler(authSvc))
+	r.Post("/auth/login/totp", LoginTOTPHandler(authSvc))
+	r.Post("/auth/password-reset", RequestPasswordResetHandler(authSvc))
+	r.Post("/auth/password-reset/confirm", ResetPasswordHandler(authSvc))
+	r.Post("/auth/verify-email", VerifyEmailHandler(authSvc))
+	r.Post("/auth/refresh", RefreshHandler(authSvc))
+	r.Post("/hooks/{triggerID}", WebhookHandler(wfSvc))
 
 	r.Group(func(protected chi.Router) {
 		protected.Use(AuthMiddleware(authSvc))
 		protected.Get("/me", MeHandler(authSvc))
+		protected.Post("/auth/logout", LogoutHandler(authSvc))
+		protected.Post("/auth/logout-all", LogoutAllHandler(authSvc))
+		protected.Post("/auth/totp/enroll", EnrollTOTPHandler(authSvc))
+		protected.Post("/auth/totp/confirm", ConfirmTOTPHandler(authSvc))
+		protected.Post("/auth/totp/disable", DisableTOTPHandler(authSvc))
+		protected.Post("/auth/change-password", ChangePasswordHandler(authSvc))
+		protected.Post("/auth/verify-email/resend", ResendVerificationHandler(authSvc))
+		protected.Route("/api-keys", func(keyRouter chi.Router) {
+			keyRouter.Get("/", ListAPIKeysHandler(authSvc))
+			keyRouter.Post("/", CreateAPIKeyHandler(authSvc))
+			keyRouter.Delete("/{id}", DeleteAPIKeyHandler(authSvc))
+		})
+		protected.Route("/orgs", func(orgRouter chi.Router) {
+			orgRouter.Get("/", ListOrgsHandler(orgSvc))
+			orgRouter.Post("/", CreateOrgHandler(orgSvc))
+			orgRouter.Get("/{orgID}/members", ListOrgMembersHandler(orgSvc))
+			orgRouter.Put("/{orgID}/members/{userID}", SetOrgMemberRoleHandler(orgSvc))
+			orgRouter.Delete("/{orgID}/members/{userID}", RemoveOrgMemberHandler(orgSvc))
+			orgRouter.Get("/{orgID}/invitations", ListInvitationsHandler(orgSvc))
+			orgRouter.Post("/{orgID}/invitations", CreateInvitationHandler(orgSvc))
+			orgRouter.Delete("/{orgID}/invitations/{invitationID}", RevokeInvitationHandler(orgSvc))
+		})
+		protected.Post("/invitations/accept", AcceptInvitationHandler(orgSvc))
+		protected.Route("/admin", func(adminRouter chi.Router) {
+			adminRouter.Use(RequireRole(auth.RoleAdmin))
+			adminRouter.Get("/users", ListUsersHandler(authSvc))
+			adminRouter.Put("/users/{id}/role", SetUserRoleHandler(authSvc))
+			adminRouter.Post("/users/{id}/disable", DisableUserHandler(authSvc))
+			adminRouter.Post("/users/{id}/enable", EnableUserHandler(authSvc))
+			adminRouter.Post("/users/{id}/logout-all", LogoutUserHandler(authSvc))
+			adminRouter.Get("/workflows", ListAllWorkflowsHandler(wfSvc))
+			adminRouter.Get("/runs", ListAllRunsHandler(wfSvc))
+			adminRouter.Get("/audit-events", ListAuditEventsHandler(authSvc))
+		})
+		protected.Get("/catalog", CatalogHandler(wfSvc))
 		protected.Route("/workflows", func(workflowRouter chi.Router) {
-			workflowRouter.Get("/", ListWorkflowsHandler(wfSvc))
-			workflowRouter.Post("/", CreateWorkflowHandler(wfSvc))
-			workflowRouter.Get("/{id}", GetWorkflowHandler(wfSvc))
-			workflowRouter.Put("/{id}", UpdateWorkflowHandler(wfSvc))
-			workflowRouter.Delete("/{id}", DeleteWorkflowHandler(wfSvc))
-			workflowRouter.Post("/{id}/run", EnqueueRunHandler(wfSvc))
-			workflowRouter.Get("/{id}/runs", ListRunsHandler(wfSvc))
+			read := workflowRouter.With(RequireScope(auth.ScopeWorkflowsRead))
+			write := workflowRouter.With(RequireScope(auth.ScopeWorkflowsWrite))
+			read.Get("/", ListWorkflowsHandler(wfSvc))
+			write.Post("/", CreateWorkflowHandler(wfSvc))
+			read.Get("/{id}", GetWorkflowHandler(wfSvc))
+			write.Put("/{id}", UpdateWorkflowHandler(wfSvc))
+			write.Delete("/{id}", DeleteWorkflowHandler(wfSvc))
+			workflowRouter.With(RequireScope(auth.ScopeRunsTrigger)).Post("/{id}/run", EnqueueRunHandler(wfSvc))
+			workflowRouter.With(RequireScope(auth.ScopeRunsRead)).Get("/{id}/runs", ListRunsHandler(wfSvc))
+			write.Get("/{id}/signing-secret", GetSigningSecretHandler(wfSvc))
+			write.Post("/{id}/signing-secret/rotate", RotateSigningSecretHandler(wfSvc))
 			workflowRouter.Route("/{workflowID}/triggers", func(trigRouter chi.Router) {
-				trigRouter.Get("/", ListTriggersHandler(wfSvc))
-				trigRouter.Post("/", CreateTriggerHandler(wfSvc))
-				trigRouter.Put("/{triggerID}", UpdateTriggerHandler(wfSvc))
-				trigRouter.Delete("/{triggerID}", DeleteTriggerHandler(wfSvc))
+				trigRouter.With(RequireScope(auth.ScopeWorkflowsRead)).Get("/", ListTriggersHandler(wfSvc))
+				trigRouter.With(RequireScope(auth.ScopeWorkflowsWrite)).Post("/", CreateTriggerHandler(wfSvc))
+				trigRouter.With(RequireScope(auth.ScopeWorkflowsWrite)).Put("/{triggerID}", UpdateTriggerHandler(wfSvc))
+				trigRouter.With(RequireScope(auth.ScopeWorkflowsWrite)).Delete("/{triggerID}", DeleteTriggerHandler(wfSvc))
 			})
 			workflowRouter.Route("/{workflowID}/actions", func(actRouter chi.Router) {
-				actRouter.Get("/", ListActionsHandler(wfSvc))
-				actRouter.Post("/", CreateActionHandler(wfSvc))
-				actRouter.Put("/{actionID}", UpdateActionHandler(wfSvc))
-				actRouter.Delete("/{actionID}", DeleteActionHandler(wfSvc))
+				actRouter.With(RequireScope(auth.ScopeWorkflowsRead)).Get("/", ListActionsHandler(wfSvc))
+				actRouter.With(RequireScope(auth.ScopeWorkflowsWrite)).Post("/", CreateActionHandler(wfSvc))
+				actRouter.With(RequireScope(auth.ScopeWorkflowsWrite)).Put("/{actionID}", UpdateActionHandler(wfSvc))
+				actRouter.With(RequireScope(auth.ScopeWorkflowsWrite)).Delete("/{actionID}", DeleteActionHandler(wfSvc))
 			})
 		})
 	})
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/auth"
+)
+
+// totpChallengeResponse answers a correct password for an account with two-factor
+// authentication; the challenge token goes to /auth/login/totp with a code.
+type totpChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"challenge_expires_at"`
+}
+
+type loginTOTPRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type disableTOTPRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginTOTPHandler finishes a two-factor login with the challenge token from LoginHandler and a
+// TOTP or recovery code, and returns the same tokens as a password login.
+func LoginTOTPHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req loginTOTPRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON body"})
+			return
+		}
+
+		if req.ChallengeToken == "" || req.Code == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "challenge_token and code are required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, tokens, err := authSvc.LoginTOTP(ctx, req.ChallengeToken, req.Code, clientIP(r))
+		if err != nil {
+			if writeThrottled(w, err) {
+				return
+			}
+			switch err {
+			case auth.ErrInvalidChallenge:
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid or expired challenge"})
+			case auth.ErrInvalidTOTPCode:
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid two-factor code"})
+			case auth.ErrAccountDisabled:
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "account disabled"})
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(newLoginResponse(user, tokens))
+	}
+}
+
+// EnrollTOTPHandler starts two-factor enrollment and returns the secret and its otpauth:// URI.
+func EnrollTOTPHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		enrollment, err := authSvc.EnrollTOTP(ctx, claims.UserID)
+		if err != nil {
+			writeTOTPError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, totpEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+	}
+}
+
+// ConfirmTOTPHandler enables two-factor authentication with a code from the enrolled app and
+// returns the recovery codes, which are never shown again.
+func ConfirmTOTPHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req totpCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			http.Error(w, "code is required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		codes, err := authSvc.ConfirmTOTP(ctx, claims.UserID, req.Code)
+		if err != nil {
+			writeTOTPError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// DisableTOTPHandler turns two-factor authentication off; it needs the current password and a
+// TOTP or recovery code. Wrong ones count as failed logins, so it can be throttled with 429.
+func DisableTOTPHandler(authSvc AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireSession(w, r)
+		if !ok {
+			return
+		}
+
+		var req disableTOTPRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+			http.Error(w, "password is required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		if err := authSvc.DisableTOTP(ctx, claims.UserID, req.Password, req.Code, clientIP(r)); err != nil {
+			if writeThrottled(w, err) {
+				return
+			}
+			writeTOTPError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeTOTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTOTPCode):
+		http.Error(w, "invalid two-factor code", http.StatusBadRequest)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		http.Error(w, "password is incorrect", http.StatusForbidden)
+	case errors.Is(err, auth.ErrTOTPAlreadyEnabled), errors.Is(err, auth.ErrTOTPNotEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to update two-factor authentication", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/auth"
+)
+
+func (f fakeAuthService) LoginTOTP(ctx context.Context, challengeToken, code, clientIP string) (auth.User, auth.TokenPair, error) {
+	if f.loggedOut != nil {
+		*f.loggedOut = append(*f.loggedOut, "totp:"+challengeToken+":"+code)
+	}
+	return f.user, auth.TokenPair{AccessToken: f.token}, f.err
+}
+
+func (f fakeAuthService) EnrollTOTP(ctx context.Context, userID string) (auth.TOTPEnrollment, error) {
+	return auth.TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/PotaFlow:a@example.com?secret=SECRET"}, f.err
+}
+
+func (f fakeAuthService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	return []string{"aaaa-bbbb-cccc-dddd"}, f.err
+}
+
+func (f fakeAuthService) DisableTOTP(ctx context.Context, userID, password, code, clientIP string) error {
+	return f.err
+}
+
+func totpRouter(svc AuthService) chi.Router {
+	r := chi.NewRouter()
+	r.Post("/auth/totp/enroll", EnrollTOTPHandler(svc))
+	r.Post("/auth/totp/confirm", ConfirmTOTPHandler(svc))
+	r.Post("/auth/totp/disable", DisableTOTPHandler(svc))
+	return r
+}
+
+func TestLoginHandler_TOTPRequired(t *testing.T) {
+	expires := time.Unix(1700000000, 0).UTC()
+	req := httptest.NewRequest(nethttp.MethodPost, "/auth/login", bytes.NewBufferString(`{"email":"a@example.com","password":"secret"}`))
+	rr := httptest.NewRecorder()
+
+	LoginHandler(fakeAuthService{err: &auth.TOTPRequiredError{ChallengeToken: "challenge", ExpiresAt: expires}}).ServeHTTP(rr, req)
+
+	var resp map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != nethttp.StatusOK || resp["two_factor_required"] != true || resp["challenge_token"] != "challenge" || resp["token"] != nil {
+		t.Fatalf("unexpected response %d %v", rr.Code, resp)
+	}
+}
+
+func TestLoginTOTPHandler(t *testing.T) {
+	var calls []string
+	req := httptest.NewRequest(nethttp.MethodPost, "/auth/login/totp", bytes.NewBufferString(`{"challenge_token":"challenge","code":"123456"}`))
+	rr := httptest.NewRecorder()
+
+	LoginTOTPHandler(fakeAuthService{token: "signed-token", loggedOut: &calls}).ServeHTTP(rr, req)
+
+	var resp map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != nethttp.StatusOK || resp["token"] != "signed-token" {
+		t.Fatalf("unexpected response %d %v", rr.Code, resp)
+	}
+	if len(calls) != 1 || calls[0] != "totp:challenge:123456" {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	for body, want := range map[string]int{
+		`{"challenge_token":"challenge"}`: nethttp.StatusBadRequest,
+		`not json`:                        nethttp.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		LoginTOTPHandler(fakeAuthService{}).ServeHTTP(rr, httptest.NewRequest(nethttp.MethodPost, "/auth/login/totp", bytes.NewBufferString(body)))
+		if rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, rr.Code)
+		}
+	}
+
+	for err, want := range map[error]int{
+		auth.ErrInvalidChallenge:                      nethttp.StatusUnauthorized,
+		auth.ErrInvalidTOTPCode:                       nethttp.StatusUnauthorized,
+		auth.ErrAccountDisabled:                       nethttp.StatusForbidden,
+		&auth.ThrottledError{RetryAfter: time.Second}: nethttp.StatusTooManyRequests,
+	} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(nethttp.MethodPost, "/auth/login/totp", bytes.NewBufferString(`{"challenge_token":"challenge","code":"123456"}`))
+		LoginTOTPHandler(fakeAuthService{err: err}).ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("%v: expected %d, got %d", err, want, rr.Code)
+		}
+	}
+}
+
+func TestTOTPHandlers(t *testing.T) {
+	session := auth.Claims{UserID: "u1", Email: "a@example.com", ID: "jti"}
+	router := totpRouter(fakeAuthService{})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/totp/enroll", "", session))
+	var enrollment map[string]any
+	_ = json.NewDecoder(rr.Body).Decode(&enrollment)
+	if rr.Code != nethttp.StatusOK || enrollment["secret"] != "SECRET" || enrollment["uri"] == "" {
+		t.Fatalf("unexpected enroll response %d %v", rr.Code, enrollment)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, "/auth/totp/confirm", `{"code":"123456"}`, session))
+	var confirmed map[string][]string
+	_ = json.NewDecoder(rr.Body).Decode(&confirmed)
+	if rr.Code != nethttp.StatusOK || len(confirmed["recovery_codes"]) != 1 {
+		t.Fatalf("unexpected confirm response %d %v", rr.Code, confirmed)
+	}
+
+	for _, tc := range []struct {
+		svc    fakeAuthService
+		target string
+		body   string
+		claims auth.Claims
+		want   int
+	}{
+		{fakeAuthService{}, "/auth/totp/confirm", `{}`, session, nethttp.StatusBadRequest},
+		{fakeAuthService{err: auth.ErrInvalidTOTPCode}, "/auth/totp/confirm", `{"code":"000000"}`, session, nethttp.StatusBadRequest},
+		{fakeAuthService{err: auth.ErrTOTPAlreadyEnabled}, "/auth/totp/enroll", "", session, nethttp.StatusConflict},
+		{fakeAuthService{}, "/auth/totp/disable", `{"password":"pw","code":"123456"}`, session, nethttp.StatusNoContent},
+		{fakeAuthService{}, "/auth/totp/disable", `{"code":"123456"}`, session, nethttp.StatusBadRequest},
+		{fakeAuthService{err: auth.ErrTOTPNotEnabled}, "/auth/totp/disable", `{"password":"pw","code":"123456"}`, session, nethttp.StatusConflict},
+		{fakeAuthService{err: auth.ErrInvalidCredentials}, "/auth/totp/disable", `{"password":"wrong","code":"123456"}`, session, nethttp.StatusForbidden},
+		{fakeAuthService{err: &auth.ThrottledError{RetryAfter: time.Second}}, "/auth/totp/disable", `{"password":"pw","code":"123456"}`, session, nethttp.StatusTooManyRequests},
+		{fakeAuthService{}, "/auth/totp/enroll", "", auth.Claims{UserID: "u1", APIKeyID: "key-1"}, nethttp.StatusForbidden},
+	} {
+		rr := httptest.NewRecorder()
+		totpRouter(tc.svc).ServeHTTP(rr, apiKeyRequest(nethttp.MethodPost, tc.target, tc.body, tc.claims))
+		if rr.Code != tc.want {
+			t.Errorf("%s %s (%v): expected %d, got %d", tc.target, tc.body, tc.svc.err, tc.want, rr.Code)
+		}
+	}
+}
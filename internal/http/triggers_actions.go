@@ -2,9 +2,11 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/catalog"
 	"github.com/groovypotato/PotaFlow/internal/workflows"
 )
 
@@ -193,10 +195,42 @@ func DeleteActionHandler(svc WorkflowService) http.HandlerFunc {
 	}
 }
 
+// CatalogHandler lists the trigger and action types with their config schemas.
+func CatalogHandler(svc WorkflowService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireClaims(w, r); !ok {
+			return
+		}
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		entries, err := svc.Catalog(ctx)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+	}
+}
+
+type validationErrorResponse struct {
+	Error  string               `json:"error"`
+	Fields []catalog.FieldError `json:"fields"`
+}
+
 func writeWorkflowError(w http.ResponseWriter, err error) {
+	var verr *catalog.ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, http.StatusUnprocessableEntity, validationErrorResponse{Error: "invalid config", Fields: verr.Fields})
+		return
+	}
 	switch err {
-	case workflows.ErrNotFound, workflows.ErrTriggerNotFound, workflows.ErrActionNotFound:
+	case workflows.ErrNotFound, workflows.ErrTriggerNotFound, workflows.ErrActionNotFound, workflows.ErrRunNotFound, workflows.ErrOrgNotFound:
 		http.Error(w, err.Error(), http.StatusNotFound)
+	case workflows.ErrForbidden:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case workflows.ErrWorkflowDisabled:
+		http.Error(w, err.Error(), http.StatusConflict)
 	default:
 		http.Error(w, "internal error", http.StatusInternalServerError)
 	}
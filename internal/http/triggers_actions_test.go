@@ -3,11 +3,13 @@ package http
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/catalog"
 	"github.com/groovypotato/PotaFlow/internal/workflows"
 )
 
@@ -48,6 +50,42 @@ func TestCreateActionHandler_Validation(t *testing.T) {
 	}
 }
 
+func TestCreateTriggerHandler_InvalidConfig(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/workflows/1/triggers", bytes.NewBufferString(`{"type":"cron","config":{}}`))
+	req = withClaims(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("workflowID", "wf-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	verr := &catalog.ValidationError{Fields: []catalog.FieldError{{Field: "config.cron_expr", Message: "is required"}}}
+	CreateTriggerHandler(fakeWorkflowService{err: verr}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	var body validationErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Fields) != 1 || body.Fields[0].Field != "config.cron_expr" {
+		t.Fatalf("unexpected field errors: %+v", body.Fields)
+	}
+}
+
+func TestCatalogHandler(t *testing.T) {
+	req := withClaims(httptest.NewRequest(http.MethodGet, "/catalog", nil))
+	rr := httptest.NewRecorder()
+
+	CatalogHandler(fakeWorkflowService{}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var entries []catalog.Entry
+	if err := json.NewDecoder(rr.Body).Decode(&entries); err != nil || len(entries) != 1 || entries[0].Type != "cron" {
+		t.Fatalf("unexpected catalog %+v (err %v)", entries, err)
+	}
+}
+
 func TestDeleteTriggerHandler_NotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodDelete, "/workflows/wf-1/triggers/tr-1", nil)
 	req = withClaims(req)
@@ -16,11 +16,16 @@ type WorkflowService interface {
 	workflows.TriggerManager
 	workflows.ActionManager
 	workflows.RunManager
+	workflows.SigningSecretManager
+	workflows.WebhookManager
+	workflows.CatalogLister
+	workflows.InstanceViewer
 }
 
 type workflowResponse struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
+	OrgID     string    `json:"org_id"`
 	Name      string    `json:"name"`
 	IsEnabled bool      `json:"is_enabled"`
 	CreatedAt time.Time `json:"created_at"`
@@ -31,6 +36,7 @@ func toWorkflowResponse(w workflows.Workflow) workflowResponse {
 	return workflowResponse{
 		ID:        w.ID,
 		UserID:    w.UserID,
+		OrgID:     w.OrgID,
 		Name:      w.Name,
 		IsEnabled: w.IsEnabled,
 		CreatedAt: w.CreatedAt,
@@ -39,7 +45,8 @@ func toWorkflowResponse(w workflows.Workflow) workflowResponse {
 }
 
 type createWorkflowRequest struct {
-	Name string `json:"name"`
+	Name  string `json:"name"`
+	OrgID string `json:"org_id"`
 }
 
 type updateWorkflowRequest struct {
@@ -47,7 +54,8 @@ type updateWorkflowRequest struct {
 	IsEnabled bool   `json:"is_enabled"`
 }
 
-// CreateWorkflowHandler inserts a new workflow for the authenticated user.
+// CreateWorkflowHandler inserts a new workflow into the requested organization, or into the
+// authenticated user's default one.
 func CreateWorkflowHandler(svc WorkflowService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := requireClaims(w, r)
@@ -64,9 +72,16 @@ func CreateWorkflowHandler(svc WorkflowService) http.HandlerFunc {
 		ctx, cancel := withTimeout(r.Context())
 		defer cancel()
 
-		wf, err := svc.Create(ctx, claims.UserID, req.Name)
+		wf, err := svc.Create(ctx, claims.UserID, req.OrgID, req.Name)
 		if err != nil {
-			http.Error(w, "failed to create workflow", http.StatusInternalServerError)
+			switch {
+			case errors.Is(err, workflows.ErrOrgNotFound):
+				http.Error(w, "organization not found", http.StatusNotFound)
+			case errors.Is(err, workflows.ErrForbidden):
+				http.Error(w, "forbidden", http.StatusForbidden)
+			default:
+				http.Error(w, "failed to create workflow", http.StatusInternalServerError)
+			}
 			return
 		}
 
@@ -74,7 +89,7 @@ func CreateWorkflowHandler(svc WorkflowService) http.HandlerFunc {
 	}
 }
 
-// ListWorkflowsHandler returns workflows for the authenticated user.
+// ListWorkflowsHandler returns the workflows of every organization the authenticated user belongs to.
 func ListWorkflowsHandler(svc WorkflowService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := requireClaims(w, r)
@@ -93,6 +108,9 @@ func ListWorkflowsHandler(svc WorkflowService) http.HandlerFunc {
 
 		resp := make([]workflowResponse, 0, len(wfs))
 		for _, wf := range wfs {
+			if !claims.AllowsWorkflow(wf.ID) {
+				continue
+			}
 			resp = append(resp, toWorkflowResponse(wf))
 		}
 
@@ -156,9 +174,12 @@ func UpdateWorkflowHandler(svc WorkflowService) http.HandlerFunc {
 
 		wf, err := svc.Update(ctx, claims.UserID, wfID, req.Name, req.IsEnabled)
 		if err != nil {
-			if errors.Is(err, workflows.ErrNotFound) {
+			switch {
+			case errors.Is(err, workflows.ErrNotFound):
 				http.Error(w, "not found", http.StatusNotFound)
-			} else {
+			case errors.Is(err, workflows.ErrForbidden):
+				http.Error(w, "forbidden", http.StatusForbidden)
+			default:
 				http.Error(w, "failed to update workflow", http.StatusInternalServerError)
 			}
 			return
@@ -186,9 +207,12 @@ func DeleteWorkflowHandler(svc WorkflowService) http.HandlerFunc {
 		defer cancel()
 
 		if err := svc.Delete(ctx, claims.UserID, wfID); err != nil {
-			if errors.Is(err, workflows.ErrNotFound) {
+			switch {
+			case errors.Is(err, workflows.ErrNotFound):
 				http.Error(w, "not found", http.StatusNotFound)
-			} else {
+			case errors.Is(err, workflows.ErrForbidden):
+				http.Error(w, "forbidden", http.StatusForbidden)
+			default:
 				http.Error(w, "failed to delete workflow", http.StatusInternalServerError)
 			}
 			return
@@ -244,3 +268,47 @@ func ListRunsHandler(svc WorkflowService) http.HandlerFunc {
 		writeJSON(w, http.StatusOK, runs)
 	}
 }
+
+type signingSecretResponse struct {
+	SigningSecret string `json:"signing_secret"`
+}
+
+// GetSigningSecretHandler returns the secret outgoing HTTP actions of the workflow are signed with.
+func GetSigningSecretHandler(svc WorkflowService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireClaims(w, r)
+		if !ok {
+			return
+		}
+		wfID := chi.URLParam(r, "id")
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		secret, err := svc.SigningSecret(ctx, claims.UserID, wfID)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, signingSecretResponse{SigningSecret: secret})
+	}
+}
+
+// RotateSigningSecretHandler replaces the workflow's signing secret and returns the new one.
+func RotateSigningSecretHandler(svc WorkflowService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireClaims(w, r)
+		if !ok {
+			return
+		}
+		wfID := chi.URLParam(r, "id")
+		ctx, cancel := withTimeout(r.Context())
+		defer cancel()
+
+		secret, err := svc.RotateSigningSecret(ctx, claims.UserID, wfID)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, signingSecretResponse{SigningSecret: secret})
+	}
+}
@@ -10,15 +10,21 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/groovypotato/PotaFlow/internal/catalog"
 	"github.com/groovypotato/PotaFlow/internal/workflows"
 )
 
 type fakeWorkflowService struct {
-	wf  workflows.Workflow
-	err error
+	wf      workflows.Workflow
+	trigger workflows.Trigger
+	run     workflows.WorkflowRun
+	input   *[]byte
+	rejects *int
+	filter  *workflows.RunFilter
+	err     error
 }
 
-func (f fakeWorkflowService) Create(ctx context.Context, userID, name string) (workflows.Workflow, error) {
+func (f fakeWorkflowService) Create(ctx context.Context, userID, orgID, name string) (workflows.Workflow, error) {
 	w := f.wf
 	if w.ID == "" {
 		w.ID = "wf-1"
@@ -26,6 +32,9 @@ func (f fakeWorkflowService) Create(ctx context.Context, userID, name string) (w
 	if w.UserID == "" {
 		w.UserID = userID
 	}
+	if w.OrgID == "" {
+		w.OrgID = orgID
+	}
 	if w.Name == "" {
 		w.Name = name
 	}
@@ -90,6 +99,35 @@ func (f fakeWorkflowService) ListRuns(ctx context.Context, userID, workflowID st
 	return nil, f.err
 }
 
+func (f fakeWorkflowService) SigningSecret(ctx context.Context, userID, workflowID string) (string, error) {
+	return "secret-1", f.err
+}
+func (f fakeWorkflowService) RotateSigningSecret(ctx context.Context, userID, workflowID string) (string, error) {
+	return "secret-2", f.err
+}
+
+func (f fakeWorkflowService) WebhookTrigger(ctx context.Context, triggerID string) (workflows.Trigger, error) {
+	return f.trigger, f.err
+}
+func (f fakeWorkflowService) EnqueueTriggeredRun(ctx context.Context, trigger workflows.Trigger, input []byte) (workflows.WorkflowRun, error) {
+	if f.input != nil {
+		*f.input = input
+	}
+	return workflows.WorkflowRun{ID: "run-1", WorkflowID: trigger.WorkflowID, Status: "pending"}, f.err
+}
+func (f fakeWorkflowService) RecordRejection(ctx context.Context, triggerID string) error {
+	if f.rejects != nil {
+		*f.rejects++
+	}
+	return nil
+}
+func (f fakeWorkflowService) GetRun(ctx context.Context, runID string) (workflows.WorkflowRun, error) {
+	return f.run, f.err
+}
+func (f fakeWorkflowService) Catalog(ctx context.Context) ([]catalog.Entry, error) {
+	return []catalog.Entry{{Kind: catalog.KindTrigger, Type: "cron", ConfigSchema: json.RawMessage(`{"type":"object"}`)}}, f.err
+}
+
 func TestCreateWorkflowHandler_Unauthorized(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/workflows", bytes.NewBufferString(`{"name":"wf"}`))
 	rr := httptest.NewRecorder()
@@ -118,6 +156,50 @@ func TestCreateWorkflowHandler_Success(t *testing.T) {
 	}
 }
 
+func TestCreateWorkflowHandler_Organization(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/workflows", bytes.NewBufferString(`{"name":"wf","org_id":"org-1"}`))
+	req = withClaims(req)
+	rr := httptest.NewRecorder()
+
+	CreateWorkflowHandler(fakeWorkflowService{}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp["org_id"] != "org-1" {
+		t.Fatalf("unexpected org_id: %v", resp["org_id"])
+	}
+
+	for err, want := range map[error]int{
+		workflows.ErrOrgNotFound: http.StatusNotFound,
+		workflows.ErrForbidden:   http.StatusForbidden,
+	} {
+		req := withClaims(httptest.NewRequest(http.MethodPost, "/workflows", bytes.NewBufferString(`{"name":"wf","org_id":"org-1"}`)))
+		rr := httptest.NewRecorder()
+		CreateWorkflowHandler(fakeWorkflowService{err: err}).ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Fatalf("%v: expected %d, got %d", err, want, rr.Code)
+		}
+	}
+}
+
+func TestUpdateWorkflowHandler_Forbidden(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/workflows/wf-1", bytes.NewBufferString(`{"name":"wf"}`))
+	req = withClaims(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "wf-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	UpdateWorkflowHandler(fakeWorkflowService{err: workflows.ErrForbidden}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
 func TestGetWorkflowHandler_NotFound(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/workflows/wf-1", nil)
 	req = withClaims(req)
@@ -135,3 +217,38 @@ func TestGetWorkflowHandler_NotFound(t *testing.T) {
 		t.Fatalf("expected 404, got %d", rr.Code)
 	}
 }
+
+func TestRotateSigningSecretHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/workflows/wf-1/signing-secret/rotate", nil)
+	req = withClaims(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "wf-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	RotateSigningSecretHandler(fakeWorkflowService{}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var resp map[string]string
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp["signing_secret"] != "secret-2" {
+		t.Fatalf("unexpected secret: %v", resp)
+	}
+}
+
+func TestGetSigningSecretHandler_NotFound(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/workflows/wf-1/signing-secret", nil)
+	req = withClaims(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "wf-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	GetSigningSecretHandler(fakeWorkflowService{err: workflows.ErrNotFound}).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
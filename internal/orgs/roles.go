@@ -0,0 +1,43 @@
+package orgs
+
+// Organization roles, from most to least privileged.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleRunner = "runner"
+	RoleViewer = "viewer"
+)
+
+// Permission is something a member may do within an organization.
+type Permission int
+
+const (
+	// PermView allows reading workflows, runs and the member list.
+	PermView Permission = iota
+	// PermRun allows triggering workflow runs and listing the triggers that start them.
+	PermRun
+	// PermEdit allows creating, changing and deleting workflows.
+	PermEdit
+	// PermManage allows managing members and invitations.
+	PermManage
+)
+
+// roleRank maps each role to the highest permission it grants.
+var roleRank = map[string]Permission{
+	RoleViewer: PermView,
+	RoleRunner: PermRun,
+	RoleEditor: PermEdit,
+	RoleOwner:  PermManage,
+}
+
+// ValidRole reports whether role is a known organization role.
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Can reports whether a member with role is granted perm.
+func Can(role string, perm Permission) bool {
+	rank, ok := roleRank[role]
+	return ok && rank >= perm
+}
@@ -0,0 +1,335 @@
+package orgs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Organization is an organization as seen by one of its members; Role is that member's role.
+type Organization struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+	Role      string
+}
+
+// Member is a user's membership of an organization.
+type Member struct {
+	UserID    string
+	Email     string
+	Role      string
+	CreatedAt time.Time
+}
+
+// Invitation asks whoever signs in with Email to join OrgID with Role.
+type Invitation struct {
+	ID         string
+	OrgID      string
+	Email      string
+	Role       string
+	InvitedBy  string
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	CreatedAt  time.Time
+}
+
+var (
+	ErrNotFound          = errors.New("organization not found")
+	ErrMemberNotFound    = errors.New("member not found")
+	ErrForbidden         = errors.New("insufficient organization role")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrLastOwner         = errors.New("organization must keep an owner")
+	ErrAlreadyMember     = errors.New("already a member")
+	ErrInvalidInvitation = errors.New("invalid invitation")
+)
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = 7 * 24 * time.Hour
+
+// Service manages organizations, their members and invitations.
+type Service struct {
+	queries queryProvider
+	now     func() time.Time
+}
+
+type queryProvider interface {
+	CreateOrganization(ctx context.Context, arg sqlc.CreateOrganizationParams) (sqlc.CreateOrganizationRow, error)
+	ListOrganizationsForUser(ctx context.Context, userID string) ([]sqlc.ListOrganizationsForUserRow, error)
+	GetOrganizationForMember(ctx context.Context, arg sqlc.GetOrganizationForMemberParams) (sqlc.GetOrganizationForMemberRow, error)
+
+	ListOrganizationMembers(ctx context.Context, orgID string) ([]sqlc.ListOrganizationMembersRow, error)
+	UpdateOrganizationMemberRole(ctx context.Context, arg sqlc.UpdateOrganizationMemberRoleParams) (int64, error)
+	DeleteOrganizationMember(ctx context.Context, arg sqlc.DeleteOrganizationMemberParams) (int64, error)
+
+	CreateOrganizationInvitation(ctx context.Context, arg sqlc.CreateOrganizationInvitationParams) (sqlc.CreateOrganizationInvitationRow, error)
+	ListOrganizationInvitations(ctx context.Context, orgID string) ([]sqlc.ListOrganizationInvitationsRow, error)
+	GetOrganizationInvitationByHash(ctx context.Context, tokenHash string) (sqlc.GetOrganizationInvitationByHashRow, error)
+	DeleteOrganizationInvitation(ctx context.Context, arg sqlc.DeleteOrganizationInvitationParams) (int64, error)
+	AcceptOrganizationInvitation(ctx context.Context, arg sqlc.AcceptOrganizationInvitationParams) (int64, error)
+}
+
+// NewService builds a Service from a sqlc DBTX (e.g., *pgxpool.Pool).
+func NewService(db sqlc.DBTX) *Service {
+	return &Service{queries: sqlc.New(db), now: time.Now}
+}
+
+// Create starts an organization with userID as its owner.
+func (s *Service) Create(ctx context.Context, userID, name string) (Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Organization{}, ErrInvalidInput
+	}
+	row, err := s.queries.CreateOrganization(ctx, sqlc.CreateOrganizationParams{Name: name, UserID: userID})
+	if err != nil {
+		return Organization{}, err
+	}
+	return Organization{ID: row.ID, Name: row.Name, CreatedAt: row.CreatedAt.Time, Role: RoleOwner}, nil
+}
+
+// List returns the organizations userID belongs to, oldest first.
+func (s *Service) List(ctx context.Context, userID string) ([]Organization, error) {
+	rows, err := s.queries.ListOrganizationsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Organization, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, Organization{ID: row.ID, Name: row.Name, CreatedAt: row.CreatedAt.Time, Role: row.Role})
+	}
+	return out, nil
+}
+
+// Get returns an organization userID belongs to; other organizations are reported as missing.
+func (s *Service) Get(ctx context.Context, userID, orgID string) (Organization, error) {
+	row, err := s.queries.GetOrganizationForMember(ctx, sqlc.GetOrganizationForMemberParams{ID: orgID, UserID: userID})
+	if err != nil {
+		if missingRow(err) {
+			return Organization{}, ErrNotFound
+		}
+		return Organization{}, err
+	}
+	return Organization{ID: row.ID, Name: row.Name, CreatedAt: row.CreatedAt.Time, Role: row.Role}, nil
+}
+
+// authorize returns the organization if userID's role in it grants perm.
+func (s *Service) authorize(ctx context.Context, userID, orgID string, perm Permission) (Organization, error) {
+	org, err := s.Get(ctx, userID, orgID)
+	if err != nil {
+		return Organization{}, err
+	}
+	if !Can(org.Role, perm) {
+		return Organization{}, ErrForbidden
+	}
+	return org, nil
+}
+
+// ListMembers returns the members of an organization userID belongs to.
+func (s *Service) ListMembers(ctx context.Context, userID, orgID string) ([]Member, error) {
+	if _, err := s.authorize(ctx, userID, orgID, PermView); err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListOrganizationMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Member, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, Member{UserID: row.UserID, Email: row.Email, Role: row.Role, CreatedAt: row.CreatedAt.Time})
+	}
+	return out, nil
+}
+
+// SetMemberRole changes a member's role; only owners may, and the last owner can't be demoted.
+func (s *Service) SetMemberRole(ctx context.Context, userID, orgID, memberID, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidInput
+	}
+	if _, err := s.authorize(ctx, userID, orgID, PermManage); err != nil {
+		return err
+	}
+	n, err := s.queries.UpdateOrganizationMemberRole(ctx, sqlc.UpdateOrganizationMemberRoleParams{OrgID: orgID, UserID: memberID, Role: role})
+	if err != nil {
+		if missingRow(err) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if n == 0 {
+		return s.unchanged(ctx, orgID, memberID)
+	}
+	return nil
+}
+
+// RemoveMember removes a member. Owners may remove anyone and every member may leave, as long
+// as the organization keeps an owner.
+func (s *Service) RemoveMember(ctx context.Context, userID, orgID, memberID string) error {
+	perm := PermManage
+	if memberID == userID {
+		perm = PermView
+	}
+	if _, err := s.authorize(ctx, userID, orgID, perm); err != nil {
+		return err
+	}
+	n, err := s.queries.DeleteOrganizationMember(ctx, sqlc.DeleteOrganizationMemberParams{OrgID: orgID, UserID: memberID})
+	if err != nil {
+		if missingRow(err) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if n == 0 {
+		return s.unchanged(ctx, orgID, memberID)
+	}
+	return nil
+}
+
+// unchanged explains why a role change or removal touched no row: the queries refuse to demote
+// or remove the last owner, atomically, so a member that is still there is that owner.
+func (s *Service) unchanged(ctx context.Context, orgID, memberID string) error {
+	member, err := s.Get(ctx, memberID, orgID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role == RoleOwner {
+		return ErrLastOwner
+	}
+	return ErrMemberNotFound
+}
+
+// Invite creates an invitation and returns it with the token that redeems it, which is only
+// stored hashed. Only owners may invite.
+func (s *Service) Invite(ctx context.Context, userID, orgID, email, role string) (Invitation, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || !strings.Contains(email, "@") || !ValidRole(role) {
+		return Invitation{}, "", ErrInvalidInput
+	}
+	if _, err := s.authorize(ctx, userID, orgID, PermManage); err != nil {
+		return Invitation{}, "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Invitation{}, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	row, err := s.queries.CreateOrganizationInvitation(ctx, sqlc.CreateOrganizationInvitationParams{
+		OrgID:     orgID,
+		Email:     email,
+		Role:      role,
+		TokenHash: hashToken(token),
+		InvitedBy: userID,
+		ExpiresAt: pgtype.Timestamptz{Time: s.now().Add(invitationTTL), Valid: true},
+	})
+	if err != nil {
+		return Invitation{}, "", err
+	}
+	return invitationFromRow(sqlc.ListOrganizationInvitationsRow(row)), token, nil
+}
+
+// ListInvitations returns an organization's pending invitations; only owners may list them.
+func (s *Service) ListInvitations(ctx context.Context, userID, orgID string) ([]Invitation, error) {
+	if _, err := s.authorize(ctx, userID, orgID, PermManage); err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListOrganizationInvitations(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Invitation, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, invitationFromRow(row))
+	}
+	return out, nil
+}
+
+// RevokeInvitation deletes a pending invitation; only owners may revoke.
+func (s *Service) RevokeInvitation(ctx context.Context, userID, orgID, invitationID string) error {
+	if _, err := s.authorize(ctx, userID, orgID, PermManage); err != nil {
+		return err
+	}
+	n, err := s.queries.DeleteOrganizationInvitation(ctx, sqlc.DeleteOrganizationInvitationParams{ID: invitationID, OrgID: orgID})
+	if err != nil {
+		if missingRow(err) {
+			return ErrInvalidInvitation
+		}
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidInvitation
+	}
+	return nil
+}
+
+// AcceptInvitation adds userID to the invitation's organization. The invitation must be pending,
+// unexpired and addressed to email.
+func (s *Service) AcceptInvitation(ctx context.Context, userID, email, token string) (Organization, error) {
+	row, err := s.queries.GetOrganizationInvitationByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Organization{}, ErrInvalidInvitation
+		}
+		return Organization{}, err
+	}
+	inv := invitationFromRow(sqlc.ListOrganizationInvitationsRow(row))
+	if inv.AcceptedAt != nil || !s.now().Before(inv.ExpiresAt) || !strings.EqualFold(inv.Email, strings.TrimSpace(email)) {
+		return Organization{}, ErrInvalidInvitation
+	}
+	if _, err := s.Get(ctx, userID, inv.OrgID); err == nil {
+		return Organization{}, ErrAlreadyMember
+	} else if !errors.Is(err, ErrNotFound) {
+		return Organization{}, err
+	}
+	n, err := s.queries.AcceptOrganizationInvitation(ctx, sqlc.AcceptOrganizationInvitationParams{ID: inv.ID, UserID: userID})
+	if err != nil {
+		return Organization{}, err
+	}
+	if n == 0 {
+		// Accepted concurrently.
+		return Organization{}, ErrInvalidInvitation
+	}
+	return s.Get(ctx, userID, inv.OrgID)
+}
+
+func invitationFromRow(row sqlc.ListOrganizationInvitationsRow) Invitation {
+	var accepted *time.Time
+	if row.AcceptedAt.Valid {
+		accepted = &row.AcceptedAt.Time
+	}
+	return Invitation{
+		ID:         row.ID,
+		OrgID:      row.OrgID,
+		Email:      row.Email,
+		Role:       row.Role,
+		InvitedBy:  row.InvitedBy,
+		ExpiresAt:  row.ExpiresAt.Time,
+		AcceptedAt: accepted,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+}
+
+// hashToken is how invitation tokens are stored: they carry enough entropy that a fast hash
+// suffices.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// missingRow reports whether a lookup by ID found nothing; a malformed ID can't match a row either.
+func missingRow(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == pgerrcode.InvalidTextRepresentation)
+}
@@ -0,0 +1,294 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeQueries struct {
+	orgs        map[string]string
+	members     map[string]map[string]string
+	invitations map[string]sqlc.ListOrganizationInvitationsRow
+	hashes      map[string]string
+	nextID      int
+}
+
+func newFakeQueries() *fakeQueries {
+	return &fakeQueries{
+		orgs:        map[string]string{},
+		members:     map[string]map[string]string{},
+		invitations: map[string]sqlc.ListOrganizationInvitationsRow{},
+		hashes:      map[string]string{},
+	}
+}
+
+func (f *fakeQueries) id(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s-%d", prefix, f.nextID)
+}
+
+func (f *fakeQueries) CreateOrganization(ctx context.Context, arg sqlc.CreateOrganizationParams) (sqlc.CreateOrganizationRow, error) {
+	id := f.id("org")
+	f.orgs[id] = arg.Name
+	f.members[id] = map[string]string{arg.UserID: RoleOwner}
+	return sqlc.CreateOrganizationRow{ID: id, Name: arg.Name}, nil
+}
+
+func (f *fakeQueries) ListOrganizationsForUser(ctx context.Context, userID string) ([]sqlc.ListOrganizationsForUserRow, error) {
+	var rows []sqlc.ListOrganizationsForUserRow
+	for id, name := range f.orgs {
+		if role, ok := f.members[id][userID]; ok {
+			rows = append(rows, sqlc.ListOrganizationsForUserRow{ID: id, Name: name, Role: role})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeQueries) GetOrganizationForMember(ctx context.Context, arg sqlc.GetOrganizationForMemberParams) (sqlc.GetOrganizationForMemberRow, error) {
+	role, ok := f.members[arg.ID][arg.UserID]
+	if !ok {
+		return sqlc.GetOrganizationForMemberRow{}, pgx.ErrNoRows
+	}
+	return sqlc.GetOrganizationForMemberRow{ID: arg.ID, Name: f.orgs[arg.ID], Role: role}, nil
+}
+
+func (f *fakeQueries) ListOrganizationMembers(ctx context.Context, orgID string) ([]sqlc.ListOrganizationMembersRow, error) {
+	var rows []sqlc.ListOrganizationMembersRow
+	for userID, role := range f.members[orgID] {
+		rows = append(rows, sqlc.ListOrganizationMembersRow{UserID: userID, Email: userID + "@example.com", Role: role})
+	}
+	return rows, nil
+}
+
+func (f *fakeQueries) UpdateOrganizationMemberRole(ctx context.Context, arg sqlc.UpdateOrganizationMemberRoleParams) (int64, error) {
+	role, ok := f.members[arg.OrgID][arg.UserID]
+	if !ok || (role == RoleOwner && arg.Role != RoleOwner && f.owners(arg.OrgID) <= 1) {
+		return 0, nil
+	}
+	f.members[arg.OrgID][arg.UserID] = arg.Role
+	return 1, nil
+}
+
+func (f *fakeQueries) DeleteOrganizationMember(ctx context.Context, arg sqlc.DeleteOrganizationMemberParams) (int64, error) {
+	role, ok := f.members[arg.OrgID][arg.UserID]
+	if !ok || (role == RoleOwner && f.owners(arg.OrgID) <= 1) {
+		return 0, nil
+	}
+	delete(f.members[arg.OrgID], arg.UserID)
+	return 1, nil
+}
+
+func (f *fakeQueries) owners(orgID string) int {
+	n := 0
+	for _, role := range f.members[orgID] {
+		if role == RoleOwner {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeQueries) CreateOrganizationInvitation(ctx context.Context, arg sqlc.CreateOrganizationInvitationParams) (sqlc.CreateOrganizationInvitationRow, error) {
+	row := sqlc.ListOrganizationInvitationsRow{
+		ID:        f.id("inv"),
+		OrgID:     arg.OrgID,
+		Email:     arg.Email,
+		Role:      arg.Role,
+		InvitedBy: arg.InvitedBy,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	f.invitations[row.ID] = row
+	f.hashes[arg.TokenHash] = row.ID
+	return sqlc.CreateOrganizationInvitationRow(row), nil
+}
+
+func (f *fakeQueries) ListOrganizationInvitations(ctx context.Context, orgID string) ([]sqlc.ListOrganizationInvitationsRow, error) {
+	var rows []sqlc.ListOrganizationInvitationsRow
+	for _, inv := range f.invitations {
+		if inv.OrgID == orgID && !inv.AcceptedAt.Valid {
+			rows = append(rows, inv)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeQueries) GetOrganizationInvitationByHash(ctx context.Context, tokenHash string) (sqlc.GetOrganizationInvitationByHashRow, error) {
+	id, ok := f.hashes[tokenHash]
+	if !ok {
+		return sqlc.GetOrganizationInvitationByHashRow{}, pgx.ErrNoRows
+	}
+	return sqlc.GetOrganizationInvitationByHashRow(f.invitations[id]), nil
+}
+
+func (f *fakeQueries) DeleteOrganizationInvitation(ctx context.Context, arg sqlc.DeleteOrganizationInvitationParams) (int64, error) {
+	inv, ok := f.invitations[arg.ID]
+	if !ok || inv.OrgID != arg.OrgID || inv.AcceptedAt.Valid {
+		return 0, nil
+	}
+	delete(f.invitations, arg.ID)
+	return 1, nil
+}
+
+func (f *fakeQueries) AcceptOrganizationInvitation(ctx context.Context, arg sqlc.AcceptOrganizationInvitationParams) (int64, error) {
+	inv, ok := f.invitations[arg.ID]
+	if !ok || inv.AcceptedAt.Valid {
+		return 0, nil
+	}
+	inv.AcceptedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	f.invitations[arg.ID] = inv
+	f.members[inv.OrgID][arg.UserID] = inv.Role
+	return 1, nil
+}
+
+func newTestService() (*Service, *fakeQueries) {
+	fq := newFakeQueries()
+	return &Service{queries: fq, now: time.Now}, fq
+}
+
+func TestCan(t *testing.T) {
+	cases := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleViewer, PermView, true},
+		{RoleViewer, PermRun, false},
+		{RoleRunner, PermRun, true},
+		{RoleRunner, PermEdit, false},
+		{RoleEditor, PermEdit, true},
+		{RoleEditor, PermManage, false},
+		{RoleOwner, PermManage, true},
+		{"admin", PermView, false},
+	}
+	for _, tc := range cases {
+		if got := Can(tc.role, tc.perm); got != tc.want {
+			t.Errorf("Can(%q, %d) = %v, want %v", tc.role, tc.perm, got, tc.want)
+		}
+	}
+}
+
+func TestServiceCreateAndGet(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "user-1", "  "); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	org, err := svc.Create(ctx, "user-1", " Acme ")
+	if err != nil || org.Name != "Acme" || org.Role != RoleOwner {
+		t.Fatalf("unexpected org %+v, err %v", org, err)
+	}
+	if _, err := svc.Get(ctx, "user-2", org.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected non-members to get ErrNotFound, got %v", err)
+	}
+	orgs, err := svc.List(ctx, "user-1")
+	if err != nil || len(orgs) != 1 {
+		t.Fatalf("unexpected orgs %+v, err %v", orgs, err)
+	}
+}
+
+func TestServiceMembers(t *testing.T) {
+	svc, fq := newTestService()
+	ctx := context.Background()
+	org, _ := svc.Create(ctx, "owner", "Acme")
+	fq.members[org.ID]["editor"] = RoleEditor
+
+	if err := svc.SetMemberRole(ctx, "editor", org.ID, "editor", RoleOwner); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected editors to be forbidden from managing members, got %v", err)
+	}
+	if err := svc.SetMemberRole(ctx, "owner", org.ID, "editor", "admin"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	if err := svc.SetMemberRole(ctx, "owner", org.ID, "owner", RoleViewer); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("expected ErrLastOwner when demoting the only owner, got %v", err)
+	}
+	if err := svc.SetMemberRole(ctx, "owner", org.ID, "nobody", RoleViewer); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("expected ErrMemberNotFound, got %v", err)
+	}
+	if err := svc.SetMemberRole(ctx, "owner", org.ID, "editor", RoleViewer); err != nil {
+		t.Fatalf("SetMemberRole error: %v", err)
+	}
+
+	if err := svc.RemoveMember(ctx, "editor", org.ID, "owner"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected viewers to be forbidden from removing others, got %v", err)
+	}
+	if err := svc.RemoveMember(ctx, "owner", org.ID, "owner"); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("expected the only owner to be unable to leave, got %v", err)
+	}
+	if err := svc.RemoveMember(ctx, "editor", org.ID, "editor"); err != nil {
+		t.Fatalf("expected members to be able to leave, got %v", err)
+	}
+	members, err := svc.ListMembers(ctx, "owner", org.ID)
+	if err != nil || len(members) != 1 {
+		t.Fatalf("unexpected members %+v, err %v", members, err)
+	}
+
+	// With a second owner either may step down, but not both.
+	fq.members[org.ID]["co-owner"] = RoleOwner
+	if err := svc.SetMemberRole(ctx, "co-owner", org.ID, "owner", RoleEditor); err != nil {
+		t.Fatalf("expected an owner to be demotable while another remains, got %v", err)
+	}
+	if err := svc.RemoveMember(ctx, "co-owner", org.ID, "co-owner"); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("expected the remaining owner to be unable to leave, got %v", err)
+	}
+}
+
+func TestServiceInvitations(t *testing.T) {
+	svc, fq := newTestService()
+	ctx := context.Background()
+	org, _ := svc.Create(ctx, "owner", "Acme")
+	fq.members[org.ID]["runner"] = RoleRunner
+
+	if _, _, err := svc.Invite(ctx, "runner", org.ID, "new@example.com", RoleEditor); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if _, _, err := svc.Invite(ctx, "owner", org.ID, "not-an-email", RoleEditor); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+	inv, token, err := svc.Invite(ctx, "owner", org.ID, " New@Example.com ", RoleEditor)
+	if err != nil || token == "" || inv.Email != "new@example.com" {
+		t.Fatalf("unexpected invitation %+v, err %v", inv, err)
+	}
+	if _, ok := fq.hashes[token]; ok {
+		t.Fatal("expected the token to be stored hashed")
+	}
+
+	if _, err := svc.AcceptInvitation(ctx, "new", "other@example.com", token); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("expected an invitation for another address to be rejected, got %v", err)
+	}
+	if _, err := svc.AcceptInvitation(ctx, "runner", "new@example.com", token); !errors.Is(err, ErrAlreadyMember) {
+		t.Fatalf("expected ErrAlreadyMember, got %v", err)
+	}
+	joined, err := svc.AcceptInvitation(ctx, "new", "NEW@example.com", token)
+	if err != nil || joined.ID != org.ID || joined.Role != RoleEditor {
+		t.Fatalf("unexpected org %+v, err %v", joined, err)
+	}
+	if _, err := svc.AcceptInvitation(ctx, "new2", "new@example.com", token); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("expected a used invitation to be rejected, got %v", err)
+	}
+
+	_, expired, _ := svc.Invite(ctx, "owner", org.ID, "late@example.com", RoleViewer)
+	svc.now = func() time.Time { return time.Now().Add(invitationTTL + time.Minute) }
+	if _, err := svc.AcceptInvitation(ctx, "late", "late@example.com", expired); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("expected an expired invitation to be rejected, got %v", err)
+	}
+	svc.now = time.Now
+
+	pending, err := svc.ListInvitations(ctx, "owner", org.ID)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("unexpected pending invitations %+v, err %v", pending, err)
+	}
+	if err := svc.RevokeInvitation(ctx, "owner", org.ID, pending[0].ID); err != nil {
+		t.Fatalf("RevokeInvitation error: %v", err)
+	}
+	if err := svc.RevokeInvitation(ctx, "owner", org.ID, pending[0].ID); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("expected ErrInvalidInvitation, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/actions"
+	"github.com/groovypotato/PotaFlow/internal/catalog"
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/rs/zerolog/log"
+)
+
+// startTimeout bounds the describe handshake and each health check.
+const startTimeout = 10 * time.Second
+
+// Manager owns the plugins discovered in a directory and keeps them healthy.
+type Manager struct {
+	plugins []*Plugin
+	// triggers maps each plugin trigger type to the plugin that fires it.
+	triggers map[string]*Plugin
+}
+
+// Discover starts every executable regular file in dir (hidden files are skipped) and registers
+// the action and trigger types each one reports. Plugins that fail to start, and types that are
+// built in or already registered, are logged and skipped so one bad plugin cannot keep the worker
+// from booting.
+func Discover(ctx context.Context, dir string, registry *actions.Registry) (*Manager, error) {
+	paths, err := executables(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{triggers: make(map[string]*Plugin)}
+	for _, path := range paths {
+		startCtx, cancel := context.WithTimeout(ctx, startTimeout)
+		p, err := Start(startCtx, path)
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Str("path", path).Msg("failed to start plugin")
+			continue
+		}
+
+		desc := p.Descriptor()
+		registered := 0
+		for _, spec := range desc.Actions {
+			if err := registry.Register(spec.Type, p); err != nil {
+				log.Warn().Err(err).Str("plugin", desc.Name).Msg("skipping plugin action type")
+				continue
+			}
+			registered++
+		}
+		triggers := 0
+		for _, spec := range desc.Triggers {
+			if _, taken := m.triggers[spec.Type]; taken || catalog.IsBuiltin(catalog.KindTrigger, spec.Type) {
+				log.Warn().Str("plugin", desc.Name).Str("type", spec.Type).Msg("skipping plugin trigger type: already registered")
+				continue
+			}
+			m.triggers[spec.Type] = p
+			triggers++
+		}
+		log.Info().
+			Str("plugin", desc.Name).
+			Str("version", desc.Version).
+			Int("actions", registered).
+			Int("triggers", triggers).
+			Msg("plugin loaded")
+		m.plugins = append(m.plugins, p)
+	}
+	return m, nil
+}
+
+// Plugins returns the running plugins.
+func (m *Manager) Plugins() []*Plugin {
+	return m.plugins
+}
+
+// Descriptors returns the descriptor of every running plugin.
+func (m *Manager) Descriptors() []Descriptor {
+	out := make([]Descriptor, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		out = append(out, p.Descriptor())
+	}
+	return out
+}
+
+// typePublisher stores plugin types where the API's catalog can see them.
+type typePublisher interface {
+	UpsertPluginType(ctx context.Context, arg sqlc.UpsertPluginTypeParams) error
+}
+
+// Publish records the action and trigger types of every running plugin (e.g., via sqlc.New(db))
+// so the API accepts and lists them. Trigger types another plugin or a built-in already claims
+// are left out.
+func (m *Manager) Publish(ctx context.Context, pub typePublisher) error {
+	for _, p := range m.plugins {
+		desc := p.Descriptor()
+		for _, spec := range desc.Actions {
+			if err := publish(ctx, pub, catalog.KindAction, desc.Name, spec); err != nil {
+				return err
+			}
+		}
+		for _, spec := range desc.Triggers {
+			if m.triggers[spec.Type] != p {
+				continue
+			}
+			if err := publish(ctx, pub, catalog.KindTrigger, desc.Name, spec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func publish(ctx context.Context, pub typePublisher, kind catalog.Kind, plugin string, spec TypeSpec) error {
+	if err := pub.UpsertPluginType(ctx, sqlc.UpsertPluginTypeParams{
+		Kind:         string(kind),
+		Type:         spec.Type,
+		Plugin:       plugin,
+		Description:  spec.Description,
+		ConfigSchema: spec.ConfigSchema,
+	}); err != nil {
+		return fmt.Errorf("publish plugin type %s: %w", spec.Type, err)
+	}
+	return nil
+}
+
+// Run health-checks every plugin on each interval and restarts the ones that fail;
+// it blocks until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.CheckOnce(ctx)
+		}
+	}
+}
+
+// CheckOnce health-checks each plugin once, restarting any that are unhealthy.
+func (m *Manager) CheckOnce(ctx context.Context) {
+	for _, p := range m.plugins {
+		checkCtx, cancel := context.WithTimeout(ctx, startTimeout)
+		err := p.Health(checkCtx)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		name := p.Descriptor().Name
+		log.Warn().Err(err).Str("plugin", name).Msg("plugin health check failed; restarting")
+		restartCtx, cancel := context.WithTimeout(ctx, startTimeout)
+		if err := p.Restart(restartCtx); err != nil {
+			log.Error().Err(err).Str("plugin", name).Msg("plugin restart failed")
+		}
+		cancel()
+	}
+}
+
+// Close stops all plugin processes.
+func (m *Manager) Close() {
+	for _, p := range m.plugins {
+		p.Close()
+	}
+}
+
+func executables(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read plugin dir: %w", err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,339 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/actions"
+	"github.com/rs/zerolog/log"
+)
+
+// maxMessageSize caps a single JSON-RPC line read from a plugin.
+const maxMessageSize = 16 << 20
+
+// ErrPluginExited is returned for calls made after the plugin process has gone away.
+var ErrPluginExited = errors.New("plugin process exited")
+
+// Plugin is a running out-of-process plugin. It can be restarted in place, so executors that
+// hold a *Plugin keep working across restarts.
+type Plugin struct {
+	path string
+
+	mu       sync.RWMutex
+	proc     *process
+	desc     Descriptor
+	watching *WatchParams
+	onFire   func(FireParams)
+}
+
+// Start launches the executable at path and performs the describe handshake.
+func Start(ctx context.Context, path string) (*Plugin, error) {
+	p := &Plugin{path: path}
+	if err := p.start(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Path returns the executable path the plugin was started from.
+func (p *Plugin) Path() string {
+	return p.path
+}
+
+// Descriptor returns the types the plugin reported during its last handshake.
+func (p *Plugin) Descriptor() Descriptor {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.desc
+}
+
+// Health asks the plugin to report its status; anything other than "ok" is an error.
+func (p *Plugin) Health(ctx context.Context) error {
+	var res HealthResult
+	if err := p.call(ctx, MethodHealth, nil, &res); err != nil {
+		return err
+	}
+	if res.Status != "ok" {
+		return fmt.Errorf("plugin reported status %q", res.Status)
+	}
+	return nil
+}
+
+// Execute implements actions.Executor by forwarding the step to the plugin.
+func (p *Plugin) Execute(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+	var res ExecuteResult
+	err := p.call(ctx, MethodExecute, ExecuteParams{
+		Type:       step.Type,
+		RunID:      step.RunID,
+		WorkflowID: step.WorkflowID,
+		ActionID:   step.ActionID,
+		Config:     step.Config,
+		Input:      step.Input,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.Output, nil
+}
+
+// Watch hands the plugin the triggers of its trigger types, replacing any it watched before.
+// The set is remembered and sent again after a restart.
+func (p *Plugin) Watch(ctx context.Context, triggers []WatchedTrigger) error {
+	params := &WatchParams{Triggers: triggers}
+	if params.Triggers == nil {
+		params.Triggers = []WatchedTrigger{}
+	}
+	p.mu.Lock()
+	p.watching = params
+	p.mu.Unlock()
+	return p.call(ctx, MethodWatch, params, nil)
+}
+
+// OnFire sets the function that receives the plugin's fire notifications. It is called on the
+// goroutine reading the plugin's output, so it must not block.
+func (p *Plugin) OnFire(fn func(FireParams)) {
+	p.mu.Lock()
+	p.onFire = fn
+	p.mu.Unlock()
+}
+
+// Restart stops the current process and launches a fresh one from the same path.
+func (p *Plugin) Restart(ctx context.Context) error {
+	p.mu.Lock()
+	old := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return p.start(ctx)
+}
+
+// Close stops the plugin process.
+func (p *Plugin) Close() {
+	p.mu.Lock()
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+	if proc != nil {
+		proc.close()
+	}
+}
+
+func (p *Plugin) start(ctx context.Context) error {
+	proc, err := spawn(p.path, p.notify)
+	if err != nil {
+		return err
+	}
+
+	var desc Descriptor
+	if err := proc.call(ctx, MethodDescribe, nil, &desc); err != nil {
+		proc.close()
+		return fmt.Errorf("describe %s: %w", p.path, err)
+	}
+	if desc.Name == "" {
+		proc.close()
+		return fmt.Errorf("describe %s: plugin returned no name", p.path)
+	}
+
+	p.mu.Lock()
+	p.proc = proc
+	p.desc = desc
+	watching := p.watching
+	p.mu.Unlock()
+
+	if watching != nil {
+		if err := proc.call(ctx, MethodWatch, watching, nil); err != nil {
+			log.Error().Err(err).Str("plugin", desc.Name).Msg("failed to restore plugin triggers")
+		}
+	}
+	return nil
+}
+
+// notify handles a notification read from the plugin. Unknown methods are ignored.
+func (p *Plugin) notify(method string, params json.RawMessage) {
+	if method != MethodFire {
+		log.Warn().Str("path", p.path).Str("method", method).Msg("ignoring unknown plugin notification")
+		return
+	}
+	var fire FireParams
+	if err := json.Unmarshal(params, &fire); err != nil || fire.TriggerID == "" {
+		log.Warn().Str("path", p.path).Msg("discarding malformed fire notification")
+		return
+	}
+	p.mu.RLock()
+	fn := p.onFire
+	p.mu.RUnlock()
+	if fn != nil {
+		fn(fire)
+	}
+}
+
+func (p *Plugin) call(ctx context.Context, method string, params, result any) error {
+	p.mu.RLock()
+	proc := p.proc
+	p.mu.RUnlock()
+	if proc == nil {
+		return ErrPluginExited
+	}
+	return proc.call(ctx, method, params, result)
+}
+
+// process is a single plugin subprocess and its JSON-RPC connection.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+
+	pendingMu sync.Mutex
+	pending   map[uint64]chan rpcMessage
+
+	// notify receives notifications; it runs on the read loop.
+	notify func(method string, params json.RawMessage)
+
+	stderrDone chan struct{}
+	done       chan struct{}
+}
+
+func spawn(path string, notify func(string, json.RawMessage)) (*process, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start plugin %s: %w", path, err)
+	}
+
+	proc := &process{
+		cmd:        cmd,
+		stdin:      stdin,
+		pending:    make(map[uint64]chan rpcMessage),
+		notify:     notify,
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go proc.forwardStderr(filepath.Base(path), stderr)
+	go proc.readLoop(stdout)
+	return proc, nil
+}
+
+func (proc *process) call(ctx context.Context, method string, params, result any) error {
+	id := proc.nextID.Add(1)
+	ch := make(chan rpcMessage, 1)
+
+	proc.pendingMu.Lock()
+	proc.pending[id] = ch
+	proc.pendingMu.Unlock()
+	defer func() {
+		proc.pendingMu.Lock()
+		delete(proc.pending, id)
+		proc.pendingMu.Unlock()
+	}()
+
+	line, err := json.Marshal(rpcRequest{JSONRPC: jsonRPCVersion, ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	proc.writeMu.Lock()
+	_, err = proc.stdin.Write(line)
+	proc.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPluginExited, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-proc.done:
+		return ErrPluginExited
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (proc *process) readLoop(stdout io.Reader) {
+	defer close(proc.done)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Warn().Err(err).Msg("discarding malformed plugin response")
+			continue
+		}
+		if msg.Method != "" {
+			if proc.notify != nil {
+				proc.notify(msg.Method, msg.Params)
+			}
+			continue
+		}
+		proc.pendingMu.Lock()
+		ch, ok := proc.pending[msg.ID]
+		proc.pendingMu.Unlock()
+		if !ok {
+			log.Warn().Uint64("id", msg.ID).Msg("discarding plugin response for unknown call")
+			continue
+		}
+		// Each call takes one response; a duplicate mustn't stall the loop.
+		select {
+		case ch <- msg:
+		default:
+			log.Warn().Uint64("id", msg.ID).Msg("discarding duplicate plugin response")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// E.g. a line over maxMessageSize. Nothing more can be read, so the process is useless
+		// even if it's alive: kill it so pending calls fail and the health check restarts it.
+		log.Error().Err(err).Msg("failed to read plugin output; killing the plugin")
+		_ = proc.cmd.Process.Kill()
+	}
+	<-proc.stderrDone
+	_ = proc.cmd.Wait()
+}
+
+func (proc *process) forwardStderr(name string, stderr io.Reader) {
+	defer close(proc.stderrDone)
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Info().Str("plugin", name).Msg(scanner.Text())
+	}
+}
+
+// close asks the plugin to exit by closing stdin, then kills it if it lingers.
+func (proc *process) close() {
+	_ = proc.stdin.Close()
+	select {
+	case <-proc.done:
+	case <-time.After(5 * time.Second):
+		_ = proc.cmd.Process.Kill()
+		<-proc.done
+	}
+}
@@ -0,0 +1,185 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/actions"
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+)
+
+// TestPluginHelperProcess is not a real test: it is re-executed by the tests below to act as a
+// plugin speaking the stdio protocol.
+func TestPluginHelperProcess(t *testing.T) {
+	if os.Getenv("POTAFLOW_TEST_PLUGIN") != "1" {
+		return
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		_ = json.Unmarshal(scanner.Bytes(), &req)
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case MethodDescribe:
+			resp["result"] = Descriptor{
+				Name:     "echo",
+				Version:  "1.0.0",
+				Actions:  []TypeSpec{{Type: "echo", ConfigSchema: json.RawMessage(`{"type":"object"}`)}},
+				Triggers: []TypeSpec{{Type: "tick"}, {Type: "cron"}},
+			}
+		case MethodHealth:
+			// A response nobody asked for and duplicates must not stall the worker.
+			_ = out.Encode(map[string]any{"jsonrpc": "2.0", "id": 999999, "result": HealthResult{Status: "ok"}})
+			resp["result"] = HealthResult{Status: "ok"}
+			_ = out.Encode(resp)
+			_ = out.Encode(resp)
+		case MethodWatch:
+			var params WatchParams
+			_ = json.Unmarshal(req.Params, &params)
+			resp["result"] = map[string]any{}
+			_ = out.Encode(resp)
+			for _, tr := range append(params.Triggers, WatchedTrigger{TriggerID: "tr-bogus"}) {
+				_ = out.Encode(map[string]any{"jsonrpc": "2.0", "method": MethodFire, "params": FireParams{
+					TriggerID: tr.TriggerID,
+					Input:     json.RawMessage(`{"trigger_id":"` + tr.TriggerID + `"}`),
+				}})
+			}
+			continue
+		case MethodExecute:
+			var params ExecuteParams
+			_ = json.Unmarshal(req.Params, &params)
+			if string(params.Config) == `{"fail":true}` {
+				resp["error"] = RPCError{Code: -32000, Message: "asked to fail"}
+				break
+			}
+			if string(params.Config) == `{"oversized":true}` {
+				// A line the worker can't read; the plugin itself keeps running.
+				_, _ = os.Stdout.WriteString(`{"jsonrpc":"2.0","id":` + fmt.Sprint(req.ID) + `,"result":{"output":"` + strings.Repeat("x", maxMessageSize) + `"}}` + "\n")
+				continue
+			}
+			resp["result"] = ExecuteResult{Output: params.Input}
+		default:
+			resp["error"] = RPCError{Code: -32601, Message: "method not found"}
+		}
+		_ = out.Encode(resp)
+	}
+	os.Exit(0)
+}
+
+func writeHelperPlugin(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	script := fmt.Sprintf("#!/bin/sh\nPOTAFLOW_TEST_PLUGIN=1 exec %q -test.run=TestPluginHelperProcess\n", os.Args[0])
+	if err := os.WriteFile(filepath.Join(dir, "echo-plugin"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write plugin: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not executable"), 0o644); err != nil {
+		t.Fatalf("write readme: %v", err)
+	}
+	return dir
+}
+
+func TestDiscoverRegistersAndExecutes(t *testing.T) {
+	dir := writeHelperPlugin(t)
+	registry := actions.NewRegistry()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m, err := Discover(ctx, dir, registry)
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+	defer m.Close()
+
+	if len(m.Plugins()) != 1 {
+		t.Fatalf("expected 1 plugin, got %d", len(m.Plugins()))
+	}
+	exec, ok := registry.Lookup("echo")
+	if !ok {
+		t.Fatalf("expected echo action to be registered")
+	}
+
+	out, err := exec.Execute(ctx, actions.Step{Type: "echo", Input: json.RawMessage(`{"hello":"world"}`)})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if string(out) != `{"hello":"world"}` {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	_, err = exec.Execute(ctx, actions.Step{Type: "echo", Config: json.RawMessage(`{"fail":true}`)})
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "asked to fail" {
+		t.Fatalf("expected plugin error, got %v", err)
+	}
+
+	pub := &fakePublisher{}
+	if err := m.Publish(ctx, pub); err != nil {
+		t.Fatalf("Publish error: %v", err)
+	}
+	// The built-in cron trigger type can't be taken over.
+	if len(pub.types) != 2 || pub.types[0].Kind != "action" || pub.types[0].Type != "echo" || pub.types[0].Plugin == "" ||
+		pub.types[1].Kind != "trigger" || pub.types[1].Type != "tick" {
+		t.Fatalf("unexpected published types: %+v", pub.types)
+	}
+}
+
+type fakePublisher struct {
+	types []sqlc.UpsertPluginTypeParams
+}
+
+func (f *fakePublisher) UpsertPluginType(ctx context.Context, arg sqlc.UpsertPluginTypeParams) error {
+	f.types = append(f.types, arg)
+	return nil
+}
+
+func TestPluginHealthAndRestart(t *testing.T) {
+	dir := writeHelperPlugin(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	p, err := Start(ctx, filepath.Join(dir, "echo-plugin"))
+	if err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := p.Health(ctx); err != nil {
+			t.Fatalf("Health error: %v", err)
+		}
+	}
+
+	p.Close()
+	if err := p.Health(ctx); !errors.Is(err, ErrPluginExited) {
+		t.Fatalf("expected ErrPluginExited after close, got %v", err)
+	}
+
+	m := &Manager{plugins: []*Plugin{p}}
+	m.CheckOnce(ctx)
+	if err := p.Health(ctx); err != nil {
+		t.Fatalf("expected plugin healthy after restart, got %v", err)
+	}
+
+	// An unreadable response kills the plugin instead of leaving the call waiting for ctx.
+	if _, err := p.Execute(ctx, actions.Step{Type: "echo", Config: json.RawMessage(`{"oversized":true}`)}); !errors.Is(err, ErrPluginExited) {
+		t.Fatalf("expected ErrPluginExited for an oversized response, got %v", err)
+	}
+	m.CheckOnce(ctx)
+	if err := p.Health(ctx); err != nil {
+		t.Fatalf("expected plugin healthy after restart, got %v", err)
+	}
+}
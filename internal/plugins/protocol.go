@@ -0,0 +1,104 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Plugins speak JSON-RPC 2.0 over stdio: the worker writes one request object per line to the
+// plugin's stdin and reads one response object per line from its stdout. Stderr is forwarded to
+// the worker log. Plugins with trigger types are sent the triggers to watch and report events
+// with fire notifications (requests without an id) on stdout.
+const (
+	MethodDescribe = "describe"
+	MethodHealth   = "health"
+	MethodExecute  = "execute"
+	MethodWatch    = "watch"
+	MethodFire     = "fire"
+
+	jsonRPCVersion = "2.0"
+)
+
+// TypeSpec describes an action or trigger type contributed by a plugin.
+type TypeSpec struct {
+	Type         string          `json:"type"`
+	Description  string          `json:"description,omitempty"`
+	ConfigSchema json.RawMessage `json:"config_schema,omitempty"`
+}
+
+// Descriptor is the result of the describe call made right after a plugin starts.
+type Descriptor struct {
+	Name     string     `json:"name"`
+	Version  string     `json:"version"`
+	Actions  []TypeSpec `json:"actions"`
+	Triggers []TypeSpec `json:"triggers"`
+}
+
+// HealthResult is the result of the health call.
+type HealthResult struct {
+	Status string `json:"status"`
+}
+
+// ExecuteParams are the params of the execute call.
+type ExecuteParams struct {
+	Type       string          `json:"type"`
+	RunID      string          `json:"run_id"`
+	WorkflowID string          `json:"workflow_id"`
+	ActionID   string          `json:"action_id"`
+	Config     json.RawMessage `json:"config"`
+	Input      json.RawMessage `json:"input"`
+}
+
+// ExecuteResult is the result of the execute call.
+type ExecuteResult struct {
+	Output json.RawMessage `json:"output"`
+}
+
+// WatchParams are the params of the watch call. Triggers replaces the whole set the plugin
+// watches; an empty list means it should stop firing.
+type WatchParams struct {
+	Triggers []WatchedTrigger `json:"triggers"`
+}
+
+// WatchedTrigger is one trigger of a plugin trigger type.
+type WatchedTrigger struct {
+	TriggerID  string          `json:"trigger_id"`
+	WorkflowID string          `json:"workflow_id"`
+	Type       string          `json:"type"`
+	Config     json.RawMessage `json:"config"`
+}
+
+// FireParams are the params of a fire notification; Input becomes the run's input.
+type FireParams struct {
+	TriggerID string          `json:"trigger_id"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// rpcMessage is a line read from a plugin: a response to a call, or a notification when Method
+// is set.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC error object returned by a plugin.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
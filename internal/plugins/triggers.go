@@ -0,0 +1,200 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/internal/scheduler"
+	"github.com/rs/zerolog/log"
+)
+
+// TriggerLockKey is the advisory lock key trigger runner replicas compete for, so only one worker
+// has its plugins watch triggers and each event starts one run.
+const TriggerLockKey = scheduler.LockKey + 2
+
+// leaderCheckInterval is how often a follower tries to take over the plugin triggers.
+const leaderCheckInterval = 5 * time.Second
+
+// TriggerRunner hands the triggers of plugin trigger types to their plugins and enqueues a run
+// for every fire notification. Only the current Leader's plugins watch.
+type TriggerRunner struct {
+	manager        *Manager
+	queries        triggerQueries
+	leader         scheduler.Leader
+	reloadInterval time.Duration
+
+	leading  bool
+	triggers map[string]watched
+	fired    chan fired
+}
+
+type watched struct {
+	plugin  *Plugin
+	trigger WatchedTrigger
+}
+
+type fired struct {
+	plugin *Plugin
+	params FireParams
+}
+
+type triggerQueries interface {
+	ListEnabledTriggersByType(ctx context.Context, type_ string) ([]sqlc.ListEnabledTriggersByTypeRow, error)
+	CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error)
+}
+
+// Triggers builds the TriggerRunner for the manager's plugin trigger types. It also reloads the
+// triggers every reloadInterval, as a fallback for missed change notifications.
+func (m *Manager) Triggers(db sqlc.DBTX, leader scheduler.Leader, reloadInterval time.Duration) *TriggerRunner {
+	return newTriggerRunner(m, sqlc.New(db), leader, reloadInterval)
+}
+
+func newTriggerRunner(m *Manager, queries triggerQueries, leader scheduler.Leader, reloadInterval time.Duration) *TriggerRunner {
+	r := &TriggerRunner{
+		manager:        m,
+		queries:        queries,
+		leader:         leader,
+		reloadInterval: reloadInterval,
+		triggers:       make(map[string]watched),
+		fired:          make(chan fired, 64),
+	}
+	for _, p := range m.plugins {
+		p := p
+		p.OnFire(func(params FireParams) {
+			select {
+			case r.fired <- fired{plugin: p, params: params}:
+			default:
+				log.Warn().Str("trigger_id", params.TriggerID).Msg("dropping plugin trigger event: too many pending")
+			}
+		})
+	}
+	return r
+}
+
+// Run watches until ctx is cancelled. A value on changes (see scheduler.Listen) triggers an
+// immediate reload.
+func (r *TriggerRunner) Run(ctx context.Context, changes <-chan struct{}) error {
+	defer r.leader.Release(context.Background())
+	defer func() {
+		if r.leading {
+			stopCtx, cancel := context.WithTimeout(context.Background(), startTimeout)
+			r.stop(stopCtx)
+			cancel()
+		}
+	}()
+
+	r.checkLeader(ctx)
+	check := time.NewTicker(leaderCheckInterval)
+	defer check.Stop()
+	reload := time.NewTicker(r.reloadInterval)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-check.C:
+			r.checkLeader(ctx)
+		case <-changes:
+			r.reload(ctx)
+		case <-reload.C:
+			r.reload(ctx)
+		case f := <-r.fired:
+			r.fire(ctx, f)
+		}
+	}
+}
+
+func (r *TriggerRunner) checkLeader(ctx context.Context) {
+	leader, err := r.leader.Acquire(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("plugin trigger leader election failed")
+	}
+	switch {
+	case leader && !r.leading:
+		r.leading = true
+		r.reload(ctx)
+	case !leader && r.leading:
+		r.leading = false
+		r.stop(ctx)
+	}
+}
+
+func (r *TriggerRunner) reload(ctx context.Context) {
+	if !r.leading {
+		return
+	}
+	if err := r.load(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to load plugin triggers")
+	}
+}
+
+// load hands every plugin the current triggers of its types.
+func (r *TriggerRunner) load(ctx context.Context) error {
+	byPlugin := make(map[*Plugin][]WatchedTrigger)
+	triggers := make(map[string]watched)
+	for typ, p := range r.manager.triggers {
+		rows, err := r.queries.ListEnabledTriggersByType(ctx, typ)
+		if err != nil {
+			return err
+		}
+		if _, ok := byPlugin[p]; !ok {
+			// Plugins without triggers still get an empty list, so removed ones stop firing.
+			byPlugin[p] = []WatchedTrigger{}
+		}
+		for _, row := range rows {
+			t := WatchedTrigger{TriggerID: row.ID, WorkflowID: row.WorkflowID, Type: typ, Config: row.Config}
+			byPlugin[p] = append(byPlugin[p], t)
+			triggers[row.ID] = watched{plugin: p, trigger: t}
+		}
+	}
+	r.triggers = triggers
+	for p, list := range byPlugin {
+		if err := p.Watch(ctx, list); err != nil {
+			log.Error().Err(err).Str("plugin", p.Descriptor().Name).Msg("failed to hand triggers to plugin")
+		}
+	}
+	return nil
+}
+
+// stop tells every plugin with trigger types to stop watching.
+func (r *TriggerRunner) stop(ctx context.Context) {
+	r.triggers = make(map[string]watched)
+	seen := make(map[*Plugin]bool)
+	for _, p := range r.manager.triggers {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if err := p.Watch(ctx, nil); err != nil {
+			log.Warn().Err(err).Str("plugin", p.Descriptor().Name).Msg("failed to stop plugin triggers")
+		}
+	}
+}
+
+// fire enqueues a run for a fire notification. Plugins may only fire the triggers they were
+// handed, and only while this replica leads.
+func (r *TriggerRunner) fire(ctx context.Context, f fired) {
+	w, ok := r.triggers[f.params.TriggerID]
+	if !r.leading || !ok || w.plugin != f.plugin {
+		log.Warn().Str("trigger_id", f.params.TriggerID).Msg("ignoring fire for a trigger the plugin doesn't watch")
+		return
+	}
+	input := f.params.Input
+	if len(input) == 0 || string(input) == "null" {
+		input = json.RawMessage(`{}`)
+	}
+	run, err := r.queries.CreateWorkflowRun(ctx, sqlc.CreateWorkflowRunParams{
+		WorkflowID:  w.trigger.WorkflowID,
+		Status:      "pending",
+		TriggerType: w.trigger.Type,
+		Input:       input,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("trigger_id", w.trigger.TriggerID).Msg("failed to fire plugin trigger")
+		return
+	}
+	log.Info().Str("trigger_id", w.trigger.TriggerID).Str("run_id", run.ID).Msg("plugin trigger fired")
+}
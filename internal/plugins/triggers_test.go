@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/actions"
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+)
+
+type fakeTriggerQueries struct {
+	triggers map[string][]sqlc.ListEnabledTriggersByTypeRow
+	runs     []sqlc.CreateWorkflowRunParams
+}
+
+func (f *fakeTriggerQueries) ListEnabledTriggersByType(ctx context.Context, type_ string) ([]sqlc.ListEnabledTriggersByTypeRow, error) {
+	return f.triggers[type_], nil
+}
+
+func (f *fakeTriggerQueries) CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error) {
+	f.runs = append(f.runs, arg)
+	return sqlc.CreateWorkflowRunRow{ID: "run-1"}, nil
+}
+
+type alwaysLeader struct{}
+
+func (alwaysLeader) Acquire(ctx context.Context) (bool, error) { return true, nil }
+func (alwaysLeader) Release(ctx context.Context)               {}
+
+func TestTriggerRunnerFiresPluginTriggers(t *testing.T) {
+	dir := writeHelperPlugin(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	m, err := Discover(ctx, dir, actions.NewRegistry())
+	if err != nil {
+		t.Fatalf("Discover error: %v", err)
+	}
+	defer m.Close()
+
+	fq := &fakeTriggerQueries{triggers: map[string][]sqlc.ListEnabledTriggersByTypeRow{
+		"tick": {{ID: "tr-1", WorkflowID: "wf-1", Config: []byte(`{}`)}},
+		"cron": {{ID: "tr-cron", WorkflowID: "wf-2", Config: []byte(`{"cron_expr":"@hourly"}`)}},
+	}}
+	r := newTriggerRunner(m, fq, alwaysLeader{}, time.Minute)
+	r.checkLeader(ctx)
+
+	// The plugin fires its own trigger and one it was never handed.
+	for i := 0; i < 2; i++ {
+		select {
+		case f := <-r.fired:
+			r.fire(ctx, f)
+		case <-ctx.Done():
+			t.Fatalf("expected fire notifications, got %d", i)
+		}
+	}
+	if len(fq.runs) != 1 {
+		t.Fatalf("expected one run, got %+v", fq.runs)
+	}
+	run := fq.runs[0]
+	var input map[string]string
+	if err := json.Unmarshal(run.Input, &input); err != nil || run.WorkflowID != "wf-1" || run.TriggerType != "tick" || input["trigger_id"] != "tr-1" {
+		t.Fatalf("unexpected run: %+v (input %s)", run, run.Input)
+	}
+
+	// A restarted plugin gets its triggers again.
+	if err := m.Plugins()[0].Restart(ctx); err != nil {
+		t.Fatalf("Restart error: %v", err)
+	}
+	select {
+	case f := <-r.fired:
+		if f.params.TriggerID != "tr-1" {
+			t.Fatalf("unexpected fire after restart: %+v", f.params)
+		}
+	case <-ctx.Done():
+		t.Fatalf("expected the restarted plugin to watch again")
+	}
+}
@@ -0,0 +1,329 @@
+// Package poll fetches HTTP feeds for "poll" triggers and splits them into items with stable IDs.
+package poll
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/actions"
+)
+
+// Feed formats. FormatAuto picks one from the Content-Type header and the body.
+const (
+	FormatAuto = "auto"
+	FormatJSON = "json"
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+)
+
+const (
+	defaultTimeout  = 30 * time.Second
+	maxTimeout      = 2 * time.Minute
+	defaultMaxItems = 50
+	maxMaxItems     = 1000
+	maxFeedBody     = 5 << 20
+)
+
+// Config is the config of a "poll" trigger. The poll frequency (every) is read by the scheduler.
+//
+// For JSON feeds, ItemsPath addresses the array of items (default: the whole document) and
+// IDPath the ID inside each item (default "id"). RSS items are identified by guid, then link;
+// Atom entries by id, then link. Items without an ID are identified by a hash of their content.
+type Config struct {
+	URL          string            `json:"url"`
+	Format       string            `json:"format,omitempty"`
+	ItemsPath    string            `json:"items_path,omitempty"`
+	IDPath       string            `json:"id_path,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	TimeoutMS    int               `json:"timeout_ms,omitempty"`
+	MaxItems     int               `json:"max_items,omitempty"`
+	EmitExisting bool              `json:"emit_existing,omitempty"`
+}
+
+// Item is one entry of a feed. Data becomes the run input.
+type Item struct {
+	ID   string
+	Data json.RawMessage
+}
+
+// ParseConfig decodes a poll trigger config and applies defaults.
+func ParseConfig(raw []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid poll config: %w", err)
+	}
+	if cfg.URL == "" {
+		return Config{}, errors.New("invalid poll config: url is required")
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = FormatAuto
+	case FormatAuto, FormatJSON, FormatRSS, FormatAtom:
+	default:
+		return Config{}, fmt.Errorf("invalid poll config: unknown format %q", cfg.Format)
+	}
+	if cfg.IDPath == "" {
+		cfg.IDPath = "id"
+	}
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = defaultMaxItems
+	}
+	cfg.MaxItems = min(cfg.MaxItems, maxMaxItems)
+	return cfg, nil
+}
+
+// Timeout returns how long a single fetch may take.
+func (c Config) Timeout() time.Duration {
+	if c.TimeoutMS <= 0 {
+		return defaultTimeout
+	}
+	return min(time.Duration(c.TimeoutMS)*time.Millisecond, maxTimeout)
+}
+
+// Fetch GETs the feed and returns its items in document order.
+func Fetch(ctx context.Context, client *http.Client, cfg Config) ([]Item, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("poll: read %s: %w", cfg.URL, err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("poll: GET %s returned %d", cfg.URL, resp.StatusCode)
+	}
+	if len(body) > maxFeedBody {
+		return nil, fmt.Errorf("poll: %s is larger than %d bytes", cfg.URL, maxFeedBody)
+	}
+	return Parse(cfg, resp.Header.Get("Content-Type"), body)
+}
+
+// Parse splits a fetched feed into items.
+func Parse(cfg Config, contentType string, body []byte) ([]Item, error) {
+	format := cfg.Format
+	if format == "" || format == FormatAuto {
+		format = detect(contentType, body)
+	}
+	switch format {
+	case FormatJSON:
+		return parseJSON(cfg, body)
+	case FormatRSS:
+		return parseRSS(body)
+	case FormatAtom:
+		return parseAtom(body)
+	}
+	return nil, errors.New("poll: could not detect the feed format; set format to json, rss or atom")
+}
+
+func detect(contentType string, body []byte) string {
+	switch ct := strings.ToLower(contentType); {
+	case strings.Contains(ct, "json"):
+		return FormatJSON
+	case strings.Contains(ct, "rss"):
+		return FormatRSS
+	case strings.Contains(ct, "atom"):
+		return FormatAtom
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return ""
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return FormatJSON
+	}
+	// Look at the root element of an XML document.
+	dec := xml.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "rss":
+				return FormatRSS
+			case "feed":
+				return FormatAtom
+			}
+			return ""
+		}
+	}
+}
+
+func parseJSON(cfg Config, body []byte) ([]Item, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("poll: invalid JSON feed: %w", err)
+	}
+	list := doc
+	if cfg.ItemsPath != "" {
+		v, ok, err := actions.Extract(doc, cfg.ItemsPath)
+		if err != nil {
+			return nil, fmt.Errorf("poll: items_path: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("poll: items_path %q not found in feed", cfg.ItemsPath)
+		}
+		list = v
+	}
+	arr, ok := list.([]any)
+	if !ok {
+		return nil, fmt.Errorf("poll: items_path %q is not an array", cfg.ItemsPath)
+	}
+
+	items := make([]Item, 0, len(arr))
+	for _, el := range arr {
+		data, err := json.Marshal(el)
+		if err != nil {
+			return nil, err
+		}
+		var id string
+		if v, ok, err := actions.Extract(el, cfg.IDPath); err != nil {
+			return nil, fmt.Errorf("poll: id_path: %w", err)
+		} else if ok {
+			id = scalarString(v)
+		}
+		items = append(items, Item{ID: orHash(id, data), Data: data})
+	}
+	return items, nil
+}
+
+type rssDoc struct {
+	Items []struct {
+		Title       string `xml:"title" json:"title,omitempty"`
+		Link        string `xml:"link" json:"link,omitempty"`
+		GUID        string `xml:"guid" json:"guid,omitempty"`
+		Description string `xml:"description" json:"description,omitempty"`
+		PubDate     string `xml:"pubDate" json:"published,omitempty"`
+	} `xml:"channel>item"`
+}
+
+func parseRSS(body []byte) ([]Item, error) {
+	var doc rssDoc
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("poll: invalid RSS feed: %w", err)
+	}
+	items := make([]Item, 0, len(doc.Items))
+	for _, it := range doc.Items {
+		data, err := json.Marshal(it)
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimSpace(it.GUID)
+		if id == "" {
+			id = strings.TrimSpace(it.Link)
+		}
+		items = append(items, Item{ID: orHash(id, data), Data: data})
+	}
+	return items, nil
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomDoc struct {
+	Entries []struct {
+		ID        string     `xml:"id"`
+		Title     string     `xml:"title"`
+		Links     []atomLink `xml:"link"`
+		Updated   string     `xml:"updated"`
+		Published string     `xml:"published"`
+		Summary   string     `xml:"summary"`
+		Content   string     `xml:"content"`
+	} `xml:"entry"`
+}
+
+type atomItem struct {
+	ID        string `json:"id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Link      string `json:"link,omitempty"`
+	Updated   string `json:"updated,omitempty"`
+	Published string `json:"published,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+func parseAtom(body []byte) ([]Item, error) {
+	var doc atomDoc
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("poll: invalid Atom feed: %w", err)
+	}
+	items := make([]Item, 0, len(doc.Entries))
+	for _, e := range doc.Entries {
+		it := atomItem{
+			ID:        strings.TrimSpace(e.ID),
+			Title:     e.Title,
+			Updated:   e.Updated,
+			Published: e.Published,
+			Summary:   e.Summary,
+			Content:   e.Content,
+		}
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				it.Link = l.Href
+				break
+			}
+		}
+		data, err := json.Marshal(it)
+		if err != nil {
+			return nil, err
+		}
+		id := it.ID
+		if id == "" {
+			id = it.Link
+		}
+		items = append(items, Item{ID: orHash(id, data), Data: data})
+	}
+	return items, nil
+}
+
+func scalarString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return fmt.Sprint(val)
+	}
+	return ""
+}
+
+func orHash(id string, data []byte) string {
+	if id != "" {
+		return id
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+package poll
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`{"url":"https://example.com/feed","max_items":5000}`))
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if cfg.Format != FormatAuto || cfg.IDPath != "id" || cfg.MaxItems != maxMaxItems || cfg.Timeout() != defaultTimeout {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	for _, raw := range []string{`{}`, `{"url":"https://example.com","format":"csv"}`, `[]`} {
+		if _, err := ParseConfig([]byte(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	cfg := Config{Format: FormatAuto, ItemsPath: "data.items", IDPath: "meta.key"}
+	body := []byte(`{"data":{"items":[{"meta":{"key":"k1"},"n":1},{"meta":{"key":42}},{"n":3}]}}`)
+	items, err := Parse(cfg, "application/json; charset=utf-8", body)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if len(items) != 3 || items[0].ID != "k1" || items[1].ID != "42" || !strings.HasPrefix(items[2].ID, "sha256:") {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	if string(items[0].Data) != `{"meta":{"key":"k1"},"n":1}` {
+		t.Fatalf("unexpected item data %s", items[0].Data)
+	}
+
+	// The hash of an item without an ID is stable.
+	again, _ := Parse(cfg, "", body)
+	if again[2].ID != items[2].ID {
+		t.Fatalf("expected a stable hash ID")
+	}
+
+	for _, tc := range []struct{ path, body string }{
+		{"missing", `{"data":[]}`},
+		{"data", `{"data":{"not":"an array"}}`},
+		{"", `not json`},
+	} {
+		if _, err := Parse(Config{Format: FormatJSON, ItemsPath: tc.path, IDPath: "id"}, "", []byte(tc.body)); err == nil {
+			t.Fatalf("expected error for %q %s", tc.path, tc.body)
+		}
+	}
+}
+
+func TestParseRSSAndAtom(t *testing.T) {
+	rss := `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><title>Second</title><link>https://example.com/2</link><guid>post-2</guid><pubDate>Tue, 02 Jan 2024 09:00:00 GMT</pubDate></item>
+<item><title>First</title><link>https://example.com/1</link></item>
+</channel></rss>`
+	items, err := Parse(Config{Format: FormatAuto}, "text/xml", []byte(rss))
+	if err != nil {
+		t.Fatalf("Parse RSS error: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "post-2" || items[1].ID != "https://example.com/1" {
+		t.Fatalf("unexpected RSS items: %+v", items)
+	}
+	var first map[string]string
+	if err := json.Unmarshal(items[0].Data, &first); err != nil || first["title"] != "Second" || first["published"] == "" {
+		t.Fatalf("unexpected RSS item data %s", items[0].Data)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><id>urn:uuid:1</id><title>Hello</title><link rel="self" href="https://example.com/self"/><link href="https://example.com/hello"/><updated>2024-01-02T09:00:00Z</updated></entry>
+</feed>`
+	items, err = Parse(Config{Format: FormatAuto}, "", []byte(atom))
+	if err != nil {
+		t.Fatalf("Parse Atom error: %v", err)
+	}
+	var entry map[string]string
+	if len(items) != 1 || items[0].ID != "urn:uuid:1" || json.Unmarshal(items[0].Data, &entry) != nil || entry["link"] != "https://example.com/hello" {
+		t.Fatalf("unexpected Atom items: %+v", items)
+	}
+
+	if _, err := Parse(Config{Format: FormatAuto}, "text/html", []byte(`<html></html>`)); err == nil {
+		t.Fatalf("expected undetectable format to fail")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"a"}]`))
+	}))
+	defer srv.Close()
+
+	cfg, _ := ParseConfig([]byte(`{"url":"` + srv.URL + `","headers":{"Authorization":"Bearer token"}}`))
+	items, err := Fetch(context.Background(), nil, cfg)
+	if err != nil || len(items) != 1 || items[0].ID != "a" {
+		t.Fatalf("unexpected fetch result %+v (err %v)", items, err)
+	}
+
+	cfg.Headers = nil
+	if _, err := Fetch(context.Background(), nil, cfg); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// LockKey is the advisory lock key scheduler replicas compete for.
+const LockKey int64 = 0x506f7461466c6f77 // "PotaFlow"
+
+// NotifyChannel is the Postgres channel notified whenever triggers or workflow enablement change.
+const NotifyChannel = "triggers_changed"
+
+// Leader reports whether this replica may fire schedules. Acquire is called before every tick
+// and must be cheap when leadership is already held.
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context)
+}
+
+// AdvisoryLock elects a leader with a session-level Postgres advisory lock held on a dedicated
+// connection, so leadership passes to another replica as soon as the holder's connection dies.
+type AdvisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+	conn *pgxpool.Conn
+}
+
+// NewAdvisoryLock builds an AdvisoryLock for key.
+func NewAdvisoryLock(pool *pgxpool.Pool, key int64) *AdvisoryLock {
+	return &AdvisoryLock{pool: pool, key: key}
+}
+
+// Acquire implements Leader.
+func (l *AdvisoryLock) Acquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if err := l.conn.Ping(ctx); err == nil {
+			return true, nil
+		}
+		// The session is gone and the lock with it; drop the connection and compete again.
+		_ = l.conn.Conn().Close(ctx)
+		l.conn.Release()
+		l.conn = nil
+		log.Warn().Msg("scheduler lost leadership")
+	}
+
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&locked); err != nil {
+		conn.Release()
+		return false, err
+	}
+	if !locked {
+		conn.Release()
+		return false, nil
+	}
+	l.conn = conn
+	log.Info().Msg("scheduler acquired leadership")
+	return true, nil
+}
+
+// Release implements Leader.
+func (l *AdvisoryLock) Release(ctx context.Context) {
+	if l.conn == nil {
+		return
+	}
+	_, _ = l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	l.conn.Release()
+	l.conn = nil
+}
+
+// Listen LISTENs on NotifyChannel and signals changes on every out channel, coalescing bursts.
+// It reconnects after errors and returns when ctx is cancelled.
+func Listen(ctx context.Context, pool *pgxpool.Pool, out ...chan<- struct{}) {
+	for ctx.Err() == nil {
+		if err := listen(ctx, pool, out); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("scheduler listen failed; retrying")
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
+
+func listen(ctx context.Context, pool *pgxpool.Pool, out []chan<- struct{}) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+NotifyChannel); err != nil {
+		return err
+	}
+	for {
+		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+			// The connection may be mid-wait; don't hand it back to the pool.
+			_ = conn.Conn().Close(context.Background())
+			return err
+		}
+		for _, ch := range out {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
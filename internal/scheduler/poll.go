@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/internal/poll"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// seenRetention is how long an item ID is remembered after it last appeared in its feed.
+const seenRetention = 30 * 24 * time.Hour
+
+// startPoll polls e's feed in the background, unless its previous poll is still running.
+func (s *Scheduler) startPoll(ctx context.Context, e *entry) {
+	s.pollMu.Lock()
+	if s.polling[e.triggerID] {
+		s.pollMu.Unlock()
+		log.Warn().Str("trigger_id", e.triggerID).Msg("previous poll still running, skipping")
+		return
+	}
+	s.polling[e.triggerID] = true
+	s.pollMu.Unlock()
+
+	triggerID, workflowID, config := e.triggerID, e.workflowID, e.config
+	go func() {
+		defer func() {
+			s.pollMu.Lock()
+			delete(s.polling, triggerID)
+			s.pollMu.Unlock()
+		}()
+		if err := s.poll(ctx, triggerID, workflowID, config); err != nil {
+			log.Warn().Err(err).Str("trigger_id", triggerID).Msg("poll failed")
+		}
+	}()
+}
+
+// poll fetches the feed of a poll trigger and enqueues a run for every item it hasn't seen. The
+// first successful poll only records the current items, unless emit_existing is set.
+func (s *Scheduler) poll(ctx context.Context, triggerID, workflowID string, config []byte) error {
+	cfg, err := poll.ParseConfig(config)
+	if err != nil {
+		return err
+	}
+	items, err := poll.Fetch(ctx, s.client, cfg)
+	if err != nil {
+		return err
+	}
+	if len(items) > cfg.MaxItems {
+		items = items[:cfg.MaxItems]
+	}
+	lastPolled, err := s.queries.GetTriggerLastPolledAt(ctx, triggerID)
+	if err != nil {
+		return err
+	}
+	baseline := !lastPolled.Valid && !cfg.EmitExisting
+
+	enqueued := 0
+	for _, item := range items {
+		inserted, err := s.queries.MarkTriggerItemSeen(ctx, sqlc.MarkTriggerItemSeenParams{TriggerID: triggerID, ItemID: item.ID})
+		if err != nil {
+			return err
+		}
+		if !inserted || baseline {
+			continue
+		}
+		if s.enqueueItem(ctx, triggerID, workflowID, item) {
+			enqueued++
+			continue
+		}
+		// Forget the item so the next poll tries again.
+		if err := s.queries.ForgetTriggerItem(ctx, sqlc.ForgetTriggerItemParams{TriggerID: triggerID, ItemID: item.ID}); err != nil {
+			log.Error().Err(err).Str("trigger_id", triggerID).Str("item_id", item.ID).Msg("failed to forget poll item")
+		}
+	}
+
+	now := s.now()
+	if err := s.queries.UpdateTriggerLastPolledAt(ctx, sqlc.UpdateTriggerLastPolledAtParams{
+		ID:           triggerID,
+		LastPolledAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}); err != nil {
+		return err
+	}
+	if err := s.queries.PruneTriggerSeenItems(ctx, sqlc.PruneTriggerSeenItemsParams{
+		TriggerID:  triggerID,
+		LastSeenAt: pgtype.Timestamptz{Time: now.Add(-seenRetention), Valid: true},
+	}); err != nil {
+		log.Error().Err(err).Str("trigger_id", triggerID).Msg("failed to prune seen poll items")
+	}
+	log.Info().Str("trigger_id", triggerID).Int("items", len(items)).Int("enqueued", enqueued).Bool("baseline", baseline).Msg("poll trigger checked feed")
+	return nil
+}
+
+func (s *Scheduler) enqueueItem(ctx context.Context, triggerID, workflowID string, item poll.Item) bool {
+	input, err := json.Marshal(map[string]any{
+		"trigger_id": triggerID,
+		"item_id":    item.ID,
+		"item":       item.Data,
+	})
+	if err != nil {
+		return false
+	}
+	run, err := s.queries.CreateWorkflowRun(ctx, sqlc.CreateWorkflowRunParams{
+		WorkflowID:  workflowID,
+		Status:      "pending",
+		TriggerType: TriggerTypePoll,
+		Input:       input,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("trigger_id", triggerID).Str("item_id", item.ID).Msg("failed to enqueue poll run")
+		return false
+	}
+	log.Info().Str("trigger_id", triggerID).Str("run_id", run.ID).Str("item_id", item.ID).Msg("poll trigger fired")
+	return true
+}
@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParsePollConfig(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	sched, err := ParseConfig(TriggerTypePoll, []byte(`{"url":"https://example.com/feed"}`), created)
+	if err != nil {
+		t.Fatalf("ParseConfig poll error: %v", err)
+	}
+	if got := sched.Next(created); !got.Equal(created.Add(DefaultPollInterval)) {
+		t.Fatalf("expected default poll interval, got %s", got)
+	}
+	// Polls missed during an outage collapse into one.
+	fire, _ := sched.Due(created.Add(5*time.Minute), created.Add(time.Hour), misfireGrace)
+	if len(fire) != 1 {
+		t.Fatalf("expected a single catch-up poll, got %v", fire)
+	}
+
+	for _, raw := range []string{`{}`, `{"url":"https://example.com","every":"10ms"}`, `{"url":"https://example.com","format":"csv"}`, `nope`} {
+		if _, err := ParseConfig(TriggerTypePoll, []byte(raw), created); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestSchedulerPollEnqueuesNewItems(t *testing.T) {
+	items := []string{`{"id":1,"name":"a"}`, `{"id":2,"name":"b"}`}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	fq := &fakeQueries{}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	config := []byte(`{"url":"` + srv.URL + `","items_path":"data"}`)
+
+	// The first poll only records what is already in the feed.
+	if err := s.poll(ctx, "tr-1", "wf-1", config); err != nil {
+		t.Fatalf("poll error: %v", err)
+	}
+	if len(fq.runs) != 0 || len(fq.seen) != 2 {
+		t.Fatalf("expected a baseline poll, got runs %+v seen %v", fq.runs, fq.seen)
+	}
+	if !fq.lastPolled["tr-1"].Equal(now) || len(fq.pruned) != 1 {
+		t.Fatalf("expected last_polled_at and pruning to be recorded")
+	}
+
+	items = append([]string{`{"id":3,"name":"c"}`}, items...)
+	if err := s.poll(ctx, "tr-1", "wf-1", config); err != nil {
+		t.Fatalf("poll error: %v", err)
+	}
+	if len(fq.runs) != 1 || fq.runs[0].TriggerType != TriggerTypePoll || fq.runs[0].WorkflowID != "wf-1" {
+		t.Fatalf("expected one run for the new item, got %+v", fq.runs)
+	}
+	var input struct {
+		TriggerID string          `json:"trigger_id"`
+		ItemID    string          `json:"item_id"`
+		Item      json.RawMessage `json:"item"`
+	}
+	if err := json.Unmarshal(fq.runs[0].Input, &input); err != nil || input.ItemID != "3" || string(input.Item) != `{"id":3,"name":"c"}` {
+		t.Fatalf("unexpected run input %s (err %v)", fq.runs[0].Input, err)
+	}
+
+	// A failed enqueue is retried on the next poll.
+	items = append([]string{`{"id":4}`}, items...)
+	fq.runErr = fmt.Errorf("db down")
+	_ = s.poll(ctx, "tr-1", "wf-1", config)
+	fq.runErr = nil
+	if err := s.poll(ctx, "tr-1", "wf-1", config); err != nil {
+		t.Fatalf("poll error: %v", err)
+	}
+	if len(fq.runs) != 3 || !strings.Contains(string(fq.runs[2].Input), `"item_id":"4"`) {
+		t.Fatalf("expected the failed item to be retried once, got %d runs", len(fq.runs))
+	}
+}
+
+func TestSchedulerPollEmitExisting(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"a"},{"id":"b"},{"id":"c"}]`)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	fq := &fakeQueries{}
+	s := newTestScheduler(fq, true, &now)
+	config := []byte(`{"url":"` + srv.URL + `","emit_existing":true,"max_items":2}`)
+	if err := s.poll(context.Background(), "tr-1", "wf-1", config); err != nil {
+		t.Fatalf("poll error: %v", err)
+	}
+	if len(fq.runs) != 2 {
+		t.Fatalf("expected existing items up to max_items to fire, got %d runs", len(fq.runs))
+	}
+}
+
+func TestSchedulerStartsPollsForDueTriggers(t *testing.T) {
+	polled := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+		polled <- struct{}{}
+	}))
+	defer srv.Close()
+
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := created.Add(30 * time.Second)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{{
+		ID:         "tr-1",
+		WorkflowID: "wf-1",
+		Type:       TriggerTypePoll,
+		Config:     []byte(`{"url":"` + srv.URL + `","every":"1m"}`),
+		CreatedAt:  pgtype.Timestamptz{Time: created, Valid: true},
+	}}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	now = created.Add(time.Minute)
+	s.tick(ctx)
+	select {
+	case <-polled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the due poll trigger to fetch its feed")
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		s.pollMu.Lock()
+		inFlight := s.polling["tr-1"]
+		s.pollMu.Unlock()
+		if !inFlight {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("poll did not finish")
+		}
+	}
+	if fq.lastPolled["tr-1"].IsZero() {
+		t.Fatalf("expected the poll to record last_polled_at")
+	}
+	if len(fq.runs) != 0 {
+		t.Fatalf("a poll must not enqueue a run by itself, got %+v", fq.runs)
+	}
+}
@@ -0,0 +1,318 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/poll"
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts standard 5-field expressions, 6-field expressions with a leading seconds
+// field, and descriptors such as @daily or @every 5m.
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Misfire policies decide what happens to slots missed while no scheduler was running.
+const (
+	MisfireSkip     = "skip"      // drop missed slots and wait for the next one (default)
+	MisfireFireOnce = "fire_once" // fire a single run for the most recent missed slot
+	MisfireFireAll  = "fire_all"  // fire every missed slot, up to MaxCatchup
+)
+
+// DefaultMaxCatchup caps MisfireFireAll when max_catchup is unset.
+const DefaultMaxCatchup = 10
+
+// maxMaxCatchup bounds max_catchup so one trigger can't flood the run queue after an outage.
+const maxMaxCatchup = 1000
+
+// maxMissedScan bounds how many missed slots Due walks, e.g. an @every 1s trigger after a long outage.
+const maxMissedScan = 100000
+
+// MinInterval is the shortest "interval" trigger period; the scheduler ticks once a second.
+const MinInterval = time.Second
+
+// DefaultPollInterval is how often a "poll" trigger fetches its feed when every is unset.
+const DefaultPollInterval = 5 * time.Minute
+
+// MisfireConfig holds the misfire settings shared by every scheduled trigger type.
+type MisfireConfig struct {
+	MisfirePolicy string `json:"misfire_policy,omitempty"`
+	MaxCatchup    *int   `json:"max_catchup,omitempty"`
+}
+
+// CronConfig is the config of a "cron" trigger. Timezone is an IANA name and defaults to UTC.
+type CronConfig struct {
+	CronExpr string `json:"cron_expr"`
+	Timezone string `json:"timezone,omitempty"`
+	MisfireConfig
+}
+
+// IntervalConfig is the config of an "interval" trigger: it fires every Every (a Go duration
+// such as "90s"), counted from StartAt or, when unset, from the trigger's creation.
+type IntervalConfig struct {
+	Every   string     `json:"every"`
+	StartAt *time.Time `json:"start_at,omitempty"`
+	MisfireConfig
+}
+
+// AtConfig is the config of an "at" trigger, which fires once and is then removed. Unlike the
+// repeating types, a missed "at" trigger defaults to fire_once.
+type AtConfig struct {
+	At time.Time `json:"at"`
+	MisfireConfig
+}
+
+// PollConfig is the scheduling part of a "poll" trigger config; the feed settings are read by
+// package poll. Polls missed while no scheduler was running collapse into one.
+type PollConfig struct {
+	Every string `json:"every,omitempty"`
+}
+
+// Schedule computes the fire times of a scheduled trigger and how missed ones are handled.
+type Schedule struct {
+	expr       string
+	schedule   cron.Schedule
+	oneShot    bool
+	misfire    string
+	maxCatchup int
+}
+
+// ParseConfig decodes and validates the config of a cron, interval, at or poll trigger.
+// createdAt anchors interval triggers without a start_at, and poll triggers.
+func ParseConfig(triggerType string, raw []byte, createdAt time.Time) (Schedule, error) {
+	switch triggerType {
+	case TriggerTypeCron:
+		return ParseCronConfig(raw)
+	case TriggerTypeInterval:
+		return ParseIntervalConfig(raw, createdAt)
+	case TriggerTypeAt:
+		return ParseAtConfig(raw)
+	case TriggerTypePoll:
+		return ParsePollConfig(raw, createdAt)
+	}
+	return Schedule{}, fmt.Errorf("unsupported scheduled trigger type %q", triggerType)
+}
+
+// ParseCronConfig decodes and validates a cron trigger config.
+func ParseCronConfig(raw []byte) (Schedule, error) {
+	var cfg CronConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Schedule{}, fmt.Errorf("invalid cron config: %w", err)
+	}
+	if cfg.CronExpr == "" {
+		return Schedule{}, errors.New("invalid cron config: cron_expr is required")
+	}
+	loc := time.UTC
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return Schedule{}, fmt.Errorf("invalid cron config: %w", err)
+		}
+	}
+	sched, err := cronParser.Parse(cfg.CronExpr)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid cron config: %w", err)
+	}
+	s := Schedule{expr: cfg.CronExpr + " " + loc.String(), schedule: inLocation{sched, loc}}
+	if err := s.setMisfire(cfg.MisfireConfig, MisfireSkip); err != nil {
+		return Schedule{}, fmt.Errorf("invalid cron config: %w", err)
+	}
+	return s, nil
+}
+
+// ParseIntervalConfig decodes and validates an interval trigger config.
+func ParseIntervalConfig(raw []byte, createdAt time.Time) (Schedule, error) {
+	var cfg IntervalConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Schedule{}, fmt.Errorf("invalid interval config: %w", err)
+	}
+	every, err := time.ParseDuration(cfg.Every)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid interval config: every: %w", err)
+	}
+	if every < MinInterval {
+		return Schedule{}, fmt.Errorf("invalid interval config: every must be at least %s", MinInterval)
+	}
+	anchor := createdAt
+	if cfg.StartAt != nil {
+		anchor = *cfg.StartAt
+	}
+	anchor = anchor.UTC().Truncate(time.Second)
+	s := Schedule{
+		expr:     fmt.Sprintf("every %s from %s", every, anchor.Format(time.RFC3339)),
+		schedule: interval{every: every, anchor: anchor},
+	}
+	if err := s.setMisfire(cfg.MisfireConfig, MisfireSkip); err != nil {
+		return Schedule{}, fmt.Errorf("invalid interval config: %w", err)
+	}
+	return s, nil
+}
+
+// ParseAtConfig decodes and validates an at trigger config.
+func ParseAtConfig(raw []byte) (Schedule, error) {
+	var cfg AtConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Schedule{}, fmt.Errorf("invalid at config: %w", err)
+	}
+	if cfg.At.IsZero() {
+		return Schedule{}, errors.New("invalid at config: at is required")
+	}
+	at := cfg.At.UTC()
+	s := Schedule{expr: "at " + at.Format(time.RFC3339Nano), schedule: once{at: at}, oneShot: true}
+	if err := s.setMisfire(cfg.MisfireConfig, MisfireFireOnce); err != nil {
+		return Schedule{}, fmt.Errorf("invalid at config: %w", err)
+	}
+	return s, nil
+}
+
+// ParsePollConfig decodes and validates a poll trigger config, including its feed settings.
+func ParsePollConfig(raw []byte, createdAt time.Time) (Schedule, error) {
+	var cfg PollConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Schedule{}, fmt.Errorf("invalid poll config: %w", err)
+	}
+	if _, err := poll.ParseConfig(raw); err != nil {
+		return Schedule{}, err
+	}
+	every := DefaultPollInterval
+	if cfg.Every != "" {
+		var err error
+		if every, err = time.ParseDuration(cfg.Every); err != nil {
+			return Schedule{}, fmt.Errorf("invalid poll config: every: %w", err)
+		}
+	}
+	if every < MinInterval {
+		return Schedule{}, fmt.Errorf("invalid poll config: every must be at least %s", MinInterval)
+	}
+	anchor := createdAt.UTC().Truncate(time.Second)
+	return Schedule{
+		expr:       fmt.Sprintf("poll every %s from %s", every, anchor.Format(time.RFC3339)),
+		schedule:   interval{every: every, anchor: anchor},
+		misfire:    MisfireFireOnce,
+		maxCatchup: 1,
+	}, nil
+}
+
+func (s *Schedule) setMisfire(cfg MisfireConfig, defaultPolicy string) error {
+	switch cfg.MisfirePolicy {
+	case "":
+		cfg.MisfirePolicy = defaultPolicy
+	case MisfireSkip, MisfireFireOnce, MisfireFireAll:
+	default:
+		return fmt.Errorf("unknown misfire_policy %q", cfg.MisfirePolicy)
+	}
+	maxCatchup := DefaultMaxCatchup
+	if cfg.MaxCatchup != nil {
+		maxCatchup = *cfg.MaxCatchup
+		if maxCatchup < 1 || maxCatchup > maxMaxCatchup {
+			return fmt.Errorf("max_catchup must be between 1 and %d", maxMaxCatchup)
+		}
+	}
+	s.misfire = cfg.MisfirePolicy
+	s.maxCatchup = maxCatchup
+	return nil
+}
+
+// ValidateCronExpr reports whether expr is an expression ParseCronConfig accepts.
+func ValidateCronExpr(expr string) error {
+	_, err := cronParser.Parse(expr)
+	return err
+}
+
+// Next returns the first fire time strictly after t, or the zero time if there is none.
+func (s Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
+
+// First returns the first fire time of a newly loaded trigger. A one-shot trigger starts at its
+// fire time even if that has passed, so its misfire policy decides whether it still runs.
+func (s Schedule) First(now time.Time) time.Time {
+	if o, ok := s.schedule.(once); ok {
+		return o.at
+	}
+	return s.Next(now)
+}
+
+// OneShot reports whether the trigger fires only once.
+func (s Schedule) OneShot() bool {
+	return s.oneShot
+}
+
+// Due returns the slots that should fire for a trigger whose first unfired slot is next, and the
+// first slot after now. Slots older than grace are misfires and are handled per the policy.
+func (s Schedule) Due(next, now time.Time, grace time.Duration) (fire []time.Time, following time.Time) {
+	var missed []time.Time
+	t := next
+	for scanned := 0; !t.IsZero() && !t.After(now); scanned++ {
+		if scanned == maxMissedScan {
+			// Very frequent schedules after a long outage; nothing older matters to any policy.
+			t = s.Next(now)
+			break
+		}
+		if now.Sub(t) <= grace {
+			fire = append(fire, t)
+		} else {
+			missed = append(missed, t)
+		}
+		t = s.Next(t)
+	}
+	if len(missed) == 0 {
+		return fire, t
+	}
+	switch s.misfire {
+	case MisfireFireOnce:
+		if len(fire) == 0 {
+			fire = missed[len(missed)-1:]
+		}
+	case MisfireFireAll:
+		// Keep the most recent slots within the cap, in order.
+		fire = append(missed, fire...)
+		if len(fire) > s.maxCatchup {
+			fire = fire[len(fire)-s.maxCatchup:]
+		}
+	}
+	return fire, t
+}
+
+// String identifies the schedule's fire times; misfire settings are not part of it.
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// inLocation evaluates a cron schedule in a time zone.
+type inLocation struct {
+	cron.Schedule
+	loc *time.Location
+}
+
+func (s inLocation) Next(t time.Time) time.Time {
+	return s.Schedule.Next(t.In(s.loc))
+}
+
+// interval fires at anchor + n*every.
+type interval struct {
+	every  time.Duration
+	anchor time.Time
+}
+
+func (s interval) Next(t time.Time) time.Time {
+	if t.Before(s.anchor) {
+		return s.anchor
+	}
+	n := t.Sub(s.anchor)/s.every + 1
+	return s.anchor.Add(n * s.every)
+}
+
+// once fires at a single instant.
+type once struct {
+	at time.Time
+}
+
+func (s once) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
@@ -0,0 +1,237 @@
+// Package scheduler fires cron, interval and at triggers by enqueueing workflow runs, and polls
+// the feeds of poll triggers.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// Trigger types handled by the scheduler.
+const (
+	TriggerTypeCron     = "cron"
+	TriggerTypeInterval = "interval"
+	TriggerTypeAt       = "at"
+	TriggerTypePoll     = "poll"
+)
+
+// tickInterval is the scheduler's resolution; 6-field expressions can fire every second.
+const tickInterval = time.Second
+
+// misfireGrace is how late a slot may fire and still count as on time rather than missed.
+const misfireGrace = time.Minute
+
+// Scheduler keeps the scheduled triggers of enabled workflows in memory and enqueues a run at
+// each fire time. Every replica keeps its schedule loaded, but only the current Leader fires.
+type Scheduler struct {
+	queries        schedulerQueries
+	leader         Leader
+	reloadInterval time.Duration
+	now            func() time.Time
+	client         *http.Client
+
+	entries map[string]*entry
+	leading bool
+
+	pollMu  sync.Mutex
+	polling map[string]bool // trigger IDs with a poll in flight
+}
+
+type entry struct {
+	triggerID   string
+	workflowID  string
+	triggerType string
+	config      []byte
+	schedule    Schedule
+	next        time.Time
+}
+
+type schedulerQueries interface {
+	ListScheduledTriggers(ctx context.Context) ([]sqlc.ListScheduledTriggersRow, error)
+	CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error)
+	UpdateTriggerLastFiredAt(ctx context.Context, arg sqlc.UpdateTriggerLastFiredAtParams) error
+	DeleteTriggerByID(ctx context.Context, id string) error
+	GetTriggerLastPolledAt(ctx context.Context, id string) (pgtype.Timestamptz, error)
+	UpdateTriggerLastPolledAt(ctx context.Context, arg sqlc.UpdateTriggerLastPolledAtParams) error
+	MarkTriggerItemSeen(ctx context.Context, arg sqlc.MarkTriggerItemSeenParams) (bool, error)
+	ForgetTriggerItem(ctx context.Context, arg sqlc.ForgetTriggerItemParams) error
+	PruneTriggerSeenItems(ctx context.Context, arg sqlc.PruneTriggerSeenItemsParams) error
+}
+
+// New builds a Scheduler that also reloads its triggers every reloadInterval, as a fallback for
+// missed change notifications.
+func New(db sqlc.DBTX, leader Leader, reloadInterval time.Duration) *Scheduler {
+	return &Scheduler{
+		queries:        sqlc.New(db),
+		leader:         leader,
+		reloadInterval: reloadInterval,
+		now:            time.Now,
+		client:         &http.Client{},
+		entries:        make(map[string]*entry),
+		polling:        make(map[string]bool),
+	}
+}
+
+// Run loads the schedule and fires due triggers until ctx is cancelled. A value on changes
+// (see Listen) triggers an immediate reload.
+func (s *Scheduler) Run(ctx context.Context, changes <-chan struct{}) error {
+	defer s.leader.Release(context.Background())
+
+	if err := s.Reload(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to load scheduled triggers")
+	}
+	tick := time.NewTicker(tickInterval)
+	defer tick.Stop()
+	reload := time.NewTicker(s.reloadInterval)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changes:
+			if err := s.Reload(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to reload scheduled triggers")
+			}
+		case <-reload.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to reload scheduled triggers")
+			}
+		case <-tick.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// Reload replaces the schedule with the current scheduled triggers. Triggers whose fire times are
+// unchanged keep their pending fire time; invalid configs are logged and skipped.
+func (s *Scheduler) Reload(ctx context.Context) error {
+	return s.load(ctx, false)
+}
+
+// load implements Reload. With resume set, triggers continue from their persisted last fire time,
+// or their creation if they never fired, instead, so slots missed while no replica was leading are
+// handed to their misfire policy.
+func (s *Scheduler) load(ctx context.Context, resume bool) error {
+	rows, err := s.queries.ListScheduledTriggers(ctx)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	entries := make(map[string]*entry, len(rows))
+	for _, row := range rows {
+		sched, err := ParseConfig(row.Type, row.Config, row.CreatedAt.Time)
+		if err != nil {
+			log.Warn().Err(err).Str("trigger_id", row.ID).Msg("skipping scheduled trigger")
+			continue
+		}
+		e := &entry{triggerID: row.ID, workflowID: row.WorkflowID, triggerType: row.Type, config: row.Config, schedule: sched}
+		if old, ok := s.entries[row.ID]; resume && row.LastFiredAt.Valid {
+			e.next = sched.Next(row.LastFiredAt.Time)
+		} else if resume && row.CreatedAt.Valid {
+			// Never fired: every slot since the trigger was created is missed.
+			e.next = sched.First(row.CreatedAt.Time)
+		} else if ok && old.schedule.String() == sched.String() {
+			e.next = old.next
+		} else {
+			e.next = sched.First(now)
+		}
+		entries[row.ID] = e
+	}
+	s.entries = entries
+	return nil
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	leader, err := s.leader.Acquire(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("scheduler leader election failed")
+	}
+	if leader && !s.leading {
+		if err := s.load(ctx, true); err != nil {
+			log.Error().Err(err).Msg("failed to resume scheduled triggers")
+		}
+	}
+	s.leading = leader
+
+	now := s.now()
+	for id, e := range s.entries {
+		if e.next.After(now) {
+			continue
+		}
+		slots, next := e.schedule.Due(e.next, now, misfireGrace)
+		e.next = next
+		// Followers only advance their fire times; a new leader resumes from last_fired_at.
+		if !leader {
+			continue
+		}
+		fired := s.fireAll(ctx, e, slots)
+		// A one-shot trigger is done once its slot has been fired or skipped. If enqueueing
+		// failed it stays, and the next leader to resume it tries again.
+		if e.schedule.OneShot() && e.next.IsZero() && fired {
+			if err := s.queries.DeleteTriggerByID(ctx, e.triggerID); err != nil {
+				log.Error().Err(err).Str("trigger_id", e.triggerID).Msg("failed to remove one-shot trigger")
+				continue
+			}
+			delete(s.entries, id)
+		}
+	}
+}
+
+// fireAll enqueues a run per slot and records the last one fired. It reports whether every slot
+// was enqueued.
+func (s *Scheduler) fireAll(ctx context.Context, e *entry, slots []time.Time) bool {
+	var last time.Time
+	ok := true
+	for _, at := range slots {
+		if s.fire(ctx, e, at) {
+			last = at
+		} else {
+			ok = false
+		}
+	}
+	if last.IsZero() {
+		return ok
+	}
+	if err := s.queries.UpdateTriggerLastFiredAt(ctx, sqlc.UpdateTriggerLastFiredAtParams{
+		ID:          e.triggerID,
+		LastFiredAt: pgtype.Timestamptz{Time: last, Valid: true},
+	}); err != nil {
+		log.Error().Err(err).Str("trigger_id", e.triggerID).Msg("failed to record fire time")
+	}
+	return ok
+}
+
+func (s *Scheduler) fire(ctx context.Context, e *entry, at time.Time) bool {
+	if e.triggerType == TriggerTypePoll {
+		// A poll enqueues its own runs, one per new feed item.
+		s.startPoll(ctx, e)
+		return true
+	}
+	input, err := json.Marshal(map[string]string{
+		"trigger_id":   e.triggerID,
+		"scheduled_at": at.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return false
+	}
+	run, err := s.queries.CreateWorkflowRun(ctx, sqlc.CreateWorkflowRunParams{
+		WorkflowID:  e.workflowID,
+		Status:      "pending",
+		TriggerType: e.triggerType,
+		Input:       input,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("trigger_id", e.triggerID).Msg("failed to enqueue scheduled run")
+		return false
+	}
+	log.Info().Str("trigger_id", e.triggerID).Str("run_id", run.ID).Time("scheduled_at", at).Msg("scheduled trigger fired")
+	return true
+}
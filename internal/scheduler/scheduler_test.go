@@ -0,0 +1,420 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeQueries struct {
+	triggers []sqlc.ListScheduledTriggersRow
+	runs     []sqlc.CreateWorkflowRunParams
+	fired    map[string]time.Time
+	deleted  []string
+	err      error
+
+	seen       map[string]bool // "trigger_id/item_id"
+	lastPolled map[string]time.Time
+	pruned     []time.Time
+	runErr     error
+}
+
+func (f *fakeQueries) ListScheduledTriggers(ctx context.Context) ([]sqlc.ListScheduledTriggersRow, error) {
+	return f.triggers, f.err
+}
+func (f *fakeQueries) CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error) {
+	f.runs = append(f.runs, arg)
+	if f.runErr != nil {
+		return sqlc.CreateWorkflowRunRow{}, f.runErr
+	}
+	return sqlc.CreateWorkflowRunRow{ID: "run-1", WorkflowID: arg.WorkflowID}, f.err
+}
+func (f *fakeQueries) UpdateTriggerLastFiredAt(ctx context.Context, arg sqlc.UpdateTriggerLastFiredAtParams) error {
+	if f.fired == nil {
+		f.fired = make(map[string]time.Time)
+	}
+	f.fired[arg.ID] = arg.LastFiredAt.Time
+	return f.err
+}
+
+func (f *fakeQueries) DeleteTriggerByID(ctx context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return f.err
+}
+
+func (f *fakeQueries) GetTriggerLastPolledAt(ctx context.Context, id string) (pgtype.Timestamptz, error) {
+	at, ok := f.lastPolled[id]
+	return pgtype.Timestamptz{Time: at, Valid: ok}, f.err
+}
+
+func (f *fakeQueries) UpdateTriggerLastPolledAt(ctx context.Context, arg sqlc.UpdateTriggerLastPolledAtParams) error {
+	if f.lastPolled == nil {
+		f.lastPolled = make(map[string]time.Time)
+	}
+	f.lastPolled[arg.ID] = arg.LastPolledAt.Time
+	return f.err
+}
+
+func (f *fakeQueries) MarkTriggerItemSeen(ctx context.Context, arg sqlc.MarkTriggerItemSeenParams) (bool, error) {
+	if f.seen == nil {
+		f.seen = make(map[string]bool)
+	}
+	key := arg.TriggerID + "/" + arg.ItemID
+	inserted := !f.seen[key]
+	f.seen[key] = true
+	return inserted, f.err
+}
+
+func (f *fakeQueries) ForgetTriggerItem(ctx context.Context, arg sqlc.ForgetTriggerItemParams) error {
+	delete(f.seen, arg.TriggerID+"/"+arg.ItemID)
+	return f.err
+}
+
+func (f *fakeQueries) PruneTriggerSeenItems(ctx context.Context, arg sqlc.PruneTriggerSeenItemsParams) error {
+	f.pruned = append(f.pruned, arg.LastSeenAt.Time)
+	return f.err
+}
+
+type fakeLeader struct{ leader bool }
+
+func (f *fakeLeader) Acquire(ctx context.Context) (bool, error) { return f.leader, nil }
+func (f *fakeLeader) Release(ctx context.Context)               {}
+
+func newTestScheduler(fq *fakeQueries, leader bool, now *time.Time) *Scheduler {
+	return &Scheduler{
+		queries:        fq,
+		leader:         &fakeLeader{leader: leader},
+		reloadInterval: time.Minute,
+		now:            func() time.Time { return *now },
+		entries:        make(map[string]*entry),
+		polling:        make(map[string]bool),
+	}
+}
+
+func TestParseCronConfig(t *testing.T) {
+	base := time.Date(2024, 3, 1, 8, 59, 30, 0, time.UTC)
+	tests := []struct {
+		name   string
+		config string
+		want   time.Time
+	}{
+		{name: "five fields", config: `{"cron_expr":"0 9 * * *"}`, want: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "six fields", config: `{"cron_expr":"45 59 8 * * *"}`, want: time.Date(2024, 3, 1, 8, 59, 45, 0, time.UTC)},
+		{name: "descriptor", config: `{"cron_expr":"@hourly"}`, want: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "time zone", config: `{"cron_expr":"0 9 * * *","timezone":"America/New_York"}`, want: time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sched, err := ParseCronConfig([]byte(tc.config))
+			if err != nil {
+				t.Fatalf("ParseCronConfig error: %v", err)
+			}
+			if got := sched.Next(base); !got.Equal(tc.want) {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	for _, raw := range []string{
+		`{}`,
+		`{"cron_expr":"61 * * * *"}`,
+		`{"cron_expr":"* * * * *","timezone":"Mars/Olympus"}`,
+		`{"cron_expr":"* * * * *","misfire_policy":"sometimes"}`,
+		`{"cron_expr":"* * * * *","max_catchup":-1}`,
+		`{"cron_expr":"* * * * *","max_catchup":0}`,
+		`{"cron_expr":"* * * * *","max_catchup":1001}`,
+		`nope`,
+	} {
+		if _, err := ParseCronConfig([]byte(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestSchedulerFiresDueTriggers(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 59, 0, 0, time.UTC)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{
+		{ID: "tr-1", WorkflowID: "wf-1", Type: "cron", Config: []byte(`{"cron_expr":"0 9 * * *"}`)},
+		{ID: "tr-bad", WorkflowID: "wf-2", Type: "cron", Config: []byte(`{"cron_expr":"nope"}`)},
+	}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+
+	if err := s.Reload(ctx); err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+	if len(s.entries) != 1 {
+		t.Fatalf("expected invalid trigger to be skipped, got %d entries", len(s.entries))
+	}
+
+	s.tick(ctx)
+	if len(fq.runs) != 0 {
+		t.Fatalf("expected nothing to fire before 09:00, got %+v", fq.runs)
+	}
+
+	now = now.Add(time.Minute)
+	s.tick(ctx)
+	s.tick(ctx)
+	if len(fq.runs) != 1 {
+		t.Fatalf("expected exactly one run at 09:00, got %d", len(fq.runs))
+	}
+	run := fq.runs[0]
+	if run.WorkflowID != "wf-1" || run.TriggerType != "cron" || run.Status != "pending" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	var input map[string]string
+	if err := json.Unmarshal(run.Input, &input); err != nil || input["scheduled_at"] != "2024-03-01T09:00:00Z" {
+		t.Fatalf("unexpected run input %s (err %v)", run.Input, err)
+	}
+}
+
+func TestSchedulerFollowerDoesNotFire(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 59, 0, 0, time.UTC)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{
+		{ID: "tr-1", WorkflowID: "wf-1", Type: "cron", Config: []byte(`{"cron_expr":"0 9 * * *"}`)},
+	}}
+	s := newTestScheduler(fq, false, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	now = now.Add(time.Minute)
+	s.tick(ctx)
+	if len(fq.runs) != 0 {
+		t.Fatalf("follower fired: %+v", fq.runs)
+	}
+	if want := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC); !s.entries["tr-1"].next.Equal(want) {
+		t.Fatalf("expected follower to advance to %s, got %s", want, s.entries["tr-1"].next)
+	}
+}
+
+func TestSchedulerReloadKeepsUnchangedSchedules(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{
+		{ID: "tr-1", WorkflowID: "wf-1", Type: "cron", Config: []byte(`{"cron_expr":"0 9 * * *"}`)},
+	}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	// A reload after the fire time must not skip the pending slot.
+	now = time.Date(2024, 3, 1, 9, 0, 30, 0, time.UTC)
+	_ = s.Reload(ctx)
+	s.tick(ctx)
+	if len(fq.runs) != 1 {
+		t.Fatalf("expected pending slot to fire after reload, got %d runs", len(fq.runs))
+	}
+
+	fq.triggers[0].Config = []byte(`{"cron_expr":"30 9 * * *"}`)
+	_ = s.Reload(ctx)
+	if want := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC); !s.entries["tr-1"].next.Equal(want) {
+		t.Fatalf("expected changed schedule to reset to %s, got %s", want, s.entries["tr-1"].next)
+	}
+
+	fq.triggers = nil
+	_ = s.Reload(ctx)
+	if len(s.entries) != 0 {
+		t.Fatalf("expected deleted trigger to be dropped")
+	}
+}
+
+func TestScheduleDueMisfirePolicies(t *testing.T) {
+	// Hourly schedule, scheduler down from just after 06:00 until 09:00:20.
+	next := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 3, 1, 9, 0, 20, 0, time.UTC)
+	hour := func(h int) time.Time { return time.Date(2024, 3, 1, h, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		config string
+		now    time.Time
+		want   []time.Time
+	}{
+		{name: "skip fires only on-time slot", config: `{"cron_expr":"@hourly"}`, now: now, want: []time.Time{hour(9)}},
+		{name: "fire once prefers on-time slot", config: `{"cron_expr":"@hourly","misfire_policy":"fire_once"}`, now: now, want: []time.Time{hour(9)}},
+		{name: "fire once without on-time slot", config: `{"cron_expr":"@hourly","misfire_policy":"fire_once"}`, now: now.Add(30 * time.Minute), want: []time.Time{hour(9)}},
+		{name: "skip without on-time slot", config: `{"cron_expr":"@hourly"}`, now: now.Add(30 * time.Minute), want: nil},
+		{name: "fire all", config: `{"cron_expr":"@hourly","misfire_policy":"fire_all"}`, now: now, want: []time.Time{hour(7), hour(8), hour(9)}},
+		{name: "fire all capped", config: `{"cron_expr":"@hourly","misfire_policy":"fire_all","max_catchup":2}`, now: now, want: []time.Time{hour(8), hour(9)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sched, err := ParseCronConfig([]byte(tc.config))
+			if err != nil {
+				t.Fatalf("ParseCronConfig error: %v", err)
+			}
+			got, following := sched.Due(next, tc.now, misfireGrace)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if !got[i].Equal(tc.want[i]) {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+			if !following.Equal(hour(10)) {
+				t.Fatalf("expected next slot 10:00, got %s", following)
+			}
+		})
+	}
+}
+
+func TestSchedulerResumesFromLastFiredAt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 5, 0, time.UTC)
+	lastFired := time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{{
+		ID:          "tr-1",
+		WorkflowID:  "wf-1",
+		Type:        "cron",
+		Config:      []byte(`{"cron_expr":"@hourly","misfire_policy":"fire_all"}`),
+		LastFiredAt: pgtype.Timestamptz{Time: lastFired, Valid: true},
+	}}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	// Becoming leader resumes from 06:00, so 07:00, 08:00 and 09:00 all fire.
+	s.tick(ctx)
+	if len(fq.runs) != 3 {
+		t.Fatalf("expected 3 catch-up runs, got %d", len(fq.runs))
+	}
+	if want := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC); !fq.fired["tr-1"].Equal(want) {
+		t.Fatalf("expected last_fired_at %s, got %s", want, fq.fired["tr-1"])
+	}
+
+	// Still leading: no resume, nothing replayed.
+	s.tick(ctx)
+	if len(fq.runs) != 3 {
+		t.Fatalf("expected no replay while leading, got %d runs", len(fq.runs))
+	}
+}
+
+func TestSchedulerResumesNeverFiredFromCreation(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 5, 0, time.UTC)
+	created := time.Date(2024, 3, 1, 6, 30, 0, 0, time.UTC)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{{
+		ID:         "tr-1",
+		WorkflowID: "wf-1",
+		Type:       "cron",
+		Config:     []byte(`{"cron_expr":"@hourly","misfire_policy":"fire_all"}`),
+		CreatedAt:  pgtype.Timestamptz{Time: created, Valid: true},
+	}}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	// The trigger never fired, so becoming leader resumes from its creation at 06:30: 07:00,
+	// 08:00 and 09:00 all fire.
+	s.tick(ctx)
+	if len(fq.runs) != 3 {
+		t.Fatalf("expected 3 catch-up runs, got %d", len(fq.runs))
+	}
+	if want := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC); !fq.fired["tr-1"].Equal(want) {
+		t.Fatalf("expected last_fired_at %s, got %s", want, fq.fired["tr-1"])
+	}
+}
+
+func TestParseIntervalAndAtConfig(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	sched, err := ParseConfig(TriggerTypeInterval, []byte(`{"every":"90s"}`), created)
+	if err != nil {
+		t.Fatalf("ParseConfig interval error: %v", err)
+	}
+	if got := sched.Next(created.Add(100 * time.Second)); !got.Equal(created.Add(180 * time.Second)) {
+		t.Fatalf("expected interval anchored at creation, got %s", got)
+	}
+	if got := sched.First(created.Add(-time.Hour)); !got.Equal(created) {
+		t.Fatalf("expected first interval slot at creation, got %s", got)
+	}
+
+	sched, err = ParseConfig(TriggerTypeInterval, []byte(`{"every":"1h","start_at":"2024-03-01T09:30:00Z"}`), created)
+	if err != nil {
+		t.Fatalf("ParseConfig interval error: %v", err)
+	}
+	if got := sched.Next(created.Add(2 * time.Hour)); !got.Equal(time.Date(2024, 3, 1, 11, 30, 0, 0, time.UTC)) {
+		t.Fatalf("expected interval anchored at start_at, got %s", got)
+	}
+
+	sched, err = ParseConfig(TriggerTypeAt, []byte(`{"at":"2026-11-01T09:00:00Z"}`), created)
+	if err != nil {
+		t.Fatalf("ParseConfig at error: %v", err)
+	}
+	at := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	if !sched.OneShot() || !sched.Next(created).Equal(at) || !sched.Next(at).IsZero() || !sched.First(at.Add(time.Hour)).Equal(at) {
+		t.Fatalf("unexpected at schedule: %s", sched)
+	}
+
+	for _, tc := range []struct{ typ, raw string }{
+		{TriggerTypeInterval, `{}`},
+		{TriggerTypeInterval, `{"every":"500ms"}`},
+		{TriggerTypeInterval, `{"every":"soon"}`},
+		{TriggerTypeAt, `{}`},
+		{TriggerTypeAt, `{"at":"tomorrow"}`},
+		{"webhook", `{}`},
+	} {
+		if _, err := ParseConfig(tc.typ, []byte(tc.raw), created); err == nil {
+			t.Fatalf("expected error for %s %s", tc.typ, tc.raw)
+		}
+	}
+}
+
+func TestSchedulerFiresIntervalTriggers(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := created.Add(10 * time.Second)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{{
+		ID:         "tr-1",
+		WorkflowID: "wf-1",
+		Type:       TriggerTypeInterval,
+		Config:     []byte(`{"every":"90s"}`),
+		CreatedAt:  pgtype.Timestamptz{Time: created, Valid: true},
+	}}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	for i := 0; i < 3; i++ {
+		now = now.Add(90 * time.Second)
+		s.tick(ctx)
+	}
+	if len(fq.runs) != 3 || fq.runs[0].TriggerType != TriggerTypeInterval {
+		t.Fatalf("expected 3 interval runs, got %+v", fq.runs)
+	}
+	if want := created.Add(270 * time.Second); !fq.fired["tr-1"].Equal(want) {
+		t.Fatalf("expected last_fired_at %s, got %s", want, fq.fired["tr-1"])
+	}
+}
+
+func TestSchedulerRemovesOneShotTriggers(t *testing.T) {
+	now := time.Date(2026, 11, 1, 8, 59, 59, 0, time.UTC)
+	fq := &fakeQueries{triggers: []sqlc.ListScheduledTriggersRow{
+		{ID: "tr-at", WorkflowID: "wf-1", Type: TriggerTypeAt, Config: []byte(`{"at":"2026-11-01T09:00:00Z"}`)},
+		{ID: "tr-missed", WorkflowID: "wf-2", Type: TriggerTypeAt, Config: []byte(`{"at":"2026-10-31T09:00:00Z"}`)},
+		{ID: "tr-skipped", WorkflowID: "wf-3", Type: TriggerTypeAt, Config: []byte(`{"at":"2026-10-31T09:00:00Z","misfire_policy":"skip"}`)},
+	}}
+	s := newTestScheduler(fq, true, &now)
+	ctx := context.Background()
+	_ = s.Reload(ctx)
+
+	// The missed one-shot still fires once (its default policy); the skipped one is just removed.
+	s.tick(ctx)
+	if len(fq.runs) != 1 || fq.runs[0].WorkflowID != "wf-2" {
+		t.Fatalf("expected only the missed one-shot to fire, got %+v", fq.runs)
+	}
+	if len(fq.deleted) != 2 || len(s.entries) != 1 {
+		t.Fatalf("expected missed and skipped one-shots to be removed, deleted %v", fq.deleted)
+	}
+
+	now = now.Add(time.Second)
+	s.tick(ctx)
+	s.tick(ctx)
+	if len(fq.runs) != 2 || fq.runs[1].WorkflowID != "wf-1" || fq.runs[1].TriggerType != TriggerTypeAt {
+		t.Fatalf("expected the one-shot to fire exactly once, got %+v", fq.runs)
+	}
+	if len(fq.deleted) != 3 || fq.deleted[2] != "tr-at" || len(s.entries) != 0 {
+		t.Fatalf("expected fired one-shot to be removed, deleted %v", fq.deleted)
+	}
+}
@@ -0,0 +1,195 @@
+// Package webhooks interprets the config of webhook triggers: how incoming requests are
+// authenticated and how the /hooks endpoint responds.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/pkg/signature"
+)
+
+// Response modes of the /hooks endpoint.
+const (
+	ResponseAsync = "async"
+	ResponseSync  = "sync"
+)
+
+// Signature presets.
+const (
+	PresetHMAC     = "hmac"
+	PresetGitHub   = "github"
+	PresetStripe   = "stripe"
+	PresetPotaFlow = "potaflow"
+)
+
+const (
+	defaultSyncTimeout = 30 * time.Second
+	maxSyncTimeout     = 2 * time.Minute
+)
+
+// Config is the config of a webhook trigger. Requests are only verified when Secret is set.
+type Config struct {
+	Secret       string          `json:"webhook_secret,omitempty"`
+	Signature    SignatureConfig `json:"signature,omitempty"`
+	ResponseMode string          `json:"response_mode,omitempty"`
+	TimeoutMS    int             `json:"timeout_ms,omitempty"`
+}
+
+// SignatureConfig selects how the signature is read from the request. Preset is one of:
+//
+//	hmac      (default) HMAC of the body in Header, using Algorithm (sha1, sha256 or sha512;
+//	          default sha256), Encoding (hex or base64; default hex) and an optional Prefix.
+//	          With TimestampHeader set, "<timestamp>.<body>" is signed instead and the
+//	          timestamp must be within ToleranceSeconds.
+//	github    X-Hub-Signature-256: sha256=<hex>
+//	stripe    Stripe-Signature: t=<unix>,v1=<hex>
+//	potaflow  PotaFlow-Signature: t=<unix>,v1=<hex>, as sent by signed HTTP actions
+type SignatureConfig struct {
+	Preset           string `json:"preset,omitempty"`
+	Header           string `json:"header,omitempty"`
+	Algorithm        string `json:"algorithm,omitempty"`
+	Encoding         string `json:"encoding,omitempty"`
+	Prefix           string `json:"prefix,omitempty"`
+	TimestampHeader  string `json:"timestamp_header,omitempty"`
+	ToleranceSeconds int    `json:"tolerance_seconds,omitempty"`
+}
+
+// ParseConfig decodes and validates a webhook trigger config. An empty config is valid.
+func ParseConfig(raw []byte) (Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return Config{}, fmt.Errorf("invalid webhook config: %w", err)
+		}
+	}
+	switch cfg.ResponseMode {
+	case "", ResponseAsync, ResponseSync:
+	default:
+		return Config{}, fmt.Errorf("invalid webhook config: unknown response_mode %q", cfg.ResponseMode)
+	}
+	if cfg.Secret == "" {
+		return cfg, nil
+	}
+
+	sig := &cfg.Signature
+	switch sig.Preset {
+	case "", PresetHMAC:
+		sig.Preset = PresetHMAC
+		if sig.Header == "" {
+			return Config{}, errors.New("invalid webhook config: signature.header is required for hmac")
+		}
+		if sig.Algorithm == "" {
+			sig.Algorithm = "sha256"
+		}
+		if newHash(sig.Algorithm) == nil {
+			return Config{}, fmt.Errorf("invalid webhook config: unsupported algorithm %q", sig.Algorithm)
+		}
+		if sig.Encoding == "" {
+			sig.Encoding = "hex"
+		}
+		if sig.Encoding != "hex" && sig.Encoding != "base64" {
+			return Config{}, fmt.Errorf("invalid webhook config: unsupported encoding %q", sig.Encoding)
+		}
+	case PresetGitHub:
+		*sig = SignatureConfig{Preset: PresetGitHub, Header: "X-Hub-Signature-256", Algorithm: "sha256", Encoding: "hex", Prefix: "sha256="}
+	case PresetStripe:
+		sig.Header = "Stripe-Signature"
+	case PresetPotaFlow:
+		sig.Header = signature.Header
+	default:
+		return Config{}, fmt.Errorf("invalid webhook config: unknown signature preset %q", sig.Preset)
+	}
+	return cfg, nil
+}
+
+// SyncTimeout is how long a sync request waits for its run.
+func (c Config) SyncTimeout() time.Duration {
+	timeout := time.Duration(c.TimeoutMS) * time.Millisecond
+	if timeout <= 0 {
+		return defaultSyncTimeout
+	}
+	return min(timeout, maxSyncTimeout)
+}
+
+// Verify authenticates a request with the given headers and raw body. It returns the errors of
+// package signature, so callers can tell missing, malformed, stale and wrong signatures apart.
+func (c Config) Verify(header http.Header, body []byte, now time.Time) error {
+	if c.Secret == "" {
+		return nil
+	}
+	sig := c.Signature
+	value := header.Get(sig.Header)
+	if value == "" {
+		return signature.ErrMissingSignature
+	}
+	tolerance := time.Duration(sig.ToleranceSeconds) * time.Second
+
+	switch sig.Preset {
+	case PresetStripe, PresetPotaFlow:
+		return signature.Verify([]byte(c.Secret), value, body, tolerance, now)
+	}
+
+	payload := body
+	if sig.TimestampHeader != "" {
+		ts := header.Get(sig.TimestampHeader)
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return signature.ErrMalformedHeader
+		}
+		if tolerance <= 0 {
+			tolerance = signature.DefaultTolerance
+		}
+		if age := now.Sub(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+			return signature.ErrExpired
+		}
+		payload = append([]byte(ts+"."), body...)
+	}
+
+	encoded, ok := strings.CutPrefix(value, sig.Prefix)
+	if !ok {
+		return signature.ErrMalformedHeader
+	}
+	var got []byte
+	var err error
+	if sig.Encoding == "base64" {
+		got, err = base64.StdEncoding.DecodeString(encoded)
+	} else {
+		got, err = hex.DecodeString(encoded)
+	}
+	if err != nil {
+		return signature.ErrMalformedHeader
+	}
+
+	mac := hmac.New(newHash(sig.Algorithm), []byte(c.Secret))
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return signature.ErrInvalidSignature
+	}
+	return nil
+}
+
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New
+	case "sha256":
+		return sha256.New
+	case "sha512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/pkg/signature"
+)
+
+func hmacHex(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyPresets(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"action":"opened"}`)
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	sha1Mac := hmac.New(sha1.New, []byte(secret))
+	sha1Mac.Write(body)
+	sha1B64 := base64.StdEncoding.EncodeToString(sha1Mac.Sum(nil))
+
+	tests := []struct {
+		name    string
+		config  string
+		headers map[string]string
+		at      time.Time
+		want    error
+	}{
+		{
+			name:   "no secret skips verification",
+			config: `{}`,
+			want:   nil,
+		},
+		{
+			name:    "github",
+			config:  `{"webhook_secret":"s3cret","signature":{"preset":"github"}}`,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + hmacHex(secret, string(body))},
+			want:    nil,
+		},
+		{
+			name:    "github wrong signature",
+			config:  `{"webhook_secret":"s3cret","signature":{"preset":"github"}}`,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + hmacHex("other", string(body))},
+			want:    signature.ErrInvalidSignature,
+		},
+		{
+			name:   "github missing header",
+			config: `{"webhook_secret":"s3cret","signature":{"preset":"github"}}`,
+			want:   signature.ErrMissingSignature,
+		},
+		{
+			name:    "stripe",
+			config:  `{"webhook_secret":"s3cret","signature":{"preset":"stripe"}}`,
+			headers: map[string]string{"Stripe-Signature": "t=" + ts + ",v1=" + hmacHex(secret, ts+"."+string(body)) + ",v0=abcd"},
+			want:    nil,
+		},
+		{
+			name:    "stripe replay",
+			config:  `{"webhook_secret":"s3cret","signature":{"preset":"stripe","tolerance_seconds":60}}`,
+			headers: map[string]string{"Stripe-Signature": "t=" + ts + ",v1=" + hmacHex(secret, ts+"."+string(body))},
+			at:      now.Add(2 * time.Minute),
+			want:    signature.ErrExpired,
+		},
+		{
+			name:    "potaflow",
+			config:  `{"webhook_secret":"s3cret","signature":{"preset":"potaflow"}}`,
+			headers: map[string]string{signature.Header: signature.Sign([]byte(secret), now, body)},
+			want:    nil,
+		},
+		{
+			name:    "generic sha1 base64",
+			config:  `{"webhook_secret":"s3cret","signature":{"header":"X-Sig","algorithm":"sha1","encoding":"base64"}}`,
+			headers: map[string]string{"X-Sig": sha1B64},
+			want:    nil,
+		},
+		{
+			name:    "generic with timestamp",
+			config:  `{"webhook_secret":"s3cret","signature":{"header":"X-Sig","prefix":"v1=","timestamp_header":"X-Ts"}}`,
+			headers: map[string]string{"X-Sig": "v1=" + hmacHex(secret, ts+"."+string(body)), "X-Ts": ts},
+			want:    nil,
+		},
+		{
+			name:    "generic stale timestamp",
+			config:  `{"webhook_secret":"s3cret","signature":{"header":"X-Sig","timestamp_header":"X-Ts"}}`,
+			headers: map[string]string{"X-Sig": hmacHex(secret, ts+"."+string(body)), "X-Ts": ts},
+			at:      now.Add(-time.Hour),
+			want:    signature.ErrExpired,
+		},
+		{
+			name:    "generic missing prefix",
+			config:  `{"webhook_secret":"s3cret","signature":{"header":"X-Sig","prefix":"sha256="}}`,
+			headers: map[string]string{"X-Sig": hmacHex(secret, string(body))},
+			want:    signature.ErrMalformedHeader,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(tc.config))
+			if err != nil {
+				t.Fatalf("ParseConfig error: %v", err)
+			}
+			header := http.Header{}
+			for k, v := range tc.headers {
+				header.Set(k, v)
+			}
+			at := tc.at
+			if at.IsZero() {
+				at = now
+			}
+			if err := cfg.Verify(header, body, at); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"webhook_secret":"x"}`,
+		`{"webhook_secret":"x","signature":{"preset":"bitbucket"}}`,
+		`{"webhook_secret":"x","signature":{"header":"X-Sig","algorithm":"md5"}}`,
+		`{"webhook_secret":"x","signature":{"header":"X-Sig","encoding":"base32"}}`,
+		`{"response_mode":"later"}`,
+		`nope`,
+	} {
+		if _, err := ParseConfig([]byte(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestSyncTimeout(t *testing.T) {
+	if got := (Config{}).SyncTimeout(); got != defaultSyncTimeout {
+		t.Fatalf("unexpected default: %v", got)
+	}
+	if got := (Config{TimeoutMS: 10_000_000}).SyncTimeout(); got != maxSyncTimeout {
+		t.Fatalf("expected cap, got %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/groovypotato/PotaFlow/internal/actions"
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CallWorkflowType is the action type that starts another workflow as a child run.
+const CallWorkflowType = "call_workflow"
+
+// MaxCallDepth is how deeply call_workflow actions may nest; a top-level run has depth 0.
+const MaxCallDepth = 5
+
+// ErrCallDepthExceeded indicates a call_workflow action would nest deeper than MaxCallDepth.
+var ErrCallDepthExceeded = errors.New("call_workflow: maximum call depth exceeded")
+
+// CallWorkflowConfig is the config of a "call_workflow" action. Input defaults to the step input.
+// With Wait set, the step finishes only once the child run has, and fails if the child fails.
+type CallWorkflowConfig struct {
+	WorkflowID string          `json:"workflow_id"`
+	Input      json.RawMessage `json:"input,omitempty"`
+	Wait       bool            `json:"wait,omitempty"`
+}
+
+// CallWorkflowResult is the step output of a "call_workflow" action. Output is only set when
+// the action waited for the child run.
+type CallWorkflowResult struct {
+	RunID  string          `json:"run_id"`
+	Status string          `json:"status"`
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// CallWorkflow returns the executor for the "call_workflow" action type. Waiting calls run the
+// child inline on this processor instead of polling for it, so a chain of waiting calls can't
+// deadlock behind runs no worker is free to pick up.
+func (p *Processor) CallWorkflow() actions.Executor {
+	return actions.ExecutorFunc(p.callWorkflow)
+}
+
+func (p *Processor) callWorkflow(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+	var cfg CallWorkflowConfig
+	if err := json.Unmarshal(step.Config, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid call_workflow config: %w", err)
+	}
+	if cfg.WorkflowID == "" {
+		return nil, errors.New("invalid call_workflow config: workflow_id is required")
+	}
+
+	parentRun, err := p.queries.GetWorkflowRun(ctx, step.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("load parent run: %w", err)
+	}
+	depth := parentRun.Depth + 1
+	if depth > MaxCallDepth {
+		return nil, fmt.Errorf("%w (%d)", ErrCallDepthExceeded, MaxCallDepth)
+	}
+
+	parent, err := p.queries.GetWorkflowByID(ctx, step.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("load parent workflow: %w", err)
+	}
+	child, err := p.queries.GetWorkflowByID(ctx, cfg.WorkflowID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && child.OrgID != parent.OrgID) {
+		// Workflows of other organizations are reported as missing so their IDs can't be probed.
+		return nil, fmt.Errorf("call_workflow: workflow %s not found", cfg.WorkflowID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load workflow: %w", err)
+	}
+	if !child.IsEnabled {
+		return nil, fmt.Errorf("call_workflow: workflow %s is disabled", cfg.WorkflowID)
+	}
+
+	input := cfg.Input
+	if len(input) == 0 {
+		input = step.Input
+	}
+
+	if !cfg.Wait {
+		runID, err := p.queries.CreateChildWorkflowRun(ctx, sqlc.CreateChildWorkflowRunParams{
+			WorkflowID:  child.ID,
+			Status:      statusPending,
+			ParentRunID: step.RunID,
+			Depth:       depth,
+			Input:       input,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("enqueue child run: %w", err)
+		}
+		return json.Marshal(CallWorkflowResult{RunID: runID, Status: statusPending})
+	}
+
+	// The child is created already running so no polling worker picks it up as well.
+	runID, err := p.queries.CreateChildWorkflowRun(ctx, sqlc.CreateChildWorkflowRunParams{
+		WorkflowID:  child.ID,
+		Status:      statusRunning,
+		StartedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ParentRunID: step.RunID,
+		Depth:       depth,
+		Input:       input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start child run: %w", err)
+	}
+	status, output := p.execute(ctx, runID, child.ID, input)
+	p.finish(ctx, runID, child.ID, status, output)
+	if status != statusSuccess {
+		return nil, fmt.Errorf("call_workflow: child run %s %s", runID, status)
+	}
+	return json.Marshal(CallWorkflowResult{RunID: runID, Status: status, Output: output})
+}
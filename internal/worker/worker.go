@@ -2,18 +2,33 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
+	"github.com/groovypotato/PotaFlow/internal/actions"
 	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
 )
 
-// Processor polls workflow_runs and marks them as succeeded (stub executor).
+const (
+	statusPending = "pending"
+	statusRunning = "running"
+	statusSuccess = "success"
+	statusFailed  = "failed"
+)
+
+// DefaultStepTimeout bounds a single action step unless NewProcessor is given another limit. It
+// is above the longest timeout the built-in actions accept, so it only stops steps that hang.
+const DefaultStepTimeout = 10 * time.Minute
+
+// Processor polls workflow_runs and executes each run's actions in position order.
 type Processor struct {
-	queries  workerQueries
-	limit    int32
-	interval time.Duration
+	queries     workerQueries
+	registry    *actions.Registry
+	limit       int32
+	interval    time.Duration
+	stepTimeout time.Duration
 }
 
 type workerQueries interface {
@@ -22,13 +37,25 @@ type workerQueries interface {
 	ListActionsByWorkflow(ctx context.Context, workflowID string) ([]sqlc.ListActionsByWorkflowRow, error)
 	InsertWorkflowRunLog(ctx context.Context, arg sqlc.InsertWorkflowRunLogParams) (sqlc.InsertWorkflowRunLogRow, error)
 	UpdateWorkflowRunStatus(ctx context.Context, arg sqlc.UpdateWorkflowRunStatusParams) (sqlc.UpdateWorkflowRunStatusRow, error)
+	GetWorkflowRun(ctx context.Context, id string) (sqlc.GetWorkflowRunRow, error)
+	GetWorkflowByID(ctx context.Context, id string) (sqlc.GetWorkflowByIDRow, error)
+	CreateChildWorkflowRun(ctx context.Context, arg sqlc.CreateChildWorkflowRunParams) (string, error)
+	ListWorkflowEventTriggers(ctx context.Context, workflowID string) ([]sqlc.ListWorkflowEventTriggersRow, error)
+	CreateChainedWorkflowRun(ctx context.Context, arg sqlc.CreateChainedWorkflowRunParams) (string, error)
 }
 
-func NewProcessor(db sqlc.DBTX, interval time.Duration) *Processor {
+// NewProcessor builds a Processor that dispatches actions to the executors in registry. A step
+// that runs longer than stepTimeout fails (DefaultStepTimeout if zero).
+func NewProcessor(db sqlc.DBTX, registry *actions.Registry, interval, stepTimeout time.Duration) *Processor {
+	if stepTimeout <= 0 {
+		stepTimeout = DefaultStepTimeout
+	}
 	return &Processor{
-		queries:  sqlc.New(db),
-		limit:    10,
-		interval: interval,
+		queries:     sqlc.New(db),
+		registry:    registry,
+		limit:       10,
+		interval:    interval,
+		stepTimeout: stepTimeout,
 	}
 }
 
@@ -49,7 +76,7 @@ func (p *Processor) Run(ctx context.Context) error {
 	}
 }
 
-// ProcessOnce picks pending runs and marks them success (placeholder executor).
+// ProcessOnce picks pending runs, executes their actions and records the final status.
 func (p *Processor) ProcessOnce(ctx context.Context) error {
 	runs, err := p.queries.ListPendingWorkflowRuns(ctx, p.limit)
 	if err != nil {
@@ -62,29 +89,89 @@ func (p *Processor) ProcessOnce(ctx context.Context) error {
 			continue
 		}
 
-		actions, err := p.queries.ListActionsByWorkflow(ctx, run.WorkflowID)
-		if err != nil {
-			log.Error().Err(err).Str("workflow_id", run.WorkflowID).Msg("failed to list actions")
-		} else {
-			for _, act := range actions {
-				_, _ = p.queries.InsertWorkflowRunLog(ctx, sqlc.InsertWorkflowRunLogParams{
-					RunID:          run.ID,
-					ActionID:       act.ID,
-					ActionPosition: act.Position,
-					Success:        true,
-					Message:        "action execution stubbed",
-				})
-			}
-		}
+		status, output := p.execute(ctx, run.ID, run.WorkflowID, run.Input)
+		p.finish(ctx, run.ID, run.WorkflowID, status, output)
+	}
+	return nil
+}
 
-		_, err = p.queries.UpdateWorkflowRunStatus(ctx, sqlc.UpdateWorkflowRunStatusParams{
-			ID:         run.ID,
-			Status:     "success",
-			FinishedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+// execute runs every action of the workflow in order, feeding the run input to the first step
+// and each step's output to the next, and stops at the first failure. It returns the run's final
+// status and, on success, the last step's output.
+func (p *Processor) execute(ctx context.Context, runID, workflowID string, runInput []byte) (string, json.RawMessage) {
+	acts, err := p.queries.ListActionsByWorkflow(ctx, workflowID)
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", workflowID).Msg("failed to list actions")
+		return statusFailed, nil
+	}
+
+	input := json.RawMessage(`{}`)
+	if len(runInput) > 0 {
+		input = runInput
+	}
+	for _, act := range acts {
+		exec, ok := p.registry.Lookup(act.Type)
+		if !ok {
+			// Action types without an executor keep the original stub behaviour: the step is
+			// logged as succeeded and its input passes through.
+			p.logStep(ctx, runID, act, true, "action execution stubbed")
+			continue
+		}
+		stepCtx, cancel := p.stepContext(ctx, act.Type)
+		output, err := exec.Execute(stepCtx, actions.Step{
+			RunID:      runID,
+			WorkflowID: workflowID,
+			ActionID:   act.ID,
+			Type:       act.Type,
+			Position:   act.Position,
+			Config:     act.Config,
+			Input:      input,
 		})
+		cancel()
 		if err != nil {
-			log.Error().Err(err).Str("run_id", run.ID).Msg("failed to mark run success")
+			p.logStep(ctx, runID, act, false, err.Error())
+			return statusFailed, nil
 		}
+		p.logStep(ctx, runID, act, true, "action succeeded")
+		input = output
+	}
+	return statusSuccess, input
+}
+
+// stepContext bounds a step by stepTimeout, so an executor that hangs (e.g. a plugin that stopped
+// answering) fails its run instead of holding the worker. Waiting call_workflow steps run a whole
+// child run whose own steps are bounded, so they aren't.
+func (p *Processor) stepContext(ctx context.Context, actionType string) (context.Context, context.CancelFunc) {
+	if p.stepTimeout <= 0 || actionType == CallWorkflowType {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.stepTimeout)
+}
+
+// finish records a run's final status and starts the workflows chained to it.
+func (p *Processor) finish(ctx context.Context, runID, workflowID, status string, output json.RawMessage) {
+	_, err := p.queries.UpdateWorkflowRunStatus(ctx, sqlc.UpdateWorkflowRunStatusParams{
+		ID:         runID,
+		Status:     status,
+		FinishedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Output:     output,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("run_id", runID).Str("status", status).Msg("failed to mark run finished")
+		return
+	}
+	p.emitWorkflowEvent(ctx, runID, workflowID, status, output)
+}
+
+func (p *Processor) logStep(ctx context.Context, runID string, act sqlc.ListActionsByWorkflowRow, success bool, message string) {
+	_, err := p.queries.InsertWorkflowRunLog(ctx, sqlc.InsertWorkflowRunLogParams{
+		RunID:          runID,
+		ActionID:       act.ID,
+		ActionPosition: act.Position,
+		Success:        success,
+		Message:        message,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("run_id", runID).Str("action_id", act.ID).Msg("failed to write run log")
 	}
-	return nil
 }
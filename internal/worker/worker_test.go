@@ -2,10 +2,15 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/groovypotato/PotaFlow/internal/actions"
 	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
 )
 
 type fakeQueries struct {
@@ -13,6 +18,14 @@ type fakeQueries struct {
 	actions     []sqlc.ListActionsByWorkflowRow
 	started     []string
 	succeeded   []string
+	statuses    []string
+	logs        []sqlc.InsertWorkflowRunLogParams
+	outputs     map[string][]byte
+	workflows   map[string]sqlc.GetWorkflowByIDRow
+	runs        map[string]sqlc.GetWorkflowRunRow
+	children    []sqlc.CreateChildWorkflowRunParams
+	eventTrigs  map[string][]sqlc.ListWorkflowEventTriggersRow
+	chained     []sqlc.CreateChainedWorkflowRunParams
 	err         error
 }
 
@@ -24,15 +37,58 @@ func (f *fakeQueries) StartWorkflowRun(ctx context.Context, id string) (sqlc.Sta
 	return sqlc.StartWorkflowRunRow{ID: id}, f.err
 }
 func (f *fakeQueries) ListActionsByWorkflow(ctx context.Context, workflowID string) ([]sqlc.ListActionsByWorkflowRow, error) {
-	return f.actions, f.err
+	var out []sqlc.ListActionsByWorkflowRow
+	for _, act := range f.actions {
+		if act.WorkflowID == workflowID {
+			out = append(out, act)
+		}
+	}
+	return out, f.err
 }
 func (f *fakeQueries) InsertWorkflowRunLog(ctx context.Context, arg sqlc.InsertWorkflowRunLogParams) (sqlc.InsertWorkflowRunLogRow, error) {
+	f.logs = append(f.logs, arg)
 	return sqlc.InsertWorkflowRunLogRow{RunID: arg.RunID, ActionID: arg.ActionID}, f.err
 }
 func (f *fakeQueries) UpdateWorkflowRunStatus(ctx context.Context, arg sqlc.UpdateWorkflowRunStatusParams) (sqlc.UpdateWorkflowRunStatusRow, error) {
 	f.succeeded = append(f.succeeded, arg.ID)
+	f.statuses = append(f.statuses, arg.Status)
+	if f.outputs == nil {
+		f.outputs = make(map[string][]byte)
+	}
+	f.outputs[arg.ID] = arg.Output
 	return sqlc.UpdateWorkflowRunStatusRow{ID: arg.ID, Status: arg.Status}, f.err
 }
+func (f *fakeQueries) GetWorkflowRun(ctx context.Context, id string) (sqlc.GetWorkflowRunRow, error) {
+	run, ok := f.runs[id]
+	if !ok {
+		return sqlc.GetWorkflowRunRow{ID: id}, f.err
+	}
+	return run, f.err
+}
+func (f *fakeQueries) GetWorkflowByID(ctx context.Context, id string) (sqlc.GetWorkflowByIDRow, error) {
+	wf, ok := f.workflows[id]
+	if !ok {
+		return sqlc.GetWorkflowByIDRow{}, pgx.ErrNoRows
+	}
+	return wf, f.err
+}
+func (f *fakeQueries) CreateChildWorkflowRun(ctx context.Context, arg sqlc.CreateChildWorkflowRunParams) (string, error) {
+	f.children = append(f.children, arg)
+	id := fmt.Sprintf("child-%d", len(f.children))
+	if f.runs == nil {
+		f.runs = make(map[string]sqlc.GetWorkflowRunRow)
+	}
+	f.runs[id] = sqlc.GetWorkflowRunRow{ID: id, WorkflowID: arg.WorkflowID, Depth: arg.Depth}
+	return id, f.err
+}
+
+func (f *fakeQueries) ListWorkflowEventTriggers(ctx context.Context, workflowID string) ([]sqlc.ListWorkflowEventTriggersRow, error) {
+	return f.eventTrigs[workflowID], f.err
+}
+func (f *fakeQueries) CreateChainedWorkflowRun(ctx context.Context, arg sqlc.CreateChainedWorkflowRunParams) (string, error) {
+	f.chained = append(f.chained, arg)
+	return fmt.Sprintf("chained-%d", len(f.chained)), f.err
+}
 
 type queryProvider interface {
 	ListPendingWorkflowRuns(ctx context.Context, limit int32) ([]sqlc.ListPendingWorkflowRunsRow, error)
@@ -49,11 +105,16 @@ func TestProcessOnce_MarksRunsAndLogs(t *testing.T) {
 			{ID: "run-1", WorkflowID: "wf-1"},
 		},
 		actions: []sqlc.ListActionsByWorkflowRow{
-			{ID: "act-1", WorkflowID: "wf-1", Position: 1},
+			{ID: "act-1", WorkflowID: "wf-1", Type: "noop", Position: 1},
 		},
 	}
+	registry := actions.NewRegistry()
+	_ = registry.Register("noop", actions.ExecutorFunc(func(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+		return step.Input, nil
+	}))
 	p := &Processor{
 		queries:  fq,
+		registry: registry,
 		limit:    10,
 		interval: time.Second,
 	}
@@ -67,8 +128,262 @@ func TestProcessOnce_MarksRunsAndLogs(t *testing.T) {
 	if len(fq.succeeded) != 1 || fq.succeeded[0] != "run-1" {
 		t.Fatalf("expected run succeeded, got %v", fq.succeeded)
 	}
+	if len(fq.statuses) != 1 || fq.statuses[0] != "success" {
+		t.Fatalf("expected success status, got %v", fq.statuses)
+	}
 	if fq.actions[0].Position != 1 || fq.actions[0].WorkflowID != "wf-1" {
 		t.Fatalf("unexpected actions used: %+v", fq.actions)
 	}
 	_ = now // keep import
 }
+
+func TestProcessOnce_StopsAtFailedStep(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{
+			{ID: "run-1", WorkflowID: "wf-1"},
+		},
+		actions: []sqlc.ListActionsByWorkflowRow{
+			{ID: "act-1", WorkflowID: "wf-1", Type: "boom", Position: 1},
+			{ID: "act-2", WorkflowID: "wf-1", Type: "unknown", Position: 2},
+		},
+	}
+	registry := actions.NewRegistry()
+	_ = registry.Register("boom", actions.ExecutorFunc(func(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+		return nil, errors.New("boom")
+	}))
+	p := &Processor{queries: fq, registry: registry, limit: 10, interval: time.Second}
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	if len(fq.statuses) != 1 || fq.statuses[0] != "failed" {
+		t.Fatalf("expected failed status, got %v", fq.statuses)
+	}
+	if len(fq.logs) != 1 || fq.logs[0].Success || fq.logs[0].Message != "boom" {
+		t.Fatalf("expected a single failed log, got %+v", fq.logs)
+	}
+}
+
+func TestProcessOnce_StepTimeout(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{
+			{ID: "run-1", WorkflowID: "wf-1"},
+		},
+		actions: []sqlc.ListActionsByWorkflowRow{
+			{ID: "act-1", WorkflowID: "wf-1", Type: "hang", Position: 1},
+		},
+	}
+	registry := actions.NewRegistry()
+	_ = registry.Register("hang", actions.ExecutorFunc(func(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	p := &Processor{queries: fq, registry: registry, limit: 10, interval: time.Second, stepTimeout: 10 * time.Millisecond}
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	if len(fq.statuses) != 1 || fq.statuses[0] != "failed" {
+		t.Fatalf("expected a hung step to fail the run, got %v", fq.statuses)
+	}
+	if len(fq.logs) != 1 || fq.logs[0].Message != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected a deadline log, got %+v", fq.logs)
+	}
+}
+
+func TestProcessOnce_UnknownActionType(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{
+			{ID: "run-1", WorkflowID: "wf-1"},
+		},
+		actions: []sqlc.ListActionsByWorkflowRow{
+			{ID: "act-1", WorkflowID: "wf-1", Type: "unknown", Position: 1},
+		},
+	}
+	p := &Processor{queries: fq, registry: actions.NewRegistry(), limit: 10, interval: time.Second}
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	// Without an executor the step falls back to the stub and the run still succeeds.
+	if len(fq.statuses) != 1 || fq.statuses[0] != "success" {
+		t.Fatalf("expected success status, got %v", fq.statuses)
+	}
+	if len(fq.logs) != 1 || !fq.logs[0].Success || fq.logs[0].Message != "action execution stubbed" {
+		t.Fatalf("expected a stubbed log, got %+v", fq.logs)
+	}
+	if string(fq.outputs["run-1"]) != `{}` {
+		t.Fatalf("expected the input to pass through, got %s", fq.outputs["run-1"])
+	}
+}
+
+func newCallWorkflowProcessor(fq *fakeQueries) *Processor {
+	registry := actions.NewRegistry()
+	p := &Processor{queries: fq, registry: registry, limit: 10, interval: time.Second}
+	_ = registry.Register("echo", actions.ExecutorFunc(func(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+		return step.Input, nil
+	}))
+	_ = registry.Register(CallWorkflowType, p.CallWorkflow())
+	return p
+}
+
+func TestCallWorkflow_WaitsForChild(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{
+			{ID: "run-1", WorkflowID: "wf-parent", Input: []byte(`{"n":1}`)},
+		},
+		actions: []sqlc.ListActionsByWorkflowRow{
+			{ID: "act-1", WorkflowID: "wf-parent", Type: CallWorkflowType, Position: 1, Config: []byte(`{"workflow_id":"wf-child","wait":true}`)},
+			{ID: "act-2", WorkflowID: "wf-child", Type: "echo", Position: 1},
+		},
+		workflows: map[string]sqlc.GetWorkflowByIDRow{
+			"wf-parent": {ID: "wf-parent", OrgID: "org-1", IsEnabled: true},
+			"wf-child":  {ID: "wf-child", OrgID: "org-1", IsEnabled: true},
+		},
+	}
+	p := newCallWorkflowProcessor(fq)
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	if len(fq.children) != 1 || fq.children[0].ParentRunID != "run-1" || fq.children[0].Depth != 1 || fq.children[0].Status != "running" {
+		t.Fatalf("unexpected child runs: %+v", fq.children)
+	}
+	if len(fq.statuses) != 2 || fq.statuses[0] != "success" || fq.statuses[1] != "success" {
+		t.Fatalf("expected child and parent to succeed, got %v", fq.statuses)
+	}
+	var result CallWorkflowResult
+	if err := json.Unmarshal(fq.outputs["run-1"], &result); err != nil {
+		t.Fatalf("decode parent output: %v", err)
+	}
+	if result.RunID != "child-1" || string(result.Output) != `{"n":1}` {
+		t.Fatalf("unexpected call result: %+v", result)
+	}
+}
+
+func TestCallWorkflow_FireAndForget(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{{ID: "run-1", WorkflowID: "wf-parent"}},
+		actions: []sqlc.ListActionsByWorkflowRow{
+			{ID: "act-1", WorkflowID: "wf-parent", Type: CallWorkflowType, Position: 1, Config: []byte(`{"workflow_id":"wf-child","input":{"x":true}}`)},
+		},
+		workflows: map[string]sqlc.GetWorkflowByIDRow{
+			"wf-parent": {ID: "wf-parent", OrgID: "org-1", IsEnabled: true},
+			"wf-child":  {ID: "wf-child", OrgID: "org-1", IsEnabled: true},
+		},
+	}
+	p := newCallWorkflowProcessor(fq)
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	if len(fq.children) != 1 || fq.children[0].Status != "pending" || string(fq.children[0].Input) != `{"x":true}` {
+		t.Fatalf("unexpected child runs: %+v", fq.children)
+	}
+	if len(fq.statuses) != 1 || fq.statuses[0] != "success" {
+		t.Fatalf("expected only the parent to finish, got %v", fq.statuses)
+	}
+}
+
+func TestCallWorkflow_Rejections(t *testing.T) {
+	tests := []struct {
+		name  string
+		child sqlc.GetWorkflowByIDRow
+		depth int32
+	}{
+		{name: "other organization", child: sqlc.GetWorkflowByIDRow{ID: "wf-child", OrgID: "org-2", IsEnabled: true}},
+		{name: "disabled", child: sqlc.GetWorkflowByIDRow{ID: "wf-child", OrgID: "org-1"}},
+		{name: "too deep", child: sqlc.GetWorkflowByIDRow{ID: "wf-child", OrgID: "org-1", IsEnabled: true}, depth: MaxCallDepth},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fq := &fakeQueries{
+				pendingRuns: []sqlc.ListPendingWorkflowRunsRow{{ID: "run-1", WorkflowID: "wf-parent"}},
+				actions: []sqlc.ListActionsByWorkflowRow{
+					{ID: "act-1", WorkflowID: "wf-parent", Type: CallWorkflowType, Position: 1, Config: []byte(`{"workflow_id":"wf-child","wait":true}`)},
+				},
+				workflows: map[string]sqlc.GetWorkflowByIDRow{
+					"wf-parent": {ID: "wf-parent", OrgID: "org-1", IsEnabled: true},
+					"wf-child":  tc.child,
+				},
+				runs: map[string]sqlc.GetWorkflowRunRow{"run-1": {ID: "run-1", Depth: tc.depth}},
+			}
+			p := newCallWorkflowProcessor(fq)
+
+			if err := p.ProcessOnce(context.Background()); err != nil {
+				t.Fatalf("ProcessOnce error: %v", err)
+			}
+			if len(fq.children) != 0 {
+				t.Fatalf("expected no child run, got %+v", fq.children)
+			}
+			if len(fq.statuses) != 1 || fq.statuses[0] != "failed" {
+				t.Fatalf("expected failed status, got %v", fq.statuses)
+			}
+		})
+	}
+}
+
+func TestWorkflowEvent_ChainsFinishedRuns(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{
+			{ID: "run-1", WorkflowID: "wf-up", Input: []byte(`{"n":1}`)},
+			{ID: "run-2", WorkflowID: "wf-broken"},
+		},
+		actions: []sqlc.ListActionsByWorkflowRow{
+			{ID: "act-1", WorkflowID: "wf-up", Type: "echo", Position: 1},
+			{ID: "act-2", WorkflowID: "wf-broken", Type: "boom", Position: 1},
+		},
+		eventTrigs: map[string][]sqlc.ListWorkflowEventTriggersRow{
+			"wf-up": {
+				{ID: "tr-default", WorkflowID: "wf-on-success", Config: []byte(`{"workflow_id":"wf-up"}`)},
+				{ID: "tr-failed", WorkflowID: "wf-on-failure", Config: []byte(`{"workflow_id":"wf-up","status":"failed"}`)},
+				{ID: "tr-any", WorkflowID: "wf-always", Config: []byte(`{"workflow_id":"wf-up","status":"any"}`)},
+			},
+			"wf-broken": {
+				{ID: "tr-failed-2", WorkflowID: "wf-alert", Config: []byte(`{"workflow_id":"wf-broken","status":"failed"}`)},
+			},
+		},
+		runs: map[string]sqlc.GetWorkflowRunRow{"run-1": {ID: "run-1", ChainDepth: 2}},
+	}
+	p := newCallWorkflowProcessor(fq)
+	_ = p.registry.Register("boom", actions.ExecutorFunc(func(ctx context.Context, step actions.Step) (json.RawMessage, error) {
+		return nil, errors.New("boom")
+	}))
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	if len(fq.chained) != 3 {
+		t.Fatalf("expected 3 chained runs, got %+v", fq.chained)
+	}
+	for i, want := range []string{"wf-on-success", "wf-always"} {
+		run := fq.chained[i]
+		if run.WorkflowID != want || run.ParentRunID != "run-1" || run.ChainDepth != 3 || string(run.Input) != `{"n":1}` {
+			t.Fatalf("unexpected chained run %d: %+v", i, run)
+		}
+	}
+	failed := fq.chained[2]
+	var input map[string]string
+	if err := json.Unmarshal(failed.Input, &input); err != nil || failed.WorkflowID != "wf-alert" || input["status"] != "failed" || input["run_id"] != "run-2" {
+		t.Fatalf("unexpected chained run for failure: %+v (input %s)", failed, failed.Input)
+	}
+}
+
+func TestWorkflowEvent_StopsAtMaxChainDepth(t *testing.T) {
+	fq := &fakeQueries{
+		pendingRuns: []sqlc.ListPendingWorkflowRunsRow{{ID: "run-1", WorkflowID: "wf-loop"}},
+		actions:     []sqlc.ListActionsByWorkflowRow{{ID: "act-1", WorkflowID: "wf-loop", Type: "echo", Position: 1}},
+		eventTrigs: map[string][]sqlc.ListWorkflowEventTriggersRow{
+			"wf-loop": {{ID: "tr-self", WorkflowID: "wf-loop", Config: []byte(`{"workflow_id":"wf-loop"}`)}},
+		},
+		runs: map[string]sqlc.GetWorkflowRunRow{"run-1": {ID: "run-1", ChainDepth: MaxChainDepth}},
+	}
+	p := newCallWorkflowProcessor(fq)
+
+	if err := p.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce error: %v", err)
+	}
+	if len(fq.statuses) != 1 || fq.statuses[0] != "success" || len(fq.chained) != 0 {
+		t.Fatalf("expected the run to finish without chaining, got statuses %v chained %+v", fq.statuses, fq.chained)
+	}
+}
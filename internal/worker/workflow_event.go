@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/rs/zerolog/log"
+)
+
+// WorkflowEventType is the trigger type that starts a workflow when another one finishes.
+const WorkflowEventType = "workflow_event"
+
+// MaxChainDepth is how many workflow_event hops a chain of runs may take, so workflows that
+// trigger each other in a cycle stop instead of running forever.
+const MaxChainDepth = 10
+
+// StatusAny makes a workflow_event trigger fire whatever the upstream run's final status.
+const StatusAny = "any"
+
+// WorkflowEventConfig is the config of a "workflow_event" trigger: it fires when the workflow
+// WorkflowID finishes with Status (success, failed or any; default success).
+type WorkflowEventConfig struct {
+	WorkflowID string `json:"workflow_id"`
+	Status     string `json:"status,omitempty"`
+}
+
+// matches reports whether a run that finished with status fires the trigger.
+func (c WorkflowEventConfig) matches(status string) bool {
+	switch c.Status {
+	case "":
+		return status == statusSuccess
+	case StatusAny:
+		return true
+	}
+	return c.Status == status
+}
+
+// emitWorkflowEvent enqueues a run of every enabled workflow whose workflow_event trigger listens
+// for this run's workflow and final status. The upstream output becomes the downstream input; a
+// run without output (e.g. a failed one) passes {"workflow_id", "run_id", "status"} instead.
+func (p *Processor) emitWorkflowEvent(ctx context.Context, runID, workflowID, status string, output json.RawMessage) {
+	triggers, err := p.queries.ListWorkflowEventTriggers(ctx, workflowID)
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", workflowID).Msg("failed to list workflow_event triggers")
+		return
+	}
+	if len(triggers) == 0 {
+		return
+	}
+
+	run, err := p.queries.GetWorkflowRun(ctx, runID)
+	if err != nil {
+		log.Error().Err(err).Str("run_id", runID).Msg("failed to load finished run")
+		return
+	}
+	depth := run.ChainDepth + 1
+	if depth > MaxChainDepth {
+		log.Warn().Str("run_id", runID).Int("max_chain_depth", MaxChainDepth).Msg("workflow_event chain too deep, not triggering downstream workflows")
+		return
+	}
+
+	input := output
+	if len(input) == 0 {
+		if input, err = json.Marshal(map[string]string{
+			"workflow_id": workflowID,
+			"run_id":      runID,
+			"status":      status,
+		}); err != nil {
+			return
+		}
+	}
+	for _, t := range triggers {
+		var cfg WorkflowEventConfig
+		if err := json.Unmarshal(t.Config, &cfg); err != nil {
+			log.Warn().Err(err).Str("trigger_id", t.ID).Msg("skipping workflow_event trigger")
+			continue
+		}
+		if !cfg.matches(status) {
+			continue
+		}
+		downstream, err := p.queries.CreateChainedWorkflowRun(ctx, sqlc.CreateChainedWorkflowRunParams{
+			WorkflowID:  t.WorkflowID,
+			ParentRunID: runID,
+			ChainDepth:  depth,
+			Input:       input,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("trigger_id", t.ID).Str("run_id", runID).Msg("failed to enqueue chained run")
+			continue
+		}
+		log.Info().Str("trigger_id", t.ID).Str("run_id", downstream).Str("upstream_run_id", runID).Msg("workflow_event trigger fired")
+	}
+}
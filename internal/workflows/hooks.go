@@ -0,0 +1,139 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TriggerTypeWebhook is the trigger type fired through the public POST /hooks/{trigger_id} endpoint.
+const TriggerTypeWebhook = "webhook"
+
+// TriggerTypeEmail is the trigger type fired by mail to <trigger_id>@<SMTP_DOMAIN>.
+const TriggerTypeEmail = "email"
+
+var (
+	ErrWorkflowDisabled = errors.New("workflow disabled")
+	ErrRunNotFound      = errors.New("run not found")
+)
+
+// WebhookManager backs the unauthenticated webhook ingress. Lookups are not scoped to a user:
+// knowing the trigger ID is what grants access.
+type WebhookManager interface {
+	WebhookTrigger(ctx context.Context, triggerID string) (Trigger, error)
+	EnqueueTriggeredRun(ctx context.Context, trigger Trigger, input []byte) (WorkflowRun, error)
+	GetRun(ctx context.Context, runID string) (WorkflowRun, error)
+	RecordRejection(ctx context.Context, triggerID string) error
+}
+
+// WebhookTrigger resolves a webhook trigger by ID. Unknown IDs and triggers of other types
+// return ErrTriggerNotFound; triggers of disabled workflows return ErrWorkflowDisabled.
+func (s *Service) WebhookTrigger(ctx context.Context, triggerID string) (Trigger, error) {
+	return s.triggerOfType(ctx, triggerID, TriggerTypeWebhook)
+}
+
+// EmailTrigger resolves an email trigger by ID, with the same errors as WebhookTrigger.
+func (s *Service) EmailTrigger(ctx context.Context, triggerID string) (Trigger, error) {
+	return s.triggerOfType(ctx, triggerID, TriggerTypeEmail)
+}
+
+func (s *Service) triggerOfType(ctx context.Context, triggerID, typ string) (Trigger, error) {
+	if !validUUID(triggerID) {
+		return Trigger{}, ErrTriggerNotFound
+	}
+	row, err := s.queries.GetTriggerWithWorkflow(ctx, triggerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Trigger{}, ErrTriggerNotFound
+		}
+		return Trigger{}, err
+	}
+	if row.Type != typ {
+		return Trigger{}, ErrTriggerNotFound
+	}
+	if !row.IsEnabled {
+		return Trigger{}, ErrWorkflowDisabled
+	}
+	return Trigger{
+		ID:         row.ID,
+		WorkflowID: row.WorkflowID,
+		Type:       row.Type,
+		Config:     row.Config,
+		CreatedAt:  row.CreatedAt.Time,
+	}, nil
+}
+
+// EnqueueTriggeredRun queues a run of the trigger's workflow with input as the first step's input.
+func (s *Service) EnqueueTriggeredRun(ctx context.Context, trigger Trigger, input []byte) (WorkflowRun, error) {
+	row, err := s.queries.CreateWorkflowRun(ctx, sqlc.CreateWorkflowRunParams{
+		WorkflowID:  trigger.WorkflowID,
+		Status:      "pending",
+		TriggerType: trigger.Type,
+		StartedAt:   pgtype.Timestamptz{},
+		Input:       input,
+	})
+	if err != nil {
+		return WorkflowRun{}, err
+	}
+	return WorkflowRun{
+		ID:          row.ID,
+		WorkflowID:  row.WorkflowID,
+		Status:      row.Status,
+		TriggerType: row.TriggerType,
+		CreatedAt:   row.CreatedAt.Time,
+	}, nil
+}
+
+// RecordRejection counts a webhook request that failed verification against the trigger.
+func (s *Service) RecordRejection(ctx context.Context, triggerID string) error {
+	return s.queries.RecordTriggerRejection(ctx, triggerID)
+}
+
+// GetRun fetches a run's status and, once finished successfully, its output.
+func (s *Service) GetRun(ctx context.Context, runID string) (WorkflowRun, error) {
+	row, err := s.queries.GetWorkflowRun(ctx, runID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return WorkflowRun{}, ErrRunNotFound
+		}
+		return WorkflowRun{}, err
+	}
+	var parent *string
+	if row.ParentRunID.Valid {
+		id := row.ParentRunID.String()
+		parent = &id
+	}
+	return WorkflowRun{
+		ID:          row.ID,
+		WorkflowID:  row.WorkflowID,
+		Status:      row.Status,
+		ParentRunID: parent,
+		Depth:       row.Depth,
+		Output:      json.RawMessage(row.Output),
+	}, nil
+}
+
+// validUUID reports whether s is a canonical UUID, so malformed IDs from public URLs are
+// rejected before they reach Postgres.
+func validUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
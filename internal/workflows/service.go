@@ -2,18 +2,23 @@ package workflows
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
+	"github.com/groovypotato/PotaFlow/internal/catalog"
 	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/internal/orgs"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// Workflow represents a user's workflow record.
+// Workflow represents a workflow owned by an organization. UserID is the member who created it
+// and is empty once they are deleted.
 type Workflow struct {
 	ID        string
 	UserID    string
+	OrgID     string
 	Name      string
 	IsEnabled bool
 	CreatedAt time.Time
@@ -22,17 +27,23 @@ type Workflow struct {
 
 var (
 	ErrNotFound        = errors.New("workflow not found")
+	ErrForbidden       = errors.New("insufficient organization role")
 	ErrTriggerNotFound = errors.New("trigger not found")
 	ErrActionNotFound  = errors.New("action not found")
+	ErrOrgNotFound     = errors.New("organization not found")
 )
 
-// Trigger represents a workflow trigger.
+// Trigger represents a workflow trigger. RejectedCount and LastRejectedAt track webhook
+// requests that failed signature verification; LastFiredAt is the last slot a cron trigger fired.
 type Trigger struct {
-	ID         string
-	WorkflowID string
-	Type       string
-	Config     []byte
-	CreatedAt  time.Time
+	ID             string
+	WorkflowID     string
+	Type           string
+	Config         []byte
+	CreatedAt      time.Time
+	RejectedCount  int64
+	LastRejectedAt *time.Time
+	LastFiredAt    *time.Time
 }
 
 // Action represents a workflow action step.
@@ -53,11 +64,14 @@ type WorkflowRun struct {
 	StartedAt   time.Time
 	FinishedAt  *time.Time
 	CreatedAt   time.Time
+	ParentRunID *string
+	Depth       int32
+	Output      json.RawMessage
 }
 
 // WorkflowManager defines CRUD for workflows.
 type WorkflowManager interface {
-	Create(ctx context.Context, userID, name string) (Workflow, error)
+	Create(ctx context.Context, userID, orgID, name string) (Workflow, error)
 	List(ctx context.Context, userID string) ([]Workflow, error)
 	Get(ctx context.Context, userID, workflowID string) (Workflow, error)
 	Update(ctx context.Context, userID, workflowID, name string, isEnabled bool) (Workflow, error)
@@ -86,17 +100,48 @@ type RunManager interface {
 	ListRuns(ctx context.Context, userID, workflowID string) ([]WorkflowRun, error)
 }
 
+// InstanceViewer lists workflows and runs of every organization, for admins.
+type InstanceViewer interface {
+	ListAllWorkflows(ctx context.Context) ([]Workflow, error)
+	ListAllRuns(ctx context.Context, filter RunFilter) ([]WorkflowRun, error)
+}
+
+// RunFilter narrows ListAllRuns; empty fields match everything.
+type RunFilter struct {
+	WorkflowID string
+	Status     string
+	Limit      int32
+}
+
+// SigningSecretManager exposes the per-workflow secret used to sign outgoing HTTP actions.
+type SigningSecretManager interface {
+	SigningSecret(ctx context.Context, userID, workflowID string) (string, error)
+	RotateSigningSecret(ctx context.Context, userID, workflowID string) (string, error)
+}
+
+// CatalogLister lists the trigger and action types that can be configured.
+type CatalogLister interface {
+	Catalog(ctx context.Context) ([]catalog.Entry, error)
+}
+
 // Service manages workflow CRUD and triggers/actions using sqlc-generated queries.
 type Service struct {
-	queries queryProvider
+	queries       queryProvider
+	catalog       *catalog.Catalog
+	triggerChecks map[string]func(config []byte) error
 }
 
 type queryProvider interface {
 	CreateWorkflow(ctx context.Context, arg sqlc.CreateWorkflowParams) (sqlc.CreateWorkflowRow, error)
-	ListWorkflowsByUser(ctx context.Context, userID string) ([]sqlc.ListWorkflowsByUserRow, error)
+	ListWorkflowsForMember(ctx context.Context, userID string) ([]sqlc.ListWorkflowsForMemberRow, error)
+	ListAllWorkflows(ctx context.Context) ([]sqlc.ListAllWorkflowsRow, error)
 	GetWorkflow(ctx context.Context, arg sqlc.GetWorkflowParams) (sqlc.GetWorkflowRow, error)
 	UpdateWorkflow(ctx context.Context, arg sqlc.UpdateWorkflowParams) (sqlc.UpdateWorkflowRow, error)
-	DeleteWorkflow(ctx context.Context, arg sqlc.DeleteWorkflowParams) (string, error)
+	DeleteWorkflow(ctx context.Context, id string) (int64, error)
+
+	GetDefaultOrganization(ctx context.Context, userID string) (string, error)
+	GetOrganizationForMember(ctx context.Context, arg sqlc.GetOrganizationForMemberParams) (sqlc.GetOrganizationForMemberRow, error)
+	CreateOrganization(ctx context.Context, arg sqlc.CreateOrganizationParams) (sqlc.CreateOrganizationRow, error)
 
 	CreateTrigger(ctx context.Context, arg sqlc.CreateTriggerParams) (sqlc.CreateTriggerRow, error)
 	ListTriggersByWorkflow(ctx context.Context, workflowID string) ([]sqlc.ListTriggersByWorkflowRow, error)
@@ -110,27 +155,99 @@ type queryProvider interface {
 
 	CreateWorkflowRun(ctx context.Context, arg sqlc.CreateWorkflowRunParams) (sqlc.CreateWorkflowRunRow, error)
 	ListWorkflowRunsByWorkflow(ctx context.Context, workflowID string) ([]sqlc.ListWorkflowRunsByWorkflowRow, error)
+	ListRecentWorkflowRuns(ctx context.Context, arg sqlc.ListRecentWorkflowRunsParams) ([]sqlc.ListRecentWorkflowRunsRow, error)
+
+	GetTriggerWithWorkflow(ctx context.Context, id string) (sqlc.GetTriggerWithWorkflowRow, error)
+	RecordTriggerRejection(ctx context.Context, id string) error
+	GetWorkflowRun(ctx context.Context, id string) (sqlc.GetWorkflowRunRow, error)
+
+	GetWorkflowSigningSecret(ctx context.Context, id string) (string, error)
+	RotateWorkflowSigningSecret(ctx context.Context, id string) (string, error)
+
+	ListPluginTypes(ctx context.Context) ([]sqlc.ListPluginTypesRow, error)
 }
 
 // NewService builds a Service from a sqlc DBTX (e.g., *pgxpool.Pool).
 func NewService(db sqlc.DBTX) *Service {
-	return &Service{queries: sqlc.New(db)}
+	q := sqlc.New(db)
+	return &Service{queries: q, catalog: catalog.New(q)}
+}
+
+// Catalog lists the built-in and plugin trigger and action types with their config schemas.
+func (s *Service) Catalog(ctx context.Context) ([]catalog.Entry, error) {
+	return s.catalog.List(ctx)
+}
+
+// SetTriggerConfigCheck makes check part of validating triggerType configs, for the rules of the
+// code that runs the trigger which its schema can't express (e.g. webhooks.ParseConfig).
+func (s *Service) SetTriggerConfigCheck(triggerType string, check func(config []byte) error) {
+	if s.triggerChecks == nil {
+		s.triggerChecks = make(map[string]func([]byte) error)
+	}
+	s.triggerChecks[triggerType] = check
+}
+
+// validateConfig rejects unknown types and configs that don't match the type's schema, or fail
+// its trigger config check, with a *catalog.ValidationError. A missing config is treated as an
+// empty object.
+func (s *Service) validateConfig(ctx context.Context, kind catalog.Kind, typ string, config []byte) ([]byte, error) {
+	if len(config) == 0 {
+		config = []byte(`{}`)
+	}
+	if err := s.catalog.Validate(ctx, kind, typ, config); err != nil {
+		return nil, err
+	}
+	if check := s.triggerChecks[typ]; kind == catalog.KindTrigger && check != nil {
+		if err := check(config); err != nil {
+			return nil, &catalog.ValidationError{Fields: []catalog.FieldError{{Field: "config", Message: err.Error()}}}
+		}
+	}
+	return config, nil
+}
+
+// triggerTypeWorkflowEvent triggers reference another workflow, which must belong to the same
+// organization.
+const triggerTypeWorkflowEvent = "workflow_event"
+
+// validateTriggerRefs rejects triggers on wf that reference workflows the user can't see or that
+// belong to another organization. config has already passed validateConfig.
+func (s *Service) validateTriggerRefs(ctx context.Context, userID string, wf Workflow, triggerType string, config []byte) error {
+	if triggerType != triggerTypeWorkflowEvent {
+		return nil
+	}
+	var cfg struct {
+		WorkflowID string `json:"workflow_id"`
+	}
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return err
+	}
+	ref, err := s.Get(ctx, userID, cfg.WorkflowID)
+	if errors.Is(err, ErrNotFound) || (err == nil && ref.OrgID != wf.OrgID) {
+		return &catalog.ValidationError{Fields: []catalog.FieldError{{Field: "config.workflow_id", Message: "workflow not found"}}}
+	}
+	return err
 }
 
-// Create inserts a new workflow for the given user.
-func (s *Service) Create(ctx context.Context, userID, name string) (Workflow, error) {
+// Create inserts a new workflow created by userID into orgID, or into their default organization
+// when orgID is empty.
+func (s *Service) Create(ctx context.Context, userID, orgID, name string) (Workflow, error) {
+	orgID, err := s.resolveOrg(ctx, userID, orgID)
+	if err != nil {
+		return Workflow{}, err
+	}
 	row, err := s.queries.CreateWorkflow(ctx, sqlc.CreateWorkflowParams{
 		UserID: userID,
+		OrgID:  orgID,
 		Name:   name,
 	})
 	if err != nil {
-
 		return Workflow{}, err
 	}
 
 	return Workflow{
 		ID:        row.ID,
 		UserID:    row.UserID,
+		OrgID:     row.OrgID,
 		Name:      row.Name,
 		IsEnabled: row.IsEnabled,
 		CreatedAt: row.CreatedAt.Time,
@@ -138,9 +255,46 @@ func (s *Service) Create(ctx context.Context, userID, name string) (Workflow, er
 	}, nil
 }
 
-// List returns all workflows for a user, newest first.
+// personalOrgName names the organization created for users who create a workflow without one.
+const personalOrgName = "Personal"
+
+// resolveOrg returns the organization a new workflow goes into. Without an explicit orgID that is
+// the user's oldest owned organization, created on demand; otherwise the user must be able to
+// edit workflows in orgID.
+func (s *Service) resolveOrg(ctx context.Context, userID, orgID string) (string, error) {
+	if orgID != "" {
+		if !validUUID(orgID) {
+			return "", ErrOrgNotFound
+		}
+		org, err := s.queries.GetOrganizationForMember(ctx, sqlc.GetOrganizationForMemberParams{ID: orgID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return "", ErrOrgNotFound
+			}
+			return "", err
+		}
+		if !orgs.Can(org.Role, orgs.PermEdit) {
+			return "", ErrForbidden
+		}
+		return org.ID, nil
+	}
+	id, err := s.queries.GetDefaultOrganization(ctx, userID)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	org, err := s.queries.CreateOrganization(ctx, sqlc.CreateOrganizationParams{Name: personalOrgName, UserID: userID})
+	if err != nil {
+		return "", err
+	}
+	return org.ID, nil
+}
+
+// List returns the workflows of every organization the user belongs to, newest first.
 func (s *Service) List(ctx context.Context, userID string) ([]Workflow, error) {
-	rows, err := s.queries.ListWorkflowsByUser(ctx, userID)
+	rows, err := s.queries.ListWorkflowsForMember(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -150,6 +304,7 @@ func (s *Service) List(ctx context.Context, userID string) ([]Workflow, error) {
 		workflows = append(workflows, Workflow{
 			ID:        row.ID,
 			UserID:    row.UserID,
+			OrgID:     row.OrgID,
 			Name:      row.Name,
 			IsEnabled: row.IsEnabled,
 			CreatedAt: row.CreatedAt.Time,
@@ -159,8 +314,14 @@ func (s *Service) List(ctx context.Context, userID string) ([]Workflow, error) {
 	return workflows, nil
 }
 
-// Get fetches a workflow by ID scoped to the given user.
+// Get fetches a workflow by ID if the user is a member of its organization.
 func (s *Service) Get(ctx context.Context, userID, workflowID string) (Workflow, error) {
+	return s.authorize(ctx, userID, workflowID, orgs.PermView)
+}
+
+// authorize fetches a workflow in one of the user's organizations and checks that their role
+// there grants perm. Workflows of other organizations are reported as missing.
+func (s *Service) authorize(ctx context.Context, userID, workflowID string, perm orgs.Permission) (Workflow, error) {
 	row, err := s.queries.GetWorkflow(ctx, sqlc.GetWorkflowParams{
 		ID:     workflowID,
 		UserID: userID,
@@ -171,10 +332,14 @@ func (s *Service) Get(ctx context.Context, userID, workflowID string) (Workflow,
 		}
 		return Workflow{}, err
 	}
+	if !orgs.Can(row.Role, perm) {
+		return Workflow{}, ErrForbidden
+	}
 
 	return Workflow{
 		ID:        row.ID,
 		UserID:    row.UserID,
+		OrgID:     row.OrgID,
 		Name:      row.Name,
 		IsEnabled: row.IsEnabled,
 		CreatedAt: row.CreatedAt.Time,
@@ -182,13 +347,15 @@ func (s *Service) Get(ctx context.Context, userID, workflowID string) (Workflow,
 	}, nil
 }
 
-// Update updates name/enable flag on a workflow for the given user.
+// Update updates name/enable flag on a workflow the user may edit.
 func (s *Service) Update(ctx context.Context, userID, workflowID, name string, isEnabled bool) (Workflow, error) {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
+		return Workflow{}, err
+	}
 	row, err := s.queries.UpdateWorkflow(ctx, sqlc.UpdateWorkflowParams{
 		ID:        workflowID,
 		Name:      name,
 		IsEnabled: isEnabled,
-		UserID:    userID,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -200,6 +367,7 @@ func (s *Service) Update(ctx context.Context, userID, workflowID, name string, i
 	return Workflow{
 		ID:        row.ID,
 		UserID:    row.UserID,
+		OrgID:     row.OrgID,
 		Name:      row.Name,
 		IsEnabled: row.IsEnabled,
 		CreatedAt: row.CreatedAt.Time,
@@ -207,23 +375,31 @@ func (s *Service) Update(ctx context.Context, userID, workflowID, name string, i
 	}, nil
 }
 
-// Delete removes a workflow scoped to the given user.
+// Delete removes a workflow the user may edit.
 func (s *Service) Delete(ctx context.Context, userID, workflowID string) error {
-	_, err := s.queries.DeleteWorkflow(ctx, sqlc.DeleteWorkflowParams{
-		ID:     workflowID,
-		UserID: userID,
-	})
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
+		return err
+	}
+	n, err := s.queries.DeleteWorkflow(ctx, workflowID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrNotFound
-		}
 		return err
 	}
+	if n == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
 func (s *Service) CreateTrigger(ctx context.Context, userID, workflowID, triggerType string, config []byte) (Trigger, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	wf, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit)
+	if err != nil {
+		return Trigger{}, err
+	}
+	config, err = s.validateConfig(ctx, catalog.KindTrigger, triggerType, config)
+	if err != nil {
+		return Trigger{}, err
+	}
+	if err := s.validateTriggerRefs(ctx, userID, wf, triggerType, config); err != nil {
 		return Trigger{}, err
 	}
 	row, err := s.queries.CreateTrigger(ctx, sqlc.CreateTriggerParams{
@@ -243,8 +419,10 @@ func (s *Service) CreateTrigger(ctx context.Context, userID, workflowID, trigger
 	}, nil
 }
 
+// ListTriggers lists a workflow's triggers. Trigger IDs (webhook and email addresses) and configs
+// (webhook secrets, poll headers) let their holder start runs, so viewers may not list them.
 func (s *Service) ListTriggers(ctx context.Context, userID, workflowID string) ([]Trigger, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermRun); err != nil {
 		return nil, err
 	}
 	rows, err := s.queries.ListTriggersByWorkflow(ctx, workflowID)
@@ -253,19 +431,38 @@ func (s *Service) ListTriggers(ctx context.Context, userID, workflowID string) (
 	}
 	var out []Trigger
 	for _, row := range rows {
+		var lastRejected *time.Time
+		if row.LastRejectedAt.Valid {
+			lastRejected = &row.LastRejectedAt.Time
+		}
+		var lastFired *time.Time
+		if row.LastFiredAt.Valid {
+			lastFired = &row.LastFiredAt.Time
+		}
 		out = append(out, Trigger{
-			ID:         row.ID,
-			WorkflowID: row.WorkflowID,
-			Type:       row.Type,
-			Config:     row.Config,
-			CreatedAt:  row.CreatedAt.Time,
+			ID:             row.ID,
+			WorkflowID:     row.WorkflowID,
+			Type:           row.Type,
+			Config:         row.Config,
+			CreatedAt:      row.CreatedAt.Time,
+			RejectedCount:  row.RejectedCount,
+			LastRejectedAt: lastRejected,
+			LastFiredAt:    lastFired,
 		})
 	}
 	return out, nil
 }
 
 func (s *Service) UpdateTrigger(ctx context.Context, userID, workflowID, triggerID, triggerType string, config []byte) (Trigger, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	wf, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit)
+	if err != nil {
+		return Trigger{}, err
+	}
+	config, err = s.validateConfig(ctx, catalog.KindTrigger, triggerType, config)
+	if err != nil {
+		return Trigger{}, err
+	}
+	if err := s.validateTriggerRefs(ctx, userID, wf, triggerType, config); err != nil {
 		return Trigger{}, err
 	}
 	row, err := s.queries.UpdateTrigger(ctx, sqlc.UpdateTriggerParams{
@@ -290,7 +487,7 @@ func (s *Service) UpdateTrigger(ctx context.Context, userID, workflowID, trigger
 }
 
 func (s *Service) DeleteTrigger(ctx context.Context, userID, workflowID, triggerID string) error {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
 		return err
 	}
 	if err := s.queries.DeleteTrigger(ctx, sqlc.DeleteTriggerParams{
@@ -306,7 +503,11 @@ func (s *Service) DeleteTrigger(ctx context.Context, userID, workflowID, trigger
 }
 
 func (s *Service) CreateAction(ctx context.Context, userID, workflowID, actionType string, position int32, config []byte) (Action, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
+		return Action{}, err
+	}
+	config, err := s.validateConfig(ctx, catalog.KindAction, actionType, config)
+	if err != nil {
 		return Action{}, err
 	}
 	row, err := s.queries.CreateAction(ctx, sqlc.CreateActionParams{
@@ -329,7 +530,7 @@ func (s *Service) CreateAction(ctx context.Context, userID, workflowID, actionTy
 }
 
 func (s *Service) ListActions(ctx context.Context, userID, workflowID string) ([]Action, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermView); err != nil {
 		return nil, err
 	}
 	rows, err := s.queries.ListActionsByWorkflow(ctx, workflowID)
@@ -351,7 +552,11 @@ func (s *Service) ListActions(ctx context.Context, userID, workflowID string) ([
 }
 
 func (s *Service) UpdateAction(ctx context.Context, userID, workflowID, actionID, actionType string, position int32, config []byte) (Action, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
+		return Action{}, err
+	}
+	config, err := s.validateConfig(ctx, catalog.KindAction, actionType, config)
+	if err != nil {
 		return Action{}, err
 	}
 	row, err := s.queries.UpdateAction(ctx, sqlc.UpdateActionParams{
@@ -378,7 +583,7 @@ func (s *Service) UpdateAction(ctx context.Context, userID, workflowID, actionID
 }
 
 func (s *Service) DeleteAction(ctx context.Context, userID, workflowID, actionID string) error {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
 		return err
 	}
 	if err := s.queries.DeleteAction(ctx, sqlc.DeleteActionParams{
@@ -394,7 +599,7 @@ func (s *Service) DeleteAction(ctx context.Context, userID, workflowID, actionID
 }
 
 func (s *Service) EnqueueRun(ctx context.Context, userID, workflowID, triggerType string) (WorkflowRun, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermRun); err != nil {
 		return WorkflowRun{}, err
 	}
 	row, err := s.queries.CreateWorkflowRun(ctx, sqlc.CreateWorkflowRunParams{
@@ -416,7 +621,7 @@ func (s *Service) EnqueueRun(ctx context.Context, userID, workflowID, triggerTyp
 }
 
 func (s *Service) ListRuns(ctx context.Context, userID, workflowID string) ([]WorkflowRun, error) {
-	if _, err := s.Get(ctx, userID, workflowID); err != nil {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermView); err != nil {
 		return nil, err
 	}
 	rows, err := s.queries.ListWorkflowRunsByWorkflow(ctx, workflowID)
@@ -425,19 +630,106 @@ func (s *Service) ListRuns(ctx context.Context, userID, workflowID string) ([]Wo
 	}
 	var runs []WorkflowRun
 	for _, r := range rows {
-		var finished *time.Time
-		if r.FinishedAt.Valid {
-			finished = &r.FinishedAt.Time
-		}
-		runs = append(runs, WorkflowRun{
-			ID:          r.ID,
-			WorkflowID:  r.WorkflowID,
-			Status:      r.Status,
-			TriggerType: r.TriggerType,
-			StartedAt:   r.StartedAt.Time,
-			FinishedAt:  finished,
-			CreatedAt:   r.CreatedAt.Time,
+		runs = append(runs, runFromRow(r))
+	}
+	return runs, nil
+}
+
+// defaultRunLimit caps ListAllRuns when the filter sets no limit.
+const defaultRunLimit = 100
+
+// ListAllWorkflows returns the workflows of every organization, newest first.
+func (s *Service) ListAllWorkflows(ctx context.Context) ([]Workflow, error) {
+	rows, err := s.queries.ListAllWorkflows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows := make([]Workflow, 0, len(rows))
+	for _, row := range rows {
+		workflows = append(workflows, Workflow{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			OrgID:     row.OrgID,
+			Name:      row.Name,
+			IsEnabled: row.IsEnabled,
+			CreatedAt: row.CreatedAt.Time,
+			UpdatedAt: row.UpdatedAt.Time,
 		})
 	}
+	return workflows, nil
+}
+
+// ListAllRuns returns the newest runs across every workflow matching filter.
+func (s *Service) ListAllRuns(ctx context.Context, filter RunFilter) ([]WorkflowRun, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultRunLimit
+	}
+	rows, err := s.queries.ListRecentWorkflowRuns(ctx, sqlc.ListRecentWorkflowRunsParams{
+		WorkflowID: pgtype.Text{String: filter.WorkflowID, Valid: filter.WorkflowID != ""},
+		Status:     pgtype.Text{String: filter.Status, Valid: filter.Status != ""},
+		RowLimit:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]WorkflowRun, 0, len(rows))
+	for _, r := range rows {
+		runs = append(runs, runFromRow(sqlc.ListWorkflowRunsByWorkflowRow(r)))
+	}
 	return runs, nil
 }
+
+func runFromRow(r sqlc.ListWorkflowRunsByWorkflowRow) WorkflowRun {
+	var finished *time.Time
+	if r.FinishedAt.Valid {
+		finished = &r.FinishedAt.Time
+	}
+	var parent *string
+	if r.ParentRunID.Valid {
+		id := r.ParentRunID.String()
+		parent = &id
+	}
+	return WorkflowRun{
+		ID:          r.ID,
+		WorkflowID:  r.WorkflowID,
+		Status:      r.Status,
+		TriggerType: r.TriggerType,
+		StartedAt:   r.StartedAt.Time,
+		FinishedAt:  finished,
+		CreatedAt:   r.CreatedAt.Time,
+		ParentRunID: parent,
+		Depth:       r.Depth,
+	}
+}
+
+// SigningSecret returns the workflow's current signing secret.
+func (s *Service) SigningSecret(ctx context.Context, userID, workflowID string) (string, error) {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
+		return "", err
+	}
+	secret, err := s.queries.GetWorkflowSigningSecret(ctx, workflowID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return secret, nil
+}
+
+// RotateSigningSecret replaces the workflow's signing secret with a freshly generated one.
+func (s *Service) RotateSigningSecret(ctx context.Context, userID, workflowID string) (string, error) {
+	if _, err := s.authorize(ctx, userID, workflowID, orgs.PermEdit); err != nil {
+		return "", err
+	}
+	secret, err := s.queries.RotateWorkflowSigningSecret(ctx, workflowID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return secret, nil
+}
@@ -4,22 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/groovypotato/PotaFlow/internal/catalog"
 	"github.com/groovypotato/PotaFlow/internal/database/sqlc"
+	"github.com/groovypotato/PotaFlow/internal/orgs"
+	"github.com/groovypotato/PotaFlow/internal/webhooks"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type fakeQueries struct {
 	workflows map[string]sqlc.GetWorkflowRow
+	members   map[string]map[string]string
 	triggers  map[string]sqlc.GetTriggerRow
 	actions   map[string]sqlc.GetActionRow
 	runs      []sqlc.CreateWorkflowRunRow
+	secrets   map[string]string
+	rejected  map[string]int
+	plugins   []sqlc.ListPluginTypesRow
 	err       error
 }
 
+// orgMembers maps organization IDs to their members' roles. Unless a test sets members, "user-1"
+// owns "org-1" and "user-2" owns "org-2".
+func (f *fakeQueries) orgMembers() map[string]map[string]string {
+	if f.members == nil {
+		f.members = map[string]map[string]string{
+			"org-1": {"user-1": orgs.RoleOwner},
+			"org-2": {"user-2": orgs.RoleOwner},
+		}
+	}
+	return f.members
+}
+
 func (f *fakeQueries) CreateWorkflow(ctx context.Context, arg sqlc.CreateWorkflowParams) (sqlc.CreateWorkflowRow, error) {
 	if f.err != nil {
 		return sqlc.CreateWorkflowRow{}, f.err
@@ -27,6 +47,7 @@ func (f *fakeQueries) CreateWorkflow(ctx context.Context, arg sqlc.CreateWorkflo
 	row := sqlc.CreateWorkflowRow{
 		ID:        "wf-1",
 		UserID:    arg.UserID,
+		OrgID:     arg.OrgID,
 		Name:      arg.Name,
 		IsEnabled: true,
 		CreatedAt: pgtype.Timestamptz{Time: time.Unix(0, 0), Valid: true},
@@ -38,6 +59,7 @@ func (f *fakeQueries) CreateWorkflow(ctx context.Context, arg sqlc.CreateWorkflo
 	f.workflows[row.ID] = sqlc.GetWorkflowRow{
 		ID:        row.ID,
 		UserID:    row.UserID,
+		OrgID:     row.OrgID,
 		Name:      row.Name,
 		IsEnabled: row.IsEnabled,
 		CreatedAt: row.CreatedAt,
@@ -46,18 +68,19 @@ func (f *fakeQueries) CreateWorkflow(ctx context.Context, arg sqlc.CreateWorkflo
 	return row, nil
 }
 
-func (f *fakeQueries) ListWorkflowsByUser(ctx context.Context, userID string) ([]sqlc.ListWorkflowsByUserRow, error) {
+func (f *fakeQueries) ListWorkflowsForMember(ctx context.Context, userID string) ([]sqlc.ListWorkflowsForMemberRow, error) {
 	if f.err != nil {
 		return nil, f.err
 	}
-	var rows []sqlc.ListWorkflowsByUserRow
+	var rows []sqlc.ListWorkflowsForMemberRow
 	for _, wf := range f.workflows {
-		if wf.UserID != userID {
+		if _, ok := f.orgMembers()[wf.OrgID][userID]; !ok {
 			continue
 		}
-		rows = append(rows, sqlc.ListWorkflowsByUserRow{
+		rows = append(rows, sqlc.ListWorkflowsForMemberRow{
 			ID:        wf.ID,
 			UserID:    wf.UserID,
+			OrgID:     wf.OrgID,
 			Name:      wf.Name,
 			IsEnabled: wf.IsEnabled,
 			CreatedAt: wf.CreatedAt,
@@ -72,9 +95,14 @@ func (f *fakeQueries) GetWorkflow(ctx context.Context, arg sqlc.GetWorkflowParam
 		return sqlc.GetWorkflowRow{}, f.err
 	}
 	wf, ok := f.workflows[arg.ID]
-	if !ok || wf.UserID != arg.UserID {
+	if !ok {
+		return sqlc.GetWorkflowRow{}, pgx.ErrNoRows
+	}
+	role, ok := f.orgMembers()[wf.OrgID][arg.UserID]
+	if !ok {
 		return sqlc.GetWorkflowRow{}, pgx.ErrNoRows
 	}
+	wf.Role = role
 	return wf, nil
 }
 
@@ -83,7 +111,7 @@ func (f *fakeQueries) UpdateWorkflow(ctx context.Context, arg sqlc.UpdateWorkflo
 		return sqlc.UpdateWorkflowRow{}, f.err
 	}
 	wf, ok := f.workflows[arg.ID]
-	if !ok || wf.UserID != arg.UserID {
+	if !ok {
 		return sqlc.UpdateWorkflowRow{}, pgx.ErrNoRows
 	}
 	wf.Name = arg.Name
@@ -92,6 +120,7 @@ func (f *fakeQueries) UpdateWorkflow(ctx context.Context, arg sqlc.UpdateWorkflo
 	return sqlc.UpdateWorkflowRow{
 		ID:        wf.ID,
 		UserID:    wf.UserID,
+		OrgID:     wf.OrgID,
 		Name:      wf.Name,
 		IsEnabled: wf.IsEnabled,
 		CreatedAt: wf.CreatedAt,
@@ -99,16 +128,47 @@ func (f *fakeQueries) UpdateWorkflow(ctx context.Context, arg sqlc.UpdateWorkflo
 	}, nil
 }
 
-func (f *fakeQueries) DeleteWorkflow(ctx context.Context, arg sqlc.DeleteWorkflowParams) (string, error) {
+func (f *fakeQueries) DeleteWorkflow(ctx context.Context, id string) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if _, ok := f.workflows[id]; !ok {
+		return 0, nil
+	}
+	delete(f.workflows, id)
+	return 1, nil
+}
+
+func (f *fakeQueries) GetDefaultOrganization(ctx context.Context, userID string) (string, error) {
 	if f.err != nil {
 		return "", f.err
 	}
-	wf, ok := f.workflows[arg.ID]
-	if !ok || wf.UserID != arg.UserID {
-		return "", pgx.ErrNoRows
+	for orgID, members := range f.orgMembers() {
+		if members[userID] == orgs.RoleOwner {
+			return orgID, nil
+		}
+	}
+	return "", pgx.ErrNoRows
+}
+
+func (f *fakeQueries) GetOrganizationForMember(ctx context.Context, arg sqlc.GetOrganizationForMemberParams) (sqlc.GetOrganizationForMemberRow, error) {
+	if f.err != nil {
+		return sqlc.GetOrganizationForMemberRow{}, f.err
+	}
+	role, ok := f.orgMembers()[arg.ID][arg.UserID]
+	if !ok {
+		return sqlc.GetOrganizationForMemberRow{}, pgx.ErrNoRows
+	}
+	return sqlc.GetOrganizationForMemberRow{ID: arg.ID, Role: role}, nil
+}
+
+func (f *fakeQueries) CreateOrganization(ctx context.Context, arg sqlc.CreateOrganizationParams) (sqlc.CreateOrganizationRow, error) {
+	if f.err != nil {
+		return sqlc.CreateOrganizationRow{}, f.err
 	}
-	delete(f.workflows, arg.ID)
-	return arg.ID, nil
+	id := fmt.Sprintf("org-%d", len(f.orgMembers())+1)
+	f.members[id] = map[string]string{arg.UserID: orgs.RoleOwner}
+	return sqlc.CreateOrganizationRow{ID: id, Name: arg.Name}, nil
 }
 
 // Triggers/actions stubs (not exercised here)
@@ -302,16 +362,107 @@ func (f *fakeQueries) ListWorkflowRunsByWorkflow(ctx context.Context, workflowID
 	return out, nil
 }
 
+func (f *fakeQueries) ListAllWorkflows(ctx context.Context) ([]sqlc.ListAllWorkflowsRow, error) {
+	var rows []sqlc.ListAllWorkflowsRow
+	for _, wf := range f.workflows {
+		rows = append(rows, sqlc.ListAllWorkflowsRow{ID: wf.ID, UserID: wf.UserID, OrgID: wf.OrgID, Name: wf.Name})
+	}
+	return rows, nil
+}
+
+func (f *fakeQueries) ListRecentWorkflowRuns(ctx context.Context, arg sqlc.ListRecentWorkflowRunsParams) ([]sqlc.ListRecentWorkflowRunsRow, error) {
+	var out []sqlc.ListRecentWorkflowRunsRow
+	for _, run := range f.runs {
+		if arg.WorkflowID.Valid && run.WorkflowID != arg.WorkflowID.String {
+			continue
+		}
+		if arg.Status.Valid && run.Status != arg.Status.String {
+			continue
+		}
+		if int32(len(out)) == arg.RowLimit {
+			break
+		}
+		out = append(out, sqlc.ListRecentWorkflowRunsRow{
+			ID:          run.ID,
+			WorkflowID:  run.WorkflowID,
+			Status:      run.Status,
+			TriggerType: run.TriggerType,
+			CreatedAt:   run.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (f *fakeQueries) ListPluginTypes(ctx context.Context) ([]sqlc.ListPluginTypesRow, error) {
+	return f.plugins, nil
+}
+
+func (f *fakeQueries) GetWorkflowSigningSecret(ctx context.Context, id string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	secret, ok := f.secrets[id]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return secret, nil
+}
+func (f *fakeQueries) RotateWorkflowSigningSecret(ctx context.Context, id string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if f.secrets == nil {
+		f.secrets = make(map[string]string)
+	}
+	f.secrets[id] = f.secrets[id] + "-rotated"
+	return f.secrets[id], nil
+}
+func (f *fakeQueries) GetTriggerWithWorkflow(ctx context.Context, id string) (sqlc.GetTriggerWithWorkflowRow, error) {
+	if f.err != nil {
+		return sqlc.GetTriggerWithWorkflowRow{}, f.err
+	}
+	tr, ok := f.triggers[id]
+	if !ok {
+		return sqlc.GetTriggerWithWorkflowRow{}, pgx.ErrNoRows
+	}
+	return sqlc.GetTriggerWithWorkflowRow{
+		ID:         tr.ID,
+		WorkflowID: tr.WorkflowID,
+		Type:       tr.Type,
+		Config:     tr.Config,
+		CreatedAt:  tr.CreatedAt,
+		IsEnabled:  f.workflows[tr.WorkflowID].IsEnabled,
+	}, nil
+}
+func (f *fakeQueries) RecordTriggerRejection(ctx context.Context, id string) error {
+	if f.rejected == nil {
+		f.rejected = make(map[string]int)
+	}
+	f.rejected[id]++
+	return f.err
+}
+func (f *fakeQueries) GetWorkflowRun(ctx context.Context, id string) (sqlc.GetWorkflowRunRow, error) {
+	if f.err != nil {
+		return sqlc.GetWorkflowRunRow{}, f.err
+	}
+	for _, run := range f.runs {
+		if run.ID == id {
+			return sqlc.GetWorkflowRunRow{ID: run.ID, WorkflowID: run.WorkflowID, Status: run.Status}, nil
+		}
+	}
+	return sqlc.GetWorkflowRunRow{}, pgx.ErrNoRows
+}
+
 func TestServiceCreateAndList(t *testing.T) {
 	fq := &fakeQueries{}
-	svc := &Service{queries: fq}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
 
 	ctx := context.Background()
-	created, err := svc.Create(ctx, "user-1", "My Workflow")
+	created, err := svc.Create(ctx, "user-1", "", "My Workflow")
 	if err != nil {
 		t.Fatalf("Create error: %v", err)
 	}
-	if created.Name != "My Workflow" || created.UserID != "user-1" {
+	if created.Name != "My Workflow" || created.UserID != "user-1" || created.OrgID != "org-1" {
 		t.Fatalf("unexpected created workflow: %+v", created)
 	}
 
@@ -326,7 +477,7 @@ func TestServiceCreateAndList(t *testing.T) {
 
 func TestServiceGetNotFound(t *testing.T) {
 	fq := &fakeQueries{workflows: make(map[string]sqlc.GetWorkflowRow)}
-	svc := &Service{queries: fq}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
 
 	_, err := svc.Get(context.Background(), "user-1", "missing")
 	if !errors.Is(err, ErrNotFound) {
@@ -336,7 +487,7 @@ func TestServiceGetNotFound(t *testing.T) {
 
 func TestServiceUpdateDeleteNotFound(t *testing.T) {
 	fq := &fakeQueries{workflows: make(map[string]sqlc.GetWorkflowRow)}
-	svc := &Service{queries: fq}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
 
 	_, err := svc.Update(context.Background(), "user-1", "missing", "Name", true)
 	if !errors.Is(err, ErrNotFound) {
@@ -350,9 +501,9 @@ func TestServiceUpdateDeleteNotFound(t *testing.T) {
 
 func TestServiceTriggersAndActions(t *testing.T) {
 	fq := &fakeQueries{workflows: map[string]sqlc.GetWorkflowRow{
-		"wf-1": {ID: "wf-1", UserID: "user-1"},
+		"wf-1": {ID: "wf-1", UserID: "user-1", OrgID: "org-1"},
 	}}
-	svc := &Service{queries: fq}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
 
 	ctx := context.Background()
 	tr, err := svc.CreateTrigger(ctx, "user-1", "wf-1", "webhook", []byte(`{}`))
@@ -366,11 +517,11 @@ func TestServiceTriggersAndActions(t *testing.T) {
 	if len(trs) != 1 {
 		t.Fatalf("expected 1 trigger, got %d", len(trs))
 	}
-	if _, err := svc.UpdateTrigger(ctx, "user-1", "wf-1", tr.ID, "cron", []byte(`{}`)); err != nil {
+	if _, err := svc.UpdateTrigger(ctx, "user-1", "wf-1", tr.ID, "cron", []byte(`{"cron_expr":"@hourly"}`)); err != nil {
 		t.Fatalf("UpdateTrigger error: %v", err)
 	}
 
-	ac, err := svc.CreateAction(ctx, "user-1", "wf-1", "http", 1, []byte(`{}`))
+	ac, err := svc.CreateAction(ctx, "user-1", "wf-1", "http", 1, []byte(`{"url":"https://example.com/hook"}`))
 	if err != nil {
 		t.Fatalf("CreateAction error: %v", err)
 	}
@@ -381,16 +532,136 @@ func TestServiceTriggersAndActions(t *testing.T) {
 	if len(acs) != 1 {
 		t.Fatalf("expected 1 action, got %d", len(acs))
 	}
-	if _, err := svc.UpdateAction(ctx, "user-1", "wf-1", ac.ID, "http", 2, []byte(`{}`)); err != nil {
+	if _, err := svc.UpdateAction(ctx, "user-1", "wf-1", ac.ID, "http", 2, []byte(`{"url":"https://example.com/hook","method":"PUT"}`)); err != nil {
 		t.Fatalf("UpdateAction error: %v", err)
 	}
 }
 
+func TestServiceRejectsInvalidConfigs(t *testing.T) {
+	fq := &fakeQueries{
+		workflows: map[string]sqlc.GetWorkflowRow{"wf-1": {ID: "wf-1", UserID: "user-1", OrgID: "org-1"}},
+		plugins:   []sqlc.ListPluginTypesRow{{Kind: "action", Type: "echo", Plugin: "echo-plugin"}},
+	}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
+	ctx := context.Background()
+
+	var verr *catalog.ValidationError
+	if _, err := svc.CreateTrigger(ctx, "user-1", "wf-1", "carrier-pigeon", nil); !errors.As(err, &verr) || verr.Fields[0].Field != "type" {
+		t.Fatalf("expected unknown type error, got %v", err)
+	}
+	if _, err := svc.CreateTrigger(ctx, "user-1", "wf-1", "cron", []byte(`{"cron_expr":"every day"}`)); !errors.As(err, &verr) || verr.Fields[0].Field != "config.cron_expr" {
+		t.Fatalf("expected cron_expr error, got %v", err)
+	}
+	// Trigger config checks reject what the schema lets through, e.g. an hmac secret without a header.
+	svc.SetTriggerConfigCheck("webhook", func(raw []byte) error { _, err := webhooks.ParseConfig(raw); return err })
+	if _, err := svc.CreateTrigger(ctx, "user-1", "wf-1", "webhook", []byte(`{"webhook_secret":"x"}`)); !errors.As(err, &verr) || verr.Fields[0].Field != "config" || !strings.Contains(verr.Fields[0].Message, "signature.header") {
+		t.Fatalf("expected the webhook config check to reject a missing header, got %v", err)
+	}
+	if _, err := svc.CreateAction(ctx, "user-1", "wf-1", "sql", 1, []byte(`garbage`)); !errors.As(err, &verr) {
+		t.Fatalf("expected invalid JSON error, got %v", err)
+	}
+	if len(fq.triggers) != 0 || len(fq.actions) != 0 {
+		t.Fatalf("invalid configs must not be stored")
+	}
+
+	// A non-member's request is still reported as not found before the config is looked at.
+	if _, err := svc.CreateTrigger(ctx, "user-2", "wf-1", "carrier-pigeon", nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// workflow_event triggers may only listen to workflows of the same organization.
+	otherID := "6f1c2b7e-1d2a-4a8e-9c43-2f0d7f3b9a10"
+	fq.workflows[otherID] = sqlc.GetWorkflowRow{ID: otherID, UserID: "user-2", OrgID: "org-2"}
+	if _, err := svc.CreateTrigger(ctx, "user-1", "wf-1", "workflow_event", []byte(`{"workflow_id":"`+otherID+`"}`)); !errors.As(err, &verr) || verr.Fields[0].Field != "config.workflow_id" {
+		t.Fatalf("expected workflow_id error for a workflow of another organization, got %v", err)
+	}
+	ownID := "0b6a3f0e-5c1d-4f7e-8a2b-9d4e6c8f1a23"
+	fq.workflows[ownID] = sqlc.GetWorkflowRow{ID: ownID, UserID: "user-1", OrgID: "org-1"}
+	evt, err := svc.CreateTrigger(ctx, "user-1", "wf-1", "workflow_event", []byte(`{"workflow_id":"`+ownID+`","status":"any"}`))
+	if err != nil {
+		t.Fatalf("expected workflow_event trigger on a workflow of the same organization, got %v", err)
+	}
+	if _, err := svc.UpdateTrigger(ctx, "user-1", "wf-1", evt.ID, "workflow_event", []byte(`{"workflow_id":"`+otherID+`"}`)); !errors.As(err, &verr) || verr.Fields[0].Field != "config.workflow_id" {
+		t.Fatalf("expected updates to check workflow_id too, got %v", err)
+	}
+
+	act, err := svc.CreateAction(ctx, "user-1", "wf-1", "echo", 1, nil)
+	if err != nil {
+		t.Fatalf("expected plugin action to be accepted, got %v", err)
+	}
+	if string(act.Config) != `{}` {
+		t.Fatalf("expected missing config to default to {}, got %s", act.Config)
+	}
+}
+
+func TestServiceOrganizationRoles(t *testing.T) {
+	const orgID = "3d5e7f90-1a2b-4c3d-8e4f-5a6b7c8d9e0f"
+	fq := &fakeQueries{
+		workflows: map[string]sqlc.GetWorkflowRow{
+			"wf-1": {ID: "wf-1", UserID: "owner", OrgID: orgID},
+		},
+		members: map[string]map[string]string{orgID: {
+			"owner":  orgs.RoleOwner,
+			"editor": orgs.RoleEditor,
+			"runner": orgs.RoleRunner,
+			"viewer": orgs.RoleViewer,
+		}},
+	}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
+	ctx := context.Background()
+
+	if _, err := svc.Get(ctx, "viewer", "wf-1"); err != nil {
+		t.Fatalf("expected viewers to see the workflow, got %v", err)
+	}
+	if _, err := svc.EnqueueRun(ctx, "viewer", "wf-1", "manual"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected viewers to be unable to run, got %v", err)
+	}
+	if _, err := svc.EnqueueRun(ctx, "runner", "wf-1", "manual"); err != nil {
+		t.Fatalf("expected runners to run, got %v", err)
+	}
+	if _, err := svc.ListTriggers(ctx, "viewer", "wf-1"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected viewers to be unable to list triggers and their secrets, got %v", err)
+	}
+	if _, err := svc.ListTriggers(ctx, "runner", "wf-1"); err != nil {
+		t.Fatalf("expected runners to list triggers, got %v", err)
+	}
+	if _, err := svc.Update(ctx, "runner", "wf-1", "Renamed", true); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected runners to be unable to edit, got %v", err)
+	}
+	if _, err := svc.CreateTrigger(ctx, "runner", "wf-1", "webhook", nil); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected runners to be unable to add triggers, got %v", err)
+	}
+	if _, err := svc.SigningSecret(ctx, "runner", "wf-1"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected runners to be unable to read the signing secret, got %v", err)
+	}
+	if wf, err := svc.Update(ctx, "editor", "wf-1", "Renamed", true); err != nil || wf.Name != "Renamed" {
+		t.Fatalf("unexpected workflow %+v, err %v", wf, err)
+	}
+
+	if _, err := svc.Create(ctx, "viewer", orgID, "wf"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected viewers to be unable to create workflows, got %v", err)
+	}
+	if wf, err := svc.Create(ctx, "editor", orgID, "wf"); err != nil || wf.OrgID != orgID {
+		t.Fatalf("unexpected workflow %+v, err %v", wf, err)
+	}
+	for _, id := range []string{"not-a-uuid", "0b6a3f0e-5c1d-4f7e-8a2b-9d4e6c8f1a23"} {
+		if _, err := svc.Create(ctx, "editor", id, "wf"); !errors.Is(err, ErrOrgNotFound) {
+			t.Fatalf("%s: expected ErrOrgNotFound, got %v", id, err)
+		}
+	}
+
+	// Without an organization of their own, a personal one is created.
+	wf, err := svc.Create(ctx, "runner", "", "wf")
+	if err != nil || wf.OrgID == "" || wf.OrgID == orgID || fq.members[wf.OrgID]["runner"] != orgs.RoleOwner {
+		t.Fatalf("expected a personal organization, got %+v (err %v)", wf, err)
+	}
+}
+
 func TestServiceRunEnqueueAndList(t *testing.T) {
 	fq := &fakeQueries{workflows: map[string]sqlc.GetWorkflowRow{
-		"wf-1": {ID: "wf-1", UserID: "user-1"},
+		"wf-1": {ID: "wf-1", UserID: "user-1", OrgID: "org-1"},
 	}}
-	svc := &Service{queries: fq}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
 
 	ctx := context.Background()
 	run, err := svc.EnqueueRun(ctx, "user-1", "wf-1", "manual")
@@ -408,3 +679,133 @@ func TestServiceRunEnqueueAndList(t *testing.T) {
 		t.Fatalf("expected 1 run, got %d", len(runs))
 	}
 }
+
+func TestServiceListAll(t *testing.T) {
+	fq := &fakeQueries{workflows: map[string]sqlc.GetWorkflowRow{
+		"wf-1": {ID: "wf-1", UserID: "user-1", OrgID: "org-1"},
+		"wf-2": {ID: "wf-2", UserID: "user-2", OrgID: "org-2"},
+	}}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
+	ctx := context.Background()
+
+	wfs, err := svc.ListAllWorkflows(ctx)
+	if err != nil || len(wfs) != 2 {
+		t.Fatalf("expected every user's workflows, got %d (err %v)", len(wfs), err)
+	}
+
+	for _, id := range []string{"wf-1", "wf-2", "wf-2"} {
+		wf := fq.workflows[id]
+		if _, err := svc.EnqueueRun(ctx, wf.UserID, id, "manual"); err != nil {
+			t.Fatalf("EnqueueRun error: %v", err)
+		}
+	}
+	runs, err := svc.ListAllRuns(ctx, RunFilter{})
+	if err != nil || len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d (err %v)", len(runs), err)
+	}
+	runs, _ = svc.ListAllRuns(ctx, RunFilter{WorkflowID: "wf-2", Status: "pending"})
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs of wf-2, got %d", len(runs))
+	}
+	runs, _ = svc.ListAllRuns(ctx, RunFilter{Limit: 1})
+	if len(runs) != 1 {
+		t.Fatalf("expected the limit to apply, got %d", len(runs))
+	}
+}
+
+func TestServiceSigningSecret(t *testing.T) {
+	fq := &fakeQueries{secrets: map[string]string{"wf-1": "s3cret"}}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "user-1", "", "wf"); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	secret, err := svc.SigningSecret(ctx, "user-1", "wf-1")
+	if err != nil || secret != "s3cret" {
+		t.Fatalf("unexpected secret %q, err %v", secret, err)
+	}
+	rotated, err := svc.RotateSigningSecret(ctx, "user-1", "wf-1")
+	if err != nil || rotated == secret {
+		t.Fatalf("expected a new secret, got %q, err %v", rotated, err)
+	}
+	if _, err := svc.SigningSecret(ctx, "user-2", "wf-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a non-member, got %v", err)
+	}
+}
+
+func TestServiceWebhookTrigger(t *testing.T) {
+	const (
+		hookID     = "6f1c2a9e-0d4b-4a57-9b8e-1c2d3e4f5a6b"
+		cronID     = "7a2d3b0f-1e5c-4b68-8c9f-2d3e4f5a6b7c"
+		disabledID = "8b3e4c1a-2f6d-4c79-9d0a-3e4f5a6b7c8d"
+	)
+	fq := &fakeQueries{
+		workflows: map[string]sqlc.GetWorkflowRow{
+			"wf-1": {ID: "wf-1", UserID: "user-1", OrgID: "org-1", IsEnabled: true},
+			"wf-2": {ID: "wf-2", UserID: "user-1", OrgID: "org-1"},
+		},
+		triggers: map[string]sqlc.GetTriggerRow{
+			hookID:     {ID: hookID, WorkflowID: "wf-1", Type: "webhook"},
+			cronID:     {ID: cronID, WorkflowID: "wf-1", Type: "cron"},
+			disabledID: {ID: disabledID, WorkflowID: "wf-2", Type: "webhook"},
+		},
+	}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
+	ctx := context.Background()
+
+	tr, err := svc.WebhookTrigger(ctx, hookID)
+	if err != nil || tr.WorkflowID != "wf-1" {
+		t.Fatalf("unexpected trigger %+v, err %v", tr, err)
+	}
+	for id, want := range map[string]error{
+		"not-a-uuid": ErrTriggerNotFound,
+		cronID:       ErrTriggerNotFound,
+		disabledID:   ErrWorkflowDisabled,
+	} {
+		if _, err := svc.WebhookTrigger(ctx, id); !errors.Is(err, want) {
+			t.Fatalf("%s: expected %v, got %v", id, want, err)
+		}
+	}
+
+	run, err := svc.EnqueueTriggeredRun(ctx, tr, []byte(`{"body":{}}`))
+	if err != nil || run.TriggerType != "webhook" {
+		t.Fatalf("unexpected run %+v, err %v", run, err)
+	}
+	got, err := svc.GetRun(ctx, run.ID)
+	if err != nil || got.Status != "pending" {
+		t.Fatalf("unexpected fetched run %+v, err %v", got, err)
+	}
+	if err := svc.RecordRejection(ctx, hookID); err != nil || fq.rejected[hookID] != 1 {
+		t.Fatalf("expected rejection to be recorded, got %v (err %v)", fq.rejected, err)
+	}
+	if _, err := svc.GetRun(ctx, "missing"); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestServiceEmailTrigger(t *testing.T) {
+	const (
+		mailID = "9c4f5d2b-3a7e-4d8a-8e1b-4f5a6b7c8d9e"
+		hookID = "6f1c2a9e-0d4b-4a57-9b8e-1c2d3e4f5a6b"
+	)
+	fq := &fakeQueries{
+		workflows: map[string]sqlc.GetWorkflowRow{
+			"wf-1": {ID: "wf-1", UserID: "user-1", OrgID: "org-1", IsEnabled: true},
+		},
+		triggers: map[string]sqlc.GetTriggerRow{
+			mailID: {ID: mailID, WorkflowID: "wf-1", Type: "email"},
+			hookID: {ID: hookID, WorkflowID: "wf-1", Type: "webhook"},
+		},
+	}
+	svc := &Service{queries: fq, catalog: catalog.New(fq)}
+	ctx := context.Background()
+
+	tr, err := svc.EmailTrigger(ctx, mailID)
+	if err != nil || tr.Type != TriggerTypeEmail {
+		t.Fatalf("unexpected trigger %+v, err %v", tr, err)
+	}
+	if _, err := svc.EmailTrigger(ctx, hookID); !errors.Is(err, ErrTriggerNotFound) {
+		t.Fatalf("expected ErrTriggerNotFound for a webhook trigger, got %v", err)
+	}
+}
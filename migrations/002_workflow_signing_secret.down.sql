@@ -0,0 +1 @@
+ALTER TABLE workflows DROP COLUMN signing_secret;
@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE workflows
+    ADD COLUMN signing_secret TEXT NOT NULL DEFAULT encode(gen_random_bytes(32), 'hex');
@@ -0,0 +1,7 @@
+DROP INDEX workflow_runs_parent_idx;
+
+ALTER TABLE workflow_runs
+    DROP COLUMN output,
+    DROP COLUMN input,
+    DROP COLUMN depth,
+    DROP COLUMN parent_run_id;
@@ -0,0 +1,7 @@
+ALTER TABLE workflow_runs
+    ADD COLUMN parent_run_id UUID REFERENCES workflow_runs(id) ON DELETE SET NULL,
+    ADD COLUMN depth         INT NOT NULL DEFAULT 0,
+    ADD COLUMN input         JSONB,
+    ADD COLUMN output        JSONB;
+
+CREATE INDEX workflow_runs_parent_idx ON workflow_runs(parent_run_id);
@@ -0,0 +1,3 @@
+ALTER TABLE triggers
+    DROP COLUMN last_rejected_at,
+    DROP COLUMN rejected_count;
@@ -0,0 +1,3 @@
+ALTER TABLE triggers
+    ADD COLUMN rejected_count   BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN last_rejected_at TIMESTAMPTZ DEFAULT NULL;
@@ -0,0 +1,5 @@
+DROP TRIGGER workflows_enabled_changed_notify ON workflows;
+
+DROP TRIGGER triggers_changed_notify ON triggers;
+
+DROP FUNCTION notify_triggers_changed();
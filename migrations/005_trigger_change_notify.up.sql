@@ -0,0 +1,15 @@
+-- The scheduler LISTENs on triggers_changed to reload cron schedules without polling.
+CREATE FUNCTION notify_triggers_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('triggers_changed', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER triggers_changed_notify
+    AFTER INSERT OR UPDATE OR DELETE ON triggers
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_triggers_changed();
+
+CREATE TRIGGER workflows_enabled_changed_notify
+    AFTER UPDATE OF is_enabled OR DELETE ON workflows
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_triggers_changed();
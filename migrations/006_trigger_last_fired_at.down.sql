@@ -0,0 +1,8 @@
+DROP TRIGGER triggers_changed_notify ON triggers;
+
+CREATE TRIGGER triggers_changed_notify
+    AFTER INSERT OR UPDATE OR DELETE ON triggers
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_triggers_changed();
+
+ALTER TABLE triggers
+    DROP COLUMN last_fired_at;
@@ -0,0 +1,9 @@
+ALTER TABLE triggers
+    ADD COLUMN last_fired_at TIMESTAMPTZ DEFAULT NULL;
+
+-- Recording a fire (or a webhook rejection) must not make every scheduler reload.
+DROP TRIGGER triggers_changed_notify ON triggers;
+
+CREATE TRIGGER triggers_changed_notify
+    AFTER INSERT OR UPDATE OF workflow_id, type, config OR DELETE ON triggers
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_triggers_changed();
@@ -0,0 +1 @@
+DROP TABLE plugin_types;
@@ -0,0 +1,10 @@
+-- Types contributed by worker plugins, published so the API can list and validate them.
+CREATE TABLE plugin_types (
+    kind            TEXT NOT NULL CHECK (kind IN ('trigger', 'action')),
+    type            TEXT NOT NULL,
+    plugin          TEXT NOT NULL,
+    description     TEXT NOT NULL DEFAULT '',
+    config_schema   JSONB DEFAULT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (kind, type)
+);
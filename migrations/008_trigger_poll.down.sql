@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS trigger_seen_items;
+
+ALTER TABLE triggers
+    DROP COLUMN last_polled_at;
@@ -0,0 +1,13 @@
+ALTER TABLE triggers
+    ADD COLUMN last_polled_at TIMESTAMPTZ DEFAULT NULL;
+
+-- Item IDs a poll trigger has already seen, so each new feed item starts exactly one run.
+CREATE TABLE trigger_seen_items (
+    trigger_id UUID NOT NULL REFERENCES triggers(id) ON DELETE CASCADE,
+    item_id TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (trigger_id, item_id)
+);
+
+CREATE INDEX trigger_seen_items_last_seen_at_idx ON trigger_seen_items (trigger_id, last_seen_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS triggers_workflow_event_idx;
+
+ALTER TABLE workflow_runs
+    DROP COLUMN chain_depth;
@@ -0,0 +1,5 @@
+-- How many workflow_event hops led to a run, so a cycle of chained workflows stops.
+ALTER TABLE workflow_runs
+    ADD COLUMN chain_depth INT NOT NULL DEFAULT 0;
+
+CREATE INDEX triggers_workflow_event_idx ON triggers ((config->>'workflow_id')) WHERE type = 'workflow_event';
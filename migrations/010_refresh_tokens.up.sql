@@ -0,0 +1,15 @@
+-- Opaque refresh tokens, stored as SHA-256 hashes. Every rotation adds a token to the same family;
+-- presenting a token that was already rotated out revokes the whole family.
+CREATE TABLE refresh_tokens (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id   UUID NOT NULL,
+    token_hash  TEXT NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    rotated_at  TIMESTAMPTZ DEFAULT NULL,
+    revoked_at  TIMESTAMPTZ DEFAULT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_idx ON refresh_tokens (user_id);
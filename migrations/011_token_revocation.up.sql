@@ -0,0 +1,13 @@
+-- Access tokens issued before tokens_valid_after are rejected ("log out everywhere").
+ALTER TABLE users
+    ADD COLUMN tokens_valid_after TIMESTAMPTZ DEFAULT NULL;
+
+-- Individually revoked access tokens (logout), kept until the token would have expired anyway.
+CREATE TABLE revoked_tokens (
+    jti         TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
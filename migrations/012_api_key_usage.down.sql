@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS api_keys_hashed_key_idx;
+
+ALTER TABLE api_keys
+    DROP COLUMN last_used_at,
+    DROP COLUMN expires_at,
+    DROP COLUMN key_prefix;
@@ -0,0 +1,6 @@
+ALTER TABLE api_keys
+    ADD COLUMN key_prefix TEXT NOT NULL DEFAULT '',
+    ADD COLUMN expires_at TIMESTAMPTZ DEFAULT NULL,
+    ADD COLUMN last_used_at TIMESTAMPTZ DEFAULT NULL;
+
+CREATE UNIQUE INDEX api_keys_hashed_key_idx ON api_keys (hashed_key);
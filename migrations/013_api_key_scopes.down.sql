@@ -0,0 +1,3 @@
+ALTER TABLE api_keys
+    DROP COLUMN workflow_ids,
+    DROP COLUMN scopes;
@@ -0,0 +1,4 @@
+-- Keys created before scopes existed keep full access.
+ALTER TABLE api_keys
+    ADD COLUMN scopes TEXT[] NOT NULL DEFAULT ARRAY['workflows:read', 'workflows:write', 'runs:trigger', 'runs:read'],
+    ADD COLUMN workflow_ids UUID[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Every user is a member unless promoted; admins may manage users and see every workflow.
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')),
+    ADD COLUMN disabled_at TIMESTAMPTZ DEFAULT NULL;
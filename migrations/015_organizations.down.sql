@@ -0,0 +1,16 @@
+-- Workflows whose creator is gone can't be given back to a user.
+DELETE FROM workflows WHERE user_id IS NULL;
+
+ALTER TABLE workflows
+    DROP CONSTRAINT workflows_user_id_fkey,
+    ADD CONSTRAINT workflows_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    ALTER COLUMN user_id SET NOT NULL;
+
+DROP INDEX IF EXISTS workflows_org_idx;
+
+ALTER TABLE workflows
+    DROP COLUMN org_id;
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,55 @@
+-- Workflows belong to an organization instead of a single user, so they outlive the people who
+-- created them. Members have one of four roles: owner > editor > runner > viewer.
+CREATE TABLE organizations (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE organization_members (
+    org_id      UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role        TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'runner', 'viewer')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_idx ON organization_members (user_id);
+
+-- Invitations are redeemed with an opaque token, stored as a SHA-256 hash, by the invited email.
+CREATE TABLE organization_invitations (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id      UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email       TEXT NOT NULL,
+    role        TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'runner', 'viewer')),
+    token_hash  TEXT NOT NULL UNIQUE,
+    invited_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ DEFAULT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX organization_invitations_org_idx ON organization_invitations (org_id);
+
+ALTER TABLE workflows
+    ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+
+-- Every user with workflows gets a personal organization (sharing the user's ID) that owns them.
+INSERT INTO organizations (id, name)
+SELECT DISTINCT user_id, 'Personal' FROM workflows;
+
+INSERT INTO organization_members (org_id, user_id, role)
+SELECT id, id, 'owner' FROM organizations;
+
+UPDATE workflows SET org_id = user_id;
+
+ALTER TABLE workflows
+    ALTER COLUMN org_id SET NOT NULL;
+
+CREATE INDEX workflows_org_idx ON workflows (org_id);
+
+-- user_id now only records who created a workflow; deleting the user keeps it.
+ALTER TABLE workflows
+    ALTER COLUMN user_id DROP NOT NULL,
+    DROP CONSTRAINT workflows_user_id_fkey,
+    ADD CONSTRAINT workflows_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
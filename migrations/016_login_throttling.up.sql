@@ -0,0 +1,22 @@
+-- Failed logins are counted per account (lowercased email, whether or not it exists) and per client
+-- IP. Too many failures within a window delay further attempts until blocked_until.
+CREATE TABLE login_throttles (
+    scope             TEXT NOT NULL CHECK (scope IN ('account', 'ip')),
+    key               TEXT NOT NULL,
+    failures          INT NOT NULL DEFAULT 0,
+    window_started_at TIMESTAMPTZ NOT NULL,
+    blocked_until     TIMESTAMPTZ DEFAULT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+-- Security-relevant events such as lockouts, for admins to review.
+CREATE TABLE audit_events (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event       TEXT NOT NULL,
+    user_id     UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip          TEXT NOT NULL DEFAULT '',
+    details     JSONB NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_created_idx ON audit_events (created_at DESC);
@@ -0,0 +1,30 @@
+-- Optional TOTP two-factor authentication. A secret is pending until enabled_at is set by
+-- confirming a code; last_step is the newest time step accepted so a code can't be replayed.
+CREATE TABLE user_totp (
+    user_id     UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret      TEXT NOT NULL,
+    enabled_at  TIMESTAMPTZ DEFAULT NULL,
+    last_step   BIGINT NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Single-use recovery codes, stored as SHA-256 hashes. They go away with the TOTP enrollment.
+CREATE TABLE recovery_codes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES user_totp(user_id) ON DELETE CASCADE,
+    code_hash   TEXT NOT NULL,
+    used_at     TIMESTAMPTZ DEFAULT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- Short-lived tokens handed out after a correct password when the second factor is still missing.
+CREATE TABLE login_challenges (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  TEXT NOT NULL UNIQUE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX login_challenges_expires_idx ON login_challenges (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE login_challenges
+    DROP COLUMN IF EXISTS new_password_hash,
+    DROP COLUMN IF EXISTS old_password_hash;
@@ -0,0 +1,5 @@
+-- A password whose stored hash is outdated is re-hashed at login; with two-factor authentication
+-- the new hash waits on the challenge until the second factor is passed.
+ALTER TABLE login_challenges
+    ADD COLUMN old_password_hash TEXT NOT NULL DEFAULT '',
+    ADD COLUMN new_password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,116 @@
+// Package signature signs and verifies PotaFlow webhook requests.
+//
+// PotaFlow sends outgoing requests from signed HTTP actions with a header of the form
+//
+//	PotaFlow-Signature: t=1700000000,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd
+//
+// where t is the Unix timestamp of the request and v1 is the hex HMAC-SHA256 of "<t>.<body>"
+// keyed with the workflow's signing secret. Receivers call Verify (or VerifyRequest) with the
+// same secret; more than one v1 value may be present while a secret is being rotated.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the name of the HTTP header carrying the signature.
+const Header = "PotaFlow-Signature"
+
+// DefaultTolerance is the maximum accepted age of a signed request.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("missing signature header")
+	ErrMalformedHeader  = errors.New("malformed signature header")
+	ErrInvalidSignature = errors.New("signature does not match")
+	ErrExpired          = errors.New("signature timestamp outside tolerance")
+)
+
+// Sign returns the header value for body sent at ts.
+func Sign(secret []byte, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(compute(secret, unix, body))
+}
+
+// Verify checks header against body. Requests whose timestamp is more than tolerance away
+// from now are rejected to prevent replays; a zero tolerance uses DefaultTolerance.
+func Verify(secret []byte, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	var (
+		unix       string
+		signatures [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedHeader
+		}
+		switch key {
+		case "t":
+			unix = val
+		case "v1":
+			sig, err := hex.DecodeString(val)
+			if err != nil {
+				return ErrMalformedHeader
+			}
+			signatures = append(signatures, sig)
+		}
+	}
+	if unix == "" || len(signatures) == 0 {
+		return ErrMalformedHeader
+	}
+
+	sec, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return ErrMalformedHeader
+	}
+	age := now.Sub(time.Unix(sec, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrExpired
+	}
+
+	expected := compute(secret, unix, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyRequest verifies r against secret and returns its body. The body is restored on r so
+// later handlers can still read it.
+func VerifyRequest(r *http.Request, secret []byte, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := Verify(secret, r.Header.Get(Header), body, tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func compute(secret []byte, unix string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unix))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return mac.Sum(nil)
+}
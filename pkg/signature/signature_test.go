@@ -0,0 +1,77 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte(`{"hello":"world"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign(secret, now, body)
+
+	if err := Verify(secret, header, body, time.Minute, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("Verify error: %v", err)
+	}
+	if err := Verify(secret, header, []byte(`{"hello":"mallory"}`), time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for tampered body, got %v", err)
+	}
+	if err := Verify([]byte("other"), header, body, time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for wrong secret, got %v", err)
+	}
+	if err := Verify(secret, header, body, time.Minute, now.Add(2*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired for stale request, got %v", err)
+	}
+}
+
+func TestVerifyHeaderFormats(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte("payload")
+	now := time.Unix(1700000000, 0)
+	valid := Sign(secret, now, body)
+	rotated := Sign([]byte("old-secret"), now, body) + "," + valid[len("t=1700000000,"):]
+
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{name: "missing", header: "", want: ErrMissingSignature},
+		{name: "garbage", header: "nonsense", want: ErrMalformedHeader},
+		{name: "no timestamp", header: "v1=abcd", want: ErrMalformedHeader},
+		{name: "bad hex", header: "t=1700000000,v1=zz", want: ErrMalformedHeader},
+		{name: "multiple signatures during rotation", header: rotated, want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Verify(secret, tc.header, body, 0, now); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestRestoresBody(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte(`{"a":1}`)
+	req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+	req.Header.Set(Header, Sign(secret, time.Now(), body))
+
+	got, err := VerifyRequest(req, secret, 0)
+	if err != nil {
+		t.Fatalf("VerifyRequest error: %v", err)
+	}
+	if string(got) != string(body) {
+		t.Fatalf("unexpected body: %s", got)
+	}
+	again, _ := io.ReadAll(req.Body)
+	if string(again) != string(body) {
+		t.Fatalf("expected body to be readable again, got %s", again)
+	}
+}